@@ -0,0 +1,153 @@
+// Package accounts — регистрация, вход и профили пользователей. Профили кэшируются,
+// чтобы просмотр чужих профилей не нагружал базу данных.
+package accounts
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "time"
+
+    "chatter-hub-server/cache"
+    "chatter-hub-server/config"
+    "chatter-hub-server/repository"
+
+    "github.com/google/uuid"
+    "golang.org/x/crypto/bcrypt"
+)
+
+const profileCacheTTL = 10 * time.Minute // Время жизни профиля в кэше
+
+var (
+    ErrLoginRequired      = errors.New("необходимо указать email или username")
+    ErrInvalidCredentials = errors.New("неверные учетные данные")
+    ErrInactive           = errors.New("аккаунт деактивирован")
+)
+
+// Service управляет учетными записями пользователей
+type Service struct {
+    users repository.UserRepository
+    cache cache.Cache
+}
+
+// NewService создает сервис учетных записей
+func NewService(users repository.UserRepository, cache cache.Cache) *Service {
+    return &Service{users: users, cache: cache}
+}
+
+// Register создает пользователя с новым ID; пароль сохраняется в виде хеша
+func (s *Service) Register(ctx context.Context, user *config.User) error {
+    hashedPassword, err := HashPassword(user.Password)
+    if err != nil {
+        return err
+    }
+    user.Password = hashedPassword
+    user.ID = uuid.New().String()
+    return s.users.Create(ctx, user)
+}
+
+// Authenticate находит пользователя по email или username и проверяет пароль
+func (s *Service) Authenticate(ctx context.Context, email, username, password string) (*config.User, error) {
+    var user *config.User
+    var err error
+    switch {
+    case email != "":
+        user, err = s.users.GetByEmail(ctx, email)
+    case username != "":
+        user, err = s.users.GetByUsername(ctx, username)
+    default:
+        return nil, ErrLoginRequired
+    }
+    if errors.Is(err, repository.ErrNotFound) {
+        return nil, ErrInvalidCredentials
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+        return nil, ErrInvalidCredentials
+    }
+    if !user.IsActive {
+        return nil, ErrInactive
+    }
+    return user, nil
+}
+
+// Get возвращает учетную запись из базы данных, минуя кэш
+func (s *Service) Get(ctx context.Context, id string) (*config.User, error) {
+    return s.users.Get(ctx, id)
+}
+
+// Profile возвращает профиль пользователя из кэша или из базы данных
+func (s *Service) Profile(ctx context.Context, id string) (config.PrivateUser, error) {
+    var profile config.PrivateUser
+
+    if cached, err := s.cache.Get(ctx, profileCacheKey(id)); err == nil {
+        if err := json.Unmarshal(cached, &profile); err == nil {
+            return profile, nil
+        }
+    }
+
+    user, err := s.users.Get(ctx, id)
+    if err != nil {
+        return profile, err
+    }
+    profile = user.Private()
+
+    // Кэшируем профиль без хеша пароля
+    if encoded, err := json.Marshal(profile); err == nil {
+        s.cache.Set(ctx, profileCacheKey(id), encoded, profileCacheTTL)
+    }
+
+    return profile, nil
+}
+
+// Update изменяет переданные поля учетной записи и сбрасывает кэш профиля
+func (s *Service) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+    if len(updates) > 0 {
+        if err := s.users.Update(ctx, id, updates); err != nil {
+            return err
+        }
+    }
+    s.invalidate(ctx, id)
+    return nil
+}
+
+// SetActive активирует или деактивирует учетную запись
+func (s *Service) SetActive(ctx context.Context, id string, active bool) error {
+    return s.Update(ctx, id, map[string]interface{}{"is_active": active})
+}
+
+// Delete удаляет учетную запись
+func (s *Service) Delete(ctx context.Context, id string) error {
+    if err := s.users.Delete(ctx, id); err != nil {
+        return err
+    }
+    s.invalidate(ctx, id)
+    return nil
+}
+
+// Search ищет пользователей, которых может найти search.ViewerID
+func (s *Service) Search(ctx context.Context, search repository.UserSearch) ([]config.User, error) {
+    return s.users.Search(ctx, search)
+}
+
+// HashPassword возвращает bcrypt-хеш пароля
+func HashPassword(password string) (string, error) {
+    hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+    if err != nil {
+        return "", err
+    }
+    return string(hashed), nil
+}
+
+// invalidate удаляет профиль из кэша. Ошибки игнорируются: профиль устареет по истечении TTL.
+func (s *Service) invalidate(ctx context.Context, id string) {
+    s.cache.Delete(ctx, profileCacheKey(id))
+}
+
+// profileCacheKey возвращает ключ кэша для профиля пользователя
+func profileCacheKey(id string) string {
+    return "user:" + id
+}
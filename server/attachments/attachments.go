@@ -0,0 +1,418 @@
+package attachments
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "mime/multipart"
+    "net/http"
+    "net/url"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/imaging"
+    "chatter-hub-server/metrics"
+    "chatter-hub-server/quota"
+    "chatter-hub-server/repository"
+    "chatter-hub-server/storage"
+
+    "github.com/gabriel-vasile/mimetype"
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+var (
+    ErrEmptyFile       = errors.New("файл пуст")
+    ErrTooLarge        = errors.New("размер файла превышает допустимый для этого типа")
+    ErrUnsupportedType = errors.New("недопустимый тип файла")
+    ErrUnavailable     = repository.ErrAttachmentUnavailable
+    ErrObjectMissing   = errors.New("файл не загружен в хранилище")
+    ErrSizeMismatch    = errors.New("размер загруженного файла не совпадает с заявленным")
+    ErrChecksumInvalid = errors.New("контрольная сумма загруженного файла не совпадает с заявленной")
+    ErrInfected        = repository.ErrAttachmentInfected
+    ErrTooLong         = errors.New("длительность голосового сообщения превышает допустимую")
+)
+
+// limits хранит максимальные размеры вложений по типам; задается через Init
+var limits = config.AttachmentConfig{
+    MaxImageSize:     10 << 20,
+    MaxVideoSize:     100 << 20,
+    MaxAudioSize:     20 << 20,
+    MaxDocumentSize:  50 << 20,
+    MaxVoiceDuration: 600,
+}
+
+// Контейнеры, которые mimetype определяет как видео, но в которых часто записывают голос
+var audioContainers = map[string]string{
+    "video/webm":      "audio/webm",
+    "application/ogg": "audio/ogg",
+}
+
+// Service загружает, проверяет и подписывает вложения. Метаданные хранятся в базе данных db,
+// файлы — в хранилище store.
+type Service struct {
+    db    *gorm.DB
+    store storage.BlobStore
+}
+
+// NewService создает сервис вложений поверх базы данных db и хранилища store
+func NewService(db *gorm.DB, store storage.BlobStore) *Service {
+    return &Service{db: db, store: store}
+}
+
+// Init задает ограничения вложений из конфигурации и регистрирует обработчики фоновых задач.
+// База данных и хранилище должны быть инициализированы заранее.
+func Init(cfg *config.Config) {
+    limits = cfg.Attachments
+    registerJobs(NewService(config.DB, storage.Store))
+}
+
+// UploadOptions задает параметры загрузки вложения
+type UploadOptions struct {
+    Kind   string // Ожидаемый тип вложения; пустая строка — любой тип
+    Bucket string // Бакет хранилища; по умолчанию config.AttachmentBucket
+}
+
+// Upload проверяет файл, загружает его в хранилище и сохраняет метаданные вложения.
+// Тип файла определяется по содержимому, заголовок Content-Type клиента не используется.
+// Размер файла резервируется в квоте владельца до загрузки.
+func (s *Service) Upload(ctx context.Context, ownerID string, header *multipart.FileHeader, opts UploadOptions) (*config.Attachment, error) {
+    if header.Size <= 0 {
+        return nil, ErrEmptyFile
+    }
+
+    file, err := header.Open()
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+
+    mime, err := mimetype.DetectReader(file)
+    if err != nil {
+        return nil, err
+    }
+    contentType, kind, err := detect(mime.String(), opts.Kind)
+    if err != nil {
+        return nil, err
+    }
+    if header.Size > MaxSize(kind) {
+        return nil, ErrTooLarge
+    }
+
+    attachment := &config.Attachment{
+        ID:          uuid.New().String(),
+        OwnerID:     ownerID,
+        Kind:        kind,
+        Bucket:      opts.Bucket,
+        FileName:    sanitizeFileName(header.Filename),
+        ContentType: contentType,
+        Size:        header.Size,
+        CreatedAt:   time.Now(),
+    }
+    if attachment.Bucket == "" {
+        attachment.Bucket = config.AttachmentBucket
+    }
+    attachment.ObjectKey = ownerID + "/" + attachment.ID
+
+    if kind == config.AttachmentImage {
+        if _, err := file.Seek(0, io.SeekStart); err != nil {
+            return nil, err
+        }
+        width, height, err := imaging.Dimensions(file)
+        if err != nil {
+            return nil, ErrUnsupportedType
+        }
+        attachment.Width, attachment.Height = width, height
+    }
+
+    if _, err := file.Seek(0, io.SeekStart); err != nil {
+        return nil, err
+    }
+
+    if err := quota.Reserve(s.db.WithContext(ctx), ownerID, header.Size); err != nil {
+        return nil, err
+    }
+
+    // Контрольную сумму считаем во время загрузки, не читая файл повторно
+    hash := sha256.New()
+    err = s.store.Put(ctx, attachment.Bucket, attachment.ObjectKey, io.TeeReader(file, hash), header.Size, contentType)
+    // Резерв квоты возвращаем и тогда, когда загрузку прервал клиент
+    release := context.WithoutCancel(ctx)
+    if err != nil {
+        quota.Adjust(s.db.WithContext(release), ownerID, -header.Size)
+        return nil, err
+    }
+    attachment.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+    if err := s.save(ctx, attachment, nil); err != nil {
+        s.store.Delete(release, attachment.Bucket, attachment.ObjectKey)
+        quota.Adjust(s.db.WithContext(release), ownerID, -header.Size)
+        return nil, err
+    }
+    metrics.UploadBytes.WithLabelValues(attachment.Kind).Add(float64(attachment.Size))
+
+    if err := s.resolve(ctx, attachment); err != nil {
+        return nil, err
+    }
+    return attachment, nil
+}
+
+// ObjectSpec описывает объект, загруженный в хранилище в обход сервера
+type ObjectSpec struct {
+    OwnerID   string
+    Kind      string // Ожидаемый тип вложения; пустая строка — любой тип
+    Bucket    string
+    ObjectKey string
+    FileName  string
+    Size      int64  // Ожидаемый размер объекта
+    Checksum  string // Ожидаемый SHA-256; пустая строка — не проверяется
+    // Claim, если задан, выполняется в транзакции сохранения вложения, например чтобы
+    // отметить загрузку завершенной. Ошибка Claim отменяет сохранение и учет в квоте,
+    // объект при этом остается в хранилище.
+    Claim func(tx *gorm.DB, attachment *config.Attachment) error
+}
+
+// CreateFromObject проверяет объект, загруженный клиентом напрямую в хранилище, и сохраняет
+// метаданные вложения. Размер и контрольная сумма сверяются с ожидаемыми, тип определяется
+// по содержимому, размер учитывается в квоте владельца в той же транзакции, что и сохранение.
+// При ошибке проверки или превышении квоты объект удаляется из хранилища.
+func (s *Service) CreateFromObject(ctx context.Context, spec ObjectSpec) (*config.Attachment, error) {
+    attachment, err := s.inspectObject(ctx, spec)
+    if err == nil {
+        err = s.save(ctx, attachment, func(tx *gorm.DB) error {
+            if err := quota.Reserve(tx, spec.OwnerID, attachment.Size); err != nil {
+                return err
+            }
+            if spec.Claim != nil {
+                return spec.Claim(tx, attachment)
+            }
+            return nil
+        })
+    }
+    if err != nil {
+        if !errors.Is(err, ErrObjectMissing) && HTTPStatus(err) != http.StatusInternalServerError {
+            s.store.Delete(ctx, spec.Bucket, spec.ObjectKey)
+        }
+        return nil, err
+    }
+    metrics.UploadBytes.WithLabelValues(attachment.Kind).Add(float64(attachment.Size))
+
+    if err := s.resolve(ctx, attachment); err != nil {
+        return nil, err
+    }
+    return attachment, nil
+}
+
+// inspectObject читает объект из хранилища один раз: определяет тип и размеры изображения
+// по началу файла и одновременно считает контрольную сумму
+func (s *Service) inspectObject(ctx context.Context, spec ObjectSpec) (*config.Attachment, error) {
+    object, info, err := s.store.Get(ctx, spec.Bucket, spec.ObjectKey)
+    if err != nil {
+        if errors.Is(err, storage.ErrNotFound) {
+            return nil, ErrObjectMissing
+        }
+        return nil, err
+    }
+    defer object.Close()
+    if info.Size == 0 {
+        return nil, ErrEmptyFile
+    }
+    if info.Size != spec.Size {
+        return nil, ErrSizeMismatch
+    }
+
+    hash := sha256.New()
+    reader := io.TeeReader(object, hash)
+
+    head := make([]byte, 3072)
+    n, err := io.ReadFull(reader, head)
+    if err != nil && err != io.ErrUnexpectedEOF {
+        return nil, err
+    }
+    head = head[:n]
+
+    contentType, kind, err := detect(mimetype.Detect(head).String(), spec.Kind)
+    if err != nil {
+        return nil, err
+    }
+    if info.Size > MaxSize(kind) {
+        return nil, ErrTooLarge
+    }
+
+    attachment := &config.Attachment{
+        ID:          uuid.New().String(),
+        OwnerID:     spec.OwnerID,
+        Kind:        kind,
+        Bucket:      spec.Bucket,
+        ObjectKey:   spec.ObjectKey,
+        FileName:    sanitizeFileName(spec.FileName),
+        ContentType: contentType,
+        Size:        info.Size,
+        CreatedAt:   time.Now(),
+    }
+
+    if kind == config.AttachmentImage {
+        width, height, err := imaging.Dimensions(io.MultiReader(bytes.NewReader(head), reader))
+        if err != nil {
+            return nil, ErrUnsupportedType
+        }
+        attachment.Width, attachment.Height = width, height
+    }
+
+    // Дочитываем оставшуюся часть файла для контрольной суммы
+    if _, err := io.Copy(io.Discard, reader); err != nil {
+        return nil, err
+    }
+    attachment.Checksum = hex.EncodeToString(hash.Sum(nil))
+    if spec.Checksum != "" && !strings.EqualFold(spec.Checksum, attachment.Checksum) {
+        return nil, ErrChecksumInvalid
+    }
+
+    return attachment, nil
+}
+
+// KindOf возвращает тип вложения для заявленного клиентом MIME-типа
+func KindOf(contentType string) string {
+    _, kind := classify(contentType)
+    return kind
+}
+
+// BucketFor возвращает бакет для файлов, загружаемых клиентом напрямую. Аудио хранится
+// рядом с голосовыми сообщениями, чтобы на него могло сослаться голосовое сообщение.
+func BucketFor(kind string) string {
+    if kind == config.AttachmentAudio {
+        return config.VoiceBucket
+    }
+    return config.AttachmentBucket
+}
+
+// CheckDeclared проверяет заявленные клиентом тип и размер файла и остаток квоты владельца
+// до начала загрузки. Окончательная проверка выполняется по содержимому после загрузки.
+func (s *Service) CheckDeclared(ctx context.Context, ownerID, kind, contentType string, size int64) error {
+    if size <= 0 {
+        return ErrEmptyFile
+    }
+    if _, _, err := detect(contentType, kind); err != nil {
+        return err
+    }
+    if size > MaxSize(kind) {
+        return ErrTooLarge
+    }
+    return quota.Check(s.db.WithContext(ctx), ownerID, size)
+}
+
+// PresignURL возвращает временную подписанную ссылку на скачивание вложения.
+// Проверка прав доступа к вложению — ответственность вызывающего кода.
+func (s *Service) PresignURL(ctx context.Context, attachment *config.Attachment) (string, error) {
+    params := url.Values{}
+    params.Set("response-content-type", attachment.ContentType)
+    params.Set("response-content-disposition", ContentDisposition(attachment))
+    return s.store.PresignGet(ctx, attachment.Bucket, attachment.ObjectKey, storage.PresignTTL(), params)
+}
+
+// ContentDisposition возвращает значение заголовка Content-Disposition для вложения:
+// медиафайлы показываются в браузере, документы — только скачиваются
+func ContentDisposition(attachment *config.Attachment) string {
+    disposition := "attachment"
+    if attachment.Kind != config.AttachmentDocument {
+        disposition = "inline"
+    }
+    return fmt.Sprintf("%s; filename*=UTF-8''%s", disposition, url.PathEscape(attachment.FileName))
+}
+
+// MaxSize возвращает максимальный размер вложения указанного типа
+func MaxSize(kind string) int64 {
+    switch kind {
+    case config.AttachmentImage:
+        return limits.MaxImageSize
+    case config.AttachmentVideo:
+        return limits.MaxVideoSize
+    case config.AttachmentAudio:
+        return limits.MaxAudioSize
+    default:
+        return limits.MaxDocumentSize
+    }
+}
+
+// MaxUploadSize возвращает наибольший допустимый размер вложения среди всех типов
+func MaxUploadSize() int64 {
+    var max int64
+    for _, kind := range []string{config.AttachmentImage, config.AttachmentVideo, config.AttachmentAudio, config.AttachmentDocument} {
+        if size := MaxSize(kind); size > max {
+            max = size
+        }
+    }
+    return max
+}
+
+// MaxVoiceDuration возвращает максимальную длительность голосового сообщения
+func MaxVoiceDuration() time.Duration {
+    return time.Duration(limits.MaxVoiceDuration) * time.Second
+}
+
+// HTTPStatus возвращает HTTP-статус ответа для ошибки загрузки или привязки вложения
+func HTTPStatus(err error) int {
+    switch {
+    case errors.Is(err, ErrEmptyFile), errors.Is(err, ErrUnavailable), errors.Is(err, ErrObjectMissing),
+        errors.Is(err, ErrSizeMismatch), errors.Is(err, ErrChecksumInvalid), errors.Is(err, ErrInfected):
+        return http.StatusBadRequest
+    case errors.Is(err, ErrTooLarge), errors.Is(err, ErrTooLong), errors.Is(err, quota.ErrExceeded):
+        return http.StatusRequestEntityTooLarge
+    case errors.Is(err, ErrUnsupportedType):
+        return http.StatusUnsupportedMediaType
+    default:
+        return http.StatusInternalServerError
+    }
+}
+
+// detect возвращает MIME-тип и тип вложения и проверяет, что он совпадает с ожидаемым.
+// Для ожидаемого аудио контейнеры WebM и Ogg считаются аудиофайлами.
+func detect(detected, expectedKind string) (string, string, error) {
+    contentType, kind := classify(detected)
+    if expectedKind == config.AttachmentAudio {
+        if container, ok := audioContainers[contentType]; ok {
+            contentType, kind = container, config.AttachmentAudio
+        }
+    }
+    if expectedKind != "" && expectedKind != kind {
+        return "", "", ErrUnsupportedType
+    }
+    return contentType, kind, nil
+}
+
+// classify возвращает MIME-тип без параметров и тип вложения для него
+func classify(detected string) (string, string) {
+    contentType := detected
+    if i := strings.IndexByte(contentType, ';'); i >= 0 {
+        contentType = contentType[:i]
+    }
+
+    switch {
+    case contentType == "image/jpeg", contentType == "image/png", contentType == "image/gif", contentType == "image/webp":
+        return contentType, config.AttachmentImage
+    case strings.HasPrefix(contentType, "video/"):
+        return contentType, config.AttachmentVideo
+    case strings.HasPrefix(contentType, "audio/"):
+        return contentType, config.AttachmentAudio
+    default:
+        return contentType, config.AttachmentDocument
+    }
+}
+
+// sanitizeFileName оставляет только имя файла без пути и ограничивает его длину
+func sanitizeFileName(name string) string {
+    name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+    if name == "." || name == "/" {
+        return "file"
+    }
+    if runes := []rune(name); len(runes) > 255 {
+        name = string(runes[:255])
+    }
+    return name
+}
@@ -0,0 +1,324 @@
+package attachments
+
+import (
+    "bytes"
+    "context"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "io"
+    "path/filepath"
+    "strings"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/imaging"
+    "chatter-hub-server/jobs"
+    "chatter-hub-server/logging"
+    "chatter-hub-server/notify"
+    "chatter-hub-server/quota"
+    "chatter-hub-server/repository"
+    "chatter-hub-server/scan"
+    "chatter-hub-server/storage"
+
+    "gorm.io/gorm"
+)
+
+// ImageJobType — тип фоновой задачи обработки изображения-вложения
+const ImageJobType = "attachments.image"
+
+// EventProcessed — уведомление о завершении обработки изображения
+const EventProcessed = "attachment.processed"
+
+// Размеры вариантов изображения по большей стороне
+var variantSizes = []struct {
+    name    string
+    maxSide int
+    quality int
+}{
+    {config.VariantThumbnail, 320, 80},
+    {config.VariantPreview, 1280, 85},
+}
+
+// imagePayload — данные задачи обработки изображения
+type imagePayload struct {
+    AttachmentID string `json:"attachment_id"`
+}
+
+// registerJobs регистрирует обработчики проверки и обработки вложений в очереди фоновых задач
+func registerJobs(s *Service) {
+    jobs.Register(ScanJobType, s.handleScan, jobs.Options{MaxAttempts: 5, Timeout: 5 * time.Minute})
+    jobs.Register(ImageJobType, s.handleImage, jobs.Options{MaxAttempts: 3, Timeout: 5 * time.Minute})
+}
+
+// save сохраняет метаданные вложения и в той же транзакции ставит его в очередь на проверку
+// или, если проверка отключена, на обработку изображения — задача не потеряется
+// и не появится без вложения. Ошибка inTx, если он задан, отменяет всю транзакцию.
+func (s *Service) save(ctx context.Context, attachment *config.Attachment, inTx func(tx *gorm.DB) error) error {
+    if scan.Enabled() {
+        attachment.ScanStatus = config.ScanPending
+    }
+    if attachment.Kind == config.AttachmentImage {
+        attachment.ProcessingStatus = config.ProcessingPending
+    }
+    return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(attachment).Error; err != nil {
+            return err
+        }
+        if inTx != nil {
+            if err := inTx(tx); err != nil {
+                return err
+            }
+        }
+        var err error
+        switch {
+        case attachment.ScanStatus == config.ScanPending:
+            _, err = jobs.EnqueueTx(tx, ScanJobType, scanPayload{AttachmentID: attachment.ID}, jobs.EnqueueOptions{})
+        case attachment.Kind == config.AttachmentImage:
+            _, err = jobs.EnqueueTx(tx, ImageJobType, imagePayload{AttachmentID: attachment.ID}, jobs.EnqueueOptions{})
+        }
+        return err
+    })
+}
+
+func (s *Service) handleImage(ctx context.Context, job *config.Job) error {
+    var payload imagePayload
+    if err := jobs.DecodePayload(job, &payload); err != nil {
+        return err
+    }
+
+    var attachment config.Attachment
+    if err := s.db.WithContext(ctx).First(&attachment, "id = ?", payload.AttachmentID).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return nil
+        }
+        return err
+    }
+    if attachment.ProcessingStatus != config.ProcessingPending {
+        return nil
+    }
+
+    err := s.ProcessImage(ctx, &attachment)
+    if errors.Is(err, imaging.ErrUnsupportedFormat) || errors.Is(err, imaging.ErrTooLarge) {
+        // Файл не удастся перекодировать и при повторе: он остается доступным только владельцу
+        logging.FromContext(ctx).Warn("Изображение не может быть обработано", "attachment_id", attachment.ID, "error", err)
+        s.db.WithContext(ctx).Model(&attachment).Update("processing_status", config.ProcessingFailed)
+        s.publishProcessed(ctx, &attachment)
+        return nil
+    }
+    if err != nil && jobs.IsFinalAttempt(job) {
+        s.db.WithContext(context.WithoutCancel(ctx)).Model(&attachment).Update("processing_status", config.ProcessingFailed)
+        s.publishProcessed(ctx, &attachment)
+    }
+    return err
+}
+
+// ProcessImage перекодирует изображение без метаданных, заменяя им оригинал в хранилище,
+// создает уменьшенные варианты и blurhash
+func (s *Service) ProcessImage(ctx context.Context, attachment *config.Attachment) error {
+    object, _, err := s.store.Get(ctx, attachment.Bucket, attachment.ObjectKey)
+    if err != nil {
+        return err
+    }
+    data, err := io.ReadAll(io.LimitReader(object, MaxSize(config.AttachmentImage)+1))
+    object.Close()
+    if err != nil {
+        return err
+    }
+
+    sanitized, err := imaging.Sanitize(data)
+    if err != nil {
+        return err
+    }
+
+    variants := make([]config.AttachmentVariant, 0, len(variantSizes))
+    for _, size := range variantSizes {
+        bounds := sanitized.Image.Bounds()
+        // Превью нужно только для изображений крупнее эскиза
+        if size.name != config.VariantThumbnail && bounds.Dx() <= size.maxSide && bounds.Dy() <= size.maxSide {
+            continue
+        }
+        variant, err := s.putVariant(ctx, attachment, sanitized, size.name, size.maxSide, size.quality)
+        if err != nil {
+            return err
+        }
+        variants = append(variants, *variant)
+    }
+
+    // Оригинал перезаписывается только после успешного создания вариантов: при повторе
+    // задачи исходный файл еще доступен
+    err = s.store.Put(ctx, attachment.Bucket, attachment.ObjectKey, bytes.NewReader(sanitized.Data),
+        int64(len(sanitized.Data)), sanitized.ContentType)
+    if err != nil {
+        return err
+    }
+
+    // Перекодированный файл и варианты учитываются в объеме владельца без проверки квоты:
+    // их создает сервер, а не пользователь
+    delta := int64(len(sanitized.Data)) - attachment.Size
+    for _, variant := range variants {
+        delta += variant.Size
+    }
+
+    sum := sha256.Sum256(sanitized.Data)
+    attachment.ContentType = sanitized.ContentType
+    attachment.FileName = replaceExtension(attachment.FileName, sanitized.ContentType)
+    attachment.Size = int64(len(sanitized.Data))
+    attachment.Checksum = hex.EncodeToString(sum[:])
+    attachment.Width, attachment.Height = sanitized.Width, sanitized.Height
+    attachment.Blurhash = imaging.Blurhash(sanitized.Image)
+    attachment.ProcessingStatus = config.ProcessingReady
+
+    err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        // Варианты от прерванной попытки заменяются новыми
+        var previous int64
+        err := tx.Model(&config.AttachmentVariant{}).Where("attachment_id = ?", attachment.ID).
+            Select("COALESCE(SUM(size), 0)").Scan(&previous).Error
+        if err != nil {
+            return err
+        }
+        if err := tx.Where("attachment_id = ?", attachment.ID).Delete(&config.AttachmentVariant{}).Error; err != nil {
+            return err
+        }
+        if err := quota.Adjust(tx, attachment.OwnerID, delta-previous); err != nil {
+            return err
+        }
+        if len(variants) > 0 {
+            if err := tx.Create(&variants).Error; err != nil {
+                return err
+            }
+        }
+        return tx.Model(attachment).Select("content_type", "file_name", "size", "checksum", "width", "height",
+            "blurhash", "processing_status").Updates(attachment).Error
+    })
+    if err != nil {
+        return err
+    }
+
+    attachment.Variants = variants
+    s.publishProcessed(ctx, attachment)
+    return nil
+}
+
+// putVariant уменьшает изображение, кодирует его в JPEG и сохраняет рядом с оригиналом
+func (s *Service) putVariant(ctx context.Context, attachment *config.Attachment, sanitized *imaging.Sanitized, name string, maxSide, quality int) (*config.AttachmentVariant, error) {
+    img := imaging.Fit(sanitized.Image, maxSide)
+    var buf bytes.Buffer
+    if err := imaging.EncodeJPEG(&buf, img, quality); err != nil {
+        return nil, err
+    }
+
+    variant := &config.AttachmentVariant{
+        AttachmentID: attachment.ID,
+        Name:         name,
+        Bucket:       attachment.Bucket,
+        ObjectKey:    attachment.ObjectKey + "." + name,
+        ContentType:  "image/jpeg",
+        Width:        img.Bounds().Dx(),
+        Height:       img.Bounds().Dy(),
+        Size:         int64(buf.Len()),
+        CreatedAt:    time.Now(),
+    }
+    err := s.store.Put(ctx, variant.Bucket, variant.ObjectKey, &buf, variant.Size, variant.ContentType)
+    if err != nil {
+        return nil, err
+    }
+    return variant, nil
+}
+
+// Ready сообщает, можно ли показывать вложение участникам переписки: только после
+// антивирусной проверки, а изображения — еще и после удаления метаданных
+func Ready(attachment *config.Attachment) bool {
+    if attachment.ScanStatus != "" && attachment.ScanStatus != config.ScanClean {
+        return false
+    }
+    return attachment.Kind != config.AttachmentImage ||
+        attachment.ProcessingStatus == "" || attachment.ProcessingStatus == config.ProcessingReady
+}
+
+// Sign подставляет во вложения подписанные ссылки на файл и загруженные ранее варианты.
+// Вложения, еще не доступные собеседнику, остаются без ссылок и вариантов.
+func (s *Service) Sign(ctx context.Context, list []config.Attachment) error {
+    for i := range list {
+        attachment := &list[i]
+        attachment.URL = ""
+        if !Ready(attachment) {
+            attachment.Variants = nil
+            continue
+        }
+        var err error
+        if attachment.URL, err = s.PresignURL(ctx, attachment); err != nil {
+            return err
+        }
+        for j := range attachment.Variants {
+            variant := &attachment.Variants[j]
+            variant.URL, err = s.store.PresignGet(ctx, variant.Bucket, variant.ObjectKey, storage.PresignTTL(), nil)
+            if err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// resolve загружает варианты изображения и подставляет подписанные ссылки
+func (s *Service) resolve(ctx context.Context, attachment *config.Attachment) error {
+    list := []config.Attachment{*attachment}
+    if err := repository.NewFiles(s.db).LoadVariants(ctx, list); err != nil {
+        return err
+    }
+    if err := s.Sign(ctx, list); err != nil {
+        return err
+    }
+    *attachment = list[0]
+    return nil
+}
+
+// publishProcessed уведомляет владельца и, если вложение уже отправлено, собеседника
+// о завершении обработки изображения
+func (s *Service) publishProcessed(ctx context.Context, attachment *config.Attachment) {
+    payload := *attachment
+    if err := s.resolve(ctx, &payload); err != nil {
+        logging.FromContext(ctx).Error("Ошибка получения ссылок на вложение", "attachment_id", attachment.ID, "error", err)
+        return
+    }
+
+    event := notify.Event{Type: EventProcessed, Payload: payload}
+    for _, userID := range s.participants(ctx, attachment) {
+        notify.Publish(ctx, userID, event)
+    }
+}
+
+// participants возвращает владельца вложения и собеседника, если вложение привязано к сообщению
+func (s *Service) participants(ctx context.Context, attachment *config.Attachment) []string {
+    users := []string{attachment.OwnerID}
+    if attachment.MessageID == nil {
+        return users
+    }
+
+    var message struct {
+        SenderID   string
+        ReceiverID string
+    }
+    model := messageModel(attachment.MessageType)
+    if model == nil {
+        return users
+    }
+    if err := s.db.WithContext(ctx).Model(model).Select("sender_id, receiver_id").Where("id = ?", *attachment.MessageID).Scan(&message).Error; err != nil {
+        return users
+    }
+    if message.ReceiverID != "" && message.ReceiverID != attachment.OwnerID {
+        users = append(users, message.ReceiverID)
+    }
+    return users
+}
+
+// replaceExtension меняет расширение имени файла, если формат изменился при перекодировании
+func replaceExtension(name, contentType string) string {
+    ext := map[string]string{"image/jpeg": ".jpg", "image/png": ".png", "image/gif": ".gif"}[contentType]
+    current := strings.ToLower(filepath.Ext(name))
+    if ext == "" || current == ext || (ext == ".jpg" && current == ".jpeg") {
+        return name
+    }
+    return strings.TrimSuffix(name, filepath.Ext(name)) + ext
+}
@@ -0,0 +1,199 @@
+package attachments
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/jobs"
+    "chatter-hub-server/logging"
+    "chatter-hub-server/notify"
+    "chatter-hub-server/quota"
+    "chatter-hub-server/scan"
+    "chatter-hub-server/storage"
+
+    "gorm.io/gorm"
+)
+
+// ScanJobType — тип фоновой задачи антивирусной проверки вложения
+const ScanJobType = "attachments.scan"
+
+// EventInfected — уведомление об удалении зараженного вложения
+const EventInfected = "attachment.infected"
+
+// InfectedEvent — содержимое уведомления EventInfected
+type InfectedEvent struct {
+    AttachmentID string `json:"attachment_id"`
+    MessageType  string `json:"message_type,omitempty"`
+    MessageID    *uint  `json:"message_id,omitempty"`
+    Reason       string `json:"reason"`
+}
+
+// scanPayload — данные задачи проверки вложения
+type scanPayload struct {
+    AttachmentID string `json:"attachment_id"`
+}
+
+func (s *Service) handleScan(ctx context.Context, job *config.Job) error {
+    var payload scanPayload
+    if err := jobs.DecodePayload(job, &payload); err != nil {
+        return err
+    }
+
+    var attachment config.Attachment
+    if err := s.db.WithContext(ctx).First(&attachment, "id = ?", payload.AttachmentID).Error; err != nil {
+        if errors.Is(err, gorm.ErrRecordNotFound) {
+            return nil
+        }
+        return err
+    }
+    if attachment.ScanStatus != config.ScanPending {
+        return nil
+    }
+
+    result, err := s.scanObject(ctx, &attachment)
+    if err != nil {
+        // Непроверенный файл остается в карантине: он по-прежнему доступен только владельцу
+        if jobs.IsFinalAttempt(job) {
+            s.db.WithContext(context.WithoutCancel(ctx)).Model(&attachment).Update("scan_status", config.ScanFailed)
+            s.publishProcessed(ctx, &attachment)
+        }
+        return err
+    }
+    if result.Infected {
+        return s.quarantine(ctx, &attachment, result.Signature)
+    }
+
+    // Изображение обрабатывается только после проверки, чтобы не перекодировать зараженный файл
+    err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Model(&attachment).Update("scan_status", config.ScanClean).Error; err != nil {
+            return err
+        }
+        if attachment.Kind != config.AttachmentImage {
+            return nil
+        }
+        _, err := jobs.EnqueueTx(tx, ImageJobType, imagePayload{AttachmentID: attachment.ID}, jobs.EnqueueOptions{})
+        return err
+    })
+    if err != nil {
+        return err
+    }
+    if attachment.Kind != config.AttachmentImage {
+        s.publishProcessed(ctx, &attachment)
+    }
+    return nil
+}
+
+func (s *Service) scanObject(ctx context.Context, attachment *config.Attachment) (*scan.Result, error) {
+    object, _, err := s.store.Get(ctx, attachment.Bucket, attachment.ObjectKey)
+    if err != nil {
+        return nil, err
+    }
+    defer object.Close()
+    return scan.Scan(ctx, object)
+}
+
+// quarantine удаляет зараженный файл и его варианты, блокирует сообщение, к которому он привязан,
+// и сообщает участникам переписки причину
+func (s *Service) quarantine(ctx context.Context, attachment *config.Attachment, signature string) error {
+    freed, err := s.deleteObjects(ctx, attachment)
+    if err != nil {
+        return err
+    }
+
+    reason := "Во вложении обнаружено вредоносное содержимое: " + signature
+    err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        err := tx.Model(attachment).Updates(map[string]interface{}{
+            "scan_status":    config.ScanInfected,
+            "scan_signature": signature,
+            "blurhash":       "",
+        }).Error
+        if err != nil {
+            return err
+        }
+        if err := quota.Adjust(tx, attachment.OwnerID, -freed); err != nil {
+            return err
+        }
+        // Перечитываем вложение: его могли привязать к сообщению во время проверки
+        if err := tx.First(attachment, "id = ?", attachment.ID).Error; err != nil {
+            return err
+        }
+        if attachment.MessageID == nil {
+            return nil
+        }
+        model := messageModel(attachment.MessageType)
+        if model == nil {
+            return nil
+        }
+        return tx.Model(model).Where("id = ?", *attachment.MessageID).
+            Updates(map[string]interface{}{"blocked": true, "block_reason": reason}).Error
+    })
+    if err != nil {
+        return err
+    }
+
+    logging.FromContext(ctx).Warn("Зараженное вложение удалено", "attachment_id", attachment.ID, "owner_id", attachment.OwnerID, "signature", signature)
+    event := notify.Event{Type: EventInfected, CreatedAt: time.Now(), Payload: InfectedEvent{
+        AttachmentID: attachment.ID,
+        MessageType:  attachment.MessageType,
+        MessageID:    attachment.MessageID,
+        Reason:       reason,
+    }}
+    for _, userID := range s.participants(ctx, attachment) {
+        notify.Publish(ctx, userID, event)
+    }
+    return nil
+}
+
+// deleteObjects удаляет из хранилища файл вложения и его варианты и возвращает освобожденный объем
+func (s *Service) deleteObjects(ctx context.Context, attachment *config.Attachment) (int64, error) {
+    var variants []config.AttachmentVariant
+    db := s.db.WithContext(ctx)
+    if err := db.Where("attachment_id = ?", attachment.ID).Find(&variants).Error; err != nil {
+        return 0, err
+    }
+    freed := attachment.Size
+    for _, variant := range variants {
+        if err := s.deleteObject(ctx, variant.Bucket, variant.ObjectKey); err != nil {
+            return 0, err
+        }
+        freed += variant.Size
+    }
+    if err := db.Where("attachment_id = ?", attachment.ID).Delete(&config.AttachmentVariant{}).Error; err != nil {
+        return 0, err
+    }
+    return freed, s.deleteObject(ctx, attachment.Bucket, attachment.ObjectKey)
+}
+
+func (s *Service) deleteObject(ctx context.Context, bucket, key string) error {
+    err := s.store.Delete(ctx, bucket, key)
+    if err != nil && !errors.Is(err, storage.ErrNotFound) {
+        return err
+    }
+    return nil
+}
+
+// Quarantined возвращает ID вложений из list, которые еще не доступны собеседнику:
+// не прошли проверку или обработку
+func Quarantined(list []config.Attachment) map[string]bool {
+    result := make(map[string]bool)
+    for i := range list {
+        if !Ready(&list[i]) {
+            result[list[i].ID] = true
+        }
+    }
+    return result
+}
+
+// messageModel возвращает модель сообщения указанного типа
+func messageModel(messageType string) interface{} {
+    switch messageType {
+    case config.MessageTypeText:
+        return &config.TextMessage{}
+    case config.MessageTypeVoice:
+        return &config.VoiceMessage{}
+    default:
+        return nil
+    }
+}
@@ -0,0 +1,110 @@
+// Package audio разбирает аудиофайлы голосовых сообщений (WAV, MP3, Ogg, WebM) без декодирования:
+// определяет длительность, кодек, частоту дискретизации и строит упрощенную форму волны.
+package audio
+
+import (
+    "bufio"
+    "bytes"
+    "errors"
+    "io"
+    "math"
+    "time"
+)
+
+// WaveformSize — количество точек формы волны
+const WaveformSize = 100
+
+var (
+    ErrUnsupportedFormat = errors.New("неподдерживаемый аудиоформат")
+    ErrInvalid           = errors.New("файл не является корректной аудиозаписью")
+)
+
+// Info содержит метаданные аудиофайла
+type Info struct {
+    Format     string // Контейнер: wav, mp3, ogg, webm
+    Codec      string // Кодек: pcm, mp3, opus, vorbis...
+    Duration   time.Duration
+    SampleRate int
+    Channels   int
+    // Амплитуда в WaveformSize точках, нормированная к 0–255. Для сжатых форматов
+    // строится по размеру пакетов или усилению кадров, а не по декодированному сигналу.
+    Waveform []byte
+}
+
+// Parse читает аудиофайл целиком и возвращает его метаданные. Если формат не распознан,
+// возвращает ErrUnsupportedFormat, если файл поврежден или не содержит звука — ErrInvalid.
+func Parse(r io.Reader) (*Info, error) {
+    br := bufio.NewReaderSize(r, 64<<10)
+    head, _ := br.Peek(12)
+
+    var info *Info
+    var err error
+    switch {
+    case len(head) >= 12 && string(head[:4]) == "RIFF" && string(head[8:12]) == "WAVE":
+        info, err = parseWAV(br)
+    case len(head) >= 4 && string(head[:4]) == "OggS":
+        info, err = parseOgg(br)
+    case len(head) >= 4 && bytes.Equal(head[:4], ebmlMagic):
+        info, err = parseWebM(br)
+    case len(head) >= 3 && string(head[:3]) == "ID3", len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0:
+        info, err = parseMP3(br)
+    default:
+        return nil, ErrUnsupportedFormat
+    }
+    if err != nil {
+        return nil, err
+    }
+    if info.Duration <= 0 || info.SampleRate <= 0 || info.Channels <= 0 {
+        return nil, ErrInvalid
+    }
+    return info, nil
+}
+
+// downsample сводит последовательность амплитуд к WaveformSize точкам, беря максимум в каждом интервале
+func downsample(values []float64) []byte {
+    if len(values) == 0 {
+        return make([]byte, WaveformSize)
+    }
+
+    peaks := make([]float64, WaveformSize)
+    for i := range peaks {
+        start := i * len(values) / WaveformSize
+        end := (i + 1) * len(values) / WaveformSize
+        if end <= start {
+            end = start + 1
+        }
+        for _, value := range values[start:end] {
+            if value > peaks[i] {
+                peaks[i] = value
+            }
+        }
+    }
+    return normalize(peaks)
+}
+
+// normalize приводит амплитуды к диапазону 0–255 относительно максимальной
+func normalize(peaks []float64) []byte {
+    var max float64
+    for _, peak := range peaks {
+        if peak > max {
+            max = peak
+        }
+    }
+
+    waveform := make([]byte, len(peaks))
+    if max == 0 {
+        return waveform
+    }
+    for i, peak := range peaks {
+        waveform[i] = byte(math.Round(peak / max * 255))
+    }
+    return waveform
+}
+
+// invalid возвращает ErrInvalid вместо ошибок неожиданного конца файла
+func invalid(err error) error {
+    if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+        return ErrInvalid
+    }
+    return err
+}
@@ -0,0 +1,282 @@
+package audio
+
+import (
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "math"
+    "reflect"
+    "testing"
+    "time"
+)
+
+// pcmFormat возвращает блок "fmt " без заголовка для целочисленных отсчетов
+func pcmFormat(channels, sampleRate, bits int) []byte {
+    buf := make([]byte, 16)
+    blockAlign := channels * bits / 8
+    binary.LittleEndian.PutUint16(buf[0:], wavFormatPCM)
+    binary.LittleEndian.PutUint16(buf[2:], uint16(channels))
+    binary.LittleEndian.PutUint32(buf[4:], uint32(sampleRate))
+    binary.LittleEndian.PutUint32(buf[8:], uint32(sampleRate*blockAlign))
+    binary.LittleEndian.PutUint16(buf[12:], uint16(blockAlign))
+    binary.LittleEndian.PutUint16(buf[14:], uint16(bits))
+    return buf
+}
+
+// riffChunk возвращает блок RIFF с выравниванием по четной границе
+func riffChunk(id string, data []byte) []byte {
+    chunk := append([]byte(id), 0, 0, 0, 0)
+    binary.LittleEndian.PutUint32(chunk[4:], uint32(len(data)))
+    chunk = append(chunk, data...)
+    if len(data)%2 == 1 {
+        chunk = append(chunk, 0)
+    }
+    return chunk
+}
+
+// wavFile собирает файл WAV из блоков
+func wavFile(chunks ...[]byte) []byte {
+    body := []byte("WAVE")
+    for _, chunk := range chunks {
+        body = append(body, chunk...)
+    }
+    return riffChunk("RIFF", body)
+}
+
+// pcm16 возвращает frames отсчетов синусоиды в 16-битном моно
+func pcm16(frames int) []byte {
+    data := make([]byte, 2*frames)
+    for i := 0; i < frames; i++ {
+        binary.LittleEndian.PutUint16(data[2*i:], uint16(int16(10000*math.Sin(float64(i)/10))))
+    }
+    return data
+}
+
+// oggPage собирает страницу Ogg с пакетами целиком; контрольная сумма не проверяется разбором
+func oggPage(granule int64, packets ...[]byte) []byte {
+    var segments, body []byte
+    for _, packet := range packets {
+        for size := len(packet); ; size -= 255 {
+            if size < 255 {
+                segments = append(segments, byte(size))
+                break
+            }
+            segments = append(segments, 255)
+        }
+        body = append(body, packet...)
+    }
+    header := make([]byte, 27)
+    copy(header, "OggS")
+    binary.LittleEndian.PutUint64(header[6:], uint64(granule))
+    binary.LittleEndian.PutUint32(header[14:], 1)
+    header[26] = byte(len(segments))
+    return append(append(header, segments...), body...)
+}
+
+// opusHead возвращает заголовок идентификации Opus
+func opusHead(channels int, preSkip uint16, sampleRate uint32) []byte {
+    head := make([]byte, 19)
+    copy(head, "OpusHead")
+    head[8] = 1
+    head[9] = byte(channels)
+    binary.LittleEndian.PutUint16(head[10:], preSkip)
+    binary.LittleEndian.PutUint32(head[12:], sampleRate)
+    return head
+}
+
+// oggOpusFile собирает запись Opus с аудиостраницами, заканчивающимися на позициях granules
+func oggOpusFile(granules ...int64) []byte {
+    file := oggPage(0, opusHead(1, 312, 16000))
+    file = append(file, oggPage(0, []byte("OpusTags"))...)
+    for _, granule := range granules {
+        file = append(file, oggPage(granule, bytes.Repeat([]byte{1}, 300), bytes.Repeat([]byte{2}, 40))...)
+    }
+    return file
+}
+
+// mp3FrameLength — длина кадра MPEG-1 Layer III 128 кбит/с 44,1 кГц без дополнения
+const mp3FrameLength = 144 * 128000 / 44100
+
+// mp3Frames возвращает n кадров MPEG-1 Layer III 128 кбит/с 44,1 кГц моно с нулевым звуком
+func mp3Frames(n int) []byte {
+    var file []byte
+    for i := 0; i < n; i++ {
+        frame := make([]byte, mp3FrameLength)
+        copy(frame, []byte{0xFF, 0xFB, 0x90, 0xC0})
+        file = append(file, frame...)
+    }
+    return file
+}
+
+// ebml собирает элемент EBML; размер записывается восемью байтами
+func ebml(id uint64, children ...[]byte) []byte {
+    var element []byte
+    for shift := 24; shift >= 0; shift -= 8 {
+        if b := byte(id >> shift); b != 0 || len(element) > 0 {
+            element = append(element, b)
+        }
+    }
+    var payload []byte
+    for _, child := range children {
+        payload = append(payload, child...)
+    }
+    size := make([]byte, 8)
+    binary.BigEndian.PutUint64(size, uint64(len(payload)))
+    size[0] = 0x01
+    return append(append(element, size...), payload...)
+}
+
+func ebmlUint(id, value uint64) []byte {
+    return ebml(id, []byte{byte(value >> 24), byte(value >> 16), byte(value >> 8), byte(value)})
+}
+
+func ebmlFloat(id uint64, value float64) []byte {
+    buf := make([]byte, 8)
+    binary.BigEndian.PutUint64(buf, math.Float64bits(value))
+    return ebml(id, buf)
+}
+
+// simpleBlock возвращает блок дорожки 1 со смещением timecode относительно кластера
+func simpleBlock(timecode int16) []byte {
+    payload := []byte{0x81, byte(uint16(timecode) >> 8), byte(timecode), 0x80}
+    return ebml(simpleBlockID, append(payload, bytes.Repeat([]byte{7}, 50)...))
+}
+
+// webmFile собирает запись WebM с одной дорожкой trackType; duration в миллисекундах, 0 — не указана
+func webmFile(docType string, trackType uint64, duration float64) []byte {
+    info := [][]byte{ebmlUint(timecodeScaleID, 1000000)}
+    if duration > 0 {
+        info = append(info, ebmlFloat(durationID, duration))
+    }
+    track := ebml(trackEntryID,
+        ebmlUint(trackNumberID, 1),
+        ebmlUint(trackTypeID, trackType),
+        ebml(codecID, []byte("A_OPUS")),
+        ebml(audioID, ebmlFloat(samplingFreqID, 48000), ebmlUint(channelsID, 2)),
+    )
+    cluster := ebml(clusterID, ebmlUint(clusterTimecodeID, 0), simpleBlock(0), simpleBlock(20), simpleBlock(40))
+    return append(
+        ebml(ebmlHeaderID, ebml(ebmlDocTypeID, []byte(docType))),
+        ebml(segmentID, ebml(infoID, info...), ebml(tracksID, track), cluster)...,
+    )
+}
+
+func TestParse(t *testing.T) {
+    validOgg := oggOpusFile(24000+312, 48000+312)
+    validMP3 := mp3Frames(3)
+
+    tests := []struct {
+        name    string
+        data    []byte
+        want    *Info // Ожидаемые метаданные без формы волны; nil — ожидается ошибка
+        wantErr error
+    }{
+        {
+            name: "WAV",
+            data: wavFile(riffChunk("fmt ", pcmFormat(1, 8000, 16)), riffChunk("LIST", []byte("odd")), riffChunk("data", pcm16(800))),
+            want: &Info{Format: "wav", Codec: "pcm", Duration: 100 * time.Millisecond, SampleRate: 8000, Channels: 1},
+        },
+        {
+            name: "WAV с обрезанными данными",
+            data: wavFile(riffChunk("fmt ", pcmFormat(1, 8000, 16)), riffChunk("data", pcm16(800)))[:44+800],
+            want: &Info{Format: "wav", Codec: "pcm", Duration: 50 * time.Millisecond, SampleRate: 8000, Channels: 1},
+        },
+        {name: "WAV без блока данных", data: wavFile(riffChunk("fmt ", pcmFormat(1, 8000, 16))), wantErr: ErrInvalid},
+        {name: "WAV с данными до формата", data: wavFile(riffChunk("data", pcm16(800)), riffChunk("fmt ", pcmFormat(1, 8000, 16))), wantErr: ErrInvalid},
+        {name: "WAV с коротким блоком формата", data: wavFile(riffChunk("fmt ", pcmFormat(1, 8000, 16)[:8]), riffChunk("data", pcm16(800))), wantErr: ErrInvalid},
+        {name: "WAV с 12-битными отсчетами", data: wavFile(riffChunk("fmt ", pcmFormat(1, 8000, 12)), riffChunk("data", pcm16(800))), wantErr: ErrUnsupportedFormat},
+        {name: "WAV без отсчетов", data: wavFile(riffChunk("fmt ", pcmFormat(1, 8000, 16)), riffChunk("data", nil)), wantErr: ErrInvalid},
+        {name: "WAV с обрезанным заголовком", data: []byte("RIFF\x00\x00\x00\x00WAVEfm"), wantErr: ErrInvalid},
+
+        {
+            name: "Ogg Opus",
+            data: validOgg,
+            want: &Info{Format: "ogg", Codec: "opus", Duration: time.Second, SampleRate: 16000, Channels: 1},
+        },
+        {
+            name: "Ogg с обрезанной последней страницей",
+            data: validOgg[:len(validOgg)-10],
+            want: &Info{Format: "ogg", Codec: "opus", Duration: 500 * time.Millisecond, SampleRate: 16000, Channels: 1},
+        },
+        {name: "Ogg без аудиостраниц", data: oggOpusFile(), wantErr: ErrInvalid},
+        {name: "Ogg с обрезанной первой страницей", data: validOgg[:20], wantErr: ErrInvalid},
+        {name: "Ogg с неизвестным кодеком", data: oggPage(0, []byte("\x80theora-header-packet")), wantErr: ErrUnsupportedFormat},
+
+        {
+            name: "MP3",
+            data: validMP3,
+            want: &Info{Format: "mp3", Codec: "mp3", Duration: 3 * 1152 * time.Second / 44100, SampleRate: 44100, Channels: 1},
+        },
+        {
+            name: "MP3 с тегом ID3v2 и обрезанным последним кадром",
+            data: append([]byte("ID3\x03\x00\x00\x00\x00\x00\x04abcd"), validMP3[:len(validMP3)-100]...),
+            want: &Info{Format: "mp3", Codec: "mp3", Duration: 2 * 1152 * time.Second / 44100, SampleRate: 44100, Channels: 1},
+        },
+        {name: "MP3 из одного кадра", data: mp3Frames(1), wantErr: ErrInvalid},
+        {name: "MP3 с обрезанным тегом ID3v2", data: []byte("ID3\x03\x00\x00\x00\x00\x10\x00short"), wantErr: ErrInvalid},
+        {name: "MP3 без кадров", data: append([]byte{0xFF, 0xE0}, make([]byte, mp3MaxJunk+10)...), wantErr: ErrInvalid},
+
+        {
+            name: "WebM",
+            data: webmFile("webm", webmTrackAudio, 1000),
+            want: &Info{Format: "webm", Codec: "opus", Duration: time.Second, SampleRate: 48000, Channels: 2},
+        },
+        {
+            name: "WebM без длительности",
+            data: webmFile("webm", webmTrackAudio, 0),
+            want: &Info{Format: "webm", Codec: "opus", Duration: 60 * time.Millisecond, SampleRate: 48000, Channels: 2},
+        },
+        {name: "WebM без звуковой дорожки", data: webmFile("webm", 1, 1000), wantErr: ErrInvalid},
+        {name: "WebM другого типа документа", data: webmFile("mkv3d", webmTrackAudio, 1000), wantErr: ErrUnsupportedFormat},
+        {name: "WebM с обрезанным заголовком EBML", data: webmFile("webm", webmTrackAudio, 1000)[:14], wantErr: ErrInvalid},
+        {name: "WebM с некорректным числом EBML", data: append(ebml(ebmlHeaderID, ebml(ebmlDocTypeID, []byte("webm"))), 0, 0, 0), wantErr: ErrInvalid},
+
+        {name: "пустой файл", data: nil, wantErr: ErrUnsupportedFormat},
+        {name: "неизвестный формат", data: []byte("not an audio file"), wantErr: ErrUnsupportedFormat},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            info, err := Parse(bytes.NewReader(tt.data))
+            if tt.want == nil {
+                if !errors.Is(err, tt.wantErr) {
+                    t.Fatalf("ошибка %v, ожидалась %v", err, tt.wantErr)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if len(info.Waveform) != WaveformSize {
+                t.Fatalf("форма волны из %d точек, ожидалось %d", len(info.Waveform), WaveformSize)
+            }
+            info.Waveform = nil
+            if !reflect.DeepEqual(info, tt.want) {
+                t.Fatalf("метаданные %+v, ожидалось %+v", *info, *tt.want)
+            }
+        })
+    }
+}
+
+// TestParseTruncated проверяет, что файлы, обрезанные в любом месте, не приводят к панике
+// и другим ошибкам, кроме ErrInvalid и ErrUnsupportedFormat
+func TestParseTruncated(t *testing.T) {
+    tests := []struct {
+        name string
+        data []byte
+    }{
+        {name: "WAV", data: wavFile(riffChunk("fmt ", pcmFormat(2, 8000, 16)), riffChunk("data", pcm16(400)))},
+        {name: "Ogg", data: oggOpusFile(24000, 48000)},
+        {name: "MP3", data: mp3Frames(3)},
+        {name: "WebM", data: webmFile("webm", webmTrackAudio, 0)},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            for n := 0; n < len(tt.data); n++ {
+                _, err := Parse(bytes.NewReader(tt.data[:n]))
+                if err != nil && !errors.Is(err, ErrInvalid) && !errors.Is(err, ErrUnsupportedFormat) {
+                    t.Fatalf("первые %d байт: ошибка %v", n, err)
+                }
+            }
+        })
+    }
+}
@@ -0,0 +1,244 @@
+package audio
+
+import (
+    "bufio"
+    "time"
+)
+
+// mp3Bitrates — битрейт в кбит/с по индексу: [MPEG-1 или MPEG-2/2.5][слой I, II, III][индекс]
+var mp3Bitrates = [2][3][16]int{
+    {
+        {0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0},
+        {0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0},
+        {0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0},
+    },
+    {
+        {0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0},
+        {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+        {0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0},
+    },
+}
+
+// mp3SampleRates — частота дискретизации по индексу для MPEG-1, MPEG-2 и MPEG-2.5
+var mp3SampleRates = [3][3]int{
+    {44100, 48000, 32000},
+    {22050, 24000, 16000},
+    {11025, 12000, 8000},
+}
+
+// mp3MaxJunk ограничивает количество байтов, пропускаемых в поисках первого кадра
+const mp3MaxJunk = 64 << 10
+
+// mp3Frame — разобранный заголовок кадра MPEG Audio
+type mp3Frame struct {
+    mpeg1      bool
+    layer      int // 1, 2 или 3
+    crc        bool
+    mono       bool
+    sampleRate int
+    samples    int // Количество отсчетов в кадре
+    length     int // Длина кадра в байтах вместе с заголовком
+}
+
+// parseMP3Header разбирает 4-байтовый заголовок кадра; false — это не заголовок кадра
+func parseMP3Header(h []byte) (mp3Frame, bool) {
+    if len(h) < 4 || h[0] != 0xFF || h[1]&0xE0 != 0xE0 {
+        return mp3Frame{}, false
+    }
+    version := (h[1] >> 3) & 3 // 0 — MPEG-2.5, 2 — MPEG-2, 3 — MPEG-1
+    layerBits := (h[1] >> 1) & 3
+    bitrateIndex := h[2] >> 4
+    rateIndex := (h[2] >> 2) & 3
+    if version == 1 || layerBits == 0 || bitrateIndex == 0 || bitrateIndex == 15 || rateIndex == 3 {
+        return mp3Frame{}, false
+    }
+
+    frame := mp3Frame{
+        mpeg1: version == 3,
+        layer: 4 - int(layerBits),
+        crc:   h[1]&1 == 0,
+        mono:  h[3]>>6 == 3,
+    }
+    table := 1
+    rateTable := 1
+    switch version {
+    case 3:
+        table, rateTable = 0, 0
+    case 0:
+        rateTable = 2
+    }
+    bitrate := mp3Bitrates[table][frame.layer-1][bitrateIndex] * 1000
+    frame.sampleRate = mp3SampleRates[rateTable][rateIndex]
+    padding := int(h[2]>>1) & 1
+
+    switch {
+    case frame.layer == 1:
+        frame.samples = 384
+        frame.length = (12*bitrate/frame.sampleRate + padding) * 4
+    case frame.layer == 2 || frame.mpeg1:
+        frame.samples = 1152
+        frame.length = 144*bitrate/frame.sampleRate + padding
+    default:
+        frame.samples = 576
+        frame.length = 72*bitrate/frame.sampleRate + padding
+    }
+    return frame, frame.length > 4
+}
+
+// sideInfoLength возвращает длину дополнительной информации кадра слоя III
+func (f mp3Frame) sideInfoLength() int {
+    switch {
+    case f.mpeg1 && f.mono:
+        return 17
+    case f.mpeg1:
+        return 32
+    case f.mono:
+        return 9
+    default:
+        return 17
+    }
+}
+
+// globalGain возвращает global_gain первой гранулы первого канала — грубую оценку громкости кадра
+// слоя III без декодирования. Для слоев I и II возвращает 0.
+func (f mp3Frame) globalGain(frame []byte) float64 {
+    if f.layer != 3 {
+        return 0
+    }
+    offset := 4
+    if f.crc {
+        offset += 2
+    }
+
+    channels := 2
+    if f.mono {
+        channels = 1
+    }
+    // Перед global_gain идут main_data_begin, private_bits, scfsi (только MPEG-1),
+    // part2_3_length и big_values
+    var bit int
+    if f.mpeg1 {
+        privateBits := 3
+        if f.mono {
+            privateBits = 5
+        }
+        bit = 9 + privateBits + 4*channels + 12 + 9
+    } else {
+        bit = 8 + channels + 12 + 9
+    }
+
+    var value int
+    for i := 0; i < 8; i++ {
+        pos := offset*8 + bit + i
+        if pos/8 >= len(frame) {
+            return 0
+        }
+        value = value<<1 | int(frame[pos/8]>>(7-pos%8))&1
+    }
+    return float64(value)
+}
+
+// isInfoFrame проверяет, что кадр содержит заголовок Xing/Info/VBRI вместо звука
+func (f mp3Frame) isInfoFrame(frame []byte) bool {
+    offset := 4 + f.sideInfoLength()
+    if f.crc {
+        offset += 2
+    }
+    if len(frame) >= offset+4 {
+        if tag := string(frame[offset : offset+4]); tag == "Xing" || tag == "Info" {
+            return true
+        }
+    }
+    return len(frame) >= 40 && string(frame[36:40]) == "VBRI"
+}
+
+func parseMP3(r *bufio.Reader) (*Info, error) {
+    if err := skipID3v2(r); err != nil {
+        return nil, err
+    }
+
+    var first mp3Frame
+    var frames int
+    var samples int64
+    var values []float64
+    junk := 0
+    for {
+        header, err := r.Peek(4)
+        if err != nil {
+            break
+        }
+        frame, ok := parseMP3Header(header)
+        // Частота дискретизации не меняется внутри файла; другая частота означает ложную синхронизацию
+        if ok && frames > 0 && frame.sampleRate != first.sampleRate {
+            ok = false
+        }
+        if !ok {
+            // Пропускаем мусор между кадрами и тег ID3v1 в конце файла
+            if frames == 0 && junk >= mp3MaxJunk {
+                return nil, ErrInvalid
+            }
+            junk++
+            r.Discard(1)
+            continue
+        }
+
+        peek, _ := r.Peek(64)
+        info := frames == 0 && frame.isInfoFrame(peek)
+        gain := frame.globalGain(peek)
+        if n, _ := r.Discard(frame.length); n < frame.length {
+            // Последний кадр обрезан
+            break
+        }
+        if frames == 0 {
+            first = frame
+        }
+        frames++
+        if info {
+            continue
+        }
+        samples += int64(frame.samples)
+        values = append(values, gain)
+    }
+
+    // Одиночный кадр в начале файла может оказаться случайным совпадением
+    if frames < 2 {
+        return nil, ErrInvalid
+    }
+
+    channels := 2
+    if first.mono {
+        channels = 1
+    }
+    codec := "mp3"
+    if first.layer != 3 {
+        codec = []string{"", "mp1", "mp2"}[first.layer]
+    }
+    return &Info{
+        Format:     "mp3",
+        Codec:      codec,
+        Duration:   time.Duration(samples) * time.Second / time.Duration(first.sampleRate),
+        SampleRate: first.sampleRate,
+        Channels:   channels,
+        Waveform:   downsample(values),
+    }, nil
+}
+
+// skipID3v2 пропускает теги ID3v2 в начале файла
+func skipID3v2(r *bufio.Reader) error {
+    for {
+        header, err := r.Peek(10)
+        if err != nil || string(header[:3]) != "ID3" {
+            return nil
+        }
+        // Размер тега записан 7-битными байтами
+        size := int(header[6]&0x7F)<<21 | int(header[7]&0x7F)<<14 | int(header[8]&0x7F)<<7 | int(header[9]&0x7F)
+        size += 10
+        if header[5]&0x10 != 0 {
+            size += 10 // Footer
+        }
+        if _, err := r.Discard(size); err != nil {
+            return invalid(err)
+        }
+    }
+}
+
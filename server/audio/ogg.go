@@ -0,0 +1,131 @@
+package audio
+
+import (
+    "bufio"
+    "encoding/binary"
+    "io"
+    "time"
+)
+
+// opusSampleRate — частота, в которой Opus считает позиции гранул, независимо от исходной частоты
+const opusSampleRate = 48000
+
+// parseOgg разбирает первый логический поток Ogg. Длительность берется из позиции гранулы
+// последней страницы, форма волны — из размеров пакетов: при переменном битрейте
+// громкие фрагменты кодируются большими пакетами.
+func parseOgg(r *bufio.Reader) (*Info, error) {
+    var info *Info
+    var serial uint32
+    var preSkip int64
+    var granuleRate int64
+    var lastGranule int64 = -1
+    var headerPackets int
+    var packets int
+    var packetSize int
+    var values []float64
+
+    for pages := 0; ; pages++ {
+        var header [27]byte
+        if _, err := io.ReadFull(r, header[:]); err != nil {
+            if pages == 0 {
+                return nil, invalid(err)
+            }
+            // Конец файла или обрезанная последняя страница
+            break
+        }
+        if string(header[:4]) != "OggS" {
+            if pages == 0 {
+                return nil, ErrInvalid
+            }
+            break
+        }
+        granule := int64(binary.LittleEndian.Uint64(header[6:14]))
+        pageSerial := binary.LittleEndian.Uint32(header[14:18])
+
+        segments := make([]byte, header[26])
+        if _, err := io.ReadFull(r, segments); err != nil {
+            break
+        }
+        var bodySize int
+        for _, segment := range segments {
+            bodySize += int(segment)
+        }
+
+        if pages == 0 {
+            // Первая страница содержит только заголовок идентификации кодека
+            serial = pageSerial
+            body := make([]byte, bodySize)
+            if _, err := io.ReadFull(r, body); err != nil {
+                return nil, invalid(err)
+            }
+            var err error
+            info, preSkip, granuleRate, headerPackets, err = parseOggIdentification(body)
+            if err != nil {
+                return nil, err
+            }
+        } else if _, err := r.Discard(bodySize); err != nil {
+            break
+        }
+        if pageSerial != serial {
+            continue
+        }
+
+        // Пакет заканчивается на сегменте короче 255 байт и может продолжаться на следующей странице
+        for _, segment := range segments {
+            packetSize += int(segment)
+            if segment < 255 {
+                if packets >= headerPackets {
+                    values = append(values, float64(packetSize))
+                }
+                packets++
+                packetSize = 0
+            }
+        }
+        // -1 означает, что на странице не заканчивается ни один пакет
+        if granule != -1 {
+            lastGranule = granule
+        }
+    }
+
+    if lastGranule <= preSkip {
+        return nil, ErrInvalid
+    }
+    info.Duration = time.Duration(lastGranule-preSkip) * time.Second / time.Duration(granuleRate)
+    info.Waveform = downsample(values)
+    return info, nil
+}
+
+// parseOggIdentification разбирает заголовок идентификации Opus или Vorbis. Возвращает
+// метаданные, количество отсчетов, пропускаемых в начале, частоту гранул и число заголовочных пакетов.
+func parseOggIdentification(packet []byte) (*Info, int64, int64, int, error) {
+    switch {
+    case len(packet) >= 19 && string(packet[:8]) == "OpusHead":
+        info := &Info{
+            Format:     "ogg",
+            Codec:      "opus",
+            Channels:   int(packet[9]),
+            SampleRate: int(binary.LittleEndian.Uint32(packet[12:16])),
+        }
+        // Исходная частота необязательна; декодер Opus всегда работает на 48 кГц
+        if info.SampleRate == 0 {
+            info.SampleRate = opusSampleRate
+        }
+        preSkip := int64(binary.LittleEndian.Uint16(packet[10:12]))
+        // OpusHead и OpusTags
+        return info, preSkip, opusSampleRate, 2, nil
+    case len(packet) >= 30 && string(packet[:7]) == "\x01vorbis":
+        info := &Info{
+            Format:     "ogg",
+            Codec:      "vorbis",
+            Channels:   int(packet[11]),
+            SampleRate: int(binary.LittleEndian.Uint32(packet[12:16])),
+        }
+        if info.SampleRate == 0 {
+            return nil, 0, 0, 0, ErrInvalid
+        }
+        // Идентификация, комментарии и таблицы кодека
+        return info, 0, int64(info.SampleRate), 3, nil
+    default:
+        return nil, 0, 0, 0, ErrUnsupportedFormat
+    }
+}
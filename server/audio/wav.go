@@ -0,0 +1,160 @@
+package audio
+
+import (
+    "bufio"
+    "encoding/binary"
+    "io"
+    "math"
+    "time"
+)
+
+// Коды формата WAV
+const (
+    wavFormatPCM        = 1
+    wavFormatFloat      = 3
+    wavFormatExtensible = 0xFFFE
+)
+
+// wavFormat — содержимое блока "fmt "
+type wavFormat struct {
+    code       uint16
+    channels   int
+    sampleRate int
+    blockAlign int
+    bits       int
+}
+
+func parseWAV(r *bufio.Reader) (*Info, error) {
+    if _, err := r.Discard(12); err != nil {
+        return nil, invalid(err)
+    }
+
+    var format *wavFormat
+    for {
+        var header [8]byte
+        if _, err := io.ReadFull(r, header[:]); err != nil {
+            // Файл закончился, а блока данных так и не было
+            return nil, invalid(err)
+        }
+        id := string(header[:4])
+        size := int64(binary.LittleEndian.Uint32(header[4:]))
+
+        switch id {
+        case "fmt ":
+            if size < 16 || size > 1<<10 {
+                return nil, ErrInvalid
+            }
+            buf := make([]byte, size+size&1)
+            if _, err := io.ReadFull(r, buf); err != nil {
+                return nil, invalid(err)
+            }
+            format = parseWAVFormat(buf[:size])
+            if format == nil {
+                return nil, ErrUnsupportedFormat
+            }
+        case "data":
+            if format == nil {
+                return nil, ErrInvalid
+            }
+            return readPCM(r, format, size)
+        default:
+            // Блоки выравниваются по четной границе
+            if _, err := r.Discard(int(size + size&1)); err != nil {
+                return nil, invalid(err)
+            }
+        }
+    }
+}
+
+// parseWAVFormat проверяет блок "fmt "; nil — формат отсчетов не поддерживается
+func parseWAVFormat(buf []byte) *wavFormat {
+    format := &wavFormat{
+        code:       binary.LittleEndian.Uint16(buf[0:2]),
+        channels:   int(binary.LittleEndian.Uint16(buf[2:4])),
+        sampleRate: int(binary.LittleEndian.Uint32(buf[4:8])),
+        blockAlign: int(binary.LittleEndian.Uint16(buf[12:14])),
+        bits:       int(binary.LittleEndian.Uint16(buf[14:16])),
+    }
+    // В расширенном формате настоящий код лежит в начале GUID подформата
+    if format.code == wavFormatExtensible && len(buf) >= 26 {
+        format.code = binary.LittleEndian.Uint16(buf[24:26])
+    }
+
+    switch {
+    case format.code == wavFormatPCM && (format.bits == 8 || format.bits == 16 || format.bits == 24 || format.bits == 32):
+    case format.code == wavFormatFloat && format.bits == 32:
+    default:
+        return nil
+    }
+    if format.channels <= 0 || format.sampleRate <= 0 || format.blockAlign != format.channels*format.bits/8 {
+        return nil
+    }
+    return format
+}
+
+// readPCM читает отсчеты и строит форму волны по пикам каждых 10 мс. Размер блока данных
+// может быть не указан (потоковая запись) или больше фактического — тогда читаем до конца файла.
+func readPCM(r *bufio.Reader, format *wavFormat, size int64) (*Info, error) {
+    var data io.Reader = r
+    if size > 0 && size != math.MaxUint32 {
+        data = io.LimitReader(r, size)
+    }
+
+    framesPerPeak := format.sampleRate / 100
+    if framesPerPeak == 0 {
+        framesPerPeak = 1
+    }
+    sampleSize := format.bits / 8
+    buf := make([]byte, format.blockAlign*framesPerPeak)
+
+    var frames int64
+    var values []float64
+    for {
+        n, err := io.ReadFull(data, buf)
+        n -= n % format.blockAlign
+        if n > 0 {
+            var peak float64
+            for offset := 0; offset < n; offset += sampleSize {
+                if amplitude := sample(buf[offset:offset+sampleSize], format); amplitude > peak {
+                    peak = amplitude
+                }
+            }
+            values = append(values, peak)
+            frames += int64(n / format.blockAlign)
+        }
+        if err == io.EOF || err == io.ErrUnexpectedEOF {
+            break
+        }
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    return &Info{
+        Format:     "wav",
+        Codec:      "pcm",
+        Duration:   time.Duration(frames) * time.Second / time.Duration(format.sampleRate),
+        SampleRate: format.sampleRate,
+        Channels:   format.channels,
+        Waveform:   downsample(values),
+    }, nil
+}
+
+// sample возвращает модуль амплитуды отсчета в диапазоне 0–1
+func sample(b []byte, format *wavFormat) float64 {
+    var value float64
+    switch {
+    case format.code == wavFormatFloat:
+        value = float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+    case format.bits == 8:
+        // 8-битные отсчеты беззнаковые
+        value = (float64(b[0]) - 128) / 128
+    case format.bits == 16:
+        value = float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+    case format.bits == 24:
+        value = float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+    default:
+        value = float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+    }
+    return math.Min(math.Abs(value), 1)
+}
@@ -0,0 +1,329 @@
+package audio
+
+import (
+    "bufio"
+    "encoding/binary"
+    "io"
+    "math"
+    "strings"
+    "time"
+)
+
+var ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+
+// Идентификаторы элементов Matroska/WebM
+const (
+    ebmlHeaderID      = 0x1A45DFA3
+    ebmlDocTypeID     = 0x4282
+    segmentID         = 0x18538067
+    infoID            = 0x1549A966
+    timecodeScaleID   = 0x2AD7B1
+    durationID        = 0x4489
+    tracksID          = 0x1654AE6B
+    trackEntryID      = 0xAE
+    trackNumberID     = 0xD7
+    trackTypeID       = 0x83
+    codecID           = 0x86
+    audioID           = 0xE1
+    samplingFreqID    = 0xB5
+    channelsID        = 0x9F
+    clusterID         = 0x1F43B675
+    clusterTimecodeID = 0xE7
+    blockGroupID      = 0xA0
+    blockID           = 0xA1
+    simpleBlockID     = 0xA3
+)
+
+// webmTrackAudio — значение TrackType для звуковой дорожки
+const webmTrackAudio = 2
+
+// webmMaxHeaderElement ограничивает размер элементов, которые читаются в память целиком
+const webmMaxHeaderElement = 1 << 20
+
+// webmTrack — сведения о звуковой дорожке
+type webmTrack struct {
+    number     uint64
+    codec      string
+    sampleRate float64
+    channels   int
+}
+
+// parseWebM читает элементы последовательно, не загружая файл в память: в сегмент, кластеры
+// и группы блоков спускается, остальные элементы пропускает. Так поддерживаются элементы
+// неизвестного размера, которые пишет MediaRecorder в браузерах.
+func parseWebM(r *bufio.Reader) (*Info, error) {
+    timecodeScale := uint64(1000000)
+    var duration float64
+    var track *webmTrack
+    var clusterTimecode uint64
+    var firstBlock, lastBlock int64 = -1, -1
+    var blocks int
+    var values []float64
+
+loop:
+    for elements := 0; ; elements++ {
+        id, size, err := readElementHeader(r)
+        if err != nil {
+            if elements == 0 {
+                return nil, invalid(err)
+            }
+            // Конец файла или обрезанный элемент в конце записи
+            break
+        }
+
+        switch id {
+        case ebmlHeaderID:
+            payload, err := readPayload(r, size)
+            if err != nil {
+                return nil, err
+            }
+            docType := "matroska"
+            walkElements(payload, func(id uint64, data []byte) {
+                if id == ebmlDocTypeID {
+                    docType = string(data)
+                }
+            })
+            if docType != "webm" && docType != "matroska" {
+                return nil, ErrUnsupportedFormat
+            }
+        case segmentID, clusterID, blockGroupID:
+            // Спускаемся в дочерние элементы
+        case infoID:
+            payload, err := readPayload(r, size)
+            if err != nil {
+                return nil, err
+            }
+            walkElements(payload, func(id uint64, data []byte) {
+                switch id {
+                case timecodeScaleID:
+                    timecodeScale = readUint(data)
+                case durationID:
+                    duration = readFloat(data)
+                }
+            })
+        case tracksID:
+            payload, err := readPayload(r, size)
+            if err != nil {
+                return nil, err
+            }
+            walkElements(payload, func(id uint64, data []byte) {
+                if id == trackEntryID && track == nil {
+                    track = parseTrackEntry(data)
+                }
+            })
+            if track == nil {
+                return nil, ErrInvalid
+            }
+        case clusterTimecodeID:
+            payload, err := readPayload(r, size)
+            if err != nil {
+                break loop
+            }
+            clusterTimecode = readUint(payload)
+        case simpleBlockID, blockID:
+            if size < 0 {
+                return nil, ErrInvalid
+            }
+            // Начало блока: номер дорожки (vint) и смещение времени относительно кластера
+            header, _ := r.Peek(11)
+            number, n, ok := readVint(header, false)
+            if ok && len(header) >= n+2 && track != nil && number == track.number {
+                timecode := int64(clusterTimecode) + int64(int16(binary.BigEndian.Uint16(header[n:n+2])))
+                if firstBlock < 0 {
+                    firstBlock = timecode
+                }
+                if timecode > lastBlock {
+                    lastBlock = timecode
+                }
+                blocks++
+                values = append(values, float64(size))
+            }
+            if err := discard(r, size); err != nil {
+                break loop
+            }
+        default:
+            if size < 0 {
+                return nil, ErrInvalid
+            }
+            if err := discard(r, size); err != nil {
+                break loop
+            }
+        }
+    }
+
+    if track == nil {
+        return nil, ErrInvalid
+    }
+
+    // Браузеры часто не записывают длительность; тогда считаем ее по времени последнего блока
+    // плюс средняя длительность блока
+    var length time.Duration
+    if duration > 0 {
+        length = time.Duration(duration * float64(timecodeScale))
+    } else if blocks > 0 {
+        last := lastBlock
+        if blocks > 1 {
+            last += (lastBlock - firstBlock) / int64(blocks-1)
+        }
+        length = time.Duration(last) * time.Duration(timecodeScale)
+    }
+
+    return &Info{
+        Format:     "webm",
+        Codec:      track.codec,
+        Duration:   length,
+        SampleRate: int(track.sampleRate),
+        Channels:   track.channels,
+        Waveform:   downsample(values),
+    }, nil
+}
+
+// parseTrackEntry возвращает сведения о дорожке, если она звуковая
+func parseTrackEntry(data []byte) *webmTrack {
+    track := &webmTrack{sampleRate: 8000, channels: 1}
+    var trackType uint64
+    walkElements(data, func(id uint64, data []byte) {
+        switch id {
+        case trackNumberID:
+            track.number = readUint(data)
+        case trackTypeID:
+            trackType = readUint(data)
+        case codecID:
+            track.codec = strings.ToLower(strings.TrimPrefix(strings.TrimRight(string(data), "\x00"), "A_"))
+        case audioID:
+            walkElements(data, func(id uint64, data []byte) {
+                switch id {
+                case samplingFreqID:
+                    track.sampleRate = readFloat(data)
+                case channelsID:
+                    track.channels = int(readUint(data))
+                }
+            })
+        }
+    })
+    if trackType != webmTrackAudio {
+        return nil
+    }
+    return track
+}
+
+// readElementHeader читает идентификатор и размер элемента. Размер -1 означает неизвестный размер.
+func readElementHeader(r *bufio.Reader) (uint64, int64, error) {
+    id, _, err := readVintFrom(r, true)
+    if err != nil {
+        return 0, 0, err
+    }
+    size, length, err := readVintFrom(r, false)
+    if err != nil {
+        return 0, 0, err
+    }
+    // Все биты значения равны единице — размер неизвестен
+    if size == 1<<(7*length)-1 {
+        return id, -1, nil
+    }
+    return id, int64(size), nil
+}
+
+// readVintFrom читает число переменной длины EBML из потока и возвращает его значение и длину
+func readVintFrom(r *bufio.Reader, keepMarker bool) (uint64, int, error) {
+    first, err := r.Peek(1)
+    if err != nil {
+        return 0, 0, err
+    }
+    length := vintLength(first[0])
+    if length == 0 {
+        return 0, 0, ErrInvalid
+    }
+    buf, err := r.Peek(length)
+    if err != nil {
+        return 0, 0, err
+    }
+    value, _, _ := readVint(buf, keepMarker)
+    r.Discard(length)
+    return value, length, nil
+}
+
+// readVint разбирает число переменной длины EBML в начале b. Для идентификаторов
+// признак длины сохраняется, для размеров — отбрасывается.
+func readVint(b []byte, keepMarker bool) (uint64, int, bool) {
+    if len(b) == 0 {
+        return 0, 0, false
+    }
+    length := vintLength(b[0])
+    if length == 0 || len(b) < length {
+        return 0, 0, false
+    }
+    value := uint64(b[0])
+    if !keepMarker {
+        value &= uint64(0xFF >> length)
+    }
+    for _, c := range b[1:length] {
+        value = value<<8 | uint64(c)
+    }
+    return value, length, true
+}
+
+// vintLength возвращает длину числа EBML по первому байту; 0 — некорректное число
+func vintLength(first byte) int {
+    for length := 1; length <= 8; length++ {
+        if first&(0x80>>(length-1)) != 0 {
+            return length
+        }
+    }
+    return 0
+}
+
+// walkElements перебирает дочерние элементы известного размера в data
+func walkElements(data []byte, fn func(id uint64, data []byte)) {
+    for len(data) > 0 {
+        id, n, ok := readVint(data, true)
+        if !ok {
+            return
+        }
+        data = data[n:]
+        size, n, ok := readVint(data, false)
+        if !ok || uint64(len(data)-n) < size {
+            return
+        }
+        data = data[n:]
+        fn(id, data[:size])
+        data = data[size:]
+    }
+}
+
+// readPayload читает содержимое элемента заголовка целиком
+func readPayload(r *bufio.Reader, size int64) ([]byte, error) {
+    if size < 0 || size > webmMaxHeaderElement {
+        return nil, ErrInvalid
+    }
+    payload := make([]byte, size)
+    if _, err := io.ReadFull(r, payload); err != nil {
+        return nil, invalid(err)
+    }
+    return payload, nil
+}
+
+// discard пропускает size байтов
+func discard(r *bufio.Reader, size int64) error {
+    _, err := io.CopyN(io.Discard, r, size)
+    return err
+}
+
+func readUint(data []byte) uint64 {
+    var value uint64
+    for _, c := range data {
+        value = value<<8 | uint64(c)
+    }
+    return value
+}
+
+func readFloat(data []byte) float64 {
+    switch len(data) {
+    case 4:
+        return float64(math.Float32frombits(binary.BigEndian.Uint32(data)))
+    case 8:
+        return math.Float64frombits(binary.BigEndian.Uint64(data))
+    default:
+        return 0
+    }
+}
@@ -7,7 +7,8 @@ import (
     "chatter-hub-server/config"
     "github.com/gin-gonic/gin"
     "net/http"
-    "log"
+    "log/slog"
+    "chatter-hub-server/logging"
 )
 
 // Claims представляет утверждения для токена JWT
@@ -16,9 +17,23 @@ type Claims struct {
     jwt.StandardClaims
 }
 
-// GenerateToken создает JWT токен для пользователя
-func GenerateToken(userID string, cfg *config.Config) (string, error) {
-    expirationTime := time.Now().Add(time.Duration(cfg.JWT.ExpiresIn) * time.Second)
+// TokenIssuer выдает JWT токены. Создается один раз при запуске сервера.
+type TokenIssuer struct {
+    secret    []byte
+    expiresIn time.Duration
+}
+
+// NewTokenIssuer создает TokenIssuer с секретом и временем жизни токена из конфигурации
+func NewTokenIssuer(cfg *config.Config) *TokenIssuer {
+    return &TokenIssuer{
+        secret:    []byte(cfg.JWT.SecretKey),
+        expiresIn: time.Duration(cfg.JWT.ExpiresIn) * time.Second,
+    }
+}
+
+// Issue создает JWT токен для пользователя
+func (t *TokenIssuer) Issue(userID string) (string, error) {
+    expirationTime := time.Now().Add(t.expiresIn)
     claims := &Claims{
         UserID: userID,
         StandardClaims: jwt.StandardClaims{
@@ -26,12 +41,11 @@ func GenerateToken(userID string, cfg *config.Config) (string, error) {
         },
     }
     token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-    tokenString, err := token.SignedString([]byte(cfg.JWT.SecretKey))
+    tokenString, err := token.SignedString(t.secret)
     if err != nil {
-        log.Printf("Ошибка создания JWT токена: %v", err)
+        slog.Error("Ошибка создания JWT токена", "user_id", userID, "error", err)
         return "", err
     }
-    log.Printf("Создан JWT токен для пользователя %s: %s", userID, tokenString)
     return tokenString, nil
 }
 
@@ -48,14 +62,13 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
         // Ожидаем, что заголовок имеет формат "Bearer <token>"
         parts := strings.SplitN(authHeader, " ", 2)
         if len(parts) != 2 || parts[0] != "Bearer" {
-            log.Println("Недействительный формат токена авторизации")
+            logging.FromContext(c.Request.Context()).Debug("Недействительный формат заголовка авторизации")
             c.JSON(http.StatusUnauthorized, config.ErrorResponse{Error: "Недействительный токен"})
             c.Abort()
             return
         }
 
         tokenString := parts[1]
-        log.Printf("Получен токен: %s", tokenString)
 
         claims := &Claims{}
         token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
@@ -63,16 +76,32 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
         })
 
         if err != nil || !token.Valid {
-            log.Printf("Ошибка проверки токена: %v", err)
+            logging.FromContext(c.Request.Context()).Debug("Недействительный токен", "error", err)
             c.JSON(http.StatusUnauthorized, config.ErrorResponse{Error: "Недействительный токен"})
             c.Abort()
             return
         }
 
-        log.Printf("Токен действителен. UserID: %s", claims.UserID)
-
         // Сохраняем идентификатор пользователя в контексте
         c.Set("userID", claims.UserID)
+        logging.With(c, "user_id", claims.UserID)
+        c.Next()
+    }
+}
+
+// AdminMiddleware пропускает только администраторов из ADMIN_USER_IDS.
+// Должен подключаться после AuthMiddleware.
+func AdminMiddleware(cfg *config.Config) gin.HandlerFunc {
+    admins := make(map[string]bool, len(cfg.Admin.UserIDs))
+    for _, id := range cfg.Admin.UserIDs {
+        admins[id] = true
+    }
+    return func(c *gin.Context) {
+        if !admins[c.GetString("userID")] {
+            c.JSON(http.StatusForbidden, config.ErrorResponse{Error: "Доступ только для администраторов"})
+            c.Abort()
+            return
+        }
         c.Next()
     }
 }
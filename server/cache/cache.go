@@ -0,0 +1,95 @@
+// Package cache — кэш значений с ограниченным временем жизни. Значения хранятся в Redis,
+// а при запуске одного экземпляра сервера без Redis — в памяти процесса.
+package cache
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/logging"
+
+    "github.com/go-redis/redis/v8"
+)
+
+var ErrMiss = errors.New("значение не найдено в кэше")
+
+// Cache хранит значения по ключу
+type Cache interface {
+    // Get возвращает значение; ErrMiss, если его нет или время жизни истекло
+    Get(ctx context.Context, key string) ([]byte, error)
+    Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+    // SetNX сохраняет значение, только если ключа еще нет; false — ключ уже существует
+    SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+    Delete(ctx context.Context, key string) error
+    // DeleteIfEquals удаляет ключ, только если в нем записано value; false — значение другое или ключа нет
+    DeleteIfEquals(ctx context.Context, key string, value []byte) (bool, error)
+}
+
+// Default — кэш, выбранный в конфигурации; задается через Init
+var Default Cache
+
+// Init создает кэш выбранного в конфигурации драйвера
+func Init(cfg *config.Config) {
+    var err error
+    Default, err = New(cfg)
+    if err != nil {
+        logging.Fatal("Ошибка инициализации кэша", "driver", cfg.Cache.Driver, "error", err)
+    }
+}
+
+// New создает кэш драйвера cfg.Cache.Driver. Для Redis используется соединение config.RedisClient.
+func New(cfg *config.Config) (Cache, error) {
+    switch cfg.Cache.Driver {
+    case "redis", "":
+        return NewRedis(config.RedisClient), nil
+    case "memory":
+        return NewMemory(), nil
+    default:
+        return nil, fmt.Errorf("неизвестный драйвер кэша %q", cfg.Cache.Driver)
+    }
+}
+
+type redisCache struct {
+    client *redis.Client
+}
+
+// NewRedis возвращает кэш в Redis
+func NewRedis(client *redis.Client) Cache {
+    return &redisCache{client: client}
+}
+
+func (r *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
+    value, err := r.client.Get(ctx, key).Bytes()
+    if err == redis.Nil {
+        return nil, ErrMiss
+    }
+    return value, err
+}
+
+func (r *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+    return r.client.Set(ctx, key, value, ttl).Err()
+}
+
+func (r *redisCache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+    return r.client.SetNX(ctx, key, value, ttl).Result()
+}
+
+func (r *redisCache) Delete(ctx context.Context, key string) error {
+    return r.client.Del(ctx, key).Err()
+}
+
+// deleteIfEqualsScript сравнивает и удаляет значение атомарно на стороне Redis
+var deleteIfEqualsScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+func (r *redisCache) DeleteIfEquals(ctx context.Context, key string, value []byte) (bool, error) {
+    deleted, err := deleteIfEqualsScript.Run(ctx, r.client, []string{key}, value).Int()
+    return deleted > 0, err
+}
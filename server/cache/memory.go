@@ -0,0 +1,95 @@
+package cache
+
+import (
+    "bytes"
+    "context"
+    "sync"
+    "time"
+)
+
+// cleanupInterval — как часто из памяти удаляются значения с истекшим временем жизни
+const cleanupInterval = time.Minute
+
+type memoryEntry struct {
+    value     []byte
+    expiresAt time.Time // Нулевое время — без ограничения
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+    return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+type memoryCache struct {
+    mu      sync.Mutex
+    entries map[string]memoryEntry
+    swept   time.Time
+}
+
+// NewMemory возвращает кэш в памяти процесса. Значения не разделяются между экземплярами сервера.
+func NewMemory() Cache {
+    return &memoryCache{entries: make(map[string]memoryEntry), swept: time.Now()}
+}
+
+func (m *memoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    entry, ok := m.entries[key]
+    if !ok || entry.expired(time.Now()) {
+        return nil, ErrMiss
+    }
+    return append([]byte(nil), entry.value...), nil
+}
+
+func (m *memoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    m.set(key, value, ttl)
+    return nil
+}
+
+func (m *memoryCache) SetNX(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if entry, ok := m.entries[key]; ok && !entry.expired(time.Now()) {
+        return false, nil
+    }
+    m.set(key, value, ttl)
+    return true, nil
+}
+
+func (m *memoryCache) Delete(ctx context.Context, key string) error {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    delete(m.entries, key)
+    return nil
+}
+
+func (m *memoryCache) DeleteIfEquals(ctx context.Context, key string, value []byte) (bool, error) {
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    entry, ok := m.entries[key]
+    if !ok || entry.expired(time.Now()) || !bytes.Equal(entry.value, value) {
+        return false, nil
+    }
+    delete(m.entries, key)
+    return true, nil
+}
+
+// set сохраняет значение и время от времени удаляет устаревшие; вызывается под m.mu
+func (m *memoryCache) set(key string, value []byte, ttl time.Duration) {
+    now := time.Now()
+    entry := memoryEntry{value: append([]byte(nil), value...)}
+    if ttl > 0 {
+        entry.expiresAt = now.Add(ttl)
+    }
+    m.entries[key] = entry
+
+    if now.Sub(m.swept) >= cleanupInterval {
+        for k, e := range m.entries {
+            if e.expired(now) {
+                delete(m.entries, k)
+            }
+        }
+        m.swept = now
+    }
+}
@@ -0,0 +1,54 @@
+package cache
+
+import (
+    "context"
+    "testing"
+    "time"
+)
+
+func TestMemoryDeleteIfEquals(t *testing.T) {
+    tests := []struct {
+        name    string
+        stored  string
+        ttl     time.Duration
+        value   string
+        deleted bool
+    }{
+        {name: "совпадает", stored: "a", value: "a", deleted: true},
+        {name: "другое значение", stored: "a", value: "b", deleted: false},
+        {name: "время жизни истекло", stored: "a", ttl: time.Nanosecond, value: "a", deleted: false},
+    }
+
+    ctx := context.Background()
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            c := NewMemory()
+            if err := c.Set(ctx, "key", []byte(tt.stored), tt.ttl); err != nil {
+                t.Fatal(err)
+            }
+            time.Sleep(time.Millisecond)
+
+            deleted, err := c.DeleteIfEquals(ctx, "key", []byte(tt.value))
+            if err != nil {
+                t.Fatal(err)
+            }
+            if deleted != tt.deleted {
+                t.Fatalf("удалено %v, ожидалось %v", deleted, tt.deleted)
+            }
+            _, err = c.Get(ctx, "key")
+            if tt.deleted && err != ErrMiss {
+                t.Fatalf("ключ остался после удаления: %v", err)
+            }
+            if !tt.deleted && tt.ttl == 0 && err != nil {
+                t.Fatalf("ключ с другим значением удален: %v", err)
+            }
+        })
+    }
+
+    t.Run("нет ключа", func(t *testing.T) {
+        deleted, err := NewMemory().DeleteIfEquals(ctx, "missing", []byte("a"))
+        if err != nil || deleted {
+            t.Fatalf("удалено %v, ошибка %v", deleted, err)
+        }
+    })
+}
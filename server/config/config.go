@@ -1,18 +1,35 @@
 package config
 
 import (
-    "log"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "log/slog"
     "os"
     "strconv"
+    "strings"
     "github.com/joho/godotenv"
 )
 
 type Config struct {
-    Minio    MinioConfig
-    Postgres PostgresConfig
-    Redis    RedisConfig
-    API      APIConfig
-    JWT      JWTConfig
+    Minio         MinioConfig
+    Storage       StorageConfig
+    Database      DatabaseConfig
+    Postgres      PostgresConfig
+    Redis         RedisConfig
+    Cache         CacheConfig
+    API           APIConfig
+    JWT           JWTConfig
+    Attachments   AttachmentConfig
+    Transcription TranscriptionConfig
+    Scan          ScanConfig
+    Jobs          JobsConfig
+    GC            GCConfig
+    Admin         AdminConfig
+    Log           LogConfig
+    Tracing       TracingConfig
+    Health        HealthConfig
+    Metrics       MetricsConfig
 }
 
 type MinioConfig struct {
@@ -20,25 +37,64 @@ type MinioConfig struct {
     AccessKey string
     SecretKey string
     UseSSL    bool
+    // Адрес MinIO, доступный клиентам, для подписанных ссылок (по умолчанию совпадает с Endpoint)
+    PublicEndpoint string
+    // Время ожидания одного обращения к MinIO в секундах. Не ограничивает передачу
+    // содержимого объектов: ее длительность зависит от размера файла и скорости клиента.
+    Timeout int64
 }
 
+// StorageConfig задает хранилище файлов
+type StorageConfig struct {
+    Driver     string // minio, local или memory
+    PresignTTL int64  // Время жизни подписанных ссылок в секундах
+    // Параметры драйверов local и memory: ссылки на файлы подписываются сервером
+    // и обслуживаются им же по адресу PublicURL/storage/...
+    LocalPath  string
+    PublicURL  string
+    SigningKey string // Если не задан, выводится из секрета JWT, но не совпадает с ним
+}
+
+// DatabaseConfig выбирает базу данных. SQLite предназначена для разработки и тестов:
+// схема создается AutoMigrate, версионированные миграции применяются только к PostgreSQL.
+type DatabaseConfig struct {
+    Driver     string // postgres или sqlite
+    SQLitePath string // Путь к файлу базы SQLite
+}
+
+// Драйверы базы данных
+const (
+    DatabasePostgres = "postgres"
+    DatabaseSQLite   = "sqlite"
+)
+
 type PostgresConfig struct {
     Host     string
     Port     string
     User     string
     Password string
     DBName   string
+    // Применять миграции при запуске сервера; при false схема обновляется командой server migrate
+    AutoMigrate bool
 }
 
 type RedisConfig struct {
     Addr     string
     Password string
     DB       int
+    Timeout  int64 // Время ожидания ответа на одну команду в миллисекундах
+}
+
+// CacheConfig выбирает хранилище кэша, блокировок и канал уведомлений
+type CacheConfig struct {
+    Driver string // redis или memory — в памяти процесса, для запуска одного экземпляра сервера
 }
 
 type APIConfig struct {
     Host string
     Port string
+    // Сколько секунд при остановке ждать завершения начатых запросов и фоновых задач
+    ShutdownTimeout int64
 }
 
 type JWTConfig struct {
@@ -46,19 +102,104 @@ type JWTConfig struct {
     ExpiresIn int64 // в секундах
 }
 
+// AttachmentConfig задает максимальные размеры вложений по типам (в байтах) и общий объем файлов пользователя
+type AttachmentConfig struct {
+    MaxImageSize     int64
+    MaxVideoSize     int64
+    MaxAudioSize     int64
+    MaxDocumentSize  int64
+    MaxVoiceDuration int64 // Максимальная длительность голосового сообщения в секундах
+    UserQuota        int64 // Квота пользователя по умолчанию в байтах
+}
+
+// JobsConfig задает обработку фоновых задач
+type JobsConfig struct {
+    Workers       int
+    PollInterval  int64 // Интервал опроса очереди в миллисекундах
+    RetentionDays int64 // Сколько дней хранить выполненные и dead задачи; 0 — не удалять
+}
+
+// GCConfig задает удаление файлов хранилища, на которые не ссылается база данных
+type GCConfig struct {
+    Interval    int64 // Интервал автоматического запуска в секундах; 0 — только вручную
+    GracePeriod int64 // Минимальный возраст удаляемого объекта в секундах
+    DryRun      bool  // Автоматический запуск только составляет отчет, ничего не удаляя; по умолчанию включен
+}
+
+// AdminConfig задает администраторов сервера
+type AdminConfig struct {
+    UserIDs []string // ID пользователей с доступом к служебным маршрутам /admin
+}
+
+// LogConfig задает журнал сервера
+type LogConfig struct {
+    Level  string // debug, info, warn или error
+    Format string // json или text
+}
+
+// TracingConfig задает трассировку OpenTelemetry
+type TracingConfig struct {
+    Exporter    string  // otlp, stdout или пустая строка — трассировка отключена
+    Endpoint    string  // Адрес коллектора OTLP/HTTP, например http://localhost:4318
+    ServiceName string
+    SampleRatio float64 // Доля записываемых трасс от 0 до 1
+}
+
+// HealthConfig задает ожидание зависимостей при запуске и проверку готовности
+type HealthConfig struct {
+    StartupTimeout int64 // Сколько секунд ждать базу данных, Redis и хранилище при запуске
+    CheckTimeout   int64 // Время ожидания проверки одной зависимости в /readyz в миллисекундах
+}
+
+// MetricsConfig задает выдачу метрик Prometheus. Без адреса и токена (по умолчанию) метрики
+// не отдаются: маршрута /metrics нет, при запуске в журнал пишется предупреждение.
+type MetricsConfig struct {
+    Addr  string // Отдельный адрес сервера метрик, например :9090; пустая строка — /metrics на адресе API
+    Token string // Токен, который сборщик передает в заголовке Authorization: Bearer; обязателен на адресе API
+}
+
+// TranscriptionConfig задает распознавание речи в голосовых сообщениях
+type TranscriptionConfig struct {
+    Driver  string // whisper, fake или пустая строка — распознавание отключено
+    URL     string // Адрес сервера с API, совместимым с OpenAI Whisper
+    APIKey  string
+    Model   string
+    Timeout int64 // Время ожидания ответа сервера в секундах
+}
+
+// ScanConfig задает антивирусную проверку загруженных файлов
+type ScanConfig struct {
+    Driver  string // clamd, fake или пустая строка — проверка отключена
+    Address string // Адрес clamd: host:port или unix:/path/to/clamd.sock
+    Timeout int64  // Время ожидания проверки одного файла в секундах
+}
+
 // LoadConfig загружает конфигурацию из .env
 func LoadConfig() (*Config, error) {
     err := godotenv.Load()
     if err != nil {
-        log.Println("Ошибка загрузки файла .env, будет использоваться системное окружение")
+        slog.Info("Файл .env не загружен, будет использоваться системное окружение")
     }
 
     cfg := &Config{
         Minio: MinioConfig{
-            Endpoint:  getEnv("MINIO_ENDPOINT", "localhost:9000"),
-            AccessKey: getEnv("MINIO_ACCESS_KEY", "minioadmin"),
-            SecretKey: getEnv("MINIO_SECRET_KEY", "minioadmin"),
-            UseSSL:    getEnvBool("MINIO_USE_SSL", false),
+            Endpoint:       getEnv("MINIO_ENDPOINT", "localhost:9000"),
+            AccessKey:      getEnv("MINIO_ACCESS_KEY", "minioadmin"),
+            SecretKey:      getEnv("MINIO_SECRET_KEY", "minioadmin"),
+            UseSSL:         getEnvBool("MINIO_USE_SSL", false),
+            PublicEndpoint: getEnv("MINIO_PUBLIC_ENDPOINT", ""),
+            Timeout:        getEnvInt64("MINIO_TIMEOUT", 30), // 30 секунд
+        },
+        Storage: StorageConfig{
+            Driver:     getEnv("STORAGE_DRIVER", "minio"),
+            PresignTTL: getEnvInt64("STORAGE_PRESIGN_TTL", getEnvInt64("MINIO_PRESIGN_TTL", 900)), // 900 секунд = 15 минут
+            LocalPath:  getEnv("STORAGE_LOCAL_PATH", "./data/storage"),
+            PublicURL:  getEnv("STORAGE_PUBLIC_URL", "http://localhost:8080"),
+            SigningKey: getEnv("STORAGE_SIGNING_KEY", ""),
+        },
+        Database: DatabaseConfig{
+            Driver:     getEnv("DB_DRIVER", DatabasePostgres),
+            SQLitePath: getEnv("SQLITE_PATH", "./data/chatter-hub.db"),
         },
         Postgres: PostgresConfig{
             Host:     getEnv("POSTGRES_HOST", "localhost"),
@@ -66,25 +207,94 @@ func LoadConfig() (*Config, error) {
             User:     getEnv("POSTGRES_USER", "postgres"),
             Password: getEnv("POSTGRES_PASSWORD", "postgres"),
             DBName:   getEnv("POSTGRES_DB", "postgres"),
+            AutoMigrate: getEnvBool("DB_AUTO_MIGRATE", true),
         },
         Redis: RedisConfig{
             Addr:     getEnv("REDIS_ADDR", "localhost:6379"),
             Password: getEnv("REDIS_PASSWORD", ""),
             DB:       getEnvInt("REDIS_DB", 0),
+            Timeout:  getEnvInt64("REDIS_TIMEOUT", 3000), // 3000 миллисекунд = 3 секунды
+        },
+        Cache: CacheConfig{
+            Driver: getEnv("CACHE_DRIVER", "redis"),
         },
         API: APIConfig{
-            Host: getEnv("API_HOST", "localhost"),
-            Port: getEnv("API_PORT", "8080"),
+            Host:            getEnv("API_HOST", "localhost"),
+            Port:            getEnv("API_PORT", "8080"),
+            ShutdownTimeout: getEnvInt64("API_SHUTDOWN_TIMEOUT", 30), // 30 секунд
         },
         JWT: JWTConfig{
 			SecretKey: getEnv("JWT_SECRET_KEY", "your-secret-key"),
 			ExpiresIn: getEnvInt64("JWT_EXPIRES_IN", 1800), // 1800 секунд = 30 минут
 		},
+        Attachments: AttachmentConfig{
+            MaxImageSize:     getEnvInt64("ATTACHMENT_MAX_IMAGE_SIZE", 10<<20),    // 10 МБ
+            MaxVideoSize:     getEnvInt64("ATTACHMENT_MAX_VIDEO_SIZE", 100<<20),   // 100 МБ
+            MaxAudioSize:     getEnvInt64("ATTACHMENT_MAX_AUDIO_SIZE", 20<<20),    // 20 МБ
+            MaxDocumentSize:  getEnvInt64("ATTACHMENT_MAX_DOCUMENT_SIZE", 50<<20), // 50 МБ
+            MaxVoiceDuration: getEnvInt64("VOICE_MAX_DURATION", 600),              // 600 секунд = 10 минут
+            UserQuota:        getEnvInt64("STORAGE_USER_QUOTA", 1<<30),            // 1 ГБ
+        },
+        Transcription: TranscriptionConfig{
+            Driver:  getEnv("TRANSCRIPTION_DRIVER", ""),
+            URL:     getEnv("TRANSCRIPTION_URL", "http://localhost:8000"),
+            APIKey:  getEnv("TRANSCRIPTION_API_KEY", ""),
+            Model:   getEnv("TRANSCRIPTION_MODEL", "whisper-1"),
+            Timeout: getEnvInt64("TRANSCRIPTION_TIMEOUT", 300), // 300 секунд = 5 минут
+        },
+        Scan: ScanConfig{
+            Driver:  getEnv("SCAN_DRIVER", ""),
+            Address: getEnv("SCAN_ADDRESS", "localhost:3310"),
+            Timeout: getEnvInt64("SCAN_TIMEOUT", 120), // 120 секунд = 2 минуты
+        },
+        Jobs: JobsConfig{
+            Workers:       getEnvInt("JOBS_WORKERS", 4),
+            PollInterval:  getEnvInt64("JOBS_POLL_INTERVAL", 1000), // 1000 миллисекунд = 1 секунда
+            RetentionDays: getEnvInt64("JOBS_RETENTION_DAYS", 30),
+        },
+        GC: GCConfig{
+            Interval:    getEnvInt64("GC_INTERVAL", 86400),     // 86400 секунд = 1 сутки
+            GracePeriod: getEnvInt64("GC_GRACE_PERIOD", 86400), // 86400 секунд = 1 сутки
+            DryRun:      getEnvBool("GC_DRY_RUN", true),
+        },
+        Admin: AdminConfig{
+            UserIDs: getEnvList("ADMIN_USER_IDS"),
+        },
+        Log: LogConfig{
+            Level:  getEnv("LOG_LEVEL", "info"),
+            Format: getEnv("LOG_FORMAT", "json"),
+        },
+        Tracing: TracingConfig{
+            Exporter:    getEnv("TRACING_EXPORTER", ""),
+            Endpoint:    getEnv("OTLP_ENDPOINT", ""),
+            ServiceName: getEnv("TRACING_SERVICE_NAME", "chatter-hub-server"),
+            SampleRatio: getEnvFloat("TRACING_SAMPLE_RATIO", 1),
+        },
+        Health: HealthConfig{
+            StartupTimeout: getEnvInt64("STARTUP_TIMEOUT", 120),      // 120 секунд = 2 минуты
+            CheckTimeout:   getEnvInt64("HEALTH_CHECK_TIMEOUT", 2000), // 2000 миллисекунд = 2 секунды
+        },
+        Metrics: MetricsConfig{
+            Addr:  getEnv("METRICS_ADDR", ""),
+            Token: getEnv("METRICS_TOKEN", ""),
+        },
+    }
+
+    // Подпись ссылок на файлы не должна раскрывать секрет JWT и подходить для токенов
+    if cfg.Storage.SigningKey == "" {
+        cfg.Storage.SigningKey = deriveKey(cfg.JWT.SecretKey, "storage")
     }
 
     return cfg, nil
 }
 
+// deriveKey выводит из секрета secret отдельный ключ для назначения label (HMAC-SHA256)
+func deriveKey(secret, label string) string {
+    mac := hmac.New(sha256.New, []byte(secret))
+    mac.Write([]byte(label))
+    return hex.EncodeToString(mac.Sum(nil))
+}
+
 // Вспомогательные функции для получения переменных окружения с дефолтными значениями
 func getEnv(key, defaultValue string) string {
     if value, exists := os.LookupEnv(key); exists {
@@ -113,6 +323,27 @@ func getEnvInt64(key string, defaultValue int64) int64 {
     return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+    if value, exists := os.LookupEnv(key); exists {
+        floatValue, err := strconv.ParseFloat(value, 64)
+        if err == nil {
+            return floatValue
+        }
+    }
+    return defaultValue
+}
+
+// getEnvList возвращает непустые значения переменной окружения, разделенные запятыми
+func getEnvList(key string) []string {
+    var values []string
+    for _, value := range strings.Split(os.Getenv(key), ",") {
+        if value = strings.TrimSpace(value); value != "" {
+            values = append(values, value)
+        }
+    }
+    return values
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
     if value, exists := os.LookupEnv(key); exists {
         boolValue, err := strconv.ParseBool(value)
@@ -2,55 +2,377 @@ package config
 
 import (
     "fmt"
-    "log"
+    "log/slog"
+    "os"
+    "path/filepath"
     "time"
 
+    "github.com/glebarez/sqlite"
     "gorm.io/driver/postgres"
     "gorm.io/gorm"
+    "gorm.io/gorm/logger"
 )
 
 // Объявление модели User
 type User struct {
-    ID       string `gorm:"primaryKey" json:"id" example:"12345"`
-    Username string `json:"username" example:"john_doe"`
-    Email    string `json:"email" example:"john@example.com"`
-    Password string `json:"password,omitempty" example:"secret"`
-    IsActive bool   `json:"is_active" gorm:"default:true"` // Новое поле
+    ID           string `gorm:"primaryKey" json:"id" example:"12345"`
+    Username     string `json:"username" example:"john_doe"`
+    DisplayName  string `json:"display_name" example:"John Doe"`
+    Bio          string `json:"bio" example:"Люблю голосовые сообщения"`
+    AvatarID     string `json:"-"` // Идентификатор текущего набора миниатюр аватара в хранилище
+    Email        string `json:"email" example:"john@example.com"`
+    Password     string `json:"password,omitempty" example:"secret"`
+    IsActive     bool   `json:"is_active" gorm:"default:true"` // Новое поле
+    Discoverable bool   `json:"discoverable" gorm:"default:true"` // Показывать ли пользователя в поиске
+    // Кто может начать переписку с пользователем: everyone или contacts
+    MessagePolicy string `json:"message_policy" gorm:"default:everyone" example:"everyone"`
 }
 
+// Значения настройки User.MessagePolicy
+const (
+    MessagePolicyEveryone = "everyone"
+    MessagePolicyContacts = "contacts"
+)
+
 // Объявление модели TextMessage
 type TextMessage struct {
-    ID         uint      `gorm:"primaryKey" json:"id"`
-    SenderID   string    `json:"sender_id"`
-    ReceiverID string    `json:"receiver_id"`
-    Content    string    `json:"content"`
-    CreatedAt  time.Time `json:"created_at"`
+    ID          uint         `gorm:"primaryKey" json:"id"`
+    SenderID    string       `json:"sender_id"`
+    ReceiverID  string       `json:"receiver_id"`
+    Content     string       `json:"content"`
+    Attachments []Attachment `gorm:"-" json:"attachments,omitempty"`
+    // Сообщение заблокировано, если во вложении обнаружено вредоносное содержимое
+    Blocked     bool      `json:"blocked,omitempty"`
+    BlockReason string    `json:"block_reason,omitempty"`
+    CreatedAt   time.Time `json:"created_at"`
 }
 
 // Объявление модели VoiceMessage
 type VoiceMessage struct {
-    ID         uint      `gorm:"primaryKey" json:"id"`
-    SenderID   string    `json:"sender_id"`
-    ReceiverID string    `json:"receiver_id"`
-    FileURL    string    `json:"file_url"`
-    CreatedAt  time.Time `json:"created_at"`
+    ID           uint   `gorm:"primaryKey" json:"id"`
+    SenderID     string `json:"sender_id"`
+    ReceiverID   string `json:"receiver_id"`
+    ObjectKey    string `json:"-"`                       // Ключ объекта в бакете VoiceBucket
+    FileURL      string `gorm:"-" json:"file_url"`       // Временная подписанная ссылка, формируется при каждом запросе
+    AttachmentID string `json:"attachment_id,omitempty"`
+    DurationMs   int64  `json:"duration_ms"`
+    Codec        string `json:"codec" example:"opus"`
+    SampleRate   int    `json:"sample_rate" example:"48000"`
+    Waveform     []byte `json:"waveform" swaggertype:"string" format:"base64"` // 100 значений амплитуды 0–255
+    // Распознанный текст; пустой статус — распознавание не запускалось
+    TranscriptionStatus string    `json:"transcription_status,omitempty" example:"completed"`
+    Transcript          string    `json:"transcript,omitempty"`
+    TranscriptLanguage  string    `json:"transcript_language,omitempty" example:"ru"`
+    // Сообщение заблокировано, если в аудиофайле обнаружено вредоносное содержимое
+    Blocked     bool      `json:"blocked,omitempty"`
+    BlockReason string    `json:"block_reason,omitempty"`
+    CreatedAt   time.Time `json:"created_at"`
+}
+
+// Статусы распознавания голосового сообщения
+const (
+    TranscriptionPending    = "pending"
+    TranscriptionProcessing = "processing"
+    TranscriptionCompleted  = "completed"
+    TranscriptionFailed     = "failed"
+)
+
+// Объявление модели Attachment — файла, загруженного пользователем и привязываемого к сообщению
+type Attachment struct {
+    ID          string    `gorm:"primaryKey" json:"id"`
+    OwnerID     string    `gorm:"index" json:"owner_id"`
+    Kind        string    `json:"kind" example:"image"`
+    Bucket      string    `json:"-"`
+    ObjectKey   string    `json:"-"`
+    FileName    string    `json:"file_name" example:"photo.jpg"`
+    ContentType string    `json:"content_type" example:"image/jpeg"` // Определяется по содержимому файла
+    Size        int64     `json:"size"`
+    Width       int       `json:"width,omitempty"`
+    Height      int       `json:"height,omitempty"`
+    Checksum    string    `json:"checksum"` // SHA-256 в шестнадцатеричном виде
+    MessageType string    `gorm:"index:idx_attachments_message" json:"message_type,omitempty"`
+    MessageID   *uint     `gorm:"index:idx_attachments_message" json:"message_id,omitempty"`
+    // Антивирусная проверка; пустой статус — проверка не выполнялась
+    ScanStatus    string `json:"scan_status,omitempty" example:"clean"`
+    ScanSignature string `json:"scan_signature,omitempty"`
+    // Обработка изображений: перекодирование без метаданных, эскизы и blurhash
+    ProcessingStatus string              `json:"processing_status,omitempty" example:"ready"`
+    Blurhash         string              `json:"blurhash,omitempty" example:"LEHV6nWB2yk8pyo0adR*.7kCMdnj"`
+    Variants         []AttachmentVariant `gorm:"-" json:"variants,omitempty"`
+    URL              string              `gorm:"-" json:"url,omitempty"`
+    CreatedAt        time.Time           `json:"created_at"`
+}
+
+// Статусы обработки изображения. Пока изображение не перекодировано, оно доступно только владельцу.
+const (
+    ProcessingPending = "pending"
+    ProcessingReady   = "ready"
+    ProcessingFailed  = "failed"
+)
+
+// StorageUsage — объем файлов пользователя в хранилище. Учитываются вложения и их варианты.
+type StorageUsage struct {
+    UserID     string `gorm:"primaryKey"`
+    UsedBytes  int64  `gorm:"not null;default:0"`
+    QuotaBytes *int64 // Индивидуальная квота; nil — квота по умолчанию
+    UpdatedAt  time.Time
+}
+
+// Статусы антивирусной проверки. До завершения проверки вложение доступно только владельцу.
+const (
+    ScanPending  = "pending"
+    ScanClean    = "clean"
+    ScanInfected = "infected"
+    ScanFailed   = "failed"
+)
+
+// AttachmentVariant — уменьшенная копия изображения-вложения
+type AttachmentVariant struct {
+    ID           uint      `gorm:"primaryKey" json:"-"`
+    AttachmentID string    `gorm:"uniqueIndex:idx_attachment_variants_name" json:"-"`
+    Name         string    `gorm:"uniqueIndex:idx_attachment_variants_name" json:"name" example:"thumbnail"`
+    Bucket       string    `json:"-"`
+    ObjectKey    string    `json:"-"`
+    ContentType  string    `json:"content_type" example:"image/jpeg"`
+    Width        int       `json:"width"`
+    Height       int       `json:"height"`
+    Size         int64     `json:"size"`
+    URL          string    `gorm:"-" json:"url"`
+    CreatedAt    time.Time `json:"created_at"`
+}
+
+// Варианты изображений
+const (
+    VariantThumbnail = "thumbnail"
+    VariantPreview   = "preview"
+)
+
+// Типы вложений
+const (
+    AttachmentImage    = "image"
+    AttachmentVideo    = "video"
+    AttachmentAudio    = "audio"
+    AttachmentDocument = "document"
+)
+
+// Типы сообщений, к которым привязываются вложения
+const (
+    MessageTypeText  = "text"
+    MessageTypeVoice = "voice"
+)
+
+// Объявление модели Upload — загрузки файла напрямую в хранилище по подписанной ссылке.
+// После подтверждения загрузки создается вложение, на которое могут ссылаться сообщения.
+type Upload struct {
+    ID           string    `gorm:"primaryKey" json:"id"`
+    OwnerID      string    `gorm:"index" json:"owner_id"`
+    Kind         string    `json:"kind" example:"audio"`
+    Bucket       string    `json:"-"`
+    ObjectKey    string    `json:"-"`
+    FileName     string    `json:"file_name" example:"voice.ogg"`
+    ContentType  string    `json:"content_type" example:"audio/ogg"` // Заявленный клиентом тип
+    Size         int64     `json:"size"`                             // Заявленный клиентом размер
+    Checksum     string    `json:"checksum,omitempty"`               // Ожидаемый SHA-256, если клиент его передал
+    Status       string    `gorm:"index" json:"status" example:"pending"`
+    AttachmentID string    `json:"attachment_id,omitempty"`
+    ExpiresAt    time.Time `gorm:"index" json:"expires_at"`
+    CreatedAt    time.Time `json:"created_at"`
+}
+
+// Статусы прямой загрузки
+const (
+    UploadPending   = "pending"
+    UploadCompleted = "completed"
+    UploadFailed    = "failed"
+)
+
+// Объявление модели TusUpload — возобновляемой загрузки по протоколу tus.
+// Данные накапливаются в составной загрузке хранилища; хвост меньше минимального размера части
+// хранится отдельным объектом до следующего запроса PATCH.
+type TusUpload struct {
+    ID           string    `gorm:"primaryKey" json:"id"`
+    OwnerID      string    `gorm:"index" json:"owner_id"`
+    Kind         string    `json:"kind"`
+    Bucket       string    `json:"-"`
+    ObjectKey    string    `json:"-"`
+    MultipartID  string    `json:"-"`
+    FileName     string    `json:"file_name"`
+    ContentType  string    `json:"content_type"`
+    Length       int64     `json:"length"`
+    Offset       int64     `json:"offset"`
+    PartCount    int       `json:"-"`
+    TailSize     int64     `json:"-"`
+    Status       string    `gorm:"index" json:"status"` // Статусы те же, что у Upload
+    AttachmentID string    `json:"attachment_id,omitempty"`
+    ExpiresAt    time.Time `gorm:"index" json:"expires_at"`
+    CreatedAt    time.Time `json:"created_at"`
+}
+
+// Объявление модели Job — фоновой задачи. Обработчики забирают задачи через
+// SELECT ... FOR UPDATE SKIP LOCKED, поэтому несколько экземпляров сервера не мешают друг другу.
+type Job struct {
+    ID          uint       `gorm:"primaryKey" json:"id"`
+    Type        string     `gorm:"index" json:"type" example:"transcription"`
+    Payload     RawJSON    `gorm:"type:jsonb;default:'{}'" json:"payload" swaggertype:"object"`
+    Status      string     `gorm:"index:idx_jobs_status_run_at,priority:1" json:"status" example:"pending"`
+    RunAt       time.Time  `gorm:"index:idx_jobs_status_run_at,priority:2" json:"run_at"` // Время следующего запуска
+    Attempts    int        `json:"attempts"`
+    MaxAttempts int        `json:"max_attempts"`
+    LastError   string     `json:"last_error,omitempty"`
+    LockedAt    *time.Time `json:"locked_at,omitempty"`
+    LockedBy    string     `json:"locked_by,omitempty"`
+    // Ключ уникальности: задача с тем же ключом не ставится повторно
+    UniqueKey   *string    `gorm:"uniqueIndex" json:"unique_key,omitempty"`
+    CompletedAt *time.Time `json:"completed_at,omitempty"`
+    CreatedAt   time.Time  `json:"created_at"`
+    UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// RawJSON — строка с JSON, которая выводится в ответах API как вложенный объект
+type RawJSON string
+
+func (j RawJSON) MarshalJSON() ([]byte, error) {
+    if j == "" {
+        return []byte("null"), nil
+    }
+    return []byte(j), nil
+}
+
+// GCReport — отчет о поиске и удалении файлов хранилища, на которые не ссылается база данных
+type GCReport struct {
+    ID            uint      `gorm:"primaryKey" json:"id"`
+    DryRun        bool      `json:"dry_run"`
+    GracePeriod   int64     `json:"grace_period"` // В секундах
+    Scanned       int       `json:"scanned"`
+    Orphaned      int       `json:"orphaned"`
+    OrphanedBytes int64     `json:"orphaned_bytes"`
+    Deleted       int       `json:"deleted"`
+    Errors        int       `json:"errors"`
+    Details       RawJSON   `gorm:"type:jsonb;default:'{}'" json:"details" swaggertype:"object"` // Итоги по бакетам и найденные объекты
+    StartedAt     time.Time `json:"started_at"`
+    FinishedAt    time.Time `json:"finished_at"`
+}
+
+// Статусы фоновой задачи
+const (
+    JobPending   = "pending"
+    JobRunning   = "running"
+    JobCompleted = "completed"
+    JobDead      = "dead" // Попытки исчерпаны, задача требует внимания администратора
+)
+
+// Объявление модели ContactRequest
+type ContactRequest struct {
+    ID          uint      `gorm:"primaryKey" json:"id"`
+    RequesterID string    `gorm:"index" json:"requester_id"`
+    AddresseeID string    `gorm:"index" json:"addressee_id"`
+    Status      string    `gorm:"index" json:"status" example:"pending"`
+    CreatedAt   time.Time `json:"created_at"`
+    UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Статусы заявки в контакты
+const (
+    ContactRequestPending   = "pending"
+    ContactRequestAccepted  = "accepted"
+    ContactRequestDeclined  = "declined"
+    ContactRequestCancelled = "cancelled"
+)
+
+// Объявление модели Contact. Связь хранится двумя записями — по одной для каждого пользователя,
+// чтобы у каждого было собственное имя контакта.
+type Contact struct {
+    OwnerID   string    `gorm:"primaryKey" json:"-"`
+    ContactID string    `gorm:"primaryKey" json:"contact_id"`
+    Nickname  string    `json:"nickname"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// Объявление модели Block: BlockerID заблокировал BlockedID
+type Block struct {
+    BlockerID string    `gorm:"primaryKey" json:"-"`
+    BlockedID string    `gorm:"primaryKey;index" json:"blocked_id"`
+    CreatedAt time.Time `json:"created_at"`
+}
+
+// Объявление модели ConversationMute: уведомления о сообщениях от PeerID не доставляются UserID
+type ConversationMute struct {
+    UserID     string     `gorm:"primaryKey" json:"-"`
+    PeerID     string     `gorm:"primaryKey" json:"peer_id"`
+    MutedUntil *time.Time `json:"muted_until"` // nil — без ограничения по времени
+    CreatedAt  time.Time  `json:"created_at"`
 }
 
 var DB *gorm.DB
 
-// InitDB инициализирует соединение с базой данных PostgreSQL
-func InitDB(cfg *Config) {
-    dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-        cfg.Postgres.Host, cfg.Postgres.Port, cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.DBName)
+// Models возвращает модели, таблицы которых создаются в базе SQLite
+func Models() []interface{} {
+    return []interface{}{&User{}, &TextMessage{}, &VoiceMessage{}, &ContactRequest{}, &Contact{}, &Block{}, &ConversationMute{},
+        &Attachment{}, &AttachmentVariant{}, &StorageUsage{}, &Upload{}, &TusUpload{}, &Job{}, &GCReport{}}
+}
 
+// InitDB подключается к базе данных и проверяет соединение.
+// Схема PostgreSQL создается и обновляется миграциями из пакета migrations,
+// схема SQLite — автоматически при подключении.
+func InitDB(cfg *Config) error {
+    var db *gorm.DB
     var err error
-    DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+    switch cfg.Database.Driver {
+    case DatabasePostgres, "":
+        dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+            cfg.Postgres.Host, cfg.Postgres.Port, cfg.Postgres.User, cfg.Postgres.Password, cfg.Postgres.DBName)
+        db, err = gorm.Open(postgres.Open(dsn), gormConfig())
+    case DatabaseSQLite:
+        db, err = openSQLite(cfg.Database.SQLitePath)
+    default:
+        return fmt.Errorf("неизвестный драйвер базы данных %q", cfg.Database.Driver)
+    }
     if err != nil {
-        log.Fatalf("Ошибка подключения к базе данных: %v", err)
+        // Пул неудачного подключения не нужен: при повторе создается новый
+        if db != nil {
+            if sqlDB, dbErr := db.DB(); dbErr == nil {
+                sqlDB.Close()
+            }
+        }
+        return err
+    }
+    DB = db
+    return nil
+}
+
+// gormConfig направляет журнал GORM в журнал сервера: записываются ошибки и медленные запросы.
+// Значения параметров запросов в журнал не попадают.
+func gormConfig() *gorm.Config {
+    return &gorm.Config{
+        Logger: logger.New(slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn), logger.Config{
+            SlowThreshold:             200 * time.Millisecond,
+            LogLevel:                  logger.Warn,
+            IgnoreRecordNotFoundError: true,
+            ParameterizedQueries:      true,
+        }),
     }
+}
 
-    // Автоматическая миграция схемы
-    if err := DB.AutoMigrate(&User{}, &TextMessage{}, &VoiceMessage{}); err != nil {
-        log.Fatalf("Ошибка миграции базы данных: %v", err)
+// openSQLite открывает базу SQLite и создает в ней таблицы моделей.
+// Транзакции сразу берут блокировку записи, а конкурирующие запросы ждут ее освобождения,
+// поэтому фоновые задачи и обработчики запросов не получают ошибку SQLITE_BUSY.
+func openSQLite(path string) (*gorm.DB, error) {
+    if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+        return nil, err
+    }
+    dsn := path + "?_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+    db, err := gorm.Open(sqlite.Open(dsn), gormConfig())
+    if err != nil {
+        return nil, err
     }
+    if err := db.AutoMigrate(Models()...); err != nil {
+        return nil, fmt.Errorf("ошибка создания таблиц: %w", err)
+    }
+    return db, nil
+}
+
+// IsSQLite сообщает, что сервер работает с базой SQLite. Запросы, использующие
+// возможности PostgreSQL, заменяются для нее упрощенными вариантами.
+func IsSQLite() bool {
+    return DB.Dialector.Name() == DatabaseSQLite
 }
@@ -1,5 +1,10 @@
 package config
 
+import (
+    "fmt"
+    "strconv"
+)
+
 // SimpleResponse представляет простой ответ с сообщением
 type SimpleResponse struct {
     Message string `json:"message" example:"Операция выполнена успешно"`
@@ -9,3 +14,52 @@ type SimpleResponse struct {
 type ErrorResponse struct {
     Error string `json:"error" example:"Описание ошибки"`
 }
+
+// AvatarSizes перечисляет размеры (в пикселях) миниатюр аватара, которые генерирует сервер
+var AvatarSizes = []int{64, 128, 256}
+
+// PublicUser представляет публичный профиль пользователя, доступный другим пользователям
+type PublicUser struct {
+    ID          string            `json:"id" example:"12345"`
+    Username    string            `json:"username" example:"john_doe"`
+    DisplayName string            `json:"display_name" example:"John Doe"`
+    Bio         string            `json:"bio" example:"Люблю голосовые сообщения"`
+    Avatar      map[string]string `json:"avatar,omitempty"` // Размер миниатюры -> URL
+}
+
+// PrivateUser представляет профиль пользователя, видимый только ему самому
+type PrivateUser struct {
+    PublicUser
+    Email         string `json:"email" example:"john@example.com"`
+    IsActive      bool   `json:"is_active"`
+    Discoverable  bool   `json:"discoverable"`
+    MessagePolicy string `json:"message_policy" example:"everyone"`
+}
+
+// Public возвращает публичные поля пользователя
+func (u User) Public() PublicUser {
+    profile := PublicUser{
+        ID:          u.ID,
+        Username:    u.Username,
+        DisplayName: u.DisplayName,
+        Bio:         u.Bio,
+    }
+    if u.AvatarID != "" {
+        profile.Avatar = make(map[string]string, len(AvatarSizes))
+        for _, size := range AvatarSizes {
+            profile.Avatar[strconv.Itoa(size)] = fmt.Sprintf("/users/%s/avatar/%d?v=%s", u.ID, size, u.AvatarID)
+        }
+    }
+    return profile
+}
+
+// Private возвращает все поля профиля, кроме хеша пароля
+func (u User) Private() PrivateUser {
+    return PrivateUser{
+        PublicUser:    u.Public(),
+        Email:         u.Email,
+        IsActive:      u.IsActive,
+        Discoverable:  u.Discoverable,
+        MessagePolicy: u.MessagePolicy,
+    }
+}
@@ -2,24 +2,27 @@ package config
 
 import (
     "context"
-    "log"
+    "time"
 
     "github.com/go-redis/redis/v8"
 )
 
 var RedisClient *redis.Client
-var Ctx = context.Background()
 
-// InitRedis инициализирует соединение с Redis
-func InitRedis(cfg *Config) {
-    RedisClient = redis.NewClient(&redis.Options{
-        Addr:     cfg.Redis.Addr,
-        Password: cfg.Redis.Password, // Используем пароль для Redis
-        DB:       cfg.Redis.DB,
-    })
-
-    _, err := RedisClient.Ping(Ctx).Result()
-    if err != nil {
-        log.Fatalf("Ошибка подключения к Redis: %v", err)
+// InitRedis создает клиент Redis и проверяет соединение.
+// Клиент создается один раз, повторный вызов после ошибки только повторяет проверку.
+func InitRedis(cfg *Config) error {
+    if RedisClient == nil {
+        timeout := time.Duration(cfg.Redis.Timeout) * time.Millisecond
+        RedisClient = redis.NewClient(&redis.Options{
+            Addr:     cfg.Redis.Addr,
+            Password: cfg.Redis.Password, // Используем пароль для Redis
+            DB:       cfg.Redis.DB,
+            // Команда, не получившая ответа за timeout, завершается ошибкой,
+            // даже если контекст вызывающего не ограничен по времени
+            ReadTimeout:  timeout,
+            WriteTimeout: timeout,
+        })
     }
+    return RedisClient.Ping(context.Background()).Err()
 }
@@ -0,0 +1,11 @@
+package config
+
+// Бакеты хранилища, используемые сервером
+const (
+    VoiceBucket      = "voice-messages"
+    AvatarBucket     = "avatars"
+    AttachmentBucket = "attachments"
+)
+
+// Buckets перечисляет бакеты, которые создаются при запуске сервера
+var Buckets = []string{VoiceBucket, AvatarBucket, AttachmentBucket}
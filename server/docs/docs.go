@@ -15,9 +15,9 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/login": {
+        "/admin/gc": {
             "post": {
-                "description": "Аутентифицирует пользователя и возвращает JWT токен",
+                "description": "Ставит в очередь поиск объектов хранилища, на которые не ссылается база данных. Объекты старше периода ожидания удаляются, в режиме dry_run только попадают в отчет. Отчет доступен в /admin/gc/reports после выполнения задачи. Доступно только администраторам.",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,25 +25,24 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
                 ],
-                "summary": "Аутентификация пользователя",
+                "summary": "Запуск сборки мусора хранилища",
                 "parameters": [
                     {
-                        "description": "Учетные данные пользователя",
-                        "name": "credentials",
+                        "description": "Параметры запуска",
+                        "name": "request",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/users.LoginRequest"
+                            "$ref": "#/definitions/admin.RunGCRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "202": {
+                        "description": "Accepted",
                         "schema": {
-                            "$ref": "#/definitions/users.TokenResponse"
+                            "$ref": "#/definitions/config.Job"
                         }
                     },
                     "400": {
@@ -52,8 +51,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/config.ErrorResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/config.ErrorResponse"
                         }
@@ -67,33 +66,22 @@ const docTemplate = `{
                 }
             }
         },
-        "/messages/text": {
+        "/admin/gc/reports": {
             "get": {
-                "description": "Возвращает список текстовых сообщений между двумя пользователями",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает последние отчеты сборки мусора без списка найденных объектов. Доступно только администраторам.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "text"
+                    "admin"
                 ],
-                "summary": "Получение текстовых сообщений",
+                "summary": "Отчеты сборки мусора хранилища",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID отправителя",
-                        "name": "sender_id",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "ID получателя",
-                        "name": "receiver_id",
-                        "in": "query",
-                        "required": true
+                        "type": "integer",
+                        "description": "Количество отчетов (по умолчанию 50, максимум 200)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -102,7 +90,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/config.TextMessage"
+                                "$ref": "#/definitions/config.GCReport"
                             }
                         }
                     },
@@ -112,6 +100,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/config.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -119,39 +113,42 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Отправляет текстовое сообщение от одного пользователя к другому",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/admin/gc/reports/{id}": {
+            "get": {
+                "description": "Возвращает отчет с итогами по бакетам и списком найденных объектов. Доступно только администраторам.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "text"
+                    "admin"
                 ],
-                "summary": "Отправка текстового сообщения",
+                "summary": "Отчет сборки мусора хранилища",
                 "parameters": [
                     {
-                        "description": "Текстовое сообщение",
-                        "name": "message",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/config.TextMessage"
-                        }
+                        "type": "integer",
+                        "description": "ID отчета",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/config.SimpleResponse"
+                            "$ref": "#/definitions/config.GCReport"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/config.ErrorResponse"
                         }
@@ -165,33 +162,40 @@ const docTemplate = `{
                 }
             }
         },
-        "/messages/voice": {
+        "/admin/jobs": {
             "get": {
-                "description": "Возвращает список голосовых сообщений между двумя пользователями",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает фоновые задачи, начиная с последних. Доступно только администраторам.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "voice"
+                    "admin"
                 ],
-                "summary": "Получение голосовых сообщений",
+                "summary": "Список фоновых задач",
                 "parameters": [
                     {
+                        "enum": [
+                            "pending",
+                            "running",
+                            "completed",
+                            "dead"
+                        ],
                         "type": "string",
-                        "description": "ID отправителя",
-                        "name": "sender_id",
-                        "in": "query",
-                        "required": true
+                        "description": "Статус задачи",
+                        "name": "status",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "ID получателя",
-                        "name": "receiver_id",
-                        "in": "query",
-                        "required": true
+                        "description": "Тип задачи",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество задач (по умолчанию 50, максимум 200)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -200,7 +204,7 @@ const docTemplate = `{
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/config.VoiceMessage"
+                                "$ref": "#/definitions/config.Job"
                             }
                         }
                     },
@@ -210,6 +214,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/config.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -217,51 +227,30 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Отправляет голосовое сообщение от одного пользователя к другому",
-                "consumes": [
-                    "multipart/form-data"
-                ],
+            }
+        },
+        "/admin/jobs/stats": {
+            "get": {
+                "description": "Возвращает количество задач по типам и статусам. Доступно только администраторам.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "voice"
-                ],
-                "summary": "Отправка голосового сообщения",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "ID отправителя",
-                        "name": "sender_id",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "ID получателя",
-                        "name": "receiver_id",
-                        "in": "formData",
-                        "required": true
-                    },
-                    {
-                        "type": "file",
-                        "description": "Аудиофайл",
-                        "name": "file",
-                        "in": "formData",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "Состояние очереди фоновых задач",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/config.SimpleResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/repository.JobStats"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/config.ErrorResponse"
                         }
@@ -275,42 +264,40 @@ const docTemplate = `{
                 }
             }
         },
-        "/users": {
-            "post": {
-                "description": "Создает нового пользователя и возвращает JWT токен",
-                "consumes": [
-                    "application/json"
-                ],
+        "/admin/jobs/{id}": {
+            "get": {
+                "description": "Возвращает задачу вместе с количеством попыток и последней ошибкой. Доступно только администраторам.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
                 ],
-                "summary": "Создание пользователя",
+                "summary": "Фоновая задача",
                 "parameters": [
                     {
-                        "description": "Информация о пользователе",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/config.User"
-                        }
+                        "type": "integer",
+                        "description": "ID задачи",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "token\" \"JWT токен",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/config.Job"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/config.ErrorResponse"
                         }
@@ -324,23 +311,20 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{id}": {
-            "get": {
-                "description": "Возвращает информацию о пользователе по ID",
-                "consumes": [
-                    "application/json"
-                ],
+        "/admin/jobs/{id}/retry": {
+            "post": {
+                "description": "Возвращает в очередь задачу в статусе dead, сбрасывая счетчик попыток. Доступно только администраторам.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
                 ],
-                "summary": "Получение информации о пользователе",
+                "summary": "Повтор фоновой задачи",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID пользователя",
+                        "type": "integer",
+                        "description": "ID задачи",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -350,7 +334,19 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/config.User"
+                            "$ref": "#/definitions/config.Job"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
                         }
                     },
                     "404": {
@@ -366,42 +362,35 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Обновляет информацию о пользователе по ID",
+            }
+        },
+        "/attachments": {
+            "post": {
+                "description": "Загружает изображение, видео, аудио или документ. Тип определяется по содержимому файла, размер ограничен для каждого типа отдельно, общий объем файлов пользователя — квотой (413). Вложение затем привязывается к сообщению. Изображения обрабатываются в фоне: метаданные удаляются, создаются эскизы и blurhash; до завершения обработки ссылка на изображение не возвращается.",
                 "consumes": [
-                    "application/json"
+                    "multipart/form-data"
                 ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "attachments"
                 ],
-                "summary": "Обновление информации о пользователе",
+                "summary": "Загрузка вложения",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID пользователя",
-                        "name": "id",
-                        "in": "path",
+                        "type": "file",
+                        "description": "Файл",
+                        "name": "file",
+                        "in": "formData",
                         "required": true
-                    },
-                    {
-                        "description": "Обновленная информация о пользователе",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/config.User"
-                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/config.User"
+                            "$ref": "#/definitions/config.Attachment"
                         }
                     },
                     "400": {
@@ -410,6 +399,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/config.ErrorResponse"
                         }
                     },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -417,9 +418,11 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Удаляет пользователя по ID",
+            }
+        },
+        "/attachments/{id}": {
+            "get": {
+                "description": "Возвращает метаданные вложения со ссылками на файл и уменьшенные варианты изображения. Доступно владельцу и участникам переписки, к сообщению которой оно привязано, пока никто из них не заблокировал другого; необработанные изображения — только владельцу.",
                 "consumes": [
                     "application/json"
                 ],
@@ -427,13 +430,13 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "attachments"
                 ],
-                "summary": "Удаление пользователя",
+                "summary": "Получение вложения",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID пользователя",
+                        "description": "ID вложения",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -443,7 +446,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/config.SimpleResponse"
+                            "$ref": "#/definitions/config.Attachment"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
                         }
                     },
                     "500": {
@@ -455,9 +464,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/users/{id}/activate": {
-            "post": {
-                "description": "Активирует учетную запись пользователя по ID",
+        "/blocks": {
+            "get": {
+                "description": "Возвращает пользователей, заблокированных текущим пользователем",
                 "consumes": [
                     "application/json"
                 ],
@@ -465,23 +474,17 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
-                ],
-                "summary": "Активация пользователя",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "ID пользователя",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
+                    "blocks"
                 ],
+                "summary": "Получение списка заблокированных пользователей",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/config.SimpleResponse"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/blocks.BlockResponse"
+                            }
                         }
                     },
                     "500": {
@@ -491,11 +494,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/users/{id}/deactivate": {
+            },
             "post": {
-                "description": "Деактивирует учетную запись пользователя по ID",
+                "description": "Блокирует пользователя: он не сможет писать текущему пользователю, видеть его профиль и находить в поиске. Контакты и заявки между пользователями удаляются.",
                 "consumes": [
                     "application/json"
                 ],
@@ -503,14 +504,2009 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "users"
+                    "blocks"
                 ],
-                "summary": "Деактивация пользователя",
+                "summary": "Блокировка пользователя",
                 "parameters": [
                     {
-                        "type": "string",
                         "description": "ID пользователя",
-                        "name": "id",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/blocks.BlockRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/config.Block"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/blocks/{id}": {
+            "delete": {
+                "description": "Снимает блокировку с пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "blocks"
+                ],
+                "summary": "Разблокировка пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.SimpleResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contacts": {
+            "get": {
+                "description": "Возвращает контакты текущего пользователя с их публичными профилями",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Получение списка контактов",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/contacts.ContactResponse"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contacts/requests": {
+            "get": {
+                "description": "Возвращает ожидающие заявки: входящие (по умолчанию) или исходящие",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Получение заявок в контакты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "incoming или outgoing",
+                        "name": "direction",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/config.ContactRequest"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Отправляет пользователю заявку в контакты. Если встречная заявка уже существует, она принимается.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Отправка заявки в контакты",
+                "parameters": [
+                    {
+                        "description": "ID пользователя",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/contacts.ContactRequestBody"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/config.ContactRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contacts/requests/{id}": {
+            "delete": {
+                "description": "Отменяет исходящую заявку в контакты",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Отмена заявки в контакты",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заявки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.ContactRequest"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contacts/requests/{id}/accept": {
+            "post": {
+                "description": "Принимает входящую заявку и добавляет пользователей в контакты друг друга",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Принятие заявки в контакты",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заявки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.ContactRequest"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contacts/requests/{id}/decline": {
+            "post": {
+                "description": "Отклоняет входящую заявку в контакты",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Отклонение заявки в контакты",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID заявки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.ContactRequest"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/contacts/{id}": {
+            "put": {
+                "description": "Задает имя, под которым контакт отображается у текущего пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Изменение имени контакта",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя-контакта",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Имя контакта",
+                        "name": "contact",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/contacts.UpdateContactRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.Contact"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет пользователя из контактов (у обоих пользователей)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "contacts"
+                ],
+                "summary": "Удаление контакта",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя-контакта",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.SimpleResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/{id}": {
+            "get": {
+                "description": "Отдает содержимое вложения через сервер. Поддерживает заголовок Range для докачки и перемотки.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "attachments"
+                ],
+                "summary": "Скачивание файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID вложения",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Диапазон байтов, например bytes=0-1023",
+                        "name": "Range",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Partial Content",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "416": {
+                        "description": "Requested Range Not Satisfiable",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Отвечает, пока процесс сервера работает. Зависимости не проверяются: если этот маршрут не отвечает, процесс нужно перезапустить.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка работоспособности процесса",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/login": {
+            "post": {
+                "description": "Аутентифицирует пользователя и возвращает JWT токен",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Аутентификация пользователя",
+                "parameters": [
+                    {
+                        "description": "Учетные данные пользователя",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/users.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/users.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/me/storage": {
+            "get": {
+                "description": "Возвращает объем файлов текущего пользователя, его квоту и ограничения на размер файлов и длительность голосовых сообщений",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Использование хранилища",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/users.StorageUsageResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/text": {
+            "get": {
+                "description": "Возвращает список текстовых сообщений между двумя пользователями. Если один из участников заблокировал другого, переписка скрыта для обоих: возвращается пустой список.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "text"
+                ],
+                "summary": "Получение текстовых сообщений",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID отправителя",
+                        "name": "sender_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID получателя",
+                        "name": "receiver_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/config.TextMessage"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Отправляет текстовое сообщение от одного пользователя к другому",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "text"
+                ],
+                "summary": "Отправка текстового сообщения",
+                "parameters": [
+                    {
+                        "description": "Текстовое сообщение",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/text.SendTextMessageRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.SimpleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/messages/voice": {
+            "get": {
+                "description": "Возвращает список голосовых сообщений между двумя пользователями. Если один из участников заблокировал другого, переписка скрыта для обоих: возвращается пустой список. У заблокированных сообщений и сообщений, файл которых еще проверяется, нет ссылки на файл.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "voice"
+                ],
+                "summary": "Получение голосовых сообщений",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID отправителя",
+                        "name": "sender_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID получателя",
+                        "name": "receiver_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/config.VoiceMessage"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Отправляет голосовое сообщение от одного пользователя к другому. Поддерживаются WAV, MP3, Ogg (Opus, Vorbis) и WebM; длительность, кодек и форма волны определяются по файлу. Аудиофайл передается в поле file или заранее загружается напрямую в хранилище через /uploads и передается как attachment_id. Размер файла, длительность записи и общий объем файлов пользователя ограничены (413). Если включена антивирусная проверка, ссылка на файл выдается только после нее; при обнаружении угрозы файл удаляется, сообщение блокируется, а участники получают уведомление attachment.infected с причиной.",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "voice"
+                ],
+                "summary": "Отправка голосового сообщения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID отправителя",
+                        "name": "sender_id",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID получателя",
+                        "name": "receiver_id",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Аудиофайл",
+                        "name": "file",
+                        "in": "formData"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID аудиофайла, загруженного через /uploads (вместо file)",
+                        "name": "attachment_id",
+                        "in": "formData"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.SimpleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/mutes": {
+            "get": {
+                "description": "Возвращает действующие отключения уведомлений текущего пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mutes"
+                ],
+                "summary": "Получение переписок с отключенными уведомлениями",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/config.ConversationMute"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/mutes/{id}": {
+            "put": {
+                "description": "Отключает уведомления о сообщениях от пользователя до указанного времени или бессрочно",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mutes"
+                ],
+                "summary": "Отключение уведомлений переписки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID собеседника",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Время окончания",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/mutes.MuteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.ConversationMute"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Снова включает уведомления о сообщениях от пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "mutes"
+                ],
+                "summary": "Включение уведомлений переписки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID собеседника",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.SimpleResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/notifications/ws": {
+            "get": {
+                "description": "Открывает WebSocket, по которому сервер отправляет уведомления текущему пользователю: каждое уведомление — JSON-объект с полями type, from, payload и created_at (новые сообщения, результат обработки вложений и распознавания речи). Уведомления из переписок с отключенными уведомлениями не приходят. Если клиент не успевает получать уведомления, лишние отбрасываются. Клиент должен отвечать на ping, иначе соединение закрывается. Пока соединение открыто, пользователь считается в сети.",
+                "tags": [
+                    "notifications"
+                ],
+                "summary": "Подписка на уведомления",
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols"
+                    },
+                    "400": {
+                        "description": "Bad Request"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Проверяет базу данных, Redis и хранилище файлов и возвращает состояние каждой зависимости. Пока хотя бы одна из них недоступна, сервер не должен получать запросы.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Проверка готовности",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/storage/{bucket}/{key}": {
+            "get": {
+                "description": "Отдает объект локального хранилища по ссылке, подписанной сервером. Используется, когда файлы хранятся не в MinIO.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "storage"
+                ],
+                "summary": "Скачивание файла по подписанной ссылке",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Бакет",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ объекта",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Срок действия ссылки (Unix-время)",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подпись ссылки",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Сохраняет тело запроса в локальное хранилище по ссылке, подписанной сервером. Используется для прямых загрузок, когда файлы хранятся не в MinIO.",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "storage"
+                ],
+                "summary": "Загрузка файла по подписанной ссылке",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Бакет",
+                        "name": "bucket",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Ключ объекта",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Срок действия ссылки (Unix-время)",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подпись ссылки",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер файла, который должен совпадать с Content-Length",
+                        "name": "content-length",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тип файла, который должен совпадать с Content-Type",
+                        "name": "content-type",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "411": {
+                        "description": "Length Required",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads": {
+            "post": {
+                "description": "Проверяет заявленные тип и размер файла и возвращает подписанную ссылку, по которой клиент загружает файл в хранилище запросом PUT с заголовками из ответа: хранилище примет только файл заявленного размера и типа. После загрузки ее нужно подтвердить через /uploads/{id}/complete.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Начало прямой загрузки файла",
+                "parameters": [
+                    {
+                        "description": "Параметры файла",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/uploads.CreateUploadRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/uploads.CreateUploadResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads/tus": {
+            "post": {
+                "description": "Создает загрузку по протоколу tus. В Upload-Metadata передаются filename, filetype и необязательный kind (image, video, audio, document) в base64. Адрес загрузки возвращается в заголовке Location.",
+                "tags": [
+                    "tus"
+                ],
+                "summary": "Создание возобновляемой загрузки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "1.0.0",
+                        "description": "Версия протокола",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер файла в байтах",
+                        "name": "Upload-Length",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Метаданные файла",
+                        "name": "Upload-Metadata",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "501": {
+                        "description": "Not Implemented",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "options": {
+                "description": "Возвращает версию протокола tus, поддерживаемые расширения и максимальный размер загрузки",
+                "tags": [
+                    "tus"
+                ],
+                "summary": "Возможности сервера tus",
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    }
+                }
+            }
+        },
+        "/uploads/tus/{id}": {
+            "delete": {
+                "description": "Отменяет незавершенную загрузку и удаляет полученные данные",
+                "tags": [
+                    "tus"
+                ],
+                "summary": "Отмена возобновляемой загрузки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "1.0.0",
+                        "description": "Версия протокола",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "423": {
+                        "description": "Locked",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "description": "Возвращает количество уже полученных байтов в заголовке Upload-Offset. Для завершенной загрузки в заголовке X-Attachment-Id возвращается ID вложения.",
+                "tags": [
+                    "tus"
+                ],
+                "summary": "Состояние возобновляемой загрузки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "1.0.0",
+                        "description": "Версия протокола",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Дописывает данные к загрузке начиная с Upload-Offset. После получения последнего байта файл проверяется и становится вложением, ID которого возвращается в заголовке X-Attachment-Id.",
+                "consumes": [
+                    "application/offset+octet-stream"
+                ],
+                "tags": [
+                    "tus"
+                ],
+                "summary": "Передача части файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "default": "1.0.0",
+                        "description": "Версия протокола",
+                        "name": "Tus-Resumable",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Смещение передаваемых данных",
+                        "name": "Upload-Offset",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "423": {
+                        "description": "Locked",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/uploads/{id}/complete": {
+            "post": {
+                "description": "Проверяет загруженный файл (размер, контрольную сумму и тип по содержимому) и создает вложение, на которое могут ссылаться сообщения. Если проверка не пройдена, файл удаляется.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "uploads"
+                ],
+                "summary": "Подтверждение прямой загрузки файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID загрузки",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/config.Attachment"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users": {
+            "post": {
+                "description": "Создает нового пользователя и возвращает JWT токен",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Создание пользователя",
+                "parameters": [
+                    {
+                        "description": "Информация о пользователе",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/users.CreateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "token\" \"JWT токен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/search": {
+            "get": {
+                "description": "Ищет активных пользователей по префиксу и триграммному сходству имени пользователя и отображаемого имени",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Поиск пользователей",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Поисковый запрос (минимум 2 символа)",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество результатов (по умолчанию 20, максимум 50)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/config.PublicUser"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}": {
+            "get": {
+                "description": "Возвращает публичный профиль пользователя по ID. Владельцу профиля дополнительно возвращаются email и настройки.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Получение информации о пользователе",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.PublicUser"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Обновляет информацию о пользователе по ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Обновление информации о пользователе",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Обновленная информация о пользователе",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/users.UpdateUserRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.PrivateUser"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет пользователя по ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Удаление пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.SimpleResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/activate": {
+            "post": {
+                "description": "Активирует учетную запись пользователя по ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Активация пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.SimpleResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/avatar": {
+            "put": {
+                "description": "Загружает изображение (JPEG, PNG, GIF, WebP) и сохраняет его квадратные миниатюры 64, 128 и 256 пикселей",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Загрузка аватара",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "Изображение",
+                        "name": "file",
+                        "in": "formData",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.PrivateUser"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет аватар пользователя",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Удаление аватара",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/config.SimpleResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/avatar/{size}": {
+            "get": {
+                "description": "Возвращает миниатюру аватара пользователя в формате JPEG",
+                "produces": [
+                    "image/jpeg"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Получение аватара",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер миниатюры (64, 128 или 256)",
+                        "name": "size",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/deactivate": {
+            "post": {
+                "description": "Деактивирует учетную запись пользователя по ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Деактивация пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     }
@@ -530,15 +2526,418 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "config.ErrorResponse": {
+        },
+        "/users/{id}/presence": {
+            "get": {
+                "description": "Возвращает, в сети ли пользователь, и время его последнего выхода из сети. Пользователь в сети, пока у него открыта подписка на уведомления (/notifications/ws). Изменения присутствия не рассылаются в уведомлениях — клиент запрашивает их сам. Заблокировавший пользователь не виден.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Присутствие пользователя в сети",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/users.PresenceResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/config.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "admin.RunGCRequest": {
+            "type": "object",
+            "properties": {
+                "dry_run": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "grace_period": {
+                    "description": "Минимальный возраст удаляемого объекта в секундах; по умолчанию из конфигурации",
+                    "type": "integer",
+                    "example": 86400
+                }
+            }
+        },
+        "blocks.BlockRequest": {
+            "type": "object",
+            "required": [
+                "user_id"
+            ],
+            "properties": {
+                "user_id": {
+                    "type": "string",
+                    "example": "12345"
+                }
+            }
+        },
+        "blocks.BlockResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "user": {
+                    "$ref": "#/definitions/config.PublicUser"
+                }
+            }
+        },
+        "config.Attachment": {
+            "type": "object",
+            "properties": {
+                "blurhash": {
+                    "type": "string",
+                    "example": "LEHV6nWB2yk8pyo0adR*.7kCMdnj"
+                },
+                "checksum": {
+                    "description": "SHA-256 в шестнадцатеричном виде",
+                    "type": "string"
+                },
+                "content_type": {
+                    "description": "Определяется по содержимому файла",
+                    "type": "string",
+                    "example": "image/jpeg"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "file_name": {
+                    "type": "string",
+                    "example": "photo.jpg"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "type": "string",
+                    "example": "image"
+                },
+                "message_id": {
+                    "type": "integer"
+                },
+                "message_type": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "string"
+                },
+                "processing_status": {
+                    "description": "Обработка изображений: перекодирование без метаданных, эскизы и blurhash",
+                    "type": "string",
+                    "example": "ready"
+                },
+                "scan_signature": {
+                    "type": "string"
+                },
+                "scan_status": {
+                    "description": "Антивирусная проверка; пустой статус — проверка не выполнялась",
+                    "type": "string",
+                    "example": "clean"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "variants": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/config.AttachmentVariant"
+                    }
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.AttachmentVariant": {
+            "type": "object",
+            "properties": {
+                "content_type": {
+                    "type": "string",
+                    "example": "image/jpeg"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "height": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "thumbnail"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                },
+                "width": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.Block": {
+            "type": "object",
+            "properties": {
+                "blocked_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.Contact": {
+            "type": "object",
+            "properties": {
+                "contact_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "nickname": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.ContactRequest": {
+            "type": "object",
+            "properties": {
+                "addressee_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "requester_id": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "pending"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.ConversationMute": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "muted_until": {
+                    "description": "nil — без ограничения по времени",
+                    "type": "string"
+                },
+                "peer_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string",
+                    "example": "Описание ошибки"
+                }
+            }
+        },
+        "config.GCReport": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer"
+                },
+                "details": {
+                    "description": "Итоги по бакетам и найденные объекты",
+                    "type": "object"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "errors": {
+                    "type": "integer"
+                },
+                "finished_at": {
+                    "type": "string"
+                },
+                "grace_period": {
+                    "description": "В секундах",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "orphaned": {
+                    "type": "integer"
+                },
+                "orphaned_bytes": {
+                    "type": "integer"
+                },
+                "scanned": {
+                    "type": "integer"
+                },
+                "started_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.Job": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "locked_at": {
+                    "type": "string"
+                },
+                "locked_by": {
+                    "type": "string"
+                },
+                "max_attempts": {
+                    "type": "integer"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "run_at": {
+                    "description": "Время следующего запуска",
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "pending"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "transcription"
+                },
+                "unique_key": {
+                    "description": "Ключ уникальности: задача с тем же ключом не ставится повторно",
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.PrivateUser": {
+            "type": "object",
+            "properties": {
+                "avatar": {
+                    "description": "Размер миниатюры -\u003e URL",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "bio": {
+                    "type": "string",
+                    "example": "Люблю голосовые сообщения"
+                },
+                "discoverable": {
+                    "type": "boolean"
+                },
+                "display_name": {
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "12345"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "message_policy": {
+                    "type": "string",
+                    "example": "everyone"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
+        "config.PublicUser": {
             "type": "object",
             "properties": {
-                "error": {
+                "avatar": {
+                    "description": "Размер миниатюры -\u003e URL",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "bio": {
                     "type": "string",
-                    "example": "Описание ошибки"
+                    "example": "Люблю голосовые сообщения"
+                },
+                "display_name": {
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "12345"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
                 }
             }
         },
@@ -554,6 +2953,19 @@ const docTemplate = `{
         "config.TextMessage": {
             "type": "object",
             "properties": {
+                "attachments": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/config.Attachment"
+                    }
+                },
+                "block_reason": {
+                    "type": "string"
+                },
+                "blocked": {
+                    "description": "Сообщение заблокировано, если во вложении обнаружено вредоносное содержимое",
+                    "type": "boolean"
+                },
                 "content": {
                     "type": "string"
                 },
@@ -571,51 +2983,282 @@ const docTemplate = `{
                 }
             }
         },
-        "config.User": {
+        "config.VoiceMessage": {
             "type": "object",
             "properties": {
-                "email": {
+                "attachment_id": {
+                    "type": "string"
+                },
+                "block_reason": {
+                    "type": "string"
+                },
+                "blocked": {
+                    "description": "Сообщение заблокировано, если в аудиофайле обнаружено вредоносное содержимое",
+                    "type": "boolean"
+                },
+                "codec": {
                     "type": "string",
-                    "example": "john@example.com"
+                    "example": "opus"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "file_url": {
+                    "description": "Временная подписанная ссылка, формируется при каждом запросе",
+                    "type": "string"
                 },
                 "id": {
+                    "type": "integer"
+                },
+                "receiver_id": {
+                    "type": "string"
+                },
+                "sample_rate": {
+                    "type": "integer",
+                    "example": 48000
+                },
+                "sender_id": {
+                    "type": "string"
+                },
+                "transcript": {
+                    "type": "string"
+                },
+                "transcript_language": {
+                    "type": "string",
+                    "example": "ru"
+                },
+                "transcription_status": {
+                    "description": "Распознанный текст; пустой статус — распознавание не запускалось",
+                    "type": "string",
+                    "example": "completed"
+                },
+                "waveform": {
+                    "description": "100 значений амплитуды 0–255",
+                    "type": "string",
+                    "format": "base64"
+                }
+            }
+        },
+        "contacts.ContactRequestBody": {
+            "type": "object",
+            "required": [
+                "user_id"
+            ],
+            "properties": {
+                "user_id": {
                     "type": "string",
                     "example": "12345"
+                }
+            }
+        },
+        "contacts.ContactResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
                 },
-                "is_active": {
-                    "description": "Новое поле",
-                    "type": "boolean"
+                "nickname": {
+                    "type": "string"
                 },
-                "password": {
+                "user": {
+                    "$ref": "#/definitions/config.PublicUser"
+                }
+            }
+        },
+        "contacts.UpdateContactRequest": {
+            "type": "object",
+            "properties": {
+                "nickname": {
                     "type": "string",
-                    "example": "secret"
+                    "example": "Коллега"
+                }
+            }
+        },
+        "health.ComponentStatus": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
                 },
-                "username": {
+                "latency_ms": {
+                    "type": "number",
+                    "example": 1.25
+                },
+                "status": {
                     "type": "string",
-                    "example": "john_doe"
+                    "example": "ok"
                 }
             }
         },
-        "config.VoiceMessage": {
+        "health.Report": {
             "type": "object",
             "properties": {
-                "created_at": {
+                "components": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.ComponentStatus"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "mutes.MuteRequest": {
+            "type": "object",
+            "properties": {
+                "until": {
+                    "description": "Время, до которого уведомления отключены (RFC 3339). Если не указано — бессрочно.",
+                    "type": "string",
+                    "example": "2030-01-01T00:00:00Z"
+                }
+            }
+        },
+        "repository.JobStats": {
+            "type": "object",
+            "properties": {
+                "count": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "status": {
+                    "type": "string",
+                    "example": "pending"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "transcription"
+                }
+            }
+        },
+        "text.SendTextMessageRequest": {
+            "type": "object",
+            "properties": {
+                "attachment_ids": {
+                    "description": "ID ранее загруженных вложений",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "content": {
+                    "type": "string",
+                    "example": "Привет!"
+                },
+                "receiver_id": {
+                    "type": "string",
+                    "example": "12345"
+                }
+            }
+        },
+        "uploads.CreateUploadRequest": {
+            "type": "object",
+            "required": [
+                "content_type",
+                "file_name",
+                "kind",
+                "size"
+            ],
+            "properties": {
+                "checksum": {
+                    "description": "SHA-256 содержимого в шестнадцатеричном виде; если указан, сверяется при подтверждении",
                     "type": "string"
                 },
-                "file_url": {
+                "content_type": {
+                    "type": "string",
+                    "example": "audio/ogg"
+                },
+                "file_name": {
+                    "type": "string",
+                    "example": "voice.ogg"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "image",
+                        "video",
+                        "audio",
+                        "document"
+                    ],
+                    "example": "audio"
+                },
+                "size": {
+                    "type": "integer",
+                    "example": 48213
+                }
+            }
+        },
+        "uploads.CreateUploadResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
                     "type": "string"
                 },
-                "id": {
+                "headers": {
+                    "description": "Заголовки, с которыми нужно отправить файл: они входят в подпись ссылки",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "max_size": {
                     "type": "integer"
                 },
-                "receiver_id": {
+                "method": {
+                    "type": "string",
+                    "example": "PUT"
+                },
+                "upload_id": {
                     "type": "string"
                 },
-                "sender_id": {
+                "upload_url": {
                     "type": "string"
                 }
             }
         },
+        "users.CreateUserRequest": {
+            "type": "object",
+            "required": [
+                "password",
+                "username"
+            ],
+            "properties": {
+                "bio": {
+                    "type": "string",
+                    "example": "Люблю голосовые сообщения"
+                },
+                "discoverable": {
+                    "description": "Показывать ли пользователя в поиске, по умолчанию true",
+                    "type": "boolean",
+                    "example": true
+                },
+                "display_name": {
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "message_policy": {
+                    "description": "Кто может начать переписку: everyone (по умолчанию) или contacts",
+                    "type": "string",
+                    "example": "everyone"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "secret"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
+        },
         "users.LoginRequest": {
             "type": "object",
             "required": [
@@ -633,6 +3276,72 @@ const docTemplate = `{
                 }
             }
         },
+        "users.PresenceResponse": {
+            "type": "object",
+            "properties": {
+                "last_seen_at": {
+                    "description": "Время закрытия последнего соединения; не возвращается, пока пользователь в сети\nи если он давно не подключался",
+                    "type": "string",
+                    "example": "2024-01-01T12:00:00Z"
+                },
+                "online": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "12345"
+                }
+            }
+        },
+        "users.StorageLimits": {
+            "type": "object",
+            "properties": {
+                "max_audio_size": {
+                    "type": "integer"
+                },
+                "max_document_size": {
+                    "type": "integer"
+                },
+                "max_image_size": {
+                    "type": "integer"
+                },
+                "max_video_size": {
+                    "type": "integer"
+                },
+                "max_voice_duration": {
+                    "description": "В секундах",
+                    "type": "integer"
+                }
+            }
+        },
+        "users.StorageUsageResponse": {
+            "type": "object",
+            "properties": {
+                "available_bytes": {
+                    "type": "integer",
+                    "example": 1063256064
+                },
+                "by_kind": {
+                    "description": "Объем вложений по типам, без эскизов",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
+                    }
+                },
+                "limits": {
+                    "$ref": "#/definitions/users.StorageLimits"
+                },
+                "quota_bytes": {
+                    "type": "integer",
+                    "example": 1073741824
+                },
+                "used_bytes": {
+                    "type": "integer",
+                    "example": 10485760
+                }
+            }
+        },
         "users.TokenResponse": {
             "type": "object",
             "properties": {
@@ -640,6 +3349,40 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "users.UpdateUserRequest": {
+            "type": "object",
+            "properties": {
+                "bio": {
+                    "type": "string",
+                    "example": "Люблю голосовые сообщения"
+                },
+                "discoverable": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "display_name": {
+                    "type": "string",
+                    "example": "John Doe"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "john@example.com"
+                },
+                "message_policy": {
+                    "description": "Кто может начать переписку: everyone или contacts",
+                    "type": "string",
+                    "example": "contacts"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "secret"
+                },
+                "username": {
+                    "type": "string",
+                    "example": "john_doe"
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -0,0 +1,339 @@
+// Package gc находит и удаляет объекты хранилища, на которые не ссылается база данных:
+// файлы, оставшиеся после ошибок записи в базу, прерванных загрузок и удаления пользователей.
+// Объекты моложе периода ожидания не трогаются, чтобы не удалить файл загрузки,
+// запись о которой еще не зафиксирована.
+package gc
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "strings"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/jobs"
+    "chatter-hub-server/logging"
+    "chatter-hub-server/storage"
+
+    "gorm.io/gorm"
+)
+
+// JobType — тип фоновой задачи сборки мусора
+const JobType = "storage.gc"
+
+const (
+    // batchSize — сколько ключей проверяется в базе одним запросом
+    batchSize = 500
+    // maxListedOrphans — сколько найденных объектов каждого бакета попадает в отчет
+    maxListedOrphans = 1000
+)
+
+// Options задает запуск сборки мусора
+type Options struct {
+    DryRun      bool          `json:"dry_run"`
+    GracePeriod time.Duration `json:"grace_period"`
+}
+
+// BucketReport — итоги по одному бакету
+type BucketReport struct {
+    Bucket        string   `json:"bucket"`
+    Scanned       int      `json:"scanned"`
+    Recent        int      `json:"recent"` // Объекты моложе периода ожидания
+    Orphaned      int      `json:"orphaned"`
+    OrphanedBytes int64    `json:"orphaned_bytes"`
+    Deleted       int      `json:"deleted"`
+    Errors        int      `json:"errors"`
+    Orphans       []Orphan `json:"orphans,omitempty"`
+}
+
+// Orphan — объект, на который не ссылается база данных
+type Orphan struct {
+    Key          string    `json:"key"`
+    Size         int64     `json:"size"`
+    LastModified time.Time `json:"last_modified"`
+}
+
+// Details — подробности отчета, сохраняемые в GCReport.Details
+type Details struct {
+    Buckets []BucketReport `json:"buckets"`
+    // Неотправленные вложения удаленных пользователей, записи о которых удалены
+    DeletedUserAttachments int64 `json:"deleted_user_attachments"`
+}
+
+// defaults — параметры автоматического запуска; задаются через Init
+var defaults = Options{GracePeriod: 24 * time.Hour}
+
+// Init регистрирует задачу сборки мусора и, если задан интервал, планирует ее
+func Init(cfg *config.Config) {
+    defaults = Options{
+        DryRun:      cfg.GC.DryRun,
+        GracePeriod: time.Duration(cfg.GC.GracePeriod) * time.Second,
+    }
+    jobs.Register(JobType, handle, jobs.Options{MaxAttempts: 1, Timeout: 6 * time.Hour})
+    if cfg.GC.Interval > 0 {
+        jobs.Schedule(JobType, time.Duration(cfg.GC.Interval)*time.Second, nil)
+    }
+}
+
+// Enqueue ставит сборку мусора в очередь. Параметры, равные нулю, берутся из конфигурации.
+func Enqueue(ctx context.Context, dryRun bool, gracePeriod time.Duration) (*config.Job, error) {
+    if gracePeriod <= 0 {
+        gracePeriod = defaults.GracePeriod
+    }
+    return jobs.Enqueue(ctx, JobType, Options{DryRun: dryRun, GracePeriod: gracePeriod}, jobs.EnqueueOptions{})
+}
+
+func handle(ctx context.Context, job *config.Job) error {
+    // Периодическая задача ставится без параметров и использует конфигурацию
+    opts := defaults
+    if job.Payload != "" && job.Payload != "{}" && job.Payload != "null" {
+        if err := jobs.DecodePayload(job, &opts); err != nil {
+            return err
+        }
+    }
+    _, err := Run(ctx, opts)
+    return err
+}
+
+// Run сверяет все бакеты с базой данных, удаляет найденные объекты (кроме режима DryRun)
+// и сохраняет отчет
+func Run(ctx context.Context, opts Options) (*config.GCReport, error) {
+    report := &config.GCReport{
+        DryRun:      opts.DryRun,
+        GracePeriod: int64(opts.GracePeriod.Seconds()),
+        StartedAt:   time.Now(),
+    }
+    cutoff := report.StartedAt.Add(-opts.GracePeriod)
+
+    var details Details
+    for _, bucket := range config.Buckets {
+        bucketReport, err := collectBucket(ctx, bucket, cutoff, opts.DryRun)
+        if err != nil {
+            return nil, err
+        }
+        details.Buckets = append(details.Buckets, *bucketReport)
+        report.Scanned += bucketReport.Scanned
+        report.Orphaned += bucketReport.Orphaned
+        report.OrphanedBytes += bucketReport.OrphanedBytes
+        report.Deleted += bucketReport.Deleted
+        report.Errors += bucketReport.Errors
+    }
+
+    // Файлы неотправленных вложений удаленных пользователей уже удалены вместе с остальными
+    // объектами без ссылок; теперь удаляем и записи о них
+    if !opts.DryRun {
+        count, err := deleteOwnerlessAttachments(ctx, cutoff)
+        if err != nil {
+            return nil, err
+        }
+        details.DeletedUserAttachments = count
+    }
+
+    data, err := json.Marshal(details)
+    if err != nil {
+        return nil, err
+    }
+    report.Details = config.RawJSON(data)
+    report.FinishedAt = time.Now()
+    if err := config.DB.WithContext(ctx).Create(report).Error; err != nil {
+        return nil, err
+    }
+
+    logging.FromContext(ctx).Info("Сборка мусора хранилища завершена", "dry_run", report.DryRun, "scanned", report.Scanned,
+        "orphaned", report.Orphaned, "orphaned_bytes", report.OrphanedBytes, "deleted", report.Deleted, "errors", report.Errors)
+    return report, nil
+}
+
+// collectBucket обходит бакет пачками по batchSize объектов
+func collectBucket(ctx context.Context, bucket string, cutoff time.Time, dryRun bool) (*BucketReport, error) {
+    report := &BucketReport{Bucket: bucket}
+    batch := make([]storage.ListedObject, 0, batchSize)
+
+    flush := func() error {
+        if len(batch) == 0 {
+            return nil
+        }
+        err := processBatch(ctx, bucket, batch, dryRun, report)
+        batch = batch[:0]
+        return err
+    }
+
+    err := storage.Store.List(ctx, bucket, func(object storage.ListedObject) error {
+        report.Scanned++
+        if object.LastModified.After(cutoff) {
+            report.Recent++
+            return nil
+        }
+        batch = append(batch, object)
+        if len(batch) == batchSize {
+            return flush()
+        }
+        return nil
+    })
+    if err == nil {
+        err = flush()
+    }
+    if errors.Is(err, storage.ErrNotFound) {
+        return report, nil
+    }
+    return report, err
+}
+
+// processBatch удаляет объекты пачки, на которые нет ссылок
+func processBatch(ctx context.Context, bucket string, batch []storage.ListedObject, dryRun bool, report *BucketReport) error {
+    keys := make([]string, len(batch))
+    for i, object := range batch {
+        keys[i] = object.Key
+    }
+    referenced, err := referencedKeys(ctx, bucket, keys)
+    if err != nil {
+        return err
+    }
+
+    for _, object := range batch {
+        if referenced[object.Key] {
+            continue
+        }
+        report.Orphaned++
+        report.OrphanedBytes += object.Size
+        if len(report.Orphans) < maxListedOrphans {
+            report.Orphans = append(report.Orphans, Orphan{Key: object.Key, Size: object.Size, LastModified: object.LastModified})
+        }
+        if dryRun {
+            continue
+        }
+        if err := storage.Store.Delete(ctx, bucket, object.Key); err != nil {
+            logging.FromContext(ctx).Error("Ошибка удаления объекта", "bucket", bucket, "key", object.Key, "error", err)
+            report.Errors++
+            continue
+        }
+        report.Deleted++
+    }
+    return nil
+}
+
+// referencedKeys возвращает ключи из keys, на которые ссылаются записи базы данных
+func referencedKeys(ctx context.Context, bucket string, keys []string) (map[string]bool, error) {
+    db := config.DB.WithContext(ctx)
+    referenced := make(map[string]bool, len(keys))
+    mark := func(found []string) {
+        for _, key := range found {
+            referenced[key] = true
+        }
+    }
+
+    // Вложения, кроме неотправленных вложений удаленных пользователей
+    var found []string
+    err := db.Model(&config.Attachment{}).
+        Where("bucket = ? AND object_key IN ?", bucket, keys).
+        Where("message_id IS NOT NULL OR owner_id IN (?)", db.Model(&config.User{}).Select("id")).
+        Pluck("object_key", &found).Error
+    if err != nil {
+        return nil, err
+    }
+    mark(found)
+
+    found = nil
+    err = db.Table("attachment_variants AS v").
+        Joins("JOIN attachments AS a ON a.id = v.attachment_id").
+        Where("v.bucket = ? AND v.object_key IN ?", bucket, keys).
+        Where("a.message_id IS NOT NULL OR a.owner_id IN (?)", db.Model(&config.User{}).Select("id")).
+        Pluck("v.object_key", &found).Error
+    if err != nil {
+        return nil, err
+    }
+    mark(found)
+
+    // Незавершенные прямые загрузки; файлы завершенных принадлежат вложениям
+    found = nil
+    err = db.Model(&config.Upload{}).
+        Where("bucket = ? AND object_key IN ? AND status = ?", bucket, keys, config.UploadPending).
+        Pluck("object_key", &found).Error
+    if err != nil {
+        return nil, err
+    }
+    mark(found)
+
+    // Возобновляемые загрузки хранят рядом с объектом незаполненную часть с суффиксом .tail
+    tusKeys := make([]string, 0, len(keys))
+    for _, key := range keys {
+        tusKeys = append(tusKeys, strings.TrimSuffix(key, ".tail"))
+    }
+    found = nil
+    err = db.Model(&config.TusUpload{}).
+        Where("bucket = ? AND object_key IN ?", bucket, tusKeys).
+        Pluck("object_key", &found).Error
+    if err != nil {
+        return nil, err
+    }
+    for _, key := range found {
+        referenced[key] = true
+        referenced[key+".tail"] = true
+    }
+
+    switch bucket {
+    case config.VoiceBucket:
+        found = nil
+        err = db.Model(&config.VoiceMessage{}).Where("object_key IN ?", keys).Pluck("object_key", &found).Error
+        if err != nil {
+            return nil, err
+        }
+        mark(found)
+    case config.AvatarBucket:
+        if err := markAvatars(ctx, keys, referenced); err != nil {
+            return nil, err
+        }
+    }
+    return referenced, nil
+}
+
+// markAvatars отмечает миниатюры текущих аватаров. Ключ миниатюры: <user>/<avatar>/<size>.jpg.
+func markAvatars(ctx context.Context, keys []string, referenced map[string]bool) error {
+    userIDs := make([]string, 0, len(keys))
+    for _, key := range keys {
+        if parts := strings.Split(key, "/"); len(parts) == 3 {
+            userIDs = append(userIDs, parts[0])
+        }
+    }
+    if len(userIDs) == 0 {
+        return nil
+    }
+
+    var users []config.User
+    if err := config.DB.WithContext(ctx).Select("id", "avatar_id").Where("id IN ? AND avatar_id <> ''", userIDs).Find(&users).Error; err != nil {
+        return err
+    }
+    current := make(map[string]string, len(users))
+    for _, user := range users {
+        current[user.ID] = user.AvatarID
+    }
+    for _, key := range keys {
+        parts := strings.Split(key, "/")
+        if len(parts) == 3 && current[parts[0]] == parts[1] {
+            referenced[key] = true
+        }
+    }
+    return nil
+}
+
+// deleteOwnerlessAttachments удаляет записи о неотправленных вложениях удаленных пользователей,
+// их варианты и учет объема
+func deleteOwnerlessAttachments(ctx context.Context, cutoff time.Time) (int64, error) {
+    var count int64
+    err := config.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        ownerless := tx.Model(&config.Attachment{}).Select("id").
+            Where("message_id IS NULL AND created_at < ? AND owner_id NOT IN (?)", cutoff, tx.Model(&config.User{}).Select("id"))
+        if err := tx.Where("attachment_id IN (?)", ownerless).Delete(&config.AttachmentVariant{}).Error; err != nil {
+            return err
+        }
+        result := tx.Where("message_id IS NULL AND created_at < ? AND owner_id NOT IN (?)", cutoff, tx.Model(&config.User{}).Select("id")).
+            Delete(&config.Attachment{})
+        if result.Error != nil {
+            return result.Error
+        }
+        count = result.RowsAffected
+        return tx.Where("user_id NOT IN (?)", tx.Model(&config.User{}).Select("id")).Delete(&config.StorageUsage{}).Error
+    })
+    return count, err
+}
@@ -0,0 +1,326 @@
+package gc
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/storage"
+)
+
+func TestMain(m *testing.M) {
+    slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+    dir, err := os.MkdirTemp("", "chatter-hub-gc")
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    cfg := &config.Config{
+        Database: config.DatabaseConfig{Driver: config.DatabaseSQLite, SQLitePath: filepath.Join(dir, "test.db")},
+        Storage:  config.StorageConfig{Driver: "memory"},
+    }
+    if err := config.InitDB(cfg); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    if err := storage.Init(cfg); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+
+    code := m.Run()
+    os.RemoveAll(dir)
+    os.Exit(code)
+}
+
+// reset очищает хранилище и таблицы, на которые смотрит сборка мусора
+func reset(t *testing.T) {
+    t.Helper()
+    ctx := context.Background()
+    for _, bucket := range config.Buckets {
+        var keys []string
+        storage.Store.List(ctx, bucket, func(object storage.ListedObject) error {
+            keys = append(keys, object.Key)
+            return nil
+        })
+        for _, key := range keys {
+            storage.Store.Delete(ctx, bucket, key)
+        }
+    }
+    for _, model := range []interface{}{
+        &config.User{}, &config.Attachment{}, &config.AttachmentVariant{}, &config.Upload{},
+        &config.TusUpload{}, &config.VoiceMessage{}, &config.GCReport{},
+    } {
+        if err := config.DB.Where("1 = 1").Delete(model).Error; err != nil {
+            t.Fatal(err)
+        }
+    }
+}
+
+// put сохраняет объект в хранилище
+func put(t *testing.T, bucket, key string) {
+    t.Helper()
+    if err := storage.Store.Put(context.Background(), bucket, key, strings.NewReader("data"), 4, "application/octet-stream"); err != nil {
+        t.Fatal(err)
+    }
+}
+
+// exists проверяет, что объект есть в хранилище
+func exists(t *testing.T, bucket, key string) bool {
+    t.Helper()
+    _, err := storage.Store.Stat(context.Background(), bucket, key)
+    if errors.Is(err, storage.ErrNotFound) {
+        return false
+    }
+    if err != nil {
+        t.Fatal(err)
+    }
+    return true
+}
+
+// create сохраняет записи в базе данных
+func create(t *testing.T, records ...interface{}) {
+    t.Helper()
+    for _, record := range records {
+        if err := config.DB.Create(record).Error; err != nil {
+            t.Fatal(err)
+        }
+    }
+}
+
+func TestRunReferences(t *testing.T) {
+    messageID := uint(1)
+    owner := &config.User{ID: "owner", Username: "owner", AvatarID: "current"}
+    const key = "object"
+
+    tests := []struct {
+        name     string
+        bucket   string
+        key      string
+        records  []interface{}
+        wantKept bool
+    }{
+        {
+            name: "вложение отправленного сообщения", bucket: config.AttachmentBucket, key: key,
+            records:  []interface{}{&config.Attachment{ID: "a", OwnerID: "deleted", Bucket: config.AttachmentBucket, ObjectKey: key, MessageID: &messageID}},
+            wantKept: true,
+        },
+        {
+            name: "неотправленное вложение пользователя", bucket: config.AttachmentBucket, key: key,
+            records:  []interface{}{owner, &config.Attachment{ID: "a", OwnerID: owner.ID, Bucket: config.AttachmentBucket, ObjectKey: key}},
+            wantKept: true,
+        },
+        {
+            name: "неотправленное вложение удаленного пользователя", bucket: config.AttachmentBucket, key: key,
+            records:  []interface{}{&config.Attachment{ID: "a", OwnerID: "deleted", Bucket: config.AttachmentBucket, ObjectKey: key}},
+            wantKept: false,
+        },
+        {
+            name: "вариант вложения", bucket: config.AttachmentBucket, key: key + "/thumbnail.jpg",
+            records: []interface{}{owner,
+                &config.Attachment{ID: "a", OwnerID: owner.ID, Bucket: config.AttachmentBucket, ObjectKey: key},
+                &config.AttachmentVariant{AttachmentID: "a", Name: "thumbnail", Bucket: config.AttachmentBucket, ObjectKey: key + "/thumbnail.jpg"},
+            },
+            wantKept: true,
+        },
+        {
+            name: "ссылка из другого бакета", bucket: config.VoiceBucket, key: key,
+            records:  []interface{}{&config.Attachment{ID: "a", OwnerID: "deleted", Bucket: config.AttachmentBucket, ObjectKey: key, MessageID: &messageID}},
+            wantKept: false,
+        },
+        {
+            name: "незавершенная прямая загрузка", bucket: config.AttachmentBucket, key: key,
+            records:  []interface{}{&config.Upload{ID: "u", OwnerID: owner.ID, Bucket: config.AttachmentBucket, ObjectKey: key, Status: config.UploadPending}},
+            wantKept: true,
+        },
+        {
+            name: "завершенная загрузка без вложения", bucket: config.AttachmentBucket, key: key,
+            records:  []interface{}{&config.Upload{ID: "u", OwnerID: owner.ID, Bucket: config.AttachmentBucket, ObjectKey: key, Status: config.UploadCompleted}},
+            wantKept: false,
+        },
+        {
+            name: "возобновляемая загрузка", bucket: config.AttachmentBucket, key: key,
+            records:  []interface{}{&config.TusUpload{ID: "t", OwnerID: owner.ID, Bucket: config.AttachmentBucket, ObjectKey: key}},
+            wantKept: true,
+        },
+        {
+            name: "хвост возобновляемой загрузки", bucket: config.AttachmentBucket, key: key + ".tail",
+            records:  []interface{}{&config.TusUpload{ID: "t", OwnerID: owner.ID, Bucket: config.AttachmentBucket, ObjectKey: key}},
+            wantKept: true,
+        },
+        {
+            name: "голосовое сообщение", bucket: config.VoiceBucket, key: key,
+            records:  []interface{}{&config.VoiceMessage{SenderID: owner.ID, ReceiverID: "receiver", ObjectKey: key}},
+            wantKept: true,
+        },
+        {
+            name: "текущий аватар", bucket: config.AvatarBucket, key: "owner/current/128.jpg",
+            records:  []interface{}{owner},
+            wantKept: true,
+        },
+        {
+            name: "прежний аватар", bucket: config.AvatarBucket, key: "owner/previous/128.jpg",
+            records:  []interface{}{owner},
+            wantKept: false,
+        },
+        {name: "объект без ссылок", bucket: config.AttachmentBucket, key: key, wantKept: false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            reset(t)
+            create(t, tt.records...)
+            put(t, tt.bucket, tt.key)
+
+            report, err := Run(context.Background(), Options{})
+            if err != nil {
+                t.Fatal(err)
+            }
+            if kept := exists(t, tt.bucket, tt.key); kept != tt.wantKept {
+                t.Fatalf("объект сохранен: %v, ожидалось %v", kept, tt.wantKept)
+            }
+            wantDeleted := 1
+            if tt.wantKept {
+                wantDeleted = 0
+            }
+            if report.Scanned != 1 || report.Orphaned != wantDeleted || report.Deleted != wantDeleted || report.Errors != 0 {
+                t.Fatalf("отчет %+v", report)
+            }
+        })
+    }
+}
+
+func TestRunDeletesOwnerlessAttachments(t *testing.T) {
+    reset(t)
+    owner := &config.User{ID: "owner", Username: "owner"}
+    create(t, owner,
+        &config.Attachment{ID: "kept", OwnerID: owner.ID, Bucket: config.AttachmentBucket, ObjectKey: "kept"},
+        &config.Attachment{ID: "ownerless", OwnerID: "deleted", Bucket: config.AttachmentBucket, ObjectKey: "ownerless"},
+        &config.AttachmentVariant{AttachmentID: "ownerless", Name: "thumbnail", Bucket: config.AttachmentBucket, ObjectKey: "ownerless/thumbnail.jpg"},
+    )
+
+    // Записи моложе периода ожидания не удаляются
+    if _, err := Run(context.Background(), Options{GracePeriod: time.Hour}); err != nil {
+        t.Fatal(err)
+    }
+    var count int64
+    config.DB.Model(&config.Attachment{}).Count(&count)
+    if count != 2 {
+        t.Fatalf("осталось %d вложений, ожидалось 2", count)
+    }
+
+    report, err := Run(context.Background(), Options{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    var ids []string
+    config.DB.Model(&config.Attachment{}).Pluck("id", &ids)
+    if len(ids) != 1 || ids[0] != "kept" {
+        t.Fatalf("остались вложения %v, ожидалось только kept", ids)
+    }
+    config.DB.Model(&config.AttachmentVariant{}).Count(&count)
+    if count != 0 {
+        t.Fatalf("осталось %d вариантов удаленного вложения", count)
+    }
+    var details Details
+    if err := json.Unmarshal([]byte(report.Details), &details); err != nil {
+        t.Fatal(err)
+    }
+    if details.DeletedUserAttachments != 1 {
+        t.Fatalf("удалено %d записей о вложениях, ожидалась 1", details.DeletedUserAttachments)
+    }
+}
+
+func TestRunOptions(t *testing.T) {
+    tests := []struct {
+        name        string
+        opts        Options
+        wantRecent  int
+        wantOrphans int
+        wantDeleted int
+    }{
+        {name: "объекты моложе периода ожидания", opts: Options{GracePeriod: time.Hour}, wantRecent: 2},
+        {name: "только отчет", opts: Options{DryRun: true}, wantOrphans: 1},
+        {name: "удаление", opts: Options{}, wantOrphans: 1, wantDeleted: 1},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            reset(t)
+            create(t, &config.VoiceMessage{SenderID: "sender", ReceiverID: "receiver", ObjectKey: "referenced"})
+            put(t, config.VoiceBucket, "referenced")
+            put(t, config.VoiceBucket, "orphan")
+
+            report, err := Run(context.Background(), tt.opts)
+            if err != nil {
+                t.Fatal(err)
+            }
+            if report.Orphaned != tt.wantOrphans || report.Deleted != tt.wantDeleted {
+                t.Fatalf("найдено %d, удалено %d; ожидалось %d и %d", report.Orphaned, report.Deleted, tt.wantOrphans, tt.wantDeleted)
+            }
+            if !exists(t, config.VoiceBucket, "referenced") {
+                t.Fatalf("удален объект, на который ссылается база данных")
+            }
+            if kept := exists(t, config.VoiceBucket, "orphan"); kept != (tt.wantDeleted == 0) {
+                t.Fatalf("объект без ссылок сохранен: %v", kept)
+            }
+
+            // Отчет сохраняется вместе со списком найденных объектов
+            var saved config.GCReport
+            if err := config.DB.First(&saved, report.ID).Error; err != nil {
+                t.Fatal(err)
+            }
+            var details Details
+            if err := json.Unmarshal([]byte(saved.Details), &details); err != nil {
+                t.Fatal(err)
+            }
+            for _, bucket := range details.Buckets {
+                if bucket.Bucket != config.VoiceBucket {
+                    continue
+                }
+                if bucket.Recent != tt.wantRecent || len(bucket.Orphans) != tt.wantOrphans {
+                    t.Fatalf("отчет по бакету %+v", bucket)
+                }
+                if tt.wantOrphans > 0 && bucket.Orphans[0].Key != "orphan" {
+                    t.Fatalf("в отчете %q вместо orphan", bucket.Orphans[0].Key)
+                }
+            }
+        })
+    }
+}
+
+func TestRunBatches(t *testing.T) {
+    reset(t)
+    // Объекты проверяются пачками; ссылка должна учитываться в любой из них
+    total := 2*batchSize + 10
+    var records []interface{}
+    for i := 0; i < total; i++ {
+        key := fmt.Sprintf("object-%04d", i)
+        put(t, config.VoiceBucket, key)
+        if i%100 == 0 {
+            records = append(records, &config.VoiceMessage{SenderID: "sender", ReceiverID: "receiver", ObjectKey: key})
+        }
+    }
+    create(t, records...)
+
+    report, err := Run(context.Background(), Options{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if report.Scanned != total || report.Deleted != total-len(records) {
+        t.Fatalf("проверено %d, удалено %d; ожидалось %d и %d", report.Scanned, report.Deleted, total, total-len(records))
+    }
+    for i := 0; i < total; i += 100 {
+        if key := fmt.Sprintf("object-%04d", i); !exists(t, config.VoiceBucket, key) {
+            t.Fatalf("удален объект %s, на который ссылается база данных", key)
+        }
+    }
+}
@@ -0,0 +1,107 @@
+// Package health проверяет доступность зависимостей сервера: базы данных, Redis и хранилища.
+// Проверки используются маршрутом /readyz, а Retry — при запуске, чтобы сервер дожидался
+// зависимостей, запущенных одновременно с ним, вместо немедленного завершения.
+package health
+
+import (
+    "context"
+    "sort"
+    "sync"
+    "time"
+)
+
+// Состояния компонентов и сервера
+const (
+    StatusOK          = "ok"
+    StatusUnavailable = "unavailable"
+)
+
+// Check проверяет одну зависимость; ошибка означает, что она недоступна
+type Check func(ctx context.Context) error
+
+// ComponentStatus — результат проверки одной зависимости
+type ComponentStatus struct {
+    Status    string  `json:"status" example:"ok"`
+    LatencyMs float64 `json:"latency_ms" example:"1.25"`
+    Error     string  `json:"error,omitempty"`
+}
+
+// Report — результат проверки всех зависимостей
+type Report struct {
+    Status     string                     `json:"status" example:"ok"`
+    Components map[string]ComponentStatus `json:"components,omitempty"`
+}
+
+// Ready сообщает, что все зависимости доступны
+func (r Report) Ready() bool {
+    return r.Status == StatusOK
+}
+
+type component struct {
+    name  string
+    check Check
+}
+
+// Checker выполняет проверки зарегистрированных зависимостей
+type Checker struct {
+    timeout    time.Duration
+    components []component
+}
+
+// NewChecker создает Checker, который ждет ответа каждой зависимости не дольше timeout
+func NewChecker(timeout time.Duration) *Checker {
+    return &Checker{timeout: timeout}
+}
+
+// Add регистрирует проверку зависимости name. Вызывается до начала обработки запросов.
+func (c *Checker) Add(name string, check Check) {
+    c.components = append(c.components, component{name: name, check: check})
+    sort.Slice(c.components, func(i, j int) bool { return c.components[i].name < c.components[j].name })
+}
+
+// Run проверяет все зависимости одновременно. Медленная зависимость не задерживает
+// ответ дольше timeout и считается недоступной.
+func (c *Checker) Run(ctx context.Context) Report {
+    report := Report{Status: StatusOK, Components: make(map[string]ComponentStatus, len(c.components))}
+    var mu sync.Mutex
+    var wg sync.WaitGroup
+    for _, comp := range c.components {
+        wg.Add(1)
+        go func(comp component) {
+            defer wg.Done()
+            status := c.run(ctx, comp.check)
+            mu.Lock()
+            defer mu.Unlock()
+            report.Components[comp.name] = status
+            if status.Status != StatusOK {
+                report.Status = StatusUnavailable
+            }
+        }(comp)
+    }
+    wg.Wait()
+    return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) ComponentStatus {
+    ctx, cancel := context.WithTimeout(ctx, c.timeout)
+    defer cancel()
+
+    start := time.Now()
+    done := make(chan error, 1)
+    // Проверка может не учитывать контекст, поэтому ответ не ждет ее дольше тайм-аута
+    go func() { done <- check(ctx) }()
+
+    var err error
+    select {
+    case err = <-done:
+    case <-ctx.Done():
+        err = ctx.Err()
+    }
+
+    status := ComponentStatus{Status: StatusOK, LatencyMs: float64(time.Since(start).Microseconds()) / 1000}
+    if err != nil {
+        status.Status = StatusUnavailable
+        status.Error = err.Error()
+    }
+    return status
+}
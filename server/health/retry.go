@@ -0,0 +1,43 @@
+package health
+
+import (
+    "fmt"
+    "log/slog"
+    "time"
+)
+
+// Задержки между попытками подключения при запуске
+const (
+    minRetryDelay = 500 * time.Millisecond
+    maxRetryDelay = 15 * time.Second
+)
+
+// Retry вызывает connect, пока подключение к зависимости name не удастся или не истечет timeout.
+// Задержка между попытками растет вдвое, но не превышает maxRetryDelay.
+func Retry(name string, timeout time.Duration, connect func() error) error {
+    deadline := time.Now().Add(timeout)
+    delay := minRetryDelay
+    for attempt := 1; ; attempt++ {
+        err := connect()
+        if err == nil {
+            if attempt > 1 {
+                slog.Info("Зависимость доступна", "component", name, "attempts", attempt)
+            }
+            return nil
+        }
+
+        if remaining := time.Until(deadline); remaining <= 0 {
+            return fmt.Errorf("%s недоступен после %d попыток: %w", name, attempt, err)
+        } else if delay > remaining {
+            delay = remaining
+        }
+        slog.Warn("Зависимость недоступна, повторяем подключение",
+            "component", name, "attempt", attempt, "retry_in", delay.String(), "error", err)
+        time.Sleep(delay)
+
+        delay *= 2
+        if delay > maxRetryDelay {
+            delay = maxRetryDelay
+        }
+    }
+}
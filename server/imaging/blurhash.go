@@ -0,0 +1,111 @@
+package imaging
+
+import (
+    "image"
+    "math"
+    "strings"
+)
+
+// Компоненты blurhash по горизонтали и вертикали: 4x3 достаточно для заглушки на время загрузки
+const (
+    blurhashX = 4
+    blurhashY = 3
+    // blurhashSide — размер уменьшенной копии, по которой считается blurhash
+    blurhashSide = 32
+)
+
+const base83 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz#$%*+,-.:;=?@[]^_{|}~"
+
+// Blurhash возвращает компактное текстовое представление размытого изображения
+// (https://blurha.sh), которое клиент показывает, пока загружается эскиз
+func Blurhash(src image.Image) string {
+    img := Fit(src, blurhashSide)
+    bounds := img.Bounds()
+    width, height := bounds.Dx(), bounds.Dy()
+
+    // Переводим пиксели в линейное пространство один раз
+    linear := make([][3]float64, width*height)
+    for y := 0; y < height; y++ {
+        for x := 0; x < width; x++ {
+            r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+            linear[y*width+x] = [3]float64{srgbToLinear(r >> 8), srgbToLinear(g >> 8), srgbToLinear(b >> 8)}
+        }
+    }
+
+    factors := make([][3]float64, 0, blurhashX*blurhashY)
+    for j := 0; j < blurhashY; j++ {
+        for i := 0; i < blurhashX; i++ {
+            normalisation := 2.0
+            if i == 0 && j == 0 {
+                normalisation = 1
+            }
+            var factor [3]float64
+            for y := 0; y < height; y++ {
+                for x := 0; x < width; x++ {
+                    basis := normalisation *
+                        math.Cos(math.Pi*float64(i)*float64(x)/float64(width)) *
+                        math.Cos(math.Pi*float64(j)*float64(y)/float64(height))
+                    pixel := linear[y*width+x]
+                    factor[0] += basis * pixel[0]
+                    factor[1] += basis * pixel[1]
+                    factor[2] += basis * pixel[2]
+                }
+            }
+            scale := 1 / float64(width*height)
+            factors = append(factors, [3]float64{factor[0] * scale, factor[1] * scale, factor[2] * scale})
+        }
+    }
+
+    var hash strings.Builder
+    encode83(&hash, (blurhashX-1)+(blurhashY-1)*9, 1)
+
+    dc, ac := factors[0], factors[1:]
+    maximum := 0.0
+    for _, factor := range ac {
+        for _, component := range factor {
+            maximum = math.Max(maximum, math.Abs(component))
+        }
+    }
+    quantisedMaximum := int(math.Max(0, math.Min(82, math.Floor(maximum*166-0.5))))
+    maximumValue := float64(quantisedMaximum+1) / 166
+    encode83(&hash, quantisedMaximum, 1)
+
+    encode83(&hash, linearToSRGB(dc[0])<<16+linearToSRGB(dc[1])<<8+linearToSRGB(dc[2]), 4)
+    for _, factor := range ac {
+        quantised := func(v float64) int {
+            return int(math.Max(0, math.Min(18, math.Floor(signPow(v/maximumValue, 0.5)*9+9.5))))
+        }
+        encode83(&hash, quantised(factor[0])*19*19+quantised(factor[1])*19+quantised(factor[2]), 2)
+    }
+    return hash.String()
+}
+
+func encode83(b *strings.Builder, value, length int) {
+    for i := 1; i <= length; i++ {
+        divisor := 1
+        for k := 0; k < length-i; k++ {
+            divisor *= 83
+        }
+        b.WriteByte(base83[(value/divisor)%83])
+    }
+}
+
+func srgbToLinear(value uint32) float64 {
+    v := float64(value) / 255
+    if v <= 0.04045 {
+        return v / 12.92
+    }
+    return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+func linearToSRGB(value float64) int {
+    v := math.Max(0, math.Min(1, value))
+    if v <= 0.0031308 {
+        return int(v*12.92*255 + 0.5)
+    }
+    return int((1.055*math.Pow(v, 1/2.4)-0.055)*255 + 0.5)
+}
+
+func signPow(value, exp float64) float64 {
+    return math.Copysign(math.Pow(math.Abs(value), exp), value)
+}
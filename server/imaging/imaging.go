@@ -0,0 +1,77 @@
+package imaging
+
+import (
+    "errors"
+    "image"
+    "image/jpeg"
+    "io"
+
+    // Регистрируем поддерживаемые форматы для image.Decode
+    _ "image/gif"
+    _ "image/png"
+
+    "golang.org/x/image/draw"
+    _ "golang.org/x/image/webp"
+)
+
+// MaxPixels ограничивает размер декодируемого изображения, чтобы защититься от "бомб" декомпрессии
+const MaxPixels = 40_000_000
+
+var (
+    ErrUnsupportedFormat = errors.New("неподдерживаемый формат изображения")
+    ErrTooLarge          = errors.New("слишком большое изображение")
+)
+
+// Dimensions возвращает ширину и высоту изображения, не декодируя его целиком
+func Dimensions(r io.Reader) (int, int, error) {
+    cfg, _, err := image.DecodeConfig(r)
+    if err != nil {
+        return 0, 0, ErrUnsupportedFormat
+    }
+    return cfg.Width, cfg.Height, nil
+}
+
+// Decode проверяет размеры изображения и декодирует его.
+// Поддерживаются JPEG, PNG, GIF и WebP.
+func Decode(r io.ReadSeeker) (image.Image, string, error) {
+    cfg, format, err := image.DecodeConfig(r)
+    if err != nil {
+        return nil, "", ErrUnsupportedFormat
+    }
+    if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+        return nil, "", ErrTooLarge
+    }
+
+    if _, err := r.Seek(0, io.SeekStart); err != nil {
+        return nil, "", err
+    }
+
+    img, format, err := image.Decode(r)
+    if err != nil {
+        return nil, "", ErrUnsupportedFormat
+    }
+    return img, format, nil
+}
+
+// SquareThumbnail вырезает квадрат из центра изображения и масштабирует его до size x size
+func SquareThumbnail(src image.Image, size int) image.Image {
+    bounds := src.Bounds()
+    side := bounds.Dx()
+    if bounds.Dy() < side {
+        side = bounds.Dy()
+    }
+    x0 := bounds.Min.X + (bounds.Dx()-side)/2
+    y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+    crop := image.Rect(x0, y0, x0+side, y0+side)
+
+    // Прозрачные области заливаем белым, так как JPEG не поддерживает альфа-канал
+    dst := image.NewRGBA(image.Rect(0, 0, size, size))
+    draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+    draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Over, nil)
+    return dst
+}
+
+// EncodeJPEG кодирует изображение в JPEG. Метаданные исходного файла при этом не сохраняются.
+func EncodeJPEG(w io.Writer, img image.Image, quality int) error {
+    return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+}
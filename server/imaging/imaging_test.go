@@ -0,0 +1,347 @@
+package imaging
+
+import (
+    "bytes"
+    "encoding/binary"
+    "errors"
+    "hash/crc32"
+    "image"
+    "image/color"
+    "image/gif"
+    "image/jpeg"
+    "image/png"
+    "strings"
+    "testing"
+)
+
+var (
+    red  = color.RGBA{R: 255, A: 255}
+    blue = color.RGBA{B: 255, A: 255}
+)
+
+// secret — метаданные, которые не должны попасть в перекодированный файл
+const secret = "GPS 55.7558 37.6173"
+
+// markedImage возвращает синее изображение с красным левым верхним пикселем
+func markedImage(width, height int) *image.RGBA {
+    img := image.NewRGBA(image.Rect(0, 0, width, height))
+    for y := 0; y < height; y++ {
+        for x := 0; x < width; x++ {
+            img.Set(x, y, blue)
+        }
+    }
+    img.Set(0, 0, red)
+    return img
+}
+
+// exifSegment возвращает сегмент APP1 с EXIF, содержащим ориентацию и строку secret
+func exifSegment(order binary.ByteOrder, orientation uint16) []byte {
+    tiff := make([]byte, 26)
+    if order == binary.LittleEndian {
+        copy(tiff, "II")
+    } else {
+        copy(tiff, "MM")
+    }
+    order.PutUint16(tiff[2:], 42)
+    order.PutUint32(tiff[4:], 8)
+    order.PutUint16(tiff[8:], 1)
+    order.PutUint16(tiff[10:], 0x0112)
+    order.PutUint16(tiff[12:], 3)
+    order.PutUint32(tiff[14:], 1)
+    order.PutUint16(tiff[18:], orientation)
+    tiff = append(tiff, secret...)
+
+    payload := append([]byte("Exif\x00\x00"), tiff...)
+    segment := []byte{0xFF, 0xE1, 0, 0}
+    binary.BigEndian.PutUint16(segment[2:], uint16(len(payload)+2))
+    return append(segment, payload...)
+}
+
+// jpegFile кодирует img в JPEG и вставляет сегменты метаданных после SOI
+func jpegFile(t *testing.T, img image.Image, segments ...[]byte) []byte {
+    t.Helper()
+    var buf bytes.Buffer
+    if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+        t.Fatal(err)
+    }
+    data := buf.Bytes()
+    file := append([]byte(nil), data[:2]...)
+    for _, segment := range segments {
+        file = append(file, segment...)
+    }
+    return append(file, data[2:]...)
+}
+
+// pngChunk возвращает блок PNG с контрольной суммой
+func pngChunk(kind string, data []byte) []byte {
+    chunk := make([]byte, 4, 12+len(data))
+    binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+    chunk = append(append(chunk, kind...), data...)
+    return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+// pngFile кодирует img в PNG и вставляет текстовый блок с secret после IHDR
+func pngFile(t *testing.T, img image.Image) []byte {
+    t.Helper()
+    var buf bytes.Buffer
+    if err := png.Encode(&buf, img); err != nil {
+        t.Fatal(err)
+    }
+    data := buf.Bytes()
+    const ihdrEnd = 8 + 25
+    file := append([]byte(nil), data[:ihdrEnd]...)
+    file = append(file, pngChunk("tEXt", []byte("Comment\x00"+secret))...)
+    return append(file, data[ihdrEnd:]...)
+}
+
+// pngHeader возвращает начало PNG с заголовком IHDR без данных изображения
+func pngHeader(width, height uint32) []byte {
+    ihdr := make([]byte, 13)
+    binary.BigEndian.PutUint32(ihdr, width)
+    binary.BigEndian.PutUint32(ihdr[4:], height)
+    ihdr[8], ihdr[9] = 8, 6 // 8 бит, RGBA
+    return append([]byte("\x89PNG\r\n\x1a\n"), pngChunk("IHDR", ihdr)...)
+}
+
+// gifFile кодирует анимацию из frames кадров
+func gifFile(t *testing.T, frames int) []byte {
+    t.Helper()
+    animation := &gif.GIF{}
+    palette := color.Palette{red, blue}
+    for i := 0; i < frames; i++ {
+        frame := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+        frame.SetColorIndex(i%4, 0, 1)
+        animation.Image = append(animation.Image, frame)
+        animation.Delay = append(animation.Delay, 10)
+    }
+    var buf bytes.Buffer
+    if err := gif.EncodeAll(&buf, animation); err != nil {
+        t.Fatal(err)
+    }
+    return buf.Bytes()
+}
+
+// isRed проверяет цвет пикселя с допуском на сжатие JPEG
+func isRed(c color.Color) bool {
+    r, g, b, _ := c.RGBA()
+    return r>>8 > 160 && g>>8 < 100 && b>>8 < 100
+}
+
+func TestOrientation(t *testing.T) {
+    plain := jpegFile(t, markedImage(4, 4))
+    tests := []struct {
+        name string
+        data []byte
+        want int
+    }{
+        {name: "без EXIF", data: plain, want: 1},
+        {name: "Intel", data: jpegFile(t, markedImage(4, 4), exifSegment(binary.LittleEndian, 6)), want: 6},
+        {name: "Motorola", data: jpegFile(t, markedImage(4, 4), exifSegment(binary.BigEndian, 3)), want: 3},
+        {name: "недопустимое значение", data: jpegFile(t, markedImage(4, 4), exifSegment(binary.LittleEndian, 9)), want: 1},
+        {name: "обрезанный TIFF", data: append([]byte{0xFF, 0xD8}, exifSegment(binary.LittleEndian, 6)[:20]...), want: 1},
+        {name: "длина сегмента больше файла", data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF, 'E', 'x'}, want: 1},
+        {name: "не JPEG", data: pngFile(t, markedImage(4, 4)), want: 1},
+        {name: "пустые данные", data: nil, want: 1},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := Orientation(tt.data); got != tt.want {
+                t.Fatalf("ориентация %d, ожидалась %d", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestApplyOrientation(t *testing.T) {
+    const w, h = 3, 2
+    tests := []struct {
+        orientation   int
+        width, height int
+        marked        image.Point // Куда попадает левый верхний пиксель исходного изображения
+    }{
+        {orientation: 1, width: w, height: h, marked: image.Pt(0, 0)},
+        {orientation: 2, width: w, height: h, marked: image.Pt(w-1, 0)},
+        {orientation: 3, width: w, height: h, marked: image.Pt(w-1, h-1)},
+        {orientation: 4, width: w, height: h, marked: image.Pt(0, h-1)},
+        {orientation: 5, width: h, height: w, marked: image.Pt(0, 0)},
+        {orientation: 6, width: h, height: w, marked: image.Pt(h-1, 0)},
+        {orientation: 7, width: h, height: w, marked: image.Pt(h-1, w-1)},
+        {orientation: 8, width: h, height: w, marked: image.Pt(0, w-1)},
+    }
+    for _, tt := range tests {
+        t.Run(string(rune('0'+tt.orientation)), func(t *testing.T) {
+            img := ApplyOrientation(markedImage(w, h), tt.orientation)
+            if bounds := img.Bounds(); bounds.Dx() != tt.width || bounds.Dy() != tt.height {
+                t.Fatalf("размер %dx%d, ожидался %dx%d", bounds.Dx(), bounds.Dy(), tt.width, tt.height)
+            }
+            for y := 0; y < tt.height; y++ {
+                for x := 0; x < tt.width; x++ {
+                    if want := image.Pt(x, y) == tt.marked; isRed(img.At(x, y)) != want {
+                        t.Fatalf("пиксель (%d, %d): отмеченный %v, ожидалось %v", x, y, !want, want)
+                    }
+                }
+            }
+        })
+    }
+}
+
+func TestSanitize(t *testing.T) {
+    transparent := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+    transparent.Set(1, 1, red)
+
+    tests := []struct {
+        name          string
+        data          []byte
+        contentType   string
+        width, height int
+        wantErr       error
+    }{
+        {
+            name:        "JPEG с EXIF и поворотом",
+            data:        jpegFile(t, markedImage(16, 8), exifSegment(binary.LittleEndian, 6), append([]byte{0xFF, 0xFE, 0, byte(len(secret) + 2)}, secret...)),
+            contentType: "image/jpeg", width: 8, height: 16,
+        },
+        {name: "PNG с текстовым блоком", data: pngFile(t, markedImage(8, 4)), contentType: "image/png", width: 8, height: 4},
+        {name: "PNG с прозрачностью", data: pngFile(t, transparent), contentType: "image/png", width: 4, height: 4},
+        {name: "анимированный GIF", data: gifFile(t, 3), contentType: "image/gif", width: 4, height: 4},
+        {name: "не изображение", data: []byte("not an image"), wantErr: ErrUnsupportedFormat},
+        {name: "обрезанный PNG", data: pngFile(t, markedImage(8, 4))[:60], wantErr: ErrUnsupportedFormat},
+        {name: "слишком большое изображение", data: pngHeader(10000, 10000), wantErr: ErrTooLarge},
+        {name: "нулевой размер", data: pngHeader(0, 10), wantErr: ErrUnsupportedFormat},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            result, err := Sanitize(tt.data)
+            if tt.wantErr != nil {
+                if !errors.Is(err, tt.wantErr) {
+                    t.Fatalf("ошибка %v, ожидалась %v", err, tt.wantErr)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if result.ContentType != tt.contentType || result.Width != tt.width || result.Height != tt.height {
+                t.Fatalf("%s %dx%d, ожидалось %s %dx%d", result.ContentType, result.Width, result.Height, tt.contentType, tt.width, tt.height)
+            }
+            if strings.Contains(string(result.Data), secret) || bytes.Contains(result.Data, []byte("Exif")) {
+                t.Fatalf("метаданные остались в перекодированном файле")
+            }
+            if Orientation(result.Data) != 1 {
+                t.Fatalf("ориентация осталась в перекодированном файле")
+            }
+
+            // Результат декодируется и совпадает по размеру с описанием
+            img, format, err := image.Decode(bytes.NewReader(result.Data))
+            if err != nil {
+                t.Fatal(err)
+            }
+            if "image/"+format != tt.contentType || img.Bounds().Dx() != tt.width || img.Bounds().Dy() != tt.height {
+                t.Fatalf("перекодированный файл: %s %v", format, img.Bounds())
+            }
+        })
+    }
+}
+
+func TestSanitizeKeepsAnimation(t *testing.T) {
+    result, err := Sanitize(gifFile(t, 3))
+    if err != nil {
+        t.Fatal(err)
+    }
+    animation, err := gif.DecodeAll(bytes.NewReader(result.Data))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(animation.Image) != 3 {
+        t.Fatalf("кадров %d, ожидалось 3", len(animation.Image))
+    }
+}
+
+func TestFit(t *testing.T) {
+    tests := []struct {
+        name          string
+        width, height int
+        maxSide       int
+        wantW, wantH  int
+    }{
+        {name: "широкое", width: 100, height: 50, maxSide: 40, wantW: 40, wantH: 20},
+        {name: "высокое", width: 50, height: 100, maxSide: 40, wantW: 20, wantH: 40},
+        {name: "квадратное", width: 64, height: 64, maxSide: 32, wantW: 32, wantH: 32},
+        {name: "меньше ограничения", width: 30, height: 20, maxSide: 40, wantW: 30, wantH: 20},
+        {name: "узкая полоса", width: 1000, height: 1, maxSide: 10, wantW: 10, wantH: 1},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            img := Fit(markedImage(tt.width, tt.height), tt.maxSide)
+            if bounds := img.Bounds(); bounds.Dx() != tt.wantW || bounds.Dy() != tt.wantH {
+                t.Fatalf("размер %dx%d, ожидался %dx%d", bounds.Dx(), bounds.Dy(), tt.wantW, tt.wantH)
+            }
+        })
+    }
+
+    // Прозрачные области заливаются белым
+    img := Fit(image.NewNRGBA(image.Rect(0, 0, 4, 4)), 8)
+    if r, g, b, a := img.At(0, 0).RGBA(); r>>8 != 255 || g>>8 != 255 || b>>8 != 255 || a>>8 != 255 {
+        t.Fatalf("прозрачный пиксель не залит белым: %v", img.At(0, 0))
+    }
+}
+
+// decode83 разбирает число в кодировке base83
+func decode83(s string) int {
+    value := 0
+    for _, c := range s {
+        value = value*83 + strings.IndexRune(base83, c)
+    }
+    return value
+}
+
+func TestBlurhash(t *testing.T) {
+    tests := []struct {
+        name    string
+        img     image.Image
+        average color.RGBA // Ожидаемый средний цвет; нулевой — не проверяется
+    }{
+        {name: "белое", img: fill(color.White, 10, 10), average: color.RGBA{R: 255, G: 255, B: 255}},
+        {name: "красное", img: fill(red, 20, 20), average: color.RGBA{R: 255}},
+        {name: "синее", img: fill(blue, 50, 20), average: color.RGBA{B: 255}},
+        {name: "с отметкой", img: markedImage(40, 30)},
+    }
+    hashes := make(map[string]string)
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            hash := Blurhash(tt.img)
+            // Размер компонентов, максимум, средний цвет и по два символа на 11 компонентов
+            if len(hash) != 1+1+4+2*(blurhashX*blurhashY-1) {
+                t.Fatalf("длина blurhash %d: %q", len(hash), hash)
+            }
+            if decode83(hash[:1]) != (blurhashX-1)+(blurhashY-1)*9 {
+                t.Fatalf("неверное число компонентов в %q", hash)
+            }
+            if hash != Blurhash(tt.img) {
+                t.Fatalf("blurhash недетерминирован")
+            }
+            if tt.average != (color.RGBA{}) {
+                dc := decode83(hash[2:6])
+                got := color.RGBA{R: uint8(dc >> 16), G: uint8(dc >> 8), B: uint8(dc)}
+                if got != tt.average {
+                    t.Fatalf("средний цвет %v, ожидался %v", got, tt.average)
+                }
+            }
+            if other, ok := hashes[hash]; ok {
+                t.Fatalf("blurhash совпадает с изображением %q", other)
+            }
+            hashes[hash] = tt.name
+        })
+    }
+}
+
+// fill возвращает изображение width x height, залитое цветом c
+func fill(c color.Color, width, height int) image.Image {
+    img := image.NewRGBA(image.Rect(0, 0, width, height))
+    for y := 0; y < height; y++ {
+        for x := 0; x < width; x++ {
+            img.Set(x, y, c)
+        }
+    }
+    return img
+}
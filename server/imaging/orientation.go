@@ -0,0 +1,116 @@
+package imaging
+
+import (
+    "bytes"
+    "encoding/binary"
+    "image"
+
+    "golang.org/x/image/draw"
+)
+
+// Orientation возвращает ориентацию из EXIF JPEG-файла (1–8); 1 — если тега нет.
+// Метаданные при перекодировании удаляются, поэтому поворот нужно применить к пикселям заранее.
+func Orientation(data []byte) int {
+    if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+        return 1
+    }
+    pos := 2
+    for pos+4 <= len(data) {
+        if data[pos] != 0xFF {
+            return 1
+        }
+        marker := data[pos+1]
+        // Начало сжатых данных: дальше метаданных нет
+        if marker == 0xDA || marker == 0xD9 {
+            return 1
+        }
+        length := int(binary.BigEndian.Uint16(data[pos+2:]))
+        if length < 2 || pos+2+length > len(data) {
+            return 1
+        }
+        segment := data[pos+4 : pos+2+length]
+        if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+            return tiffOrientation(segment[6:])
+        }
+        pos += 2 + length
+    }
+    return 1
+}
+
+// tiffOrientation читает тег Orientation (0x0112) из IFD0 структуры TIFF
+func tiffOrientation(tiff []byte) int {
+    if len(tiff) < 8 {
+        return 1
+    }
+    var order binary.ByteOrder
+    switch string(tiff[:2]) {
+    case "II":
+        order = binary.LittleEndian
+    case "MM":
+        order = binary.BigEndian
+    default:
+        return 1
+    }
+    offset := int(order.Uint32(tiff[4:]))
+    if offset < 8 || offset+2 > len(tiff) {
+        return 1
+    }
+    count := int(order.Uint16(tiff[offset:]))
+    for i := 0; i < count; i++ {
+        entry := offset + 2 + i*12
+        if entry+12 > len(tiff) {
+            return 1
+        }
+        if order.Uint16(tiff[entry:]) == 0x0112 {
+            value := int(order.Uint16(tiff[entry+8:]))
+            if value < 1 || value > 8 {
+                return 1
+            }
+            return value
+        }
+    }
+    return 1
+}
+
+// ApplyOrientation поворачивает и отражает изображение согласно ориентации EXIF
+func ApplyOrientation(src image.Image, orientation int) image.Image {
+    if orientation <= 1 || orientation > 8 {
+        return src
+    }
+
+    bounds := src.Bounds()
+    rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+    draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+    w, h := bounds.Dx(), bounds.Dy()
+
+    dw, dh := w, h
+    if orientation >= 5 {
+        dw, dh = h, w
+    }
+    dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+    for y := 0; y < dh; y++ {
+        for x := 0; x < dw; x++ {
+            var sx, sy int
+            switch orientation {
+            case 2: // Отражение по горизонтали
+                sx, sy = w-1-x, y
+            case 3: // Поворот на 180°
+                sx, sy = w-1-x, h-1-y
+            case 4: // Отражение по вертикали
+                sx, sy = x, h-1-y
+            case 5: // Отражение относительно главной диагонали
+                sx, sy = y, x
+            case 6: // Поворот на 90° по часовой стрелке
+                sx, sy = y, h-1-x
+            case 7: // Отражение относительно побочной диагонали
+                sx, sy = w-1-y, h-1-x
+            case 8: // Поворот на 90° против часовой стрелки
+                sx, sy = w-1-y, x
+            }
+            si := rgba.PixOffset(sx, sy)
+            di := dst.PixOffset(x, y)
+            copy(dst.Pix[di:di+4], rgba.Pix[si:si+4])
+        }
+    }
+    return dst
+}
@@ -0,0 +1,107 @@
+package imaging
+
+import (
+    "bytes"
+    "image"
+    "image/gif"
+    "image/png"
+
+    "golang.org/x/image/draw"
+)
+
+// Sanitized — изображение, перекодированное без метаданных
+type Sanitized struct {
+    Data        []byte
+    ContentType string
+    Image       image.Image // Первый кадр с примененной ориентацией, для эскизов
+    Width       int
+    Height      int
+}
+
+// sanitizedQuality — качество JPEG при перекодировании оригинала
+const sanitizedQuality = 90
+
+// Sanitize декодирует изображение и кодирует его заново стандартными кодеками Go.
+// Метаданные (EXIF с координатами GPS, XMP, комментарии) при этом отбрасываются,
+// ориентация из EXIF применяется к пикселям. JPEG, PNG и GIF сохраняют формат
+// (у GIF сохраняется анимация), WebP перекодируется в JPEG или, при наличии прозрачности, в PNG.
+func Sanitize(data []byte) (*Sanitized, error) {
+    img, format, err := Decode(bytes.NewReader(data))
+    if err != nil {
+        return nil, err
+    }
+
+    var out bytes.Buffer
+    result := &Sanitized{}
+    switch {
+    case format == "gif":
+        animation, err := gif.DecodeAll(bytes.NewReader(data))
+        if err != nil {
+            return nil, ErrUnsupportedFormat
+        }
+        if err := gif.EncodeAll(&out, animation); err != nil {
+            return nil, err
+        }
+        result.ContentType = "image/gif"
+    case format == "png", format == "webp" && !isOpaque(img):
+        if err := png.Encode(&out, img); err != nil {
+            return nil, err
+        }
+        result.ContentType = "image/png"
+    default:
+        if format == "jpeg" {
+            img = ApplyOrientation(img, Orientation(data))
+        }
+        if err := EncodeJPEG(&out, flatten(img), sanitizedQuality); err != nil {
+            return nil, err
+        }
+        result.ContentType = "image/jpeg"
+    }
+
+    result.Data = out.Bytes()
+    result.Image = img
+    result.Width, result.Height = img.Bounds().Dx(), img.Bounds().Dy()
+    return result, nil
+}
+
+// Fit уменьшает изображение так, чтобы большая сторона не превышала maxSide, сохраняя пропорции.
+// Прозрачные области заливаются белым, так как эскизы кодируются в JPEG.
+func Fit(src image.Image, maxSide int) image.Image {
+    bounds := src.Bounds()
+    width, height := bounds.Dx(), bounds.Dy()
+    if width > maxSide || height > maxSide {
+        if width >= height {
+            width, height = maxSide, max(1, height*maxSide/width)
+        } else {
+            width, height = max(1, width*maxSide/height), maxSide
+        }
+    }
+
+    dst := image.NewRGBA(image.Rect(0, 0, width, height))
+    draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+    if width == bounds.Dx() && height == bounds.Dy() {
+        draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Over)
+        return dst
+    }
+    draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+    return dst
+}
+
+// flatten заливает прозрачные области белым
+func flatten(img image.Image) image.Image {
+    if isOpaque(img) {
+        return img
+    }
+    bounds := img.Bounds()
+    dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+    draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+    draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Over)
+    return dst
+}
+
+func isOpaque(img image.Image) bool {
+    if o, ok := img.(interface{ Opaque() bool }); ok {
+        return o.Opaque()
+    }
+    return false
+}
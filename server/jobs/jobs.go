@@ -0,0 +1,355 @@
+// Package jobs — надежная очередь фоновых задач в PostgreSQL. Задачи хранятся в таблице jobs,
+// обработчики забирают их через SELECT ... FOR UPDATE SKIP LOCKED, неудачные попытки
+// повторяются с экспоненциальной задержкой, после исчерпания попыток задача помечается
+// как dead и остается в таблице для разбора администратором. Выполненные и dead задачи
+// удаляются после срока хранения (InitRetention).
+package jobs
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log/slog"
+    "math/rand"
+    "os"
+    "runtime/debug"
+    "sync"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/logging"
+    "chatter-hub-server/tracing"
+
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+)
+
+// Handler обрабатывает задачу. Ошибка приводит к повторной попытке.
+type Handler func(ctx context.Context, job *config.Job) error
+
+// Options задает параметры задач одного типа
+type Options struct {
+    MaxAttempts int           // По умолчанию 5
+    Timeout     time.Duration // Время на одну попытку, по умолчанию 10 минут
+}
+
+// EnqueueOptions задает параметры постановки задачи в очередь
+type EnqueueOptions struct {
+    Delay     time.Duration // Отложить первый запуск
+    UniqueKey string        // Не ставить задачу, если задача с таким ключом уже есть
+}
+
+const (
+    defaultMaxAttempts = 5
+    defaultTimeout     = 10 * time.Minute
+    // lockTimeout — через это время задача, обработчик которой пропал, возвращается в очередь
+    lockTimeout = 30 * time.Minute
+    minBackoff  = 10 * time.Second
+    maxBackoff  = time.Hour
+)
+
+var ErrUnknownType = errors.New("неизвестный тип задачи")
+
+type registration struct {
+    handler Handler
+    options Options
+}
+
+var (
+    mu       sync.RWMutex
+    handlers = make(map[string]registration)
+    workerID = fmt.Sprintf("%s-%d", hostname(), os.Getpid())
+
+    // stop останавливает обработчики и планировщик, запущенные Start; running ждет их завершения
+    stop    context.CancelFunc
+    running sync.WaitGroup
+)
+
+// Register регистрирует обработчик задач типа jobType
+func Register(jobType string, handler Handler, opts Options) {
+    if opts.MaxAttempts <= 0 {
+        opts.MaxAttempts = defaultMaxAttempts
+    }
+    if opts.Timeout <= 0 {
+        opts.Timeout = defaultTimeout
+    }
+    mu.Lock()
+    defer mu.Unlock()
+    handlers[jobType] = registration{handler: handler, options: opts}
+}
+
+// Enqueue ставит задачу в очередь. payload сериализуется в JSON.
+func Enqueue(ctx context.Context, jobType string, payload interface{}, opts EnqueueOptions) (*config.Job, error) {
+    return EnqueueTx(config.DB.WithContext(ctx), jobType, payload, opts)
+}
+
+// EnqueueTx ставит задачу в очередь в рамках транзакции tx: задача появится,
+// только если транзакция будет зафиксирована
+func EnqueueTx(tx *gorm.DB, jobType string, payload interface{}, opts EnqueueOptions) (*config.Job, error) {
+    mu.RLock()
+    registered, ok := handlers[jobType]
+    mu.RUnlock()
+    if !ok {
+        return nil, fmt.Errorf("%w: %s", ErrUnknownType, jobType)
+    }
+
+    data := []byte("{}")
+    if payload != nil {
+        var err error
+        if data, err = json.Marshal(payload); err != nil {
+            return nil, err
+        }
+    }
+
+    job := &config.Job{
+        Type:        jobType,
+        Payload:     config.RawJSON(data),
+        Status:      config.JobPending,
+        RunAt:       time.Now().Add(opts.Delay),
+        MaxAttempts: registered.options.MaxAttempts,
+    }
+    if opts.UniqueKey != "" {
+        job.UniqueKey = &opts.UniqueKey
+    }
+    if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(job).Error; err != nil {
+        return nil, err
+    }
+    return job, nil
+}
+
+// DecodePayload разбирает данные задачи в v
+func DecodePayload(job *config.Job, v interface{}) error {
+    return json.Unmarshal([]byte(job.Payload), v)
+}
+
+// IsFinalAttempt сообщает, что после неудачи этой попытки задача не будет повторена
+func IsFinalAttempt(job *config.Job) bool {
+    return job.Attempts >= job.MaxAttempts
+}
+
+// Start запускает workers обработчиков, опрашивающих очередь с интервалом pollInterval,
+// и планировщик периодических задач
+func Start(workers int, pollInterval time.Duration) {
+    if workers <= 0 {
+        workers = 1
+    }
+    if pollInterval <= 0 {
+        pollInterval = time.Second
+    }
+    ctx, cancel := context.WithCancel(context.Background())
+    stop = cancel
+    for i := 0; i < workers; i++ {
+        running.Add(1)
+        go func() {
+            defer running.Done()
+            work(ctx, pollInterval)
+        }()
+    }
+    running.Add(1)
+    go func() {
+        defer running.Done()
+        runScheduler(ctx)
+    }()
+}
+
+// Stop прекращает забирать задачи из очереди и ждет, пока обработчики завершат начатые,
+// но не дольше отмены ctx. Задачи, не успевшие завершиться, вернутся в очередь
+// через lockTimeout, как при аварийной остановке сервера.
+func Stop(ctx context.Context) error {
+    if stop == nil {
+        return nil
+    }
+    stop()
+
+    done := make(chan struct{})
+    go func() {
+        running.Wait()
+        close(done)
+    }()
+    select {
+    case <-done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// work забирает и выполняет задачи, пока они есть, затем ждет pollInterval.
+// Начатая задача выполняется до конца и после отмены ctx.
+func work(ctx context.Context, pollInterval time.Duration) {
+    for ctx.Err() == nil {
+        job, err := claim(ctx)
+        if err != nil {
+            slog.Error("Ошибка получения задачи из очереди", "error", err)
+        }
+        if job != nil {
+            run(ctx, job)
+            continue
+        }
+        select {
+        case <-ctx.Done():
+        case <-time.After(pollInterval):
+        }
+    }
+}
+
+// claim атомарно забирает готовую к запуску задачу зарегистрированного типа
+func claim(ctx context.Context) (*config.Job, error) {
+    mu.RLock()
+    types := make([]string, 0, len(handlers))
+    for jobType := range handlers {
+        types = append(types, jobType)
+    }
+    mu.RUnlock()
+    if len(types) == 0 {
+        return nil, nil
+    }
+
+    // SQLite не поддерживает блокировку строк, но выполняет записи по одной,
+    // поэтому одну задачу все равно заберет только один обработчик
+    locking := "FOR UPDATE SKIP LOCKED"
+    if config.IsSQLite() {
+        locking = ""
+    }
+
+    var jobs []config.Job
+    err := config.DB.WithContext(ctx).Raw(`
+        UPDATE jobs SET status = ?, attempts = attempts + 1, locked_at = ?, locked_by = ?, updated_at = ?
+        WHERE id = (
+            SELECT id FROM jobs
+            WHERE status = ? AND run_at <= ? AND type IN ?
+            ORDER BY run_at, id
+            LIMIT 1
+            `+locking+`
+        )
+        RETURNING *`,
+        config.JobRunning, time.Now(), workerID, time.Now(),
+        config.JobPending, time.Now(), types,
+    ).Scan(&jobs).Error
+    if err != nil || len(jobs) == 0 {
+        return nil, err
+    }
+    return &jobs[0], nil
+}
+
+// run выполняет задачу и записывает результат. Остановка обработчиков через ctx не прерывает
+// начатую задачу: она завершается, и ее результат сохраняется.
+func run(ctx context.Context, job *config.Job) {
+    ctx = context.WithoutCancel(ctx)
+    mu.RLock()
+    registered, ok := handlers[job.Type]
+    mu.RUnlock()
+
+    var err error
+    if ok {
+        err = execute(ctx, registered, job)
+    } else {
+        err = fmt.Errorf("%w: %s", ErrUnknownType, job.Type)
+    }
+
+    db := config.DB.WithContext(ctx)
+    if err == nil {
+        now := time.Now()
+        db.Model(job).Updates(map[string]interface{}{
+            "status":       config.JobCompleted,
+            "completed_at": &now,
+            "locked_at":    nil,
+            "locked_by":    "",
+            "last_error":   "",
+        })
+        return
+    }
+
+    updates := map[string]interface{}{
+        "last_error": err.Error(),
+        "locked_at":  nil,
+        "locked_by":  "",
+    }
+    logger := slog.With("job_id", job.ID, "job_type", job.Type, "attempt", job.Attempts)
+    if IsFinalAttempt(job) {
+        updates["status"] = config.JobDead
+        logger.Error("Задача перемещена в dead после исчерпания попыток", "error", err)
+    } else {
+        updates["status"] = config.JobPending
+        updates["run_at"] = time.Now().Add(backoff(job.Attempts))
+        logger.Warn("Ошибка выполнения задачи", "error", err)
+    }
+    if err := db.Model(job).Updates(updates).Error; err != nil {
+        logger.Error("Ошибка сохранения состояния задачи", "error", err)
+    }
+}
+
+// execute вызывает обработчик с ограничением по времени; паника считается ошибкой попытки
+func execute(ctx context.Context, registered registration, job *config.Job) (err error) {
+    ctx, cancel := context.WithTimeout(ctx, registered.options.Timeout)
+    ctx = logging.WithLogger(ctx, slog.With("job_id", job.ID, "job_type", job.Type))
+    defer cancel()
+
+    // Каждая задача — отдельная трасса, в которую попадают ее запросы к базе данных и хранилищу
+    ctx, span := tracing.Tracer().Start(ctx, "job "+job.Type, trace.WithAttributes(
+        attribute.Int64("job.id", int64(job.ID)), attribute.Int("job.attempt", job.Attempts)))
+    defer func() { tracing.End(span, err) }()
+    defer func() {
+        if r := recover(); r != nil {
+            err = fmt.Errorf("паника: %v\n%s", r, debug.Stack())
+        }
+    }()
+    return registered.handler(ctx, job)
+}
+
+// backoff возвращает задержку перед следующей попыткой: экспоненциальный рост со случайным разбросом
+func backoff(attempt int) time.Duration {
+    delay := minBackoff << uint(attempt-1)
+    if delay > maxBackoff || delay <= 0 {
+        delay = maxBackoff
+    }
+    return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// Retry возвращает задачу в статусе dead в очередь с новым набором попыток
+func Retry(ctx context.Context, id uint) (*config.Job, error) {
+    db := config.DB.WithContext(ctx)
+    result := db.Model(&config.Job{}).
+        Where("id = ? AND status = ?", id, config.JobDead).
+        Updates(map[string]interface{}{
+            "status":   config.JobPending,
+            "attempts": 0,
+            "run_at":   time.Now(),
+        })
+    if result.Error != nil {
+        return nil, result.Error
+    }
+    if result.RowsAffected == 0 {
+        return nil, gorm.ErrRecordNotFound
+    }
+
+    var job config.Job
+    if err := db.First(&job, id).Error; err != nil {
+        return nil, err
+    }
+    return &job, nil
+}
+
+// releaseStale возвращает в очередь задачи, обработчик которых не отчитался за lockTimeout
+// (например, экземпляр сервера был остановлен во время выполнения)
+func releaseStale(ctx context.Context) error {
+    return config.DB.WithContext(ctx).Model(&config.Job{}).
+        Where("status = ? AND locked_at < ?", config.JobRunning, time.Now().Add(-lockTimeout)).
+        Updates(map[string]interface{}{
+            "status":    config.JobPending,
+            "locked_at": nil,
+            "locked_by": "",
+            "run_at":    time.Now(),
+        }).Error
+}
+
+func hostname() string {
+    name, err := os.Hostname()
+    if err != nil {
+        return "worker"
+    }
+    return name
+}
@@ -0,0 +1,452 @@
+package jobs
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
+    "os"
+    "path/filepath"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "chatter-hub-server/config"
+
+    "gorm.io/gorm"
+)
+
+const testJobType = "test"
+
+func TestMain(m *testing.M) {
+    slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+    dir, err := os.MkdirTemp("", "chatter-hub-jobs")
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    cfg := &config.Config{Database: config.DatabaseConfig{Driver: config.DatabaseSQLite, SQLitePath: filepath.Join(dir, "test.db")}}
+    if err := config.InitDB(cfg); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+
+    code := m.Run()
+    os.RemoveAll(dir)
+    os.Exit(code)
+}
+
+// setup очищает очередь и регистрирует обработчик задач testJobType
+func setup(t *testing.T, handler Handler, opts Options) {
+    t.Helper()
+    if err := config.DB.Where("1 = 1").Delete(&config.Job{}).Error; err != nil {
+        t.Fatal(err)
+    }
+    mu.Lock()
+    handlers = make(map[string]registration)
+    mu.Unlock()
+    Register(testJobType, handler, opts)
+}
+
+// reload читает задачу из базы данных
+func reload(t *testing.T, id uint) *config.Job {
+    t.Helper()
+    var job config.Job
+    if err := config.DB.First(&job, id).Error; err != nil {
+        t.Fatal(err)
+    }
+    return &job
+}
+
+// makeReady переносит запуск задачи на текущее время, не дожидаясь задержки повтора
+func makeReady(t *testing.T, id uint) {
+    t.Helper()
+    if err := config.DB.Model(&config.Job{}).Where("id = ?", id).Update("run_at", time.Now().Add(-time.Second)).Error; err != nil {
+        t.Fatal(err)
+    }
+}
+
+func TestBackoff(t *testing.T) {
+    tests := []struct {
+        attempt  int
+        min, max time.Duration
+    }{
+        {attempt: 1, min: 5 * time.Second, max: 10 * time.Second},
+        {attempt: 2, min: 10 * time.Second, max: 20 * time.Second},
+        {attempt: 4, min: 40 * time.Second, max: 80 * time.Second},
+        {attempt: 20, min: maxBackoff / 2, max: maxBackoff},
+        {attempt: 100, min: maxBackoff / 2, max: maxBackoff}, // Сдвиг переполняет Duration
+    }
+    for _, tt := range tests {
+        t.Run(fmt.Sprint(tt.attempt), func(t *testing.T) {
+            for i := 0; i < 100; i++ {
+                if delay := backoff(tt.attempt); delay < tt.min || delay > tt.max {
+                    t.Fatalf("задержка %v вне [%v, %v]", delay, tt.min, tt.max)
+                }
+            }
+        })
+    }
+}
+
+func TestIsFinalAttempt(t *testing.T) {
+    tests := []struct {
+        attempts, maxAttempts int
+        want                  bool
+    }{
+        {attempts: 1, maxAttempts: 3, want: false},
+        {attempts: 2, maxAttempts: 3, want: false},
+        {attempts: 3, maxAttempts: 3, want: true},
+        {attempts: 1, maxAttempts: 1, want: true},
+        {attempts: 4, maxAttempts: 3, want: true}, // Попытка после возврата зависшей задачи
+    }
+    for _, tt := range tests {
+        job := &config.Job{Attempts: tt.attempts, MaxAttempts: tt.maxAttempts}
+        if got := IsFinalAttempt(job); got != tt.want {
+            t.Errorf("попытка %d из %d: IsFinalAttempt = %v, ожидалось %v", tt.attempts, tt.maxAttempts, got, tt.want)
+        }
+    }
+}
+
+func TestEnqueue(t *testing.T) {
+    setup(t, func(context.Context, *config.Job) error { return nil }, Options{MaxAttempts: 3})
+    ctx := context.Background()
+
+    job, err := Enqueue(ctx, testJobType, map[string]string{"key": "value"}, EnqueueOptions{UniqueKey: "unique"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if job.Status != config.JobPending || job.MaxAttempts != 3 {
+        t.Fatalf("задача %+v", job)
+    }
+    var payload map[string]string
+    if err := DecodePayload(reload(t, job.ID), &payload); err != nil || payload["key"] != "value" {
+        t.Fatalf("данные задачи %v: %v", payload, err)
+    }
+
+    // Задача с тем же ключом уникальности не ставится повторно
+    if _, err := Enqueue(ctx, testJobType, nil, EnqueueOptions{UniqueKey: "unique"}); err != nil {
+        t.Fatal(err)
+    }
+    var count int64
+    config.DB.Model(&config.Job{}).Count(&count)
+    if count != 1 {
+        t.Fatalf("в очереди %d задач, ожидалась 1", count)
+    }
+
+    if _, err := Enqueue(ctx, "unregistered", nil, EnqueueOptions{}); !errors.Is(err, ErrUnknownType) {
+        t.Fatalf("ошибка %v, ожидалась %v", err, ErrUnknownType)
+    }
+}
+
+func TestClaim(t *testing.T) {
+    now := time.Now()
+    tests := []struct {
+        name string
+        job  config.Job
+        want bool
+    }{
+        {name: "готовая задача", job: config.Job{Type: testJobType, Status: config.JobPending, RunAt: now.Add(-time.Second)}, want: true},
+        {name: "отложенная задача", job: config.Job{Type: testJobType, Status: config.JobPending, RunAt: now.Add(time.Hour)}, want: false},
+        {name: "незарегистрированный тип", job: config.Job{Type: "unregistered", Status: config.JobPending, RunAt: now.Add(-time.Second)}, want: false},
+        {name: "выполняемая задача", job: config.Job{Type: testJobType, Status: config.JobRunning, RunAt: now.Add(-time.Second)}, want: false},
+        {name: "задача в dead", job: config.Job{Type: testJobType, Status: config.JobDead, RunAt: now.Add(-time.Second)}, want: false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            setup(t, func(context.Context, *config.Job) error { return nil }, Options{})
+            job := tt.job
+            job.MaxAttempts = defaultMaxAttempts
+            if err := config.DB.Create(&job).Error; err != nil {
+                t.Fatal(err)
+            }
+
+            claimed, err := claim(context.Background())
+            if err != nil {
+                t.Fatal(err)
+            }
+            if (claimed != nil) != tt.want {
+                t.Fatalf("задача получена: %v, ожидалось %v", claimed != nil, tt.want)
+            }
+            if claimed == nil {
+                return
+            }
+            if claimed.Status != config.JobRunning || claimed.Attempts != 1 || claimed.LockedBy != workerID || claimed.LockedAt == nil {
+                t.Fatalf("полученная задача не заблокирована: %+v", claimed)
+            }
+        })
+    }
+}
+
+func TestClaimConcurrent(t *testing.T) {
+    setup(t, func(context.Context, *config.Job) error { return nil }, Options{})
+    const total = 20
+    for i := 0; i < total; i++ {
+        if _, err := Enqueue(context.Background(), testJobType, nil, EnqueueOptions{}); err != nil {
+            t.Fatal(err)
+        }
+    }
+
+    // Несколько обработчиков забирают задачи одновременно; каждая достается одному из них
+    var claimedMu sync.Mutex
+    claimed := make(map[uint]int)
+    var wg sync.WaitGroup
+    for w := 0; w < 8; w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for {
+                job, err := claim(context.Background())
+                if err != nil {
+                    t.Error(err)
+                    return
+                }
+                if job == nil {
+                    return
+                }
+                claimedMu.Lock()
+                claimed[job.ID]++
+                claimedMu.Unlock()
+            }
+        }()
+    }
+    wg.Wait()
+
+    if len(claimed) != total {
+        t.Fatalf("получено %d задач из %d", len(claimed), total)
+    }
+    for id, n := range claimed {
+        if n != 1 {
+            t.Errorf("задача %d получена %d раз", id, n)
+        }
+    }
+}
+
+func TestRun(t *testing.T) {
+    errFailed := errors.New("ошибка обработки")
+    tests := []struct {
+        name        string
+        maxAttempts int
+        handler     Handler
+        wantStatus  string
+        wantAttempt int
+        wantError   string
+    }{
+        {
+            name:        "успех с первой попытки",
+            maxAttempts: 3,
+            handler:     func(context.Context, *config.Job) error { return nil },
+            wantStatus:  config.JobCompleted,
+            wantAttempt: 1,
+        },
+        {
+            name:        "успех после повтора",
+            maxAttempts: 3,
+            handler: func(_ context.Context, job *config.Job) error {
+                if job.Attempts < 2 {
+                    return errFailed
+                }
+                return nil
+            },
+            wantStatus:  config.JobCompleted,
+            wantAttempt: 2,
+        },
+        {
+            name:        "успех только на последней попытке",
+            maxAttempts: 4,
+            handler: func(_ context.Context, job *config.Job) error {
+                if !IsFinalAttempt(job) {
+                    return errFailed
+                }
+                return nil
+            },
+            wantStatus:  config.JobCompleted,
+            wantAttempt: 4,
+        },
+        {
+            name:        "попытки исчерпаны",
+            maxAttempts: 3,
+            handler:     func(context.Context, *config.Job) error { return errFailed },
+            wantStatus:  config.JobDead,
+            wantAttempt: 3,
+            wantError:   errFailed.Error(),
+        },
+        {
+            name:        "паника в обработчике",
+            maxAttempts: 2,
+            handler:     func(context.Context, *config.Job) error { panic("сбой") },
+            wantStatus:  config.JobDead,
+            wantAttempt: 2,
+            wantError:   "паника: сбой",
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            setup(t, tt.handler, Options{MaxAttempts: tt.maxAttempts})
+            ctx := context.Background()
+            queued, err := Enqueue(ctx, testJobType, nil, EnqueueOptions{})
+            if err != nil {
+                t.Fatal(err)
+            }
+
+            for attempt := 1; ; attempt++ {
+                job, err := claim(ctx)
+                if err != nil {
+                    t.Fatal(err)
+                }
+                if job == nil {
+                    break
+                }
+                if attempt > tt.maxAttempts {
+                    t.Fatalf("попытка %d сверх максимума %d", attempt, tt.maxAttempts)
+                }
+                started := time.Now()
+                run(ctx, job)
+
+                job = reload(t, queued.ID)
+                if job.Status != config.JobPending {
+                    break
+                }
+                // Неудачная попытка откладывает следующую на время задержки
+                if delay := job.RunAt.Sub(started); delay < minBackoff/2 {
+                    t.Fatalf("следующая попытка через %v, ожидалось не меньше %v", delay, minBackoff/2)
+                }
+                if next, err := claim(ctx); err != nil || next != nil {
+                    t.Fatalf("задача получена до истечения задержки: %v, %v", next, err)
+                }
+                makeReady(t, job.ID)
+            }
+
+            job := reload(t, queued.ID)
+            if job.Status != tt.wantStatus || job.Attempts != tt.wantAttempt {
+                t.Fatalf("статус %s после %d попыток, ожидалось %s после %d", job.Status, job.Attempts, tt.wantStatus, tt.wantAttempt)
+            }
+            if !strings.Contains(job.LastError, tt.wantError) || (tt.wantError == "" && job.LastError != "") {
+                t.Fatalf("последняя ошибка %q, ожидалась %q", job.LastError, tt.wantError)
+            }
+            if job.LockedAt != nil || job.LockedBy != "" {
+                t.Fatalf("задача осталась заблокированной: %+v", job)
+            }
+        })
+    }
+}
+
+func TestRetry(t *testing.T) {
+    setup(t, func(context.Context, *config.Job) error { return nil }, Options{MaxAttempts: 2})
+    ctx := context.Background()
+    dead := &config.Job{Type: testJobType, Status: config.JobDead, Attempts: 2, MaxAttempts: 2, RunAt: time.Now()}
+    pending := &config.Job{Type: testJobType, Status: config.JobPending, MaxAttempts: 2, RunAt: time.Now().Add(time.Hour)}
+    if err := config.DB.Create(dead).Error; err != nil {
+        t.Fatal(err)
+    }
+    if err := config.DB.Create(pending).Error; err != nil {
+        t.Fatal(err)
+    }
+
+    job, err := Retry(ctx, dead.ID)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if job.Status != config.JobPending || job.Attempts != 0 {
+        t.Fatalf("задача после повтора: %+v", job)
+    }
+
+    // Повторить можно только задачу в dead
+    if _, err := Retry(ctx, pending.ID); !errors.Is(err, gorm.ErrRecordNotFound) {
+        t.Fatalf("ошибка %v, ожидалась %v", err, gorm.ErrRecordNotFound)
+    }
+}
+
+func TestReleaseStale(t *testing.T) {
+    setup(t, func(context.Context, *config.Job) error { return nil }, Options{})
+    tests := []struct {
+        name       string
+        lockedAgo  time.Duration
+        wantStatus string
+    }{
+        {name: "обработчик пропал", lockedAgo: lockTimeout + time.Minute, wantStatus: config.JobPending},
+        {name: "задача выполняется", lockedAgo: time.Minute, wantStatus: config.JobRunning},
+    }
+    ids := make([]uint, len(tests))
+    for i, tt := range tests {
+        lockedAt := time.Now().Add(-tt.lockedAgo)
+        job := &config.Job{Type: testJobType, Status: config.JobRunning, Attempts: 1, MaxAttempts: 3, RunAt: lockedAt, LockedAt: &lockedAt, LockedBy: "other"}
+        if err := config.DB.Create(job).Error; err != nil {
+            t.Fatal(err)
+        }
+        ids[i] = job.ID
+    }
+
+    if err := releaseStale(context.Background()); err != nil {
+        t.Fatal(err)
+    }
+    for i, tt := range tests {
+        if job := reload(t, ids[i]); job.Status != tt.wantStatus {
+            t.Errorf("%s: статус %s, ожидался %s", tt.name, job.Status, tt.wantStatus)
+        }
+    }
+}
+
+func TestRetention(t *testing.T) {
+    setup(t, func(context.Context, *config.Job) error { return nil }, Options{})
+    const retention = 7 * 24 * time.Hour
+    InitRetention(retention)
+    defer func() {
+        scheduleMu.Lock()
+        schedules = nil
+        scheduleMu.Unlock()
+    }()
+
+    tests := []struct {
+        name       string
+        status     string
+        age        time.Duration
+        wantExists bool
+    }{
+        {name: "старая выполненная", status: config.JobCompleted, age: retention + time.Hour, wantExists: false},
+        {name: "старая dead", status: config.JobDead, age: retention + time.Hour, wantExists: false},
+        {name: "недавняя выполненная", status: config.JobCompleted, age: time.Hour, wantExists: true},
+        {name: "недавняя dead", status: config.JobDead, age: time.Hour, wantExists: true},
+        {name: "давно ожидающая", status: config.JobPending, age: retention + time.Hour, wantExists: true},
+        {name: "давно выполняющаяся", status: config.JobRunning, age: retention + time.Hour, wantExists: true},
+    }
+    ids := make([]uint, len(tests))
+    for i, tt := range tests {
+        updatedAt := time.Now().Add(-tt.age)
+        job := &config.Job{Type: testJobType, Status: tt.status, MaxAttempts: 1, RunAt: updatedAt, CreatedAt: updatedAt, UpdatedAt: updatedAt}
+        if err := config.DB.Create(job).Error; err != nil {
+            t.Fatal(err)
+        }
+        ids[i] = job.ID
+    }
+
+    mu.RLock()
+    registered, ok := handlers[RetentionJobType]
+    mu.RUnlock()
+    if !ok {
+        t.Fatal("обработчик удаления старых задач не зарегистрирован")
+    }
+    if err := registered.handler(context.Background(), &config.Job{Type: RetentionJobType}); err != nil {
+        t.Fatal(err)
+    }
+
+    for i, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            var count int64
+            if err := config.DB.Model(&config.Job{}).Where("id = ?", ids[i]).Count(&count).Error; err != nil {
+                t.Fatal(err)
+            }
+            if (count > 0) != tt.wantExists {
+                t.Fatalf("задача сохранилась: %v, ожидалось %v", count > 0, tt.wantExists)
+            }
+        })
+    }
+
+    scheduleMu.Lock()
+    defer scheduleMu.Unlock()
+    if len(schedules) != 1 || schedules[0].jobType != RetentionJobType {
+        t.Fatalf("удаление старых задач не запланировано: %+v", schedules)
+    }
+}
@@ -0,0 +1,41 @@
+package jobs
+
+import (
+    "context"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/logging"
+)
+
+// RetentionJobType — тип периодической задачи удаления старых завершенных задач
+const RetentionJobType = "jobs.retention"
+
+// retentionInterval — как часто удаляются старые задачи
+const retentionInterval = 24 * time.Hour
+
+// InitRetention регистрирует и планирует ежедневное удаление выполненных и dead задач,
+// которые не менялись дольше retention. Если retention не больше нуля, задачи не удаляются.
+func InitRetention(retention time.Duration) {
+    if retention <= 0 {
+        return
+    }
+    Register(RetentionJobType, func(ctx context.Context, job *config.Job) error {
+        deleted, err := DeleteFinished(ctx, time.Now().Add(-retention))
+        if err != nil {
+            return err
+        }
+        logging.FromContext(ctx).Info("Удалены старые фоновые задачи", "deleted", deleted, "retention", retention.String())
+        return nil
+    }, Options{MaxAttempts: 1})
+    Schedule(RetentionJobType, retentionInterval, nil)
+}
+
+// DeleteFinished удаляет выполненные и dead задачи, последний раз измененные раньше before,
+// и возвращает их количество. Ожидающие и выполняемые задачи не удаляются.
+func DeleteFinished(ctx context.Context, before time.Time) (int64, error) {
+    result := config.DB.WithContext(ctx).
+        Where("status IN ? AND updated_at < ?", []string{config.JobCompleted, config.JobDead}, before).
+        Delete(&config.Job{})
+    return result.RowsAffected, result.Error
+}
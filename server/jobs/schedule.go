@@ -0,0 +1,63 @@
+package jobs
+
+import (
+    "context"
+    "log/slog"
+    "strconv"
+    "sync"
+    "time"
+)
+
+// schedule — периодическая задача
+type schedule struct {
+    jobType  string
+    interval time.Duration
+    payload  interface{}
+}
+
+var (
+    scheduleMu sync.Mutex
+    schedules  []schedule
+)
+
+// schedulerTick — как часто планировщик проверяет периодические задачи
+const schedulerTick = time.Minute
+
+// Schedule ставит задачу jobType в очередь каждые interval. Каждый экземпляр сервера
+// запускает свой планировщик, но задача одного интервала попадает в очередь один раз:
+// ее ключ уникальности содержит номер интервала.
+func Schedule(jobType string, interval time.Duration, payload interface{}) {
+    scheduleMu.Lock()
+    defer scheduleMu.Unlock()
+    schedules = append(schedules, schedule{jobType: jobType, interval: interval, payload: payload})
+}
+
+func runScheduler(ctx context.Context) {
+    ticker := time.NewTicker(schedulerTick)
+    defer ticker.Stop()
+    for {
+        enqueueScheduled(ctx, time.Now())
+        if err := releaseStale(ctx); err != nil {
+            slog.Error("Ошибка возврата зависших задач в очередь", "error", err)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+func enqueueScheduled(ctx context.Context, now time.Time) {
+    scheduleMu.Lock()
+    current := append([]schedule(nil), schedules...)
+    scheduleMu.Unlock()
+
+    for _, s := range current {
+        slot := now.UnixNano() / int64(s.interval)
+        key := "schedule:" + s.jobType + ":" + strconv.FormatInt(slot, 10)
+        if _, err := Enqueue(ctx, s.jobType, s.payload, EnqueueOptions{UniqueKey: key}); err != nil {
+            slog.Error("Ошибка постановки периодической задачи", "job_type", s.jobType, "error", err)
+        }
+    }
+}
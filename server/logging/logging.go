@@ -0,0 +1,79 @@
+// Package logging настраивает структурированный журнал сервера на основе log/slog.
+// Уровень и формат задаются конфигурацией, токены, пароли и адреса почты
+// маскируются до записи в журнал. Сообщения пакета log стандартной библиотеки
+// и отладочный вывод Gin направляются в тот же журнал.
+package logging
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "log/slog"
+    "os"
+    "strings"
+
+    "chatter-hub-server/config"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Форматы журнала
+const (
+    FormatJSON = "json"
+    FormatText = "text"
+)
+
+type loggerKey struct{}
+
+// Init делает журнал с уровнем и форматом из конфигурации журналом по умолчанию
+func Init(cfg *config.Config) {
+    logger, err := New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+    if err != nil {
+        // Журнал еще не настроен, поэтому ошибка конфигурации выводится обработчиком по умолчанию
+        Fatal("Некорректная настройка журнала", "error", err)
+    }
+    slog.SetDefault(logger)
+
+    gin.DebugPrintFunc = func(format string, values ...interface{}) {
+        slog.Debug(strings.TrimSpace(fmt.Sprintf(format, values...)), "component", "gin")
+    }
+    gin.DebugPrintRouteFunc = func(method, path, handler string, handlers int) {
+        slog.Debug("Маршрут", "component", "gin", "method", method, "path", path, "handler", handler)
+    }
+}
+
+// New создает журнал, который пишет в w записи уровня level и выше в формате format
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+    var lvl slog.Level
+    if err := lvl.UnmarshalText([]byte(level)); err != nil {
+        return nil, fmt.Errorf("неизвестный уровень журнала %q", level)
+    }
+    options := &slog.HandlerOptions{Level: lvl, ReplaceAttr: redact}
+    switch format {
+    case FormatJSON, "":
+        return slog.New(slog.NewJSONHandler(w, options)), nil
+    case FormatText:
+        return slog.New(slog.NewTextHandler(w, options)), nil
+    default:
+        return nil, fmt.Errorf("неизвестный формат журнала %q", format)
+    }
+}
+
+// WithLogger возвращает контекст с журналом запроса
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+    return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext возвращает журнал запроса с его идентификатором или журнал по умолчанию
+func FromContext(ctx context.Context) *slog.Logger {
+    if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+        return logger
+    }
+    return slog.Default()
+}
+
+// Fatal записывает ошибку в журнал и завершает процесс
+func Fatal(msg string, args ...any) {
+    slog.Error(msg, args...)
+    os.Exit(1)
+}
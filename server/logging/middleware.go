@@ -0,0 +1,95 @@
+package logging
+
+import (
+    "fmt"
+    "log/slog"
+    "net/http"
+    "regexp"
+    "runtime/debug"
+    "time"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+)
+
+// RequestIDHeader — заголовок с идентификатором запроса
+const RequestIDHeader = "X-Request-ID"
+
+// RequestIDKey — ключ идентификатора запроса в контексте Gin
+const RequestIDKey = "requestID"
+
+// Идентификатор из заголовка клиента принимается, только если он не длиннее 128 символов
+// и не содержит символов, которые могут исказить журнал
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:/+=-]{1,128}$`)
+
+// RequestID присваивает запросу идентификатор или берет его из заголовка X-Request-ID,
+// возвращает его в ответе и добавляет журнал с этим идентификатором в контекст запроса
+func RequestID() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        requestID := c.GetHeader(RequestIDHeader)
+        if !validRequestID.MatchString(requestID) {
+            requestID = uuid.NewString()
+        }
+        c.Set(RequestIDKey, requestID)
+        c.Header(RequestIDHeader, requestID)
+
+        With(c, "request_id", requestID)
+        c.Next()
+    }
+}
+
+// With добавляет атрибуты в журнал запроса: они попадут во все последующие записи
+// обработчиков и в запись журнала доступа
+func With(c *gin.Context, args ...any) {
+    ctx := c.Request.Context()
+    c.Request = c.Request.WithContext(WithLogger(ctx, FromContext(ctx).With(args...)))
+}
+
+// AccessLog записывает в журнал каждый запрос: маршрут, статус и время обработки.
+// Пользователя добавляет в журнал запроса AuthMiddleware.
+// Строка запроса не записывается — в ней передаются подписи ссылок на файлы.
+// Подключается после RequestID.
+func AccessLog() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        start := time.Now()
+        c.Next()
+
+        status := c.Writer.Status()
+        level := slog.LevelInfo
+        switch {
+        case status >= http.StatusInternalServerError:
+            level = slog.LevelError
+        case status >= http.StatusBadRequest:
+            level = slog.LevelWarn
+        }
+
+        attrs := []slog.Attr{
+            slog.String("method", c.Request.Method),
+            slog.String("path", c.Request.URL.Path),
+            slog.String("route", c.FullPath()),
+            slog.Int("status", status),
+            slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+            slog.Int("bytes", c.Writer.Size()),
+            slog.String("client_ip", c.ClientIP()),
+        }
+        if len(c.Errors) > 0 {
+            attrs = append(attrs, slog.String("error", c.Errors.String()))
+        }
+        FromContext(c.Request.Context()).LogAttrs(c.Request.Context(), level, "Запрос", attrs...)
+    }
+}
+
+// Recovery перехватывает панику обработчика, записывает ее в журнал запроса со стеком
+// и отвечает статусом 500. Подключается после AccessLog, чтобы запрос попал в журнал доступа.
+func Recovery() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        defer func() {
+            if recovered := recover(); recovered != nil {
+                FromContext(c.Request.Context()).Error("Паника при обработке запроса",
+                    "panic", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+                c.AbortWithStatus(http.StatusInternalServerError)
+            }
+        }()
+        c.Next()
+    }
+}
@@ -0,0 +1,55 @@
+package logging
+
+import (
+    "log/slog"
+    "regexp"
+    "strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys — части имен атрибутов, значения которых не записываются в журнал
+var sensitiveKeys = []string{"password", "token", "secret", "authorization", "api_key", "apikey", "cookie"}
+
+var (
+    jwtPattern    = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+    bearerPattern = regexp.MustCompile(`(?i)(bearer\s+)\S+`)
+    emailPattern  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// redact заменяет значения секретных атрибутов, а в строках и ошибках маскирует
+// JWT токены и адреса почты, в том числе в тексте сообщения
+func redact(groups []string, attr slog.Attr) slog.Attr {
+    key := strings.ToLower(attr.Key)
+    for _, sensitive := range sensitiveKeys {
+        if strings.Contains(key, sensitive) {
+            return slog.String(attr.Key, redacted)
+        }
+    }
+
+    switch attr.Value.Kind() {
+    case slog.KindString:
+        return slog.String(attr.Key, RedactString(attr.Value.String()))
+    case slog.KindAny:
+        if err, ok := attr.Value.Any().(error); ok {
+            return slog.String(attr.Key, RedactString(err.Error()))
+        }
+    }
+    return attr
+}
+
+// RedactString маскирует JWT токены и адреса почты в строке
+func RedactString(s string) string {
+    s = jwtPattern.ReplaceAllString(s, redacted)
+    s = bearerPattern.ReplaceAllString(s, "${1}"+redacted)
+    return emailPattern.ReplaceAllStringFunc(s, MaskEmail)
+}
+
+// MaskEmail оставляет от адреса почты первую букву имени и домен: j***@example.com
+func MaskEmail(email string) string {
+    at := strings.LastIndexByte(email, '@')
+    if at <= 0 {
+        return redacted
+    }
+    return email[:1] + "***" + email[at:]
+}
@@ -1,20 +1,34 @@
 package main
 
 import (
+    "context"
     "fmt"
-    "log"
+    "log/slog"
+    "net"
+    "os"
+    "os/signal"
+    "syscall"
+    "time"
 
-    "chatter-hub-server/auth"
+    "chatter-hub-server/attachments"
+    "chatter-hub-server/cache"
     "chatter-hub-server/config"
+    "chatter-hub-server/gc"
+    "chatter-hub-server/health"
+    "chatter-hub-server/jobs"
+    "chatter-hub-server/logging"
+    "chatter-hub-server/metrics"
+    "chatter-hub-server/migrations"
+    "chatter-hub-server/notify"
+    "chatter-hub-server/quota"
+    "chatter-hub-server/repository"
     "chatter-hub-server/routers"
+    "chatter-hub-server/scan"
+    "chatter-hub-server/storage"
+    "chatter-hub-server/tracing"
+    "chatter-hub-server/transcribe"
 
     _ "chatter-hub-server/docs" // Это нужно для загрузки сгенерированных файлов Swagger
-
-    "github.com/gin-gonic/gin"
-    ginSwagger "github.com/swaggo/gin-swagger"
-    "github.com/swaggo/files"
-	"chatter-hub-server/routers/users" // Добавьте этот импорт
-	
 )
 
 // @title           Messenger API
@@ -31,36 +45,157 @@ func main() {
     // Загружаем конфигурацию
     cfg, err := config.LoadConfig()
     if err != nil {
-        log.Fatalf("Ошибка загрузки конфигурации: %v", err)
+        logging.Fatal("Ошибка загрузки конфигурации", "error", err)
     }
 
-    // Инициализируем базу данных
-    config.InitDB(cfg)
+    // Настраиваем уровень и формат журнала
+    logging.Init(cfg)
 
-    // Инициализируем Redis
-    config.InitRedis(cfg)
+    // Настраиваем экспорт трассировки; накопленные спаны отправляются при остановке сервера
+    shutdownTracing, err := tracing.Init(cfg)
+    if err != nil {
+        logging.Fatal("Ошибка настройки трассировки", "error", err)
+    }
 
-    // Инициализируем MinIO
-    config.InitMinio(cfg)
+    // Подключаемся к базе данных; при одновременном запуске с сервером она может быть еще недоступна
+    startupTimeout := time.Duration(cfg.Health.StartupTimeout) * time.Second
+    if err := health.Retry("database", startupTimeout, func() error { return config.InitDB(cfg) }); err != nil {
+        logging.Fatal("Ошибка подключения к базе данных", "error", err)
+    }
+    sqlDB, err := config.DB.DB()
+    if err != nil {
+        logging.Fatal("Ошибка подключения к базе данных", "error", err)
+    }
+    if err := metrics.RegisterDB(sqlDB); err != nil {
+        logging.Fatal("Ошибка регистрации метрик базы данных", "error", err)
+    }
+    if err := config.DB.Use(tracing.GormPlugin()); err != nil {
+        logging.Fatal("Ошибка подключения трассировки запросов", "error", err)
+    }
 
-    // Инициализируем роутер Gin
-    router := gin.Default()
+    // server migrate <команда> управляет схемой базы данных и завершает работу
+    if len(os.Args) > 1 && os.Args[1] == "migrate" {
+        if config.IsSQLite() {
+            logging.Fatal("Схема SQLite создается автоматически, миграции применяются только к PostgreSQL")
+        }
+        if err := migrations.Command(context.Background(), sqlDB, os.Args[2:], os.Stdout); err != nil {
+            logging.Fatal("Ошибка миграции базы данных", "error", err)
+        }
+        return
+    }
 
-    // Незапрашиваемые маршруты
-	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-	router.POST("/users", users.CreateUser)  // Создание пользователя (не защищено)
-	router.POST("/login", users.LoginUser)   // Аутентификация (не защищено)
+    // Применяем новые миграции схемы; экземпляры сервера, запущенные одновременно, ждут друг друга
+    if cfg.Postgres.AutoMigrate && !config.IsSQLite() {
+        migrator, err := migrations.New(sqlDB)
+        if err != nil {
+            logging.Fatal("Ошибка загрузки миграций", "error", err)
+        }
+        if _, err := migrator.Up(context.Background()); err != nil {
+            logging.Fatal("Ошибка миграции базы данных", "error", err)
+        }
+    }
+
+    // Инициализируем Redis, если кэш и уведомления не хранятся в памяти процесса
+    if cfg.Cache.Driver != "memory" {
+        if err := health.Retry("redis", startupTimeout, func() error { return config.InitRedis(cfg) }); err != nil {
+            logging.Fatal("Ошибка подключения к Redis", "error", err)
+        }
+        config.RedisClient.AddHook(metrics.RedisHook{})
+        config.RedisClient.AddHook(tracing.RedisHook{})
+    }
+    cache.Init(cfg)
+    notify.Init(cfg, repository.NewMutes(config.DB))
 
-    // Защищенные маршруты
-    authorized := router.Group("/")
-    authorized.Use(auth.AuthMiddleware(cfg)) // Middleware аутентификации для защищенных маршрутов
-    {
-        routers.RegisterProtectedRoutes(authorized)
+    // Инициализируем хранилище файлов
+    if err := health.Retry("storage", startupTimeout, func() error { return storage.Init(cfg) }); err != nil {
+        logging.Fatal("Ошибка инициализации хранилища", "error", err)
     }
 
+    // Подключаем антивирусную проверку загруженных файлов, если она включена
+    scan.Init(cfg)
+
+    // Применяем ограничения вложений и квоту пользователей
+    attachments.Init(cfg)
+    quota.Init(cfg)
+
+    // Запускаем распознавание речи в голосовых сообщениях, если оно включено
+    transcribe.Init(cfg)
+
+    // Создаем репозитории, сервисы и обработчики запросов
+    handlers := routers.NewHandlers(cfg)
+
+    // Периодически удаляем просроченные и неподтвержденные загрузки
+    handlers.Tus.RegisterJobs()
+    handlers.Uploads.RegisterJobs()
+
+    // Периодически удаляем файлы хранилища, на которые не ссылается база данных
+    gc.Init(cfg)
+
+    // Периодически удаляем старые выполненные и dead задачи
+    jobs.InitRetention(time.Duration(cfg.Jobs.RetentionDays) * 24 * time.Hour)
+
+    // Запускаем обработчики фоновых задач
+    jobs.Start(cfg.Jobs.Workers, time.Duration(cfg.Jobs.PollInterval)*time.Millisecond)
+
+    // Создаем роутер Gin
+    router := routers.NewRouter(cfg, handlers)
+
     // Запуск сервера
     address := fmt.Sprintf("%s:%s", cfg.API.Host, cfg.API.Port)
-    if err := router.Run(address); err != nil {
-        log.Fatalf("Ошибка запуска сервера: %v", err)
+    listener, err := net.Listen("tcp", address)
+    if err != nil {
+        logging.Fatal("Ошибка запуска сервера", "address", address, "error", err)
+    }
+    slog.Info("Запуск сервера", "address", address)
+
+    // SIGTERM отправляет оркестратор при остановке контейнера, SIGINT — Ctrl+C в терминале
+    ctx, stopSignals := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+    go func() {
+        // Повторный сигнал завершает процесс, не дожидаясь остановки
+        <-ctx.Done()
+        stopSignals()
+    }()
+    shutdownTimeout := time.Duration(cfg.API.ShutdownTimeout) * time.Second
+
+    // Метрики на отдельном адресе, недоступном клиентам API
+    switch {
+    case cfg.Metrics.Addr != "":
+        metricsListener, err := net.Listen("tcp", cfg.Metrics.Addr)
+        if err != nil {
+            logging.Fatal("Ошибка запуска сервера метрик", "address", cfg.Metrics.Addr, "error", err)
+        }
+        slog.Info("Запуск сервера метрик", "address", cfg.Metrics.Addr)
+        go func() {
+            if err := serve(ctx, newServer(metrics.Handler(cfg.Metrics.Token)), metricsListener, shutdownTimeout); err != nil {
+                logging.Fatal("Ошибка запуска сервера метрик", "address", cfg.Metrics.Addr, "error", err)
+            }
+        }()
+    case cfg.Metrics.Token == "":
+        slog.Warn("Метрики не отдаются: задайте METRICS_ADDR или METRICS_TOKEN")
+    }
+
+    if err := serve(ctx, newServer(router), listener, shutdownTimeout); err != nil {
+        logging.Fatal("Ошибка запуска сервера", "address", address, "error", err)
+    }
+
+    // Фоновым задачам и закрытию соединений отводится такое же время, как запросам
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+    defer cancel()
+    if err := jobs.Stop(shutdownCtx); err != nil {
+        slog.Error("Не все фоновые задачи завершились до остановки сервера", "error", err)
+    }
+
+    // Соединения с зависимостями закрываем, когда ими уже никто не пользуется
+    if config.RedisClient != nil {
+        if err := config.RedisClient.Close(); err != nil {
+            slog.Error("Ошибка закрытия соединений с Redis", "error", err)
+        }
+    }
+    if err := sqlDB.Close(); err != nil {
+        slog.Error("Ошибка закрытия соединений с базой данных", "error", err)
+    }
+    if err := shutdownTracing(shutdownCtx); err != nil {
+        slog.Error("Ошибка отправки спанов трассировки", "error", err)
     }
+    slog.Info("Сервер остановлен")
 }
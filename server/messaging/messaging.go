@@ -0,0 +1,110 @@
+// Package messaging проверяет, могут ли пользователи писать друг другу.
+package messaging
+
+import (
+    "context"
+    "errors"
+    "net/http"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/repository"
+)
+
+var (
+    ErrReceiverRequired = errors.New("необходимо указать получателя")
+    ErrReceiverNotFound = errors.New("получатель не найден")
+    ErrContactsOnly     = errors.New("получатель принимает сообщения только от своих контактов")
+    ErrBlocked          = errors.New("нельзя отправить сообщение этому пользователю")
+)
+
+// Service проверяет, могут ли пользователи писать друг другу
+type Service struct {
+    users  repository.UserRepository
+    policy repository.PolicyRepository
+}
+
+// NewService создает сервис проверки прав на переписку
+func NewService(users repository.UserRepository, policy repository.PolicyRepository) *Service {
+    return &Service{users: users, policy: policy}
+}
+
+// CheckCanSend проверяет, может ли отправитель написать получателю.
+// Если получатель ограничил круг собеседников контактами, писать ему можно только
+// пользователям из его списка контактов или тем, с кем он уже сам переписывался.
+func (s *Service) CheckCanSend(ctx context.Context, senderID, receiverID string) error {
+    if receiverID == "" {
+        return ErrReceiverRequired
+    }
+
+    receiver, err := s.users.Get(ctx, receiverID)
+    if errors.Is(err, repository.ErrNotFound) {
+        return ErrReceiverNotFound
+    }
+    if err != nil {
+        return err
+    }
+    if !receiver.IsActive {
+        return ErrReceiverNotFound
+    }
+
+    // Блокировка в любую сторону запрещает переписку
+    blocked, err := s.policy.IsBlocked(ctx, senderID, receiverID)
+    if err != nil {
+        return err
+    }
+    if blocked {
+        return ErrBlocked
+    }
+
+    if receiver.MessagePolicy != config.MessagePolicyContacts || senderID == receiverID {
+        return nil
+    }
+
+    isContact, err := s.policy.IsContact(ctx, receiverID, senderID)
+    if err != nil {
+        return err
+    }
+    if isContact {
+        return nil
+    }
+
+    // Переписка уже начата, если получатель сам писал отправителю
+    started, err := s.policy.HasWrittenTo(ctx, receiverID, senderID)
+    if err != nil {
+        return err
+    }
+    if started {
+        return nil
+    }
+
+    return ErrContactsOnly
+}
+
+// HasBlocked проверяет, заблокировал ли blockerID пользователя blockedID
+func (s *Service) HasBlocked(ctx context.Context, blockerID, blockedID string) (bool, error) {
+    return s.policy.HasBlocked(ctx, blockerID, blockedID)
+}
+
+// IsBlocked проверяет, заблокировал ли кто-либо из двух пользователей другого
+func (s *Service) IsBlocked(ctx context.Context, userID, otherID string) (bool, error) {
+    return s.policy.IsBlocked(ctx, userID, otherID)
+}
+
+// IsContact проверяет, есть ли contactID в списке контактов ownerID
+func (s *Service) IsContact(ctx context.Context, ownerID, contactID string) (bool, error) {
+    return s.policy.IsContact(ctx, ownerID, contactID)
+}
+
+// HTTPStatus возвращает HTTP-статус ответа для ошибки CheckCanSend
+func HTTPStatus(err error) int {
+    switch {
+    case errors.Is(err, ErrReceiverRequired):
+        return http.StatusBadRequest
+    case errors.Is(err, ErrReceiverNotFound):
+        return http.StatusNotFound
+    case errors.Is(err, ErrContactsOnly), errors.Is(err, ErrBlocked):
+        return http.StatusForbidden
+    default:
+        return http.StatusInternalServerError
+    }
+}
@@ -0,0 +1,28 @@
+package metrics
+
+import (
+    "strconv"
+    "time"
+
+    "github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute — метка запросов к несуществующим маршрутам. Путь запроса в метку
+// не попадает, чтобы сканирование адресов не создавало неограниченное число рядов.
+const unmatchedRoute = "unmatched"
+
+// Middleware считает HTTP-запросы и время их обработки по шаблону маршрута и статусу
+func Middleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        start := time.Now()
+        c.Next()
+
+        route := c.FullPath()
+        if route == "" {
+            route = unmatchedRoute
+        }
+        status := strconv.Itoa(c.Writer.Status())
+        HTTPRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+        HTTPDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+    }
+}
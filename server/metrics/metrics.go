@@ -0,0 +1,135 @@
+// Package metrics собирает метрики сервера в формате Prometheus и отдает их по адресу /metrics
+// на отдельном адресе или на адресе API по токену.
+// Метрики регистрируются в собственном реестре пакета, поэтому в выдачу попадают только
+// метрики сервера, процесса и среды выполнения Go.
+package metrics
+
+import (
+    "crypto/subtle"
+    "database/sql"
+    "net/http"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    "github.com/prometheus/client_golang/prometheus/collectors"
+    "github.com/prometheus/client_golang/prometheus/promauto"
+    "github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "chatter"
+
+// Registry — реестр метрик сервера
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+    // HTTPRequests — обработанные HTTP-запросы по маршруту и статусу
+    HTTPRequests = factory.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "http_requests_total",
+        Help:      "Количество обработанных HTTP-запросов.",
+    }, []string{"method", "route", "status"})
+
+    // HTTPDuration — время обработки HTTP-запросов по маршруту и статусу
+    HTTPDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace: namespace,
+        Name:      "http_request_duration_seconds",
+        Help:      "Время обработки HTTP-запросов в секундах.",
+        Buckets:   prometheus.DefBuckets,
+    }, []string{"method", "route", "status"})
+
+    // MessagesSent — отправленные сообщения по типу: text или voice
+    MessagesSent = factory.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "messages_sent_total",
+        Help:      "Количество отправленных сообщений по типу.",
+    }, []string{"type"})
+
+    // UploadBytes — объем загруженных файлов по типу вложения или avatar
+    UploadBytes = factory.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "upload_bytes_total",
+        Help:      "Объем загруженных файлов в байтах по типу.",
+    }, []string{"kind"})
+
+    // Logins — попытки входа: success или failure
+    Logins = factory.NewCounterVec(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "logins_total",
+        Help:      "Количество попыток входа по результату.",
+    }, []string{"result"})
+
+    // NotificationSubscriptions — открытые подписки на уведомления в реальном времени
+    NotificationSubscriptions = factory.NewGauge(prometheus.GaugeOpts{
+        Namespace: namespace,
+        Name:      "notification_subscriptions_active",
+        Help:      "Количество открытых подписок на уведомления в реальном времени.",
+    })
+
+    // NotificationsDropped — уведомления, отброшенные из-за медленного подписчика
+    NotificationsDropped = factory.NewCounter(prometheus.CounterOpts{
+        Namespace: namespace,
+        Name:      "notifications_dropped_total",
+        Help:      "Количество уведомлений, отброшенных из-за переполнения очереди подписчика.",
+    })
+
+    // RedisDuration — время выполнения команд Redis
+    RedisDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace: namespace,
+        Name:      "redis_command_duration_seconds",
+        Help:      "Время выполнения команд Redis в секундах.",
+        Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+    }, []string{"command", "result"})
+
+    // StorageDuration — время обращений к хранилищу файлов
+    StorageDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+        Namespace: namespace,
+        Name:      "storage_request_duration_seconds",
+        Help:      "Время обращений к хранилищу файлов в секундах.",
+        Buckets:   prometheus.DefBuckets,
+    }, []string{"driver", "operation", "result"})
+)
+
+func init() {
+    Registry.MustRegister(
+        collectors.NewGoCollector(),
+        collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+    )
+}
+
+// RegisterDB добавляет в выдачу статистику пула соединений с базой данных
+func RegisterDB(db *sql.DB) error {
+    return Registry.Register(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// Handler отдает метрики в формате Prometheus. Если token не пустой, запрос должен
+// содержать заголовок Authorization: Bearer <token>.
+func Handler(token string) http.Handler {
+    handler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+    if token == "" {
+        return handler
+    }
+    expected := []byte("Bearer " + token)
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+            w.Header().Set("WWW-Authenticate", "Bearer")
+            http.Error(w, "Требуется токен метрик", http.StatusUnauthorized)
+            return
+        }
+        handler.ServeHTTP(w, r)
+    })
+}
+
+// Result возвращает значение метки result для ошибки операции
+func Result(err error) string {
+    if err != nil {
+        return "error"
+    }
+    return "ok"
+}
+
+// ObserveStorage записывает время обращения к хранилищу драйвера driver, начатого в start
+func ObserveStorage(driver, operation string, start time.Time, err error) {
+    StorageDuration.WithLabelValues(driver, operation, Result(err)).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "github.com/go-redis/redis/v8"
+)
+
+type redisStartKey struct{}
+
+// RedisHook записывает время выполнения команд и конвейеров Redis.
+// Подключается к клиенту через AddHook.
+type RedisHook struct{}
+
+func (RedisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+    return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+func (RedisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+    observeRedis(ctx, cmd.Name(), cmd.Err())
+    return nil
+}
+
+func (RedisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+    return context.WithValue(ctx, redisStartKey{}, time.Now()), nil
+}
+
+func (RedisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+    var err error
+    for _, cmd := range cmds {
+        if cmd.Err() != nil && !errors.Is(cmd.Err(), redis.Nil) {
+            err = cmd.Err()
+            break
+        }
+    }
+    observeRedis(ctx, "pipeline", err)
+    return nil
+}
+
+// observeRedis записывает время команды; отсутствие ключа (redis.Nil) ошибкой не считается
+func observeRedis(ctx context.Context, command string, err error) {
+    start, ok := ctx.Value(redisStartKey{}).(time.Time)
+    if !ok {
+        return
+    }
+    if errors.Is(err, redis.Nil) {
+        err = nil
+    }
+    RedisDuration.WithLabelValues(command, Result(err)).Observe(time.Since(start).Seconds())
+}
@@ -0,0 +1,71 @@
+package migrations
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+    "io"
+    "strconv"
+)
+
+// Usage — справка по подкоманде migrate
+const Usage = `Использование: server migrate <команда>
+  up          применить все новые миграции
+  down [N]    откатить последние N миграций (по умолчанию 1)
+  status      показать примененные и ожидающие миграции
+  to VERSION  привести схему к версии VERSION (0 — пустая схема)`
+
+// Command выполняет подкоманду migrate с аргументами args и выводит результат в out
+func Command(ctx context.Context, db *sql.DB, args []string, out io.Writer) error {
+    if len(args) == 0 {
+        return fmt.Errorf("не указана команда\n%s", Usage)
+    }
+    migrator, err := New(db)
+    if err != nil {
+        return err
+    }
+
+    var count int
+    switch args[0] {
+    case "up":
+        count, err = migrator.Up(ctx)
+    case "down":
+        steps := 1
+        if len(args) > 1 {
+            steps, err = strconv.Atoi(args[1])
+            if err != nil || steps < 1 {
+                return fmt.Errorf("некорректное количество миграций %q", args[1])
+            }
+        }
+        count, err = migrator.Down(ctx, steps)
+    case "to":
+        if len(args) < 2 {
+            return fmt.Errorf("не указана версия\n%s", Usage)
+        }
+        version, parseErr := strconv.ParseInt(args[1], 10, 64)
+        if parseErr != nil || version < 0 {
+            return fmt.Errorf("некорректная версия %q", args[1])
+        }
+        count, err = migrator.To(ctx, version)
+    case "status":
+        statuses, err := migrator.Status(ctx)
+        if err != nil {
+            return err
+        }
+        for _, status := range statuses {
+            state := "ожидает"
+            if status.AppliedAt != nil {
+                state = "применена " + status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+            }
+            fmt.Fprintf(out, "%04d  %-30s  %s\n", status.Version, status.Name, state)
+        }
+        return nil
+    default:
+        return fmt.Errorf("неизвестная команда %q\n%s", args[0], Usage)
+    }
+    if err != nil {
+        return err
+    }
+    fmt.Fprintf(out, "Выполнено миграций: %d\n", count)
+    return nil
+}
@@ -0,0 +1,327 @@
+// Package migrations применяет версионированные SQL-миграции схемы базы данных.
+// Файлы миграций встроены в бинарник и называются NNNN_имя.up.sql и NNNN_имя.down.sql.
+// На время применения берется advisory-блокировка PostgreSQL, поэтому несколько экземпляров
+// сервера, запущенных одновременно, не применяют одну миграцию дважды.
+package migrations
+
+import (
+    "context"
+    "database/sql"
+    "embed"
+    "errors"
+    "fmt"
+    "io/fs"
+    "log/slog"
+    "path"
+    "regexp"
+    "sort"
+    "strconv"
+    "time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// lockKey — ключ advisory-блокировки миграций, общий для всех экземпляров сервера
+const lockKey int64 = 0x63686174746572 // "chatter"
+
+var ErrNoDown = errors.New("у миграции нет файла отката")
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration — одна версия схемы
+type Migration struct {
+    Version int64
+    Name    string
+    Up      string
+    Down    string
+}
+
+// Status — состояние миграции в базе данных
+type Status struct {
+    Version   int64
+    Name      string
+    Applied   bool
+    AppliedAt *time.Time
+}
+
+// dialect описывает запросы механизма миграций, которые различаются в разных СУБД
+type dialect struct {
+    lock        string // Захват блокировки миграций; пустая строка — блокировка не нужна
+    unlock      string
+    createTable string // Создание таблицы schema_migrations
+}
+
+var postgresDialect = dialect{
+    lock:   "SELECT pg_advisory_lock($1)",
+    unlock: "SELECT pg_advisory_unlock($1)",
+    createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+        version bigint PRIMARY KEY,
+        name text NOT NULL,
+        applied_at timestamptz NOT NULL DEFAULT now()
+    )`,
+}
+
+// Migrator применяет и откатывает миграции
+type Migrator struct {
+    db         *sql.DB
+    dialect    dialect
+    migrations []Migration
+}
+
+// New загружает встроенные миграции PostgreSQL
+func New(db *sql.DB) (*Migrator, error) {
+    return newMigrator(db, postgresDialect, files)
+}
+
+func newMigrator(db *sql.DB, dialect dialect, fsys fs.FS) (*Migrator, error) {
+    migrations, err := load(fsys)
+    if err != nil {
+        return nil, err
+    }
+    return &Migrator{db: db, dialect: dialect, migrations: migrations}, nil
+}
+
+// load читает файлы миграций и упорядочивает их по версии
+func load(fsys fs.FS) ([]Migration, error) {
+    entries, err := fs.ReadDir(fsys, "sql")
+    if err != nil {
+        return nil, err
+    }
+    byVersion := map[int64]*Migration{}
+    for _, entry := range entries {
+        match := fileName.FindStringSubmatch(entry.Name())
+        if match == nil {
+            return nil, fmt.Errorf("некорректное имя файла миграции %s", entry.Name())
+        }
+        version, _ := strconv.ParseInt(match[1], 10, 64)
+        data, err := fs.ReadFile(fsys, path.Join("sql", entry.Name()))
+        if err != nil {
+            return nil, err
+        }
+        migration, ok := byVersion[version]
+        if !ok {
+            migration = &Migration{Version: version, Name: match[2]}
+            byVersion[version] = migration
+        } else if migration.Name != match[2] {
+            return nil, fmt.Errorf("у версии %d несколько миграций: %s и %s", version, migration.Name, match[2])
+        }
+        if match[3] == "up" {
+            migration.Up = string(data)
+        } else {
+            migration.Down = string(data)
+        }
+    }
+
+    migrations := make([]Migration, 0, len(byVersion))
+    for _, migration := range byVersion {
+        if migration.Up == "" {
+            return nil, fmt.Errorf("у миграции %04d_%s нет файла up", migration.Version, migration.Name)
+        }
+        migrations = append(migrations, *migration)
+    }
+    sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+    return migrations, nil
+}
+
+// Latest возвращает номер последней известной версии
+func (m *Migrator) Latest() int64 {
+    if len(m.migrations) == 0 {
+        return 0
+    }
+    return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up применяет все еще не примененные миграции и возвращает их количество
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+    return m.To(ctx, m.Latest())
+}
+
+// Down откатывает последние steps примененных миграций
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+    count := 0
+    err := m.withLock(ctx, func(conn *sql.Conn) error {
+        applied, err := appliedVersions(ctx, conn)
+        if err != nil {
+            return err
+        }
+        for i := len(m.migrations) - 1; i >= 0 && count < steps; i-- {
+            migration := m.migrations[i]
+            if _, ok := applied[migration.Version]; !ok {
+                continue
+            }
+            if err := rollback(ctx, conn, migration); err != nil {
+                return err
+            }
+            count++
+        }
+        return nil
+    })
+    return count, err
+}
+
+// To приводит схему к версии version: применяет недостающие миграции до нее включительно
+// и откатывает примененные миграции новее нее. Версия 0 — пустая схема.
+func (m *Migrator) To(ctx context.Context, version int64) (int, error) {
+    if version != 0 && !m.known(version) {
+        return 0, fmt.Errorf("неизвестная версия миграции %d", version)
+    }
+    count := 0
+    err := m.withLock(ctx, func(conn *sql.Conn) error {
+        applied, err := appliedVersions(ctx, conn)
+        if err != nil {
+            return err
+        }
+        for i := len(m.migrations) - 1; i >= 0; i-- {
+            migration := m.migrations[i]
+            if _, ok := applied[migration.Version]; !ok || migration.Version <= version {
+                continue
+            }
+            if err := rollback(ctx, conn, migration); err != nil {
+                return err
+            }
+            count++
+        }
+        for _, migration := range m.migrations {
+            if _, ok := applied[migration.Version]; ok || migration.Version > version {
+                continue
+            }
+            if err := apply(ctx, conn, migration); err != nil {
+                return err
+            }
+            count++
+        }
+        return nil
+    })
+    return count, err
+}
+
+// Status возвращает список миграций с отметкой о применении
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+    var result []Status
+    err := m.withLock(ctx, func(conn *sql.Conn) error {
+        applied, err := appliedVersions(ctx, conn)
+        if err != nil {
+            return err
+        }
+        for _, migration := range m.migrations {
+            status := Status{Version: migration.Version, Name: migration.Name}
+            if appliedAt, ok := applied[migration.Version]; ok {
+                status.Applied = true
+                status.AppliedAt = &appliedAt
+                delete(applied, migration.Version)
+            }
+            result = append(result, status)
+        }
+        // Версии из базы, которых нет в этой сборке: схему обновил более новый сервер
+        for version, appliedAt := range applied {
+            appliedAt := appliedAt
+            result = append(result, Status{Version: version, Name: "?", Applied: true, AppliedAt: &appliedAt})
+        }
+        sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+        return nil
+    })
+    return result, err
+}
+
+func (m *Migrator) known(version int64) bool {
+    for _, migration := range m.migrations {
+        if migration.Version == version {
+            return true
+        }
+    }
+    return false
+}
+
+// withLock выполняет fn на отдельном соединении, удерживая advisory-блокировку миграций.
+// Блокировка сессионная, поэтому все запросы выполняются на одном соединении.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+    conn, err := m.db.Conn(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+
+    if m.dialect.lock != "" {
+        if _, err := conn.ExecContext(ctx, m.dialect.lock, lockKey); err != nil {
+            return fmt.Errorf("не удалось получить блокировку миграций: %w", err)
+        }
+        defer func() {
+            if _, err := conn.ExecContext(context.WithoutCancel(ctx), m.dialect.unlock, lockKey); err != nil {
+                slog.Error("Ошибка снятия блокировки миграций", "error", err)
+            }
+        }()
+    }
+
+    if _, err := conn.ExecContext(ctx, m.dialect.createTable); err != nil {
+        return err
+    }
+    return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+    rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+    if err != nil {
+        return nil, err
+    }
+    defer rows.Close()
+
+    applied := map[int64]time.Time{}
+    for rows.Next() {
+        var version int64
+        var appliedAt time.Time
+        if err := rows.Scan(&version, &appliedAt); err != nil {
+            return nil, err
+        }
+        applied[version] = appliedAt
+    }
+    return applied, rows.Err()
+}
+
+// apply выполняет миграцию и записывает версию в одной транзакции.
+// Текст миграции передается без параметров, поэтому в одном файле может быть несколько команд.
+func apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+    err := inTx(ctx, conn, func(tx *sql.Tx) error {
+        if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+            return err
+        }
+        _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+            migration.Version, migration.Name, time.Now().UTC())
+        return err
+    })
+    if err != nil {
+        return fmt.Errorf("миграция %04d_%s: %w", migration.Version, migration.Name, err)
+    }
+    slog.Info("Применена миграция", "version", migration.Version, "name", migration.Name)
+    return nil
+}
+
+func rollback(ctx context.Context, conn *sql.Conn, migration Migration) error {
+    if migration.Down == "" {
+        return fmt.Errorf("%w: %04d_%s", ErrNoDown, migration.Version, migration.Name)
+    }
+    err := inTx(ctx, conn, func(tx *sql.Tx) error {
+        if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+            return err
+        }
+        _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+        return err
+    })
+    if err != nil {
+        return fmt.Errorf("откат миграции %04d_%s: %w", migration.Version, migration.Name, err)
+    }
+    slog.Info("Откачена миграция", "version", migration.Version, "name", migration.Name)
+    return nil
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+    tx, err := conn.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    if err := fn(tx); err != nil {
+        tx.Rollback()
+        return err
+    }
+    return tx.Commit()
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS voice_messages;
+DROP TABLE IF EXISTS text_messages;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,27 @@
+-- Исходная схема из трех таблиц, которую создавал AutoMigrate первых версий сервера.
+-- Таблицы создаются с IF NOT EXISTS, поэтому миграция применяется и к новой базе, и к базе,
+-- созданной прежними версиями; недостающие колонки и таблицы добавляют следующие миграции.
+
+CREATE TABLE IF NOT EXISTS users (
+    id text PRIMARY KEY,
+    username text,
+    email text,
+    password text,
+    is_active boolean DEFAULT true
+);
+
+CREATE TABLE IF NOT EXISTS text_messages (
+    id bigserial PRIMARY KEY,
+    sender_id text,
+    receiver_id text,
+    content text,
+    created_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS voice_messages (
+    id bigserial PRIMARY KEY,
+    sender_id text,
+    receiver_id text,
+    file_url text,
+    created_at timestamptz
+);
@@ -0,0 +1,10 @@
+-- Расширение pg_trgm не удаляется: им могут пользоваться другие приложения в той же базе
+DROP INDEX IF EXISTS idx_users_display_name_trgm;
+DROP INDEX IF EXISTS idx_users_username_trgm;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS message_policy,
+    DROP COLUMN IF EXISTS discoverable,
+    DROP COLUMN IF EXISTS avatar_id,
+    DROP COLUMN IF EXISTS bio,
+    DROP COLUMN IF EXISTS display_name;
@@ -0,0 +1,11 @@
+-- Профиль, поиск пользователей и настройка приватности переписки
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS display_name text,
+    ADD COLUMN IF NOT EXISTS bio text,
+    ADD COLUMN IF NOT EXISTS avatar_id text,
+    ADD COLUMN IF NOT EXISTS discoverable boolean DEFAULT true,
+    ADD COLUMN IF NOT EXISTS message_policy text DEFAULT 'everyone';
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING gin (lower(username) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_users_display_name_trgm ON users USING gin (lower(display_name) gin_trgm_ops);
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS contact_requests (
+    id bigserial PRIMARY KEY,
+    requester_id text,
+    addressee_id text,
+    status text,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_contact_requests_requester_id ON contact_requests (requester_id);
+CREATE INDEX IF NOT EXISTS idx_contact_requests_addressee_id ON contact_requests (addressee_id);
+CREATE INDEX IF NOT EXISTS idx_contact_requests_status ON contact_requests (status);
+
+CREATE TABLE IF NOT EXISTS contacts (
+    owner_id text,
+    contact_id text,
+    nickname text,
+    created_at timestamptz,
+    PRIMARY KEY (owner_id, contact_id)
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS blocks (
+    blocker_id text,
+    blocked_id text,
+    created_at timestamptz,
+    PRIMARY KEY (blocker_id, blocked_id)
+);
+CREATE INDEX IF NOT EXISTS idx_blocks_blocked_id ON blocks (blocked_id);
+
+CREATE TABLE IF NOT EXISTS conversation_mutes (
+    user_id text,
+    peer_id text,
+    muted_until timestamptz,
+    created_at timestamptz,
+    PRIMARY KEY (user_id, peer_id)
+);
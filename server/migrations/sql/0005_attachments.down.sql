@@ -0,0 +1,2 @@
+ALTER TABLE voice_messages DROP COLUMN IF EXISTS attachment_id;
+DROP TABLE IF EXISTS attachments;
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS attachments (
+    id text PRIMARY KEY,
+    owner_id text,
+    kind text,
+    bucket text,
+    object_key text,
+    file_name text,
+    content_type text,
+    size bigint,
+    width bigint,
+    height bigint,
+    checksum text,
+    message_type text,
+    message_id bigint,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_attachments_owner_id ON attachments (owner_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_message ON attachments (message_type, message_id);
+
+ALTER TABLE voice_messages ADD COLUMN IF NOT EXISTS attachment_id text;
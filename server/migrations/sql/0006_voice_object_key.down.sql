@@ -0,0 +1,4 @@
+-- Ссылки восстанавливаются без адреса сервера: прежде он брался из настроек MinIO
+ALTER TABLE voice_messages ADD COLUMN IF NOT EXISTS file_url text;
+UPDATE voice_messages SET file_url = '/voice-messages/' || object_key WHERE object_key IS NOT NULL AND object_key <> '';
+ALTER TABLE voice_messages DROP COLUMN IF EXISTS object_key;
@@ -0,0 +1,16 @@
+-- Раньше в voice_messages хранилась прямая ссылка на MinIO. Переносим из нее ключ объекта
+-- и удаляем колонку. В базах, созданных AutoMigrate после перехода на ключи, колонки file_url нет.
+ALTER TABLE voice_messages ADD COLUMN IF NOT EXISTS object_key text;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_schema = current_schema() AND table_name = 'voice_messages' AND column_name = 'file_url') THEN
+        UPDATE voice_messages
+        SET object_key = regexp_replace(file_url, '^https?://[^/]+/voice-messages/', '')
+        WHERE (object_key IS NULL OR object_key = '') AND file_url LIKE '%/voice-messages/%';
+
+        ALTER TABLE voice_messages DROP COLUMN file_url;
+    END IF;
+END
+$$;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS uploads (
+    id text PRIMARY KEY,
+    owner_id text,
+    kind text,
+    bucket text,
+    object_key text,
+    file_name text,
+    content_type text,
+    size bigint,
+    checksum text,
+    status text,
+    attachment_id text,
+    expires_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_uploads_owner_id ON uploads (owner_id);
+CREATE INDEX IF NOT EXISTS idx_uploads_status ON uploads (status);
+CREATE INDEX IF NOT EXISTS idx_uploads_expires_at ON uploads (expires_at);
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS tus_uploads (
+    id text PRIMARY KEY,
+    owner_id text,
+    kind text,
+    bucket text,
+    object_key text,
+    multipart_id text,
+    file_name text,
+    content_type text,
+    length bigint,
+    "offset" bigint,
+    part_count bigint,
+    tail_size bigint,
+    status text,
+    attachment_id text,
+    expires_at timestamptz,
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_tus_uploads_owner_id ON tus_uploads (owner_id);
+CREATE INDEX IF NOT EXISTS idx_tus_uploads_status ON tus_uploads (status);
+CREATE INDEX IF NOT EXISTS idx_tus_uploads_expires_at ON tus_uploads (expires_at);
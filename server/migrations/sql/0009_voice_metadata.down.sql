@@ -0,0 +1,5 @@
+ALTER TABLE voice_messages
+    DROP COLUMN IF EXISTS waveform,
+    DROP COLUMN IF EXISTS sample_rate,
+    DROP COLUMN IF EXISTS codec,
+    DROP COLUMN IF EXISTS duration_ms;
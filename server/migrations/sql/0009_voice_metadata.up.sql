@@ -0,0 +1,5 @@
+ALTER TABLE voice_messages
+    ADD COLUMN IF NOT EXISTS duration_ms bigint,
+    ADD COLUMN IF NOT EXISTS codec text,
+    ADD COLUMN IF NOT EXISTS sample_rate bigint,
+    ADD COLUMN IF NOT EXISTS waveform bytea;
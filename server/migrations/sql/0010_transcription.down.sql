@@ -0,0 +1,4 @@
+ALTER TABLE voice_messages
+    DROP COLUMN IF EXISTS transcript_language,
+    DROP COLUMN IF EXISTS transcript,
+    DROP COLUMN IF EXISTS transcription_status;
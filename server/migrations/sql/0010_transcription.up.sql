@@ -0,0 +1,4 @@
+ALTER TABLE voice_messages
+    ADD COLUMN IF NOT EXISTS transcription_status text,
+    ADD COLUMN IF NOT EXISTS transcript text,
+    ADD COLUMN IF NOT EXISTS transcript_language text;
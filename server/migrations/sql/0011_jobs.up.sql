@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS jobs (
+    id bigserial PRIMARY KEY,
+    type text,
+    payload jsonb DEFAULT '{}',
+    status text,
+    run_at timestamptz,
+    attempts bigint,
+    max_attempts bigint,
+    last_error text,
+    locked_at timestamptz,
+    locked_by text,
+    unique_key text,
+    completed_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_type ON jobs (type);
+CREATE INDEX IF NOT EXISTS idx_jobs_status_run_at ON jobs (status, run_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_unique_key ON jobs (unique_key);
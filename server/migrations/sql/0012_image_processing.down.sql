@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS attachment_variants;
+
+ALTER TABLE attachments
+    DROP COLUMN IF EXISTS blurhash,
+    DROP COLUMN IF EXISTS processing_status;
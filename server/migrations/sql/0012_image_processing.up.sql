@@ -0,0 +1,17 @@
+ALTER TABLE attachments
+    ADD COLUMN IF NOT EXISTS processing_status text,
+    ADD COLUMN IF NOT EXISTS blurhash text;
+
+CREATE TABLE IF NOT EXISTS attachment_variants (
+    id bigserial PRIMARY KEY,
+    attachment_id text,
+    name text,
+    bucket text,
+    object_key text,
+    content_type text,
+    width bigint,
+    height bigint,
+    size bigint,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_attachment_variants_name ON attachment_variants (attachment_id, name);
@@ -0,0 +1,11 @@
+ALTER TABLE voice_messages
+    DROP COLUMN IF EXISTS block_reason,
+    DROP COLUMN IF EXISTS blocked;
+
+ALTER TABLE text_messages
+    DROP COLUMN IF EXISTS block_reason,
+    DROP COLUMN IF EXISTS blocked;
+
+ALTER TABLE attachments
+    DROP COLUMN IF EXISTS scan_signature,
+    DROP COLUMN IF EXISTS scan_status;
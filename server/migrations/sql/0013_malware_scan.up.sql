@@ -0,0 +1,11 @@
+ALTER TABLE attachments
+    ADD COLUMN IF NOT EXISTS scan_status text,
+    ADD COLUMN IF NOT EXISTS scan_signature text;
+
+ALTER TABLE text_messages
+    ADD COLUMN IF NOT EXISTS blocked boolean,
+    ADD COLUMN IF NOT EXISTS block_reason text;
+
+ALTER TABLE voice_messages
+    ADD COLUMN IF NOT EXISTS blocked boolean,
+    ADD COLUMN IF NOT EXISTS block_reason text;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS storage_usages (
+    user_id text PRIMARY KEY,
+    used_bytes bigint NOT NULL DEFAULT 0,
+    quota_bytes bigint,
+    updated_at timestamptz
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS gc_reports;
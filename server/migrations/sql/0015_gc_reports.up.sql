@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS gc_reports (
+    id bigserial PRIMARY KEY,
+    dry_run boolean,
+    grace_period bigint,
+    scanned bigint,
+    orphaned bigint,
+    orphaned_bytes bigint,
+    deleted bigint,
+    errors bigint,
+    details jsonb DEFAULT '{}',
+    started_at timestamptz,
+    finished_at timestamptz
+);
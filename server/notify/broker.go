@@ -0,0 +1,144 @@
+package notify
+
+import (
+    "context"
+    "sync"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/logging"
+    "chatter-hub-server/metrics"
+    "chatter-hub-server/repository"
+
+    "github.com/go-redis/redis/v8"
+)
+
+// subscriberBuffer — сколько уведомлений ждет получения подписчиком; лишние отбрасываются
+const subscriberBuffer = 64
+
+// Broker доставляет уведомления подписчикам канала
+type Broker interface {
+    Publish(ctx context.Context, channel string, payload []byte) error
+    // Subscribe возвращает поток уведомлений канала и функцию отмены подписки. Подписка
+    // отменяется и при отмене ctx; после отмены поток закрывается. Уведомления, которые
+    // подписчик не успевает забирать, отбрасываются.
+    Subscribe(ctx context.Context, channel string) (<-chan []byte, func())
+}
+
+// Default — Notifier, выбранный через Init; до этого уведомления доставляются внутри процесса
+var Default = NewNotifier(NewLocalBroker(), nil)
+
+// Init выбирает канал уведомлений: Redis Pub/Sub или память процесса
+func Init(cfg *config.Config, mutes repository.MuteRepository) {
+    switch cfg.Cache.Driver {
+    case "redis", "":
+        Default = NewNotifier(NewRedisBroker(config.RedisClient), mutes)
+    case "memory":
+        Default = NewNotifier(NewLocalBroker(), mutes)
+    default:
+        logging.Fatal("Неизвестный драйвер уведомлений", "driver", cfg.Cache.Driver)
+    }
+}
+
+// Subscribe подписывается на уведомления пользователя. Открытые подписки учитываются в метриках.
+func (n *Notifier) Subscribe(ctx context.Context, userID string) (<-chan []byte, func()) {
+    events, cancel := n.broker.Subscribe(ctx, Channel(userID))
+    metrics.NotificationSubscriptions.Inc()
+    var once sync.Once
+    return events, func() {
+        once.Do(metrics.NotificationSubscriptions.Dec)
+        cancel()
+    }
+}
+
+type redisBroker struct {
+    client *redis.Client
+}
+
+// NewRedisBroker возвращает Broker на основе Redis Pub/Sub
+func NewRedisBroker(client *redis.Client) Broker {
+    return &redisBroker{client: client}
+}
+
+func (b *redisBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+    return b.client.Publish(ctx, channel, payload).Err()
+}
+
+func (b *redisBroker) Subscribe(ctx context.Context, channel string) (<-chan []byte, func()) {
+    pubsub := b.client.Subscribe(ctx, channel)
+    messages := pubsub.Channel()
+    out := make(chan []byte, subscriberBuffer)
+    go func() {
+        defer close(out)
+        defer pubsub.Close()
+        for {
+            select {
+            case <-ctx.Done():
+                return
+            case message, ok := <-messages:
+                if !ok {
+                    return
+                }
+                deliver(out, []byte(message.Payload))
+            }
+        }
+    }()
+    var once sync.Once
+    return out, func() { once.Do(func() { pubsub.Close() }) }
+}
+
+// deliver передает уведомление подписчику, не дожидаясь его: медленный подписчик
+// теряет уведомления, но не задерживает отправителя и чтение канала
+func deliver(subscriber chan<- []byte, payload []byte) {
+    select {
+    case subscriber <- payload:
+    default:
+        metrics.NotificationsDropped.Inc()
+    }
+}
+
+type localBroker struct {
+    mu          sync.Mutex
+    subscribers map[string]map[chan []byte]struct{}
+}
+
+// NewLocalBroker возвращает Broker, доставляющий уведомления только внутри процесса
+func NewLocalBroker() Broker {
+    return &localBroker{subscribers: make(map[string]map[chan []byte]struct{})}
+}
+
+func (b *localBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    for subscriber := range b.subscribers[channel] {
+        deliver(subscriber, payload)
+    }
+    return nil
+}
+
+func (b *localBroker) Subscribe(ctx context.Context, channel string) (<-chan []byte, func()) {
+    subscriber := make(chan []byte, subscriberBuffer)
+    b.mu.Lock()
+    if b.subscribers[channel] == nil {
+        b.subscribers[channel] = make(map[chan []byte]struct{})
+    }
+    b.subscribers[channel][subscriber] = struct{}{}
+    b.mu.Unlock()
+
+    var once sync.Once
+    cancel := func() {
+        once.Do(func() {
+            b.mu.Lock()
+            delete(b.subscribers[channel], subscriber)
+            if len(b.subscribers[channel]) == 0 {
+                delete(b.subscribers, channel)
+            }
+            b.mu.Unlock()
+            close(subscriber)
+        })
+    }
+    stop := context.AfterFunc(ctx, cancel)
+    return subscriber, func() {
+        stop()
+        cancel()
+    }
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+    "context"
+    "fmt"
+    "testing"
+    "time"
+)
+
+func TestLocalBroker(t *testing.T) {
+    tests := []struct {
+        name      string
+        published int
+        want      int
+    }{
+        {name: "все уведомления доставлены", published: 3, want: 3},
+        {name: "заполнен буфер", published: subscriberBuffer, want: subscriberBuffer},
+        {name: "лишние уведомления отброшены", published: subscriberBuffer + 10, want: subscriberBuffer},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            broker := NewLocalBroker()
+            events, cancel := broker.Subscribe(context.Background(), "channel")
+            defer cancel()
+
+            // Подписчик не читает поток: Publish не должен блокироваться
+            done := make(chan struct{})
+            go func() {
+                for i := 0; i < tt.published; i++ {
+                    broker.Publish(context.Background(), "channel", []byte(fmt.Sprint(i)))
+                }
+                close(done)
+            }()
+            select {
+            case <-done:
+            case <-time.After(5 * time.Second):
+                t.Fatalf("Publish заблокирован медленным подписчиком")
+            }
+
+            cancel()
+            received := 0
+            for payload := range events {
+                if string(payload) != fmt.Sprint(received) {
+                    t.Fatalf("уведомление %q, ожидалось %d", payload, received)
+                }
+                received++
+            }
+            if received != tt.want {
+                t.Fatalf("получено %d уведомлений, ожидалось %d", received, tt.want)
+            }
+        })
+    }
+}
+
+func TestLocalBrokerContextCancel(t *testing.T) {
+    broker := NewLocalBroker()
+    ctx, cancel := context.WithCancel(context.Background())
+    events, unsubscribe := broker.Subscribe(ctx, "channel")
+    defer unsubscribe()
+
+    cancel()
+    select {
+    case _, ok := <-events:
+        if ok {
+            t.Fatalf("после отмены контекста пришло уведомление")
+        }
+    case <-time.After(5 * time.Second):
+        t.Fatalf("поток не закрыт после отмены контекста")
+    }
+
+    // Канал без подписчиков удален, публикация в него ничего не делает
+    if err := broker.Publish(context.Background(), "channel", []byte("after")); err != nil {
+        t.Fatal(err)
+    }
+    if n := len(broker.(*localBroker).subscribers); n != 0 {
+        t.Fatalf("осталось %d каналов с подписчиками", n)
+    }
+}
@@ -0,0 +1,82 @@
+package notify
+
+import (
+    "context"
+    "encoding/json"
+    "time"
+
+    "chatter-hub-server/logging"
+    "chatter-hub-server/repository"
+)
+
+// Типы уведомлений
+const (
+    EventTextMessage  = "message.text"
+    EventVoiceMessage = "message.voice"
+)
+
+// Event представляет уведомление, которое публикуется в канал пользователя
+type Event struct {
+    Type      string      `json:"type"`
+    From      string      `json:"from,omitempty"`
+    Payload   interface{} `json:"payload,omitempty"`
+    CreatedAt time.Time   `json:"created_at"`
+}
+
+// Publisher отправляет уведомления пользователям
+type Publisher interface {
+    Publish(ctx context.Context, userID string, event Event)
+}
+
+// Notifier публикует уведомления через Broker и подписывает на них
+type Notifier struct {
+    broker Broker
+    mutes  repository.MuteRepository
+}
+
+// NewNotifier создает Notifier поверх broker. Если mutes задан, уведомления из переписок
+// с отключенными уведомлениями не отправляются.
+func NewNotifier(broker Broker, mutes repository.MuteRepository) *Notifier {
+    return &Notifier{broker: broker, mutes: mutes}
+}
+
+// Channel возвращает имя канала с уведомлениями пользователя
+func Channel(userID string) string {
+    return "notifications:" + userID
+}
+
+// Publish отправляет уведомление через Default. Используется фоновыми задачами,
+// обработчики HTTP получают Publisher при создании.
+func Publish(ctx context.Context, userID string, event Event) {
+    Default.Publish(ctx, userID, event)
+}
+
+// Publish отправляет уведомление пользователю, если он не отключил уведомления
+// от отправителя события. Ошибки доставки не прерывают основную операцию и только логируются.
+// Уведомление отправляется, даже если ctx уже отменен: операция, о которой оно сообщает, выполнена.
+func (n *Notifier) Publish(ctx context.Context, userID string, event Event) {
+    ctx = context.WithoutCancel(ctx)
+    if event.From != "" && n.mutes != nil {
+        muted, err := n.mutes.IsMuted(ctx, userID, event.From)
+        if err != nil {
+            logging.FromContext(ctx).Error("Ошибка проверки отключения уведомлений", "user_id", userID, "error", err)
+        }
+        if muted {
+            return
+        }
+    }
+
+    if event.CreatedAt.IsZero() {
+        event.CreatedAt = time.Now()
+    }
+
+    payload, err := json.Marshal(event)
+    if err != nil {
+        logging.FromContext(ctx).Error("Ошибка сериализации уведомления", "type", event.Type, "error", err)
+        return
+    }
+
+    if err := n.broker.Publish(ctx, Channel(userID), payload); err != nil {
+        logging.FromContext(ctx).Error("Ошибка публикации уведомления", "user_id", userID, "type", event.Type, "error", err)
+    }
+}
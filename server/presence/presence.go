@@ -0,0 +1,110 @@
+// Package presence отслеживает, какие пользователи сейчас в сети. Пользователь в сети, пока у него
+// открыта подписка на уведомления: соединение ставит отметку в кэше и продлевает ее при каждой
+// проверке соединения. Кэш общий для всех экземпляров сервера, поэтому отметку видит каждый из них.
+package presence
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "time"
+
+    "chatter-hub-server/cache"
+)
+
+const (
+    // onlineTTL — время жизни отметки без продления. Соединение продлевает ее чаще,
+    // а оборванное без закрытия соединение перестает считаться через это время.
+    onlineTTL = 90 * time.Second
+    // lastSeenTTL — сколько хранится время последнего выхода из сети
+    lastSeenTTL = 30 * 24 * time.Hour
+)
+
+// Status — присутствие пользователя в сети
+type Status struct {
+    Online bool `json:"online" example:"false"`
+    // Время закрытия последнего соединения; не возвращается, пока пользователь в сети
+    // и если он давно не подключался
+    LastSeenAt *time.Time `json:"last_seen_at,omitempty" example:"2024-01-01T12:00:00Z"`
+}
+
+// Tracker ведет отметки присутствия. Открытые соединения считаются в каждом процессе отдельно:
+// отметка снимается, когда закрывается последнее соединение пользователя с этим экземпляром.
+// Соединения с другими экземплярами восстановят ее при следующем продлении.
+type Tracker struct {
+    cache cache.Cache
+
+    mu          sync.Mutex
+    connections map[string]int // Открытые соединения по ID пользователя
+}
+
+// NewTracker создает учет присутствия в кэше c
+func NewTracker(c cache.Cache) *Tracker {
+    return &Tracker{cache: c, connections: make(map[string]int)}
+}
+
+func onlineKey(userID string) string {
+    return "presence:online:" + userID
+}
+
+func lastSeenKey(userID string) string {
+    return "presence:last_seen:" + userID
+}
+
+// Connect отмечает пользователя в сети при открытии соединения. Каждому вызову Connect
+// должен соответствовать вызов Disconnect.
+func (t *Tracker) Connect(ctx context.Context, userID string) error {
+    // Отметка ставится под блокировкой, чтобы одновременное закрытие другого соединения не сняло ее
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    t.connections[userID]++
+    return t.Refresh(ctx, userID)
+}
+
+// Refresh продлевает отметку; вызывается периодически, пока соединение открыто
+func (t *Tracker) Refresh(ctx context.Context, userID string) error {
+    return t.cache.Set(ctx, onlineKey(userID), []byte{'1'}, onlineTTL)
+}
+
+// Disconnect учитывает закрытие соединения. После последнего соединения отметка снимается
+// и запоминается время выхода из сети.
+func (t *Tracker) Disconnect(ctx context.Context, userID string) error {
+    t.mu.Lock()
+    defer t.mu.Unlock()
+    if t.connections[userID] > 1 {
+        t.connections[userID]--
+        return nil
+    }
+    delete(t.connections, userID)
+
+    now := time.Now().UTC().Format(time.RFC3339)
+    if err := t.cache.Set(ctx, lastSeenKey(userID), []byte(now), lastSeenTTL); err != nil {
+        return err
+    }
+    return t.cache.Delete(ctx, onlineKey(userID))
+}
+
+// Status возвращает присутствие пользователя
+func (t *Tracker) Status(ctx context.Context, userID string) (Status, error) {
+    _, err := t.cache.Get(ctx, onlineKey(userID))
+    if err == nil {
+        return Status{Online: true}, nil
+    }
+    if !errors.Is(err, cache.ErrMiss) {
+        return Status{}, err
+    }
+
+    value, err := t.cache.Get(ctx, lastSeenKey(userID))
+    if errors.Is(err, cache.ErrMiss) {
+        return Status{}, nil
+    }
+    if err != nil {
+        return Status{}, err
+    }
+    lastSeen, err := time.Parse(time.RFC3339, string(value))
+    if err != nil {
+        // Испорченное значение не мешает ответить, что пользователь не в сети
+        return Status{}, nil
+    }
+    return Status{LastSeenAt: &lastSeen}, nil
+}
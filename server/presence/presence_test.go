@@ -0,0 +1,70 @@
+package presence
+
+import (
+    "context"
+    "testing"
+
+    "chatter-hub-server/cache"
+)
+
+func TestTracker(t *testing.T) {
+    tests := []struct {
+        name         string
+        connects     int
+        disconnects  int
+        wantOnline   bool
+        wantLastSeen bool
+    }{
+        {name: "не подключался", wantOnline: false, wantLastSeen: false},
+        {name: "соединение открыто", connects: 1, wantOnline: true, wantLastSeen: false},
+        {name: "закрыто одно из двух соединений", connects: 2, disconnects: 1, wantOnline: true, wantLastSeen: false},
+        {name: "закрыты все соединения", connects: 2, disconnects: 2, wantOnline: false, wantLastSeen: true},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            ctx := context.Background()
+            tracker := NewTracker(cache.NewMemory())
+            for i := 0; i < tt.connects; i++ {
+                if err := tracker.Connect(ctx, "user"); err != nil {
+                    t.Fatal(err)
+                }
+            }
+            for i := 0; i < tt.disconnects; i++ {
+                if err := tracker.Disconnect(ctx, "user"); err != nil {
+                    t.Fatal(err)
+                }
+            }
+
+            status, err := tracker.Status(ctx, "user")
+            if err != nil {
+                t.Fatal(err)
+            }
+            if status.Online != tt.wantOnline {
+                t.Fatalf("online = %v, ожидалось %v", status.Online, tt.wantOnline)
+            }
+            if (status.LastSeenAt != nil) != tt.wantLastSeen {
+                t.Fatalf("last_seen_at = %v, ожидалось наличие: %v", status.LastSeenAt, tt.wantLastSeen)
+            }
+        })
+    }
+}
+
+func TestTrackerSharedCache(t *testing.T) {
+    // Два экземпляра сервера с общим кэшем видят отметки друг друга
+    ctx := context.Background()
+    shared := cache.NewMemory()
+    first, second := NewTracker(shared), NewTracker(shared)
+
+    if err := first.Connect(ctx, "user"); err != nil {
+        t.Fatal(err)
+    }
+    if status, err := second.Status(ctx, "user"); err != nil || !status.Online {
+        t.Fatalf("отметка другого экземпляра не видна: %+v, %v", status, err)
+    }
+    if err := first.Disconnect(ctx, "user"); err != nil {
+        t.Fatal(err)
+    }
+    if status, err := second.Status(ctx, "user"); err != nil || status.Online {
+        t.Fatalf("отметка не снята после закрытия соединения: %+v, %v", status, err)
+    }
+}
@@ -0,0 +1,118 @@
+// Package quota учитывает объем файлов пользователей в PostgreSQL и ограничивает его квотой.
+// Объем резервируется до сохранения файла и освобождается при его удалении.
+package quota
+
+import (
+    "errors"
+    "time"
+
+    "chatter-hub-server/config"
+
+    "gorm.io/gorm"
+)
+
+var ErrExceeded = errors.New("превышен доступный объем хранилища")
+
+// defaultQuota — квота пользователя, если для него не задана индивидуальная; задается через Init
+var defaultQuota int64 = 1 << 30
+
+// Usage — занятый и доступный пользователю объем в байтах
+type Usage struct {
+    Used  int64
+    Quota int64
+}
+
+// Available возвращает оставшийся объем
+func (u Usage) Available() int64 {
+    if u.Used >= u.Quota {
+        return 0
+    }
+    return u.Quota - u.Used
+}
+
+// Init задает квоту по умолчанию из конфигурации
+func Init(cfg *config.Config) {
+    defaultQuota = cfg.Attachments.UserQuota
+}
+
+// Get возвращает объем файлов пользователя
+func Get(tx *gorm.DB, userID string) (*Usage, error) {
+    if err := ensure(tx, userID); err != nil {
+        return nil, err
+    }
+    var usage config.StorageUsage
+    if err := tx.First(&usage, "user_id = ?", userID).Error; err != nil {
+        return nil, err
+    }
+    result := &Usage{Used: usage.UsedBytes, Quota: defaultQuota}
+    if usage.QuotaBytes != nil {
+        result.Quota = *usage.QuotaBytes
+    }
+    return result, nil
+}
+
+// Check проверяет, поместится ли файл размером size, ничего не резервируя
+func Check(tx *gorm.DB, userID string, size int64) error {
+    usage, err := Get(tx, userID)
+    if err != nil {
+        return err
+    }
+    if size > usage.Available() {
+        return ErrExceeded
+    }
+    return nil
+}
+
+// Reserve учитывает size байт в объеме пользователя или возвращает ErrExceeded,
+// если квота будет превышена. Проверка и учет выполняются одним запросом, поэтому
+// параллельные загрузки не превысят квоту.
+func Reserve(tx *gorm.DB, userID string, size int64) error {
+    if err := ensure(tx, userID); err != nil {
+        return err
+    }
+    result := tx.Model(&config.StorageUsage{}).
+        Where("user_id = ? AND used_bytes + ? <= COALESCE(quota_bytes, ?)", userID, size, defaultQuota).
+        Updates(map[string]interface{}{
+            "used_bytes": gorm.Expr("used_bytes + ?", size),
+            "updated_at": time.Now(),
+        })
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return ErrExceeded
+    }
+    return nil
+}
+
+// Adjust изменяет учтенный объем на delta байт без проверки квоты: освобождает место
+// при удалении файлов (отрицательное delta) и учитывает файлы, созданные сервером
+func Adjust(tx *gorm.DB, userID string, delta int64) error {
+    if delta == 0 {
+        return nil
+    }
+    if err := ensure(tx, userID); err != nil {
+        return err
+    }
+    return tx.Model(&config.StorageUsage{}).
+        Where("user_id = ?", userID).
+        Updates(map[string]interface{}{
+            "used_bytes": gorm.Expr("CASE WHEN used_bytes + ? < 0 THEN 0 ELSE used_bytes + ? END", delta, delta),
+            "updated_at": time.Now(),
+        }).Error
+}
+
+// ensure создает запись об объеме пользователя. Для пользователей, загрузивших файлы
+// до появления учета, объем считается по уже сохраненным вложениям.
+func ensure(tx *gorm.DB, userID string) error {
+    return tx.Exec(`INSERT INTO storage_usages (user_id, used_bytes, updated_at)
+        SELECT ?,
+            (SELECT COALESCE(SUM(size), 0) FROM attachments
+                WHERE owner_id = ? AND COALESCE(scan_status, '') <> ?) +
+            (SELECT COALESCE(SUM(v.size), 0) FROM attachment_variants v
+                JOIN attachments a ON a.id = v.attachment_id WHERE a.owner_id = ?),
+            ?
+        WHERE true
+        ON CONFLICT (user_id) DO NOTHING`,
+        userID, userID, config.ScanInfected, userID, time.Now()).Error
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+    "context"
+
+    "chatter-hub-server/config"
+
+    "gorm.io/gorm"
+)
+
+// BlockRepository хранит блокировки пользователей
+type BlockRepository interface {
+    // Create блокирует пользователя и в той же транзакции удаляет взаимные контакты
+    // и отменяет ожидающие заявки в контакты между пользователями
+    Create(ctx context.Context, block *config.Block) error
+    Delete(ctx context.Context, blockerID, blockedID string) error
+    // List возвращает блокировки пользователя, начиная с последних
+    List(ctx context.Context, blockerID string) ([]config.Block, error)
+}
+
+type blockRepository struct {
+    db *gorm.DB
+}
+
+// NewBlocks возвращает репозиторий блокировок в базе данных db
+func NewBlocks(db *gorm.DB) BlockRepository {
+    return &blockRepository{db: db}
+}
+
+func (r *blockRepository) Create(ctx context.Context, block *config.Block) error {
+    userID, otherID := block.BlockerID, block.BlockedID
+    return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Where(config.Block{BlockerID: userID, BlockedID: otherID}).FirstOrCreate(block).Error; err != nil {
+            return err
+        }
+
+        // Удаляем взаимные контакты
+        if err := tx.Where("(owner_id = ? AND contact_id = ?) OR (owner_id = ? AND contact_id = ?)",
+            userID, otherID, otherID, userID).Delete(&config.Contact{}).Error; err != nil {
+            return err
+        }
+
+        // Отменяем ожидающие заявки в контакты в обе стороны
+        return tx.Model(&config.ContactRequest{}).
+            Where("((requester_id = ? AND addressee_id = ?) OR (requester_id = ? AND addressee_id = ?)) AND status = ?",
+                userID, otherID, otherID, userID, config.ContactRequestPending).
+            Update("status", config.ContactRequestCancelled).Error
+    })
+}
+
+func (r *blockRepository) Delete(ctx context.Context, blockerID, blockedID string) error {
+    return r.db.WithContext(ctx).Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).Delete(&config.Block{}).Error
+}
+
+func (r *blockRepository) List(ctx context.Context, blockerID string) ([]config.Block, error) {
+    var blocks []config.Block
+    err := r.db.WithContext(ctx).Where("blocker_id = ?", blockerID).Order("created_at desc").Find(&blocks).Error
+    return blocks, err
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+    "context"
+
+    "chatter-hub-server/config"
+
+    "gorm.io/gorm"
+)
+
+// Направления заявок в контакты относительно пользователя
+const (
+    RequestsIncoming = "incoming" // Заявки, адресованные пользователю
+    RequestsOutgoing = "outgoing" // Заявки, отправленные пользователем
+)
+
+// ContactRepository хранит контакты пользователей и заявки в контакты
+type ContactRepository interface {
+    // List возвращает контакты пользователя в порядке добавления
+    List(ctx context.Context, ownerID string) ([]config.Contact, error)
+    Get(ctx context.Context, ownerID, contactID string) (*config.Contact, error)
+    SetNickname(ctx context.Context, contact *config.Contact, nickname string) error
+    // Delete удаляет контакт у обоих пользователей
+    Delete(ctx context.Context, userID, contactID string) error
+
+    // PendingRequests возвращает ожидающие заявки пользователя в направлении direction, начиная с последних
+    PendingRequests(ctx context.Context, userID, direction string) ([]config.ContactRequest, error)
+    // GetPendingRequest находит ожидающую заявку id, в которой пользователь — адресат (RequestsIncoming)
+    // или отправитель (RequestsOutgoing)
+    GetPendingRequest(ctx context.Context, id, userID, direction string) (*config.ContactRequest, error)
+    // FindPendingRequest находит ожидающую заявку от requesterID к addresseeID
+    FindPendingRequest(ctx context.Context, requesterID, addresseeID string) (*config.ContactRequest, error)
+    CreateRequest(ctx context.Context, request *config.ContactRequest) error
+    SetRequestStatus(ctx context.Context, request *config.ContactRequest, status string) error
+    // AcceptRequest помечает заявку принятой и создает записи контактов для обоих пользователей
+    AcceptRequest(ctx context.Context, request *config.ContactRequest) error
+}
+
+type contactRepository struct {
+    db *gorm.DB
+}
+
+// NewContacts возвращает репозиторий контактов в базе данных db
+func NewContacts(db *gorm.DB) ContactRepository {
+    return &contactRepository{db: db}
+}
+
+func (r *contactRepository) List(ctx context.Context, ownerID string) ([]config.Contact, error) {
+    var contacts []config.Contact
+    err := r.db.WithContext(ctx).Where("owner_id = ?", ownerID).Order("created_at asc").Find(&contacts).Error
+    return contacts, err
+}
+
+func (r *contactRepository) Get(ctx context.Context, ownerID, contactID string) (*config.Contact, error) {
+    var contact config.Contact
+    if err := r.db.WithContext(ctx).First(&contact, "owner_id = ? AND contact_id = ?", ownerID, contactID).Error; err != nil {
+        return nil, translate(err)
+    }
+    return &contact, nil
+}
+
+func (r *contactRepository) SetNickname(ctx context.Context, contact *config.Contact, nickname string) error {
+    return r.db.WithContext(ctx).Model(contact).Update("nickname", nickname).Error
+}
+
+func (r *contactRepository) Delete(ctx context.Context, userID, contactID string) error {
+    return r.db.WithContext(ctx).Where("(owner_id = ? AND contact_id = ?) OR (owner_id = ? AND contact_id = ?)",
+        userID, contactID, contactID, userID).Delete(&config.Contact{}).Error
+}
+
+func (r *contactRepository) PendingRequests(ctx context.Context, userID, direction string) ([]config.ContactRequest, error) {
+    var requests []config.ContactRequest
+    err := r.db.WithContext(ctx).Where(requestColumn(direction)+" = ? AND status = ?", userID, config.ContactRequestPending).
+        Order("created_at desc").Find(&requests).Error
+    return requests, err
+}
+
+func (r *contactRepository) GetPendingRequest(ctx context.Context, id, userID, direction string) (*config.ContactRequest, error) {
+    return r.firstPending(ctx, "id = ? AND "+requestColumn(direction)+" = ?", id, userID)
+}
+
+func (r *contactRepository) FindPendingRequest(ctx context.Context, requesterID, addresseeID string) (*config.ContactRequest, error) {
+    return r.firstPending(ctx, "requester_id = ? AND addressee_id = ?", requesterID, addresseeID)
+}
+
+func (r *contactRepository) firstPending(ctx context.Context, query string, args ...interface{}) (*config.ContactRequest, error) {
+    var request config.ContactRequest
+    err := r.db.WithContext(ctx).Where(query, args...).Where("status = ?", config.ContactRequestPending).First(&request).Error
+    if err != nil {
+        return nil, translate(err)
+    }
+    return &request, nil
+}
+
+func (r *contactRepository) CreateRequest(ctx context.Context, request *config.ContactRequest) error {
+    return r.db.WithContext(ctx).Create(request).Error
+}
+
+func (r *contactRepository) SetRequestStatus(ctx context.Context, request *config.ContactRequest, status string) error {
+    request.Status = status
+    return r.db.WithContext(ctx).Save(request).Error
+}
+
+func (r *contactRepository) AcceptRequest(ctx context.Context, request *config.ContactRequest) error {
+    return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        request.Status = config.ContactRequestAccepted
+        if err := tx.Save(request).Error; err != nil {
+            return err
+        }
+
+        contacts := []config.Contact{
+            {OwnerID: request.RequesterID, ContactID: request.AddresseeID},
+            {OwnerID: request.AddresseeID, ContactID: request.RequesterID},
+        }
+        for _, contact := range contacts {
+            if err := tx.Where(contact).FirstOrCreate(&contact).Error; err != nil {
+                return err
+            }
+        }
+        return nil
+    })
+}
+
+// requestColumn возвращает колонку заявки, в которой указан пользователь для направления direction
+func requestColumn(direction string) string {
+    if direction == RequestsOutgoing {
+        return "requester_id"
+    }
+    return "addressee_id"
+}
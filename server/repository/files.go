@@ -0,0 +1,126 @@
+package repository
+
+import (
+    "context"
+
+    "chatter-hub-server/config"
+
+    "gorm.io/gorm"
+)
+
+// FileRepository хранит сведения о вложениях пользователей
+type FileRepository interface {
+    Get(ctx context.Context, id string) (*config.Attachment, error)
+    // GetUnlinked возвращает вложение владельца, еще не привязанное к сообщению
+    GetUnlinked(ctx context.Context, id, ownerID string) (*config.Attachment, error)
+    // UsageByKind возвращает объем вложений владельца по типам; зараженные вложения не учитываются
+    UsageByKind(ctx context.Context, ownerID string) (map[string]int64, error)
+    // List возвращает вложения с указанными ID; отсутствующие ID пропускаются
+    List(ctx context.Context, ids []string) ([]config.Attachment, error)
+    // ForMessages возвращает вложения сообщений указанного типа с вариантами изображений,
+    // сгруппированные по ID сообщения
+    ForMessages(ctx context.Context, messageType string, messageIDs []uint) (map[uint][]config.Attachment, error)
+    // LoadVariants загружает уменьшенные варианты изображений из list одним запросом
+    LoadVariants(ctx context.Context, list []config.Attachment) error
+}
+
+type fileRepository struct {
+    db *gorm.DB
+}
+
+// NewFiles возвращает репозиторий вложений в базе данных db
+func NewFiles(db *gorm.DB) FileRepository {
+    return &fileRepository{db: db}
+}
+
+func (r *fileRepository) Get(ctx context.Context, id string) (*config.Attachment, error) {
+    var attachment config.Attachment
+    if err := r.db.WithContext(ctx).First(&attachment, "id = ?", id).Error; err != nil {
+        return nil, translate(err)
+    }
+    return &attachment, nil
+}
+
+func (r *fileRepository) GetUnlinked(ctx context.Context, id, ownerID string) (*config.Attachment, error) {
+    var attachment config.Attachment
+    err := r.db.WithContext(ctx).First(&attachment, "id = ? AND owner_id = ? AND message_id IS NULL", id, ownerID).Error
+    if err != nil {
+        return nil, translate(err)
+    }
+    return &attachment, nil
+}
+
+func (r *fileRepository) UsageByKind(ctx context.Context, ownerID string) (map[string]int64, error) {
+    var rows []struct {
+        Kind string
+        Size int64
+    }
+    err := r.db.WithContext(ctx).Model(&config.Attachment{}).
+        Select("kind, COALESCE(SUM(size), 0) AS size").
+        Where("owner_id = ? AND COALESCE(scan_status, '') <> ?", ownerID, config.ScanInfected).
+        Group("kind").
+        Scan(&rows).Error
+    if err != nil {
+        return nil, err
+    }
+    byKind := make(map[string]int64, len(rows))
+    for _, row := range rows {
+        byKind[row.Kind] = row.Size
+    }
+    return byKind, nil
+}
+
+func (r *fileRepository) List(ctx context.Context, ids []string) ([]config.Attachment, error) {
+    var list []config.Attachment
+    if len(ids) == 0 {
+        return list, nil
+    }
+    err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&list).Error
+    return list, err
+}
+
+func (r *fileRepository) ForMessages(ctx context.Context, messageType string, messageIDs []uint) (map[uint][]config.Attachment, error) {
+    result := make(map[uint][]config.Attachment)
+    if len(messageIDs) == 0 {
+        return result, nil
+    }
+
+    var list []config.Attachment
+    err := r.db.WithContext(ctx).Where("message_type = ? AND message_id IN ?", messageType, messageIDs).
+        Order("created_at asc").Find(&list).Error
+    if err != nil {
+        return nil, err
+    }
+    if err := r.LoadVariants(ctx, list); err != nil {
+        return nil, err
+    }
+    for _, attachment := range list {
+        result[*attachment.MessageID] = append(result[*attachment.MessageID], attachment)
+    }
+    return result, nil
+}
+
+func (r *fileRepository) LoadVariants(ctx context.Context, list []config.Attachment) error {
+    ids := make([]string, 0, len(list))
+    for _, attachment := range list {
+        if attachment.Kind == config.AttachmentImage {
+            ids = append(ids, attachment.ID)
+        }
+    }
+    if len(ids) == 0 {
+        return nil
+    }
+
+    var variants []config.AttachmentVariant
+    if err := r.db.WithContext(ctx).Where("attachment_id IN ?", ids).Order("id").Find(&variants).Error; err != nil {
+        return err
+    }
+    byAttachment := make(map[string][]config.AttachmentVariant)
+    for _, variant := range variants {
+        byAttachment[variant.AttachmentID] = append(byAttachment[variant.AttachmentID], variant)
+    }
+    for i := range list {
+        list[i].Variants = byAttachment[list[i].ID]
+    }
+    return nil
+}
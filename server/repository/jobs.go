@@ -0,0 +1,101 @@
+package repository
+
+import (
+    "context"
+
+    "chatter-hub-server/config"
+
+    "gorm.io/gorm"
+)
+
+// JobFilter — условия выборки фоновых задач; пустые поля не ограничивают выборку
+type JobFilter struct {
+    Status string
+    Type   string
+    Limit  int
+}
+
+// JobStats — количество задач одного типа в одном статусе
+type JobStats struct {
+    Type   string `json:"type" example:"transcription"`
+    Status string `json:"status" example:"pending"`
+    Count  int64  `json:"count" example:"3"`
+}
+
+// JobRepository предоставляет администраторам сведения об очереди фоновых задач
+type JobRepository interface {
+    // List возвращает задачи, начиная с последних
+    List(ctx context.Context, filter JobFilter) ([]config.Job, error)
+    Stats(ctx context.Context) ([]JobStats, error)
+    Get(ctx context.Context, id string) (*config.Job, error)
+}
+
+type jobRepository struct {
+    db *gorm.DB
+}
+
+// NewJobs возвращает репозиторий фоновых задач в базе данных db
+func NewJobs(db *gorm.DB) JobRepository {
+    return &jobRepository{db: db}
+}
+
+func (r *jobRepository) List(ctx context.Context, filter JobFilter) ([]config.Job, error) {
+    query := r.db.WithContext(ctx).Order("id DESC").Limit(filter.Limit)
+    if filter.Status != "" {
+        query = query.Where("status = ?", filter.Status)
+    }
+    if filter.Type != "" {
+        query = query.Where("type = ?", filter.Type)
+    }
+    list := []config.Job{}
+    err := query.Find(&list).Error
+    return list, err
+}
+
+func (r *jobRepository) Stats(ctx context.Context) ([]JobStats, error) {
+    stats := []JobStats{}
+    err := r.db.WithContext(ctx).Model(&config.Job{}).
+        Select("type, status, COUNT(*) AS count").
+        Group("type, status").
+        Order("type, status").
+        Scan(&stats).Error
+    return stats, err
+}
+
+func (r *jobRepository) Get(ctx context.Context, id string) (*config.Job, error) {
+    var job config.Job
+    if err := r.db.WithContext(ctx).First(&job, "id = ?", id).Error; err != nil {
+        return nil, translate(err)
+    }
+    return &job, nil
+}
+
+// GCReportRepository хранит отчеты сборки мусора хранилища
+type GCReportRepository interface {
+    // List возвращает последние отчеты без списка найденных объектов
+    List(ctx context.Context, limit int) ([]config.GCReport, error)
+    Get(ctx context.Context, id string) (*config.GCReport, error)
+}
+
+type gcReportRepository struct {
+    db *gorm.DB
+}
+
+// NewGCReports возвращает репозиторий отчетов сборки мусора в базе данных db
+func NewGCReports(db *gorm.DB) GCReportRepository {
+    return &gcReportRepository{db: db}
+}
+
+func (r *gcReportRepository) List(ctx context.Context, limit int) ([]config.GCReport, error) {
+    reports := []config.GCReport{}
+    err := r.db.WithContext(ctx).Omit("details").Order("id DESC").Limit(limit).Find(&reports).Error
+    return reports, err
+}
+
+func (r *gcReportRepository) Get(ctx context.Context, id string) (*config.GCReport, error) {
+    var report config.GCReport
+    if err := r.db.WithContext(ctx).First(&report, "id = ?", id).Error; err != nil {
+        return nil, translate(err)
+    }
+    return &report, nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+    "context"
+    "errors"
+
+    "chatter-hub-server/config"
+
+    "gorm.io/gorm"
+)
+
+var (
+    ErrAttachmentUnavailable = errors.New("вложение не найдено или уже привязано к другому сообщению")
+    ErrAttachmentInfected    = errors.New("во вложении обнаружено вредоносное содержимое, оно удалено")
+)
+
+// MessageRepository хранит текстовые и голосовые сообщения
+type MessageRepository interface {
+    // CreateText сохраняет сообщение и привязывает к нему вложения отправителя в одной транзакции
+    CreateText(ctx context.Context, message *config.TextMessage, attachmentIDs []string) error
+    // CreateVoice сохраняет сообщение и привязывает к нему аудиофайл message.AttachmentID
+    CreateVoice(ctx context.Context, message *config.VoiceMessage) error
+    // TextConversation возвращает текстовые сообщения между двумя пользователями по времени отправки
+    TextConversation(ctx context.Context, userID, peerID string) ([]config.TextMessage, error)
+    VoiceConversation(ctx context.Context, userID, peerID string) ([]config.VoiceMessage, error)
+    SetTranscriptionStatus(ctx context.Context, messageID uint, status string) error
+    // IsParticipant проверяет, что userID — отправитель или получатель сообщения
+    IsParticipant(ctx context.Context, messageType string, messageID uint, userID string) (bool, error)
+}
+
+type messageRepository struct {
+    db *gorm.DB
+}
+
+// NewMessages возвращает репозиторий сообщений в базе данных db
+func NewMessages(db *gorm.DB) MessageRepository {
+    return &messageRepository{db: db}
+}
+
+func (r *messageRepository) CreateText(ctx context.Context, message *config.TextMessage, attachmentIDs []string) error {
+    return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(message).Error; err != nil {
+            return err
+        }
+        return linkAttachments(tx, message.SenderID, attachmentIDs, config.MessageTypeText, message.ID)
+    })
+}
+
+func (r *messageRepository) CreateVoice(ctx context.Context, message *config.VoiceMessage) error {
+    return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(message).Error; err != nil {
+            return err
+        }
+        return linkAttachments(tx, message.SenderID, []string{message.AttachmentID}, config.MessageTypeVoice, message.ID)
+    })
+}
+
+func (r *messageRepository) TextConversation(ctx context.Context, userID, peerID string) ([]config.TextMessage, error) {
+    var messages []config.TextMessage
+    err := r.conversation(ctx, userID, peerID).Find(&messages).Error
+    return messages, err
+}
+
+func (r *messageRepository) VoiceConversation(ctx context.Context, userID, peerID string) ([]config.VoiceMessage, error) {
+    var messages []config.VoiceMessage
+    err := r.conversation(ctx, userID, peerID).Find(&messages).Error
+    return messages, err
+}
+
+func (r *messageRepository) conversation(ctx context.Context, userID, peerID string) *gorm.DB {
+    return r.db.WithContext(ctx).
+        Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)", userID, peerID, peerID, userID).
+        Order("created_at asc")
+}
+
+func (r *messageRepository) SetTranscriptionStatus(ctx context.Context, messageID uint, status string) error {
+    return r.db.WithContext(ctx).Model(&config.VoiceMessage{}).Where("id = ?", messageID).Update("transcription_status", status).Error
+}
+
+func (r *messageRepository) IsParticipant(ctx context.Context, messageType string, messageID uint, userID string) (bool, error) {
+    model := messageModel(messageType)
+    if model == nil {
+        return false, nil
+    }
+    var count int64
+    err := r.db.WithContext(ctx).Model(model).
+        Where("id = ? AND (sender_id = ? OR receiver_id = ?)", messageID, userID, userID).
+        Count(&count).Error
+    return count > 0, err
+}
+
+// linkAttachments привязывает вложения владельца к сообщению. Каждое вложение можно привязать
+// только один раз, зараженные вложения привязать нельзя. Повторы в attachmentIDs не учитываются.
+func linkAttachments(tx *gorm.DB, ownerID string, attachmentIDs []string, messageType string, messageID uint) error {
+    attachmentIDs = uniqueIDs(attachmentIDs)
+    if len(attachmentIDs) == 0 {
+        return nil
+    }
+
+    result := tx.Model(&config.Attachment{}).
+        Where("id IN ? AND owner_id = ? AND message_id IS NULL", attachmentIDs, ownerID).
+        Where("COALESCE(scan_status, '') <> ?", config.ScanInfected).
+        Updates(map[string]interface{}{"message_type": messageType, "message_id": messageID})
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected != int64(len(attachmentIDs)) {
+        var infected int64
+        tx.Model(&config.Attachment{}).
+            Where("id IN ? AND owner_id = ? AND scan_status = ?", attachmentIDs, ownerID, config.ScanInfected).
+            Count(&infected)
+        if infected > 0 {
+            return ErrAttachmentInfected
+        }
+        return ErrAttachmentUnavailable
+    }
+    return nil
+}
+
+// uniqueIDs возвращает ID без повторов в порядке первого появления
+func uniqueIDs(ids []string) []string {
+    seen := make(map[string]bool, len(ids))
+    unique := make([]string, 0, len(ids))
+    for _, id := range ids {
+        if !seen[id] {
+            seen[id] = true
+            unique = append(unique, id)
+        }
+    }
+    return unique
+}
+
+// messageModel возвращает модель сообщения указанного типа
+func messageModel(messageType string) interface{} {
+    switch messageType {
+    case config.MessageTypeText:
+        return &config.TextMessage{}
+    case config.MessageTypeVoice:
+        return &config.VoiceMessage{}
+    default:
+        return nil
+    }
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+    "context"
+    "time"
+
+    "chatter-hub-server/config"
+
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+)
+
+// MuteRepository хранит отключения уведомлений переписок
+type MuteRepository interface {
+    // Save отключает уведомления или меняет срок уже действующего отключения
+    Save(ctx context.Context, mute *config.ConversationMute) error
+    Delete(ctx context.Context, userID, peerID string) error
+    // Active возвращает действующие отключения пользователя, начиная с последних
+    Active(ctx context.Context, userID string) ([]config.ConversationMute, error)
+    // IsMuted проверяет, отключил ли userID уведомления о сообщениях от peerID
+    IsMuted(ctx context.Context, userID, peerID string) (bool, error)
+}
+
+type muteRepository struct {
+    db *gorm.DB
+}
+
+// NewMutes возвращает репозиторий отключений уведомлений в базе данных db
+func NewMutes(db *gorm.DB) MuteRepository {
+    return &muteRepository{db: db}
+}
+
+func (r *muteRepository) Save(ctx context.Context, mute *config.ConversationMute) error {
+    return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+        Columns:   []clause.Column{{Name: "user_id"}, {Name: "peer_id"}},
+        DoUpdates: clause.AssignmentColumns([]string{"muted_until"}),
+    }).Create(mute).Error
+}
+
+func (r *muteRepository) Delete(ctx context.Context, userID, peerID string) error {
+    return r.db.WithContext(ctx).Where("user_id = ? AND peer_id = ?", userID, peerID).Delete(&config.ConversationMute{}).Error
+}
+
+func (r *muteRepository) Active(ctx context.Context, userID string) ([]config.ConversationMute, error) {
+    var mutes []config.ConversationMute
+    err := r.active(ctx).Where("user_id = ?", userID).Order("created_at desc").Find(&mutes).Error
+    return mutes, err
+}
+
+func (r *muteRepository) IsMuted(ctx context.Context, userID, peerID string) (bool, error) {
+    var count int64
+    err := r.active(ctx).Model(&config.ConversationMute{}).Where("user_id = ? AND peer_id = ?", userID, peerID).Count(&count).Error
+    return count > 0, err
+}
+
+func (r *muteRepository) active(ctx context.Context) *gorm.DB {
+    return r.db.WithContext(ctx).Where("muted_until IS NULL OR muted_until > ?", time.Now())
+}
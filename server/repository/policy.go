@@ -0,0 +1,60 @@
+package repository
+
+import (
+    "context"
+
+    "chatter-hub-server/config"
+
+    "gorm.io/gorm"
+)
+
+// PolicyRepository отвечает на вопросы о связях двух пользователей, от которых зависит,
+// можно ли им переписываться и видеть профили друг друга
+type PolicyRepository interface {
+    // IsContact проверяет, есть ли contactID в списке контактов ownerID
+    IsContact(ctx context.Context, ownerID, contactID string) (bool, error)
+    // HasBlocked проверяет, заблокировал ли blockerID пользователя blockedID
+    HasBlocked(ctx context.Context, blockerID, blockedID string) (bool, error)
+    // IsBlocked проверяет, заблокировал ли кто-либо из двух пользователей другого
+    IsBlocked(ctx context.Context, userID, otherID string) (bool, error)
+    // HasWrittenTo проверяет, отправлял ли senderID хотя бы одно сообщение receiverID
+    HasWrittenTo(ctx context.Context, senderID, receiverID string) (bool, error)
+}
+
+type policyRepository struct {
+    db *gorm.DB
+}
+
+// NewPolicy возвращает репозиторий связей пользователей в базе данных db
+func NewPolicy(db *gorm.DB) PolicyRepository {
+    return &policyRepository{db: db}
+}
+
+func (r *policyRepository) IsContact(ctx context.Context, ownerID, contactID string) (bool, error) {
+    return r.exists(ctx, &config.Contact{}, "owner_id = ? AND contact_id = ?", ownerID, contactID)
+}
+
+func (r *policyRepository) HasBlocked(ctx context.Context, blockerID, blockedID string) (bool, error) {
+    return r.exists(ctx, &config.Block{}, "blocker_id = ? AND blocked_id = ?", blockerID, blockedID)
+}
+
+func (r *policyRepository) IsBlocked(ctx context.Context, userID, otherID string) (bool, error) {
+    return r.exists(ctx, &config.Block{}, "(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)",
+        userID, otherID, otherID, userID)
+}
+
+func (r *policyRepository) HasWrittenTo(ctx context.Context, senderID, receiverID string) (bool, error) {
+    for _, model := range []interface{}{&config.TextMessage{}, &config.VoiceMessage{}} {
+        written, err := r.exists(ctx, model, "sender_id = ? AND receiver_id = ?", senderID, receiverID)
+        if err != nil || written {
+            return written, err
+        }
+    }
+    return false, nil
+}
+
+func (r *policyRepository) exists(ctx context.Context, model interface{}, query string, args ...interface{}) (bool, error) {
+    var count int64
+    err := r.db.WithContext(ctx).Model(model).Where(query, args...).Limit(1).Count(&count).Error
+    return count > 0, err
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+    "context"
+
+    "chatter-hub-server/quota"
+
+    "gorm.io/gorm"
+)
+
+// QuotaRepository возвращает объем файлов пользователей и их квоты
+type QuotaRepository interface {
+    // Usage возвращает занятый и доступный пользователю объем
+    Usage(ctx context.Context, userID string) (*quota.Usage, error)
+}
+
+type quotaRepository struct {
+    db *gorm.DB
+}
+
+// NewQuotas возвращает репозиторий квот в базе данных db
+func NewQuotas(db *gorm.DB) QuotaRepository {
+    return &quotaRepository{db: db}
+}
+
+func (r *quotaRepository) Usage(ctx context.Context, userID string) (*quota.Usage, error) {
+    return quota.Get(r.db.WithContext(ctx), userID)
+}
@@ -0,0 +1,19 @@
+// Package repository отделяет обработчики HTTP от базы данных. Обработчики получают
+// интерфейсы репозиториев при создании, поэтому в тестах базу данных можно заменить подделкой.
+package repository
+
+import (
+    "errors"
+
+    "gorm.io/gorm"
+)
+
+var ErrNotFound = errors.New("запись не найдена")
+
+// translate заменяет ошибку GORM об отсутствии записи на ErrNotFound
+func translate(err error) error {
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return ErrNotFound
+    }
+    return err
+}
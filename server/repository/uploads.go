@@ -0,0 +1,146 @@
+package repository
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "chatter-hub-server/config"
+
+    "gorm.io/gorm"
+)
+
+// ErrUploadCompleted — загрузку уже подтвердил другой запрос
+var ErrUploadCompleted = errors.New("загрузка уже завершена")
+
+// UploadRepository хранит прямые загрузки файлов в хранилище
+type UploadRepository interface {
+    Create(ctx context.Context, upload *config.Upload) error
+    // GetOwned возвращает загрузку, если она принадлежит ownerID
+    GetOwned(ctx context.Context, id, ownerID string) (*config.Upload, error)
+    // CompleteTx отмечает ожидающую загрузку завершенной в транзакции tx создания вложения.
+    // Если загрузка уже не ожидает подтверждения, возвращает ErrUploadCompleted.
+    CompleteTx(tx *gorm.DB, id, attachmentID string) error
+    // Fail отмечает ожидающую загрузку неудачной
+    Fail(ctx context.Context, id string) error
+    // Expired возвращает ожидающие загрузки, срок которых истек до now
+    Expired(ctx context.Context, now time.Time) ([]config.Upload, error)
+}
+
+type uploadRepository struct {
+    db *gorm.DB
+}
+
+// NewUploads возвращает репозиторий прямых загрузок в базе данных db
+func NewUploads(db *gorm.DB) UploadRepository {
+    return &uploadRepository{db: db}
+}
+
+func (r *uploadRepository) Create(ctx context.Context, upload *config.Upload) error {
+    return r.db.WithContext(ctx).Create(upload).Error
+}
+
+func (r *uploadRepository) GetOwned(ctx context.Context, id, ownerID string) (*config.Upload, error) {
+    var upload config.Upload
+    if err := r.db.WithContext(ctx).First(&upload, "id = ? AND owner_id = ?", id, ownerID).Error; err != nil {
+        return nil, translate(err)
+    }
+    return &upload, nil
+}
+
+func (r *uploadRepository) CompleteTx(tx *gorm.DB, id, attachmentID string) error {
+    result := tx.Model(&config.Upload{}).
+        Where("id = ? AND status = ?", id, config.UploadPending).
+        Updates(map[string]interface{}{"status": config.UploadCompleted, "attachment_id": attachmentID})
+    if result.Error != nil {
+        return result.Error
+    }
+    if result.RowsAffected == 0 {
+        return ErrUploadCompleted
+    }
+    return nil
+}
+
+func (r *uploadRepository) Fail(ctx context.Context, id string) error {
+    return r.db.WithContext(ctx).Model(&config.Upload{}).
+        Where("id = ? AND status = ?", id, config.UploadPending).
+        Update("status", config.UploadFailed).Error
+}
+
+func (r *uploadRepository) Expired(ctx context.Context, now time.Time) ([]config.Upload, error) {
+    var uploads []config.Upload
+    err := r.db.WithContext(ctx).Where("status = ? AND expires_at < ?", config.UploadPending, now).Find(&uploads).Error
+    return uploads, err
+}
+
+// TusUploadRepository хранит возобновляемые загрузки по протоколу tus
+type TusUploadRepository interface {
+    Create(ctx context.Context, upload *config.TusUpload) error
+    // GetOwned возвращает загрузку, если она принадлежит ownerID
+    GetOwned(ctx context.Context, id, ownerID string) (*config.TusUpload, error)
+    // SaveProgress сохраняет смещение и состояние составной загрузки
+    SaveProgress(ctx context.Context, upload *config.TusUpload) error
+    // ClearMultipart отмечает, что составная загрузка собрана в объект
+    ClearMultipart(ctx context.Context, upload *config.TusUpload) error
+    // CompleteTx отмечает загрузку завершенной в транзакции tx создания вложения
+    CompleteTx(tx *gorm.DB, upload *config.TusUpload, attachmentID string) error
+    Fail(ctx context.Context, upload *config.TusUpload) error
+    Delete(ctx context.Context, upload *config.TusUpload) error
+    // Expired возвращает загрузки в любом статусе, срок которых истек до now
+    Expired(ctx context.Context, now time.Time) ([]config.TusUpload, error)
+}
+
+type tusUploadRepository struct {
+    db *gorm.DB
+}
+
+// NewTusUploads возвращает репозиторий возобновляемых загрузок в базе данных db
+func NewTusUploads(db *gorm.DB) TusUploadRepository {
+    return &tusUploadRepository{db: db}
+}
+
+func (r *tusUploadRepository) Create(ctx context.Context, upload *config.TusUpload) error {
+    return r.db.WithContext(ctx).Create(upload).Error
+}
+
+func (r *tusUploadRepository) GetOwned(ctx context.Context, id, ownerID string) (*config.TusUpload, error) {
+    var upload config.TusUpload
+    if err := r.db.WithContext(ctx).First(&upload, "id = ? AND owner_id = ?", id, ownerID).Error; err != nil {
+        return nil, translate(err)
+    }
+    return &upload, nil
+}
+
+func (r *tusUploadRepository) SaveProgress(ctx context.Context, upload *config.TusUpload) error {
+    return r.db.WithContext(ctx).Model(upload).Updates(map[string]interface{}{
+        "offset":     upload.Offset,
+        "part_count": upload.PartCount,
+        "tail_size":  upload.TailSize,
+    }).Error
+}
+
+func (r *tusUploadRepository) ClearMultipart(ctx context.Context, upload *config.TusUpload) error {
+    upload.MultipartID = ""
+    return r.db.WithContext(ctx).Model(upload).Update("multipart_id", "").Error
+}
+
+func (r *tusUploadRepository) CompleteTx(tx *gorm.DB, upload *config.TusUpload, attachmentID string) error {
+    return tx.Model(upload).Updates(map[string]interface{}{
+        "status":        config.UploadCompleted,
+        "attachment_id": attachmentID,
+    }).Error
+}
+
+func (r *tusUploadRepository) Fail(ctx context.Context, upload *config.TusUpload) error {
+    return r.db.WithContext(ctx).Model(upload).Update("status", config.UploadFailed).Error
+}
+
+func (r *tusUploadRepository) Delete(ctx context.Context, upload *config.TusUpload) error {
+    return r.db.WithContext(ctx).Delete(upload).Error
+}
+
+func (r *tusUploadRepository) Expired(ctx context.Context, now time.Time) ([]config.TusUpload, error) {
+    var uploads []config.TusUpload
+    err := r.db.WithContext(ctx).Where("expires_at < ?", now).Find(&uploads).Error
+    return uploads, err
+}
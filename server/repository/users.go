@@ -0,0 +1,132 @@
+package repository
+
+import (
+    "context"
+    "strings"
+
+    "chatter-hub-server/config"
+
+    "gorm.io/gorm"
+    "gorm.io/gorm/clause"
+)
+
+// UserSearch — параметры поиска пользователей
+type UserSearch struct {
+    Query    string // Запрос в нижнем регистре
+    ViewerID string // Пользователь, который ищет; он сам и связанные с ним блокировками в результат не попадают
+    Limit    int
+}
+
+// UserRepository хранит учетные записи пользователей
+type UserRepository interface {
+    Create(ctx context.Context, user *config.User) error
+    Get(ctx context.Context, id string) (*config.User, error)
+    GetByEmail(ctx context.Context, email string) (*config.User, error)
+    GetByUsername(ctx context.Context, username string) (*config.User, error)
+    // GetMany возвращает пользователей с указанными ID; отсутствующие ID пропускаются
+    GetMany(ctx context.Context, ids []string) ([]config.User, error)
+    // Update изменяет только переданные столбцы
+    Update(ctx context.Context, id string, updates map[string]interface{}) error
+    Delete(ctx context.Context, id string) error
+    Search(ctx context.Context, search UserSearch) ([]config.User, error)
+}
+
+type userRepository struct {
+    db *gorm.DB
+}
+
+// NewUsers возвращает репозиторий пользователей в базе данных db
+func NewUsers(db *gorm.DB) UserRepository {
+    return &userRepository{db: db}
+}
+
+// Create сохраняет пользователя. GORM заменяет false в полях со значением по умолчанию true
+// на это значение, поэтому выключенные флаги записываются вторым запросом той же транзакции.
+func (r *userRepository) Create(ctx context.Context, user *config.User) error {
+    active, discoverable := user.IsActive, user.Discoverable
+    return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := tx.Create(user).Error; err != nil {
+            return err
+        }
+        if active && discoverable {
+            return nil
+        }
+        user.IsActive, user.Discoverable = active, discoverable
+        return tx.Model(user).Updates(map[string]interface{}{"is_active": active, "discoverable": discoverable}).Error
+    })
+}
+
+func (r *userRepository) Get(ctx context.Context, id string) (*config.User, error) {
+    return r.first(ctx, "id = ?", id)
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*config.User, error) {
+    return r.first(ctx, "email = ?", email)
+}
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*config.User, error) {
+    return r.first(ctx, "username = ?", username)
+}
+
+func (r *userRepository) GetMany(ctx context.Context, ids []string) ([]config.User, error) {
+    var users []config.User
+    if len(ids) == 0 {
+        return users, nil
+    }
+    err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error
+    return users, err
+}
+
+func (r *userRepository) first(ctx context.Context, query string, args ...interface{}) (*config.User, error) {
+    var user config.User
+    if err := r.db.WithContext(ctx).Where(query, args...).First(&user).Error; err != nil {
+        return nil, translate(err)
+    }
+    return &user, nil
+}
+
+func (r *userRepository) Update(ctx context.Context, id string, updates map[string]interface{}) error {
+    return r.db.WithContext(ctx).Model(&config.User{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *userRepository) Delete(ctx context.Context, id string) error {
+    return r.db.WithContext(ctx).Delete(&config.User{}, "id = ?", id).Error
+}
+
+// Search ищет активных пользователей по префиксу и триграммному сходству имени
+func (r *userRepository) Search(ctx context.Context, search UserSearch) ([]config.User, error) {
+    // Экранируем спецсимволы LIKE, чтобы запрос искал только по префиксу
+    prefix := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(search.Query) + "%"
+
+    query := r.db.WithContext(ctx).
+        Where("is_active = ? AND discoverable = ? AND id <> ?", true, true, search.ViewerID).
+        // Пользователи, заблокировавшие текущего или заблокированные им, в поиск не попадают
+        Where("id NOT IN (SELECT blocker_id FROM blocks WHERE blocked_id = ?) AND id NOT IN (SELECT blocked_id FROM blocks WHERE blocker_id = ?)",
+            search.ViewerID, search.ViewerID).
+        Limit(search.Limit)
+
+    var users []config.User
+    if r.db.Dialector.Name() == config.DatabaseSQLite {
+        // В SQLite нет pg_trgm: вместо сходства ищем подстроку, совпадения по префиксу выше
+        contains := "%" + strings.TrimSuffix(prefix, "%") + "%"
+        err := query.
+            Where(`lower(username) LIKE ? ESCAPE '\' OR lower(display_name) LIKE ? ESCAPE '\'`, contains, contains).
+            Order(clause.Expr{
+                SQL:  `(lower(username) LIKE ? ESCAPE '\' OR lower(display_name) LIKE ? ESCAPE '\') DESC, username ASC`,
+                Vars: []interface{}{prefix, prefix},
+            }).
+            Find(&users).Error
+        return users, err
+    }
+
+    // Сначала совпадения по префиксу, затем по триграммному сходству (оператор % из pg_trgm)
+    err := query.
+        Where("lower(username) LIKE ? OR lower(display_name) LIKE ? OR lower(username) % ? OR lower(display_name) % ?",
+            prefix, prefix, search.Query, search.Query).
+        Order(clause.Expr{
+            SQL:  "(lower(username) LIKE ? OR lower(display_name) LIKE ?) DESC, GREATEST(similarity(lower(username), ?), similarity(lower(display_name), ?)) DESC, username ASC",
+            Vars: []interface{}{prefix, prefix, search.Query, search.Query},
+        }).
+        Find(&users).Error
+    return users, err
+}
@@ -0,0 +1,111 @@
+package admin
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/gc"
+    "chatter-hub-server/repository"
+
+    "github.com/gin-gonic/gin"
+)
+
+// RunGCRequest задает запуск сборки мусора хранилища
+type RunGCRequest struct {
+    DryRun bool `json:"dry_run" example:"true"`
+    // Минимальный возраст удаляемого объекта в секундах; по умолчанию из конфигурации
+    GracePeriod int64 `json:"grace_period,omitempty" example:"86400"`
+}
+
+// RunGC godoc
+// @Summary      Запуск сборки мусора хранилища
+// @Description  Ставит в очередь поиск объектов хранилища, на которые не ссылается база данных. Объекты старше периода ожидания удаляются, в режиме dry_run только попадают в отчет. Отчет доступен в /admin/gc/reports после выполнения задачи. Доступно только администраторам.
+// @Tags         admin
+// @Accept       json
+// @Produce      json
+// @Param        request  body      RunGCRequest  false  "Параметры запуска"
+// @Success      202      {object}  config.Job
+// @Failure      400      {object}  config.ErrorResponse
+// @Failure      403      {object}  config.ErrorResponse
+// @Failure      500      {object}  config.ErrorResponse
+// @Router       /admin/gc [post]
+func (h *Handler) RunGC(c *gin.Context) {
+    var req RunGCRequest
+    if c.Request.ContentLength != 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: err.Error()})
+            return
+        }
+    }
+    if req.GracePeriod < 0 {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Период ожидания не может быть отрицательным"})
+        return
+    }
+
+    job, err := gc.Enqueue(c.Request.Context(), req.DryRun, time.Duration(req.GracePeriod)*time.Second)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка запуска сборки мусора"})
+        return
+    }
+    c.JSON(http.StatusAccepted, job)
+}
+
+// GetGCReports godoc
+// @Summary      Отчеты сборки мусора хранилища
+// @Description  Возвращает последние отчеты сборки мусора без списка найденных объектов. Доступно только администраторам.
+// @Tags         admin
+// @Produce      json
+// @Param        limit  query     int  false  "Количество отчетов (по умолчанию 50, максимум 200)"
+// @Success      200    {array}   config.GCReport
+// @Failure      400    {object}  config.ErrorResponse
+// @Failure      403    {object}  config.ErrorResponse
+// @Failure      500    {object}  config.ErrorResponse
+// @Router       /admin/gc/reports [get]
+func (h *Handler) GetGCReports(c *gin.Context) {
+    limit := jobsDefaultLimit
+    if rawLimit := c.Query("limit"); rawLimit != "" {
+        parsedLimit, err := strconv.Atoi(rawLimit)
+        if err != nil || parsedLimit < 1 {
+            c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Некорректное значение limit"})
+            return
+        }
+        if parsedLimit > jobsMaxLimit {
+            parsedLimit = jobsMaxLimit
+        }
+        limit = parsedLimit
+    }
+
+    reports, err := h.reports.List(c.Request.Context(), limit)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения отчетов"})
+        return
+    }
+    c.JSON(http.StatusOK, reports)
+}
+
+// GetGCReport godoc
+// @Summary      Отчет сборки мусора хранилища
+// @Description  Возвращает отчет с итогами по бакетам и списком найденных объектов. Доступно только администраторам.
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "ID отчета"
+// @Success      200  {object}  config.GCReport
+// @Failure      403  {object}  config.ErrorResponse
+// @Failure      404  {object}  config.ErrorResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /admin/gc/reports/{id} [get]
+func (h *Handler) GetGCReport(c *gin.Context) {
+    report, err := h.reports.Get(c.Request.Context(), c.Param("id"))
+    if errors.Is(err, repository.ErrNotFound) {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Отчет не найден"})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения отчета"})
+        return
+    }
+    c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,142 @@
+package admin
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/jobs"
+    "chatter-hub-server/repository"
+
+    "github.com/gin-gonic/gin"
+    "gorm.io/gorm"
+)
+
+const (
+    jobsDefaultLimit = 50  // Количество задач в списке по умолчанию
+    jobsMaxLimit     = 200 // Максимальное количество задач в списке
+)
+
+// Handler обрабатывает административные запросы к очереди задач и сборке мусора
+type Handler struct {
+    jobs    repository.JobRepository
+    reports repository.GCReportRepository
+}
+
+// NewHandler создает административные обработчики
+func NewHandler(jobs repository.JobRepository, reports repository.GCReportRepository) *Handler {
+    return &Handler{jobs: jobs, reports: reports}
+}
+
+// GetJobs godoc
+// @Summary      Список фоновых задач
+// @Description  Возвращает фоновые задачи, начиная с последних. Доступно только администраторам.
+// @Tags         admin
+// @Produce      json
+// @Param        status  query     string  false  "Статус задачи"  Enums(pending, running, completed, dead)
+// @Param        type    query     string  false  "Тип задачи"
+// @Param        limit   query     int     false  "Количество задач (по умолчанию 50, максимум 200)"
+// @Success      200     {array}   config.Job
+// @Failure      400     {object}  config.ErrorResponse
+// @Failure      403     {object}  config.ErrorResponse
+// @Failure      500     {object}  config.ErrorResponse
+// @Router       /admin/jobs [get]
+func (h *Handler) GetJobs(c *gin.Context) {
+    limit := jobsDefaultLimit
+    if rawLimit := c.Query("limit"); rawLimit != "" {
+        parsedLimit, err := strconv.Atoi(rawLimit)
+        if err != nil || parsedLimit < 1 {
+            c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Некорректное значение limit"})
+            return
+        }
+        if parsedLimit > jobsMaxLimit {
+            parsedLimit = jobsMaxLimit
+        }
+        limit = parsedLimit
+    }
+
+    list, err := h.jobs.List(c.Request.Context(), repository.JobFilter{
+        Status: c.Query("status"),
+        Type:   c.Query("type"),
+        Limit:  limit,
+    })
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения задач"})
+        return
+    }
+    c.JSON(http.StatusOK, list)
+}
+
+// GetJobStats godoc
+// @Summary      Состояние очереди фоновых задач
+// @Description  Возвращает количество задач по типам и статусам. Доступно только администраторам.
+// @Tags         admin
+// @Produce      json
+// @Success      200  {array}   repository.JobStats
+// @Failure      403  {object}  config.ErrorResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /admin/jobs/stats [get]
+func (h *Handler) GetJobStats(c *gin.Context) {
+    stats, err := h.jobs.Stats(c.Request.Context())
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения статистики задач"})
+        return
+    }
+    c.JSON(http.StatusOK, stats)
+}
+
+// GetJob godoc
+// @Summary      Фоновая задача
+// @Description  Возвращает задачу вместе с количеством попыток и последней ошибкой. Доступно только администраторам.
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "ID задачи"
+// @Success      200  {object}  config.Job
+// @Failure      403  {object}  config.ErrorResponse
+// @Failure      404  {object}  config.ErrorResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /admin/jobs/{id} [get]
+func (h *Handler) GetJob(c *gin.Context) {
+    job, err := h.jobs.Get(c.Request.Context(), c.Param("id"))
+    if errors.Is(err, repository.ErrNotFound) {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Задача не найдена"})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения задачи"})
+        return
+    }
+    c.JSON(http.StatusOK, job)
+}
+
+// RetryJob godoc
+// @Summary      Повтор фоновой задачи
+// @Description  Возвращает в очередь задачу в статусе dead, сбрасывая счетчик попыток. Доступно только администраторам.
+// @Tags         admin
+// @Produce      json
+// @Param        id   path      int  true  "ID задачи"
+// @Success      200  {object}  config.Job
+// @Failure      400  {object}  config.ErrorResponse
+// @Failure      403  {object}  config.ErrorResponse
+// @Failure      404  {object}  config.ErrorResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /admin/jobs/{id}/retry [post]
+func (h *Handler) RetryJob(c *gin.Context) {
+    id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+    if err != nil {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Некорректный ID задачи"})
+        return
+    }
+
+    job, err := jobs.Retry(c.Request.Context(), uint(id))
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Задача не найдена или не находится в статусе dead"})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка повтора задачи"})
+        return
+    }
+    c.JSON(http.StatusOK, job)
+}
@@ -0,0 +1,121 @@
+package blobs
+
+import (
+    "errors"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/storage"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Handler обслуживает ссылки на объекты хранилища, подписанные самим сервером
+type Handler struct {
+    store storage.BlobStore
+}
+
+// NewHandler создает обработчики подписанных ссылок на объекты хранилища store
+func NewHandler(store storage.BlobStore) *Handler {
+    return &Handler{store: store}
+}
+
+// GetObject godoc
+//	@Summary		Скачивание файла по подписанной ссылке
+//	@Description	Отдает объект локального хранилища по ссылке, подписанной сервером. Используется, когда файлы хранятся не в MinIO.
+//	@Tags			storage
+//	@Produce		octet-stream
+//	@Param			bucket		path		string	true	"Бакет"
+//	@Param			key			path		string	true	"Ключ объекта"
+//	@Param			expires		query		int		true	"Срок действия ссылки (Unix-время)"
+//	@Param			signature	query		string	true	"Подпись ссылки"
+//	@Success		200			{file}		binary
+//	@Failure		403			{object}	config.ErrorResponse
+//	@Failure		404			{object}	config.ErrorResponse
+//	@Router			/storage/{bucket}/{key} [get]
+func (h *Handler) GetObject(c *gin.Context) {
+    bucket, key, ok := h.verify(c)
+    if !ok {
+        return
+    }
+
+    object, info, err := h.store.Get(c.Request.Context(), bucket, key)
+    if err != nil {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Файл не найден"})
+        return
+    }
+    defer object.Close()
+
+    contentType := c.Query("response-content-type")
+    if contentType == "" {
+        contentType = info.ContentType
+    }
+    if contentType != "" {
+        c.Header("Content-Type", contentType)
+    }
+    if disposition := c.Query("response-content-disposition"); disposition != "" {
+        c.Header("Content-Disposition", disposition)
+    }
+    c.Header("X-Content-Type-Options", "nosniff")
+
+    http.ServeContent(c.Writer, c.Request, "", info.LastModified, object)
+}
+
+// PutObject godoc
+//	@Summary		Загрузка файла по подписанной ссылке
+//	@Description	Сохраняет тело запроса в локальное хранилище по ссылке, подписанной сервером. Используется для прямых загрузок, когда файлы хранятся не в MinIO.
+//	@Tags			storage
+//	@Accept			octet-stream
+//	@Param			bucket		path	string	true	"Бакет"
+//	@Param			key			path	string	true	"Ключ объекта"
+//	@Param			expires		query	int		true	"Срок действия ссылки (Unix-время)"
+//	@Param			signature	query	string	true	"Подпись ссылки"
+//	@Param			content-length	query	int		true	"Размер файла, который должен совпадать с Content-Length"
+//	@Param			content-type	query	string	true	"Тип файла, который должен совпадать с Content-Type"
+//	@Success		200
+//	@Failure		403	{object}	config.ErrorResponse
+//	@Failure		411	{object}	config.ErrorResponse
+//	@Failure		500	{object}	config.ErrorResponse
+//	@Router			/storage/{bucket}/{key} [put]
+func (h *Handler) PutObject(c *gin.Context) {
+    bucket, key, ok := h.verify(c)
+    if !ok {
+        return
+    }
+    if c.Request.ContentLength < 0 {
+        c.JSON(http.StatusLengthRequired, config.ErrorResponse{Error: "Необходим заголовок Content-Length"})
+        return
+    }
+    // Размер и тип подписаны вместе со ссылкой, как подписанные заголовки в ссылках MinIO
+    if strconv.FormatInt(c.Request.ContentLength, 10) != c.Query(storage.ParamContentLength) ||
+        c.GetHeader("Content-Type") != c.Query(storage.ParamContentType) {
+        c.JSON(http.StatusForbidden, config.ErrorResponse{Error: "Размер или тип файла не совпадают с указанными в ссылке"})
+        return
+    }
+
+    err := h.store.Put(c.Request.Context(), bucket, key, c.Request.Body, c.Request.ContentLength, c.ContentType())
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка сохранения файла"})
+        return
+    }
+    c.Status(http.StatusOK)
+}
+
+// verify проверяет подпись ссылки. При ошибке сам отправляет ответ клиенту.
+func (h *Handler) verify(c *gin.Context) (string, string, bool) {
+    bucket := c.Param("bucket")
+    key := strings.TrimPrefix(c.Param("key"), "/")
+
+    err := storage.VerifySigned(h.store, c.Request.Method, bucket, key, c.Request.URL.Query())
+    if err != nil {
+        message := "Недействительная ссылка"
+        if errors.Is(err, storage.ErrExpired) {
+            message = "Срок действия ссылки истек"
+        }
+        c.JSON(http.StatusForbidden, config.ErrorResponse{Error: message})
+        return "", "", false
+    }
+    return bucket, key, true
+}
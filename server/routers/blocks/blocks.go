@@ -0,0 +1,142 @@
+package blocks
+
+import (
+    "errors"
+    "net/http"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/repository"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Handler обрабатывает запросы к блокировкам пользователей
+type Handler struct {
+    blocks repository.BlockRepository
+    users  repository.UserRepository
+}
+
+// NewHandler создает обработчики блокировок
+func NewHandler(blocks repository.BlockRepository, users repository.UserRepository) *Handler {
+    return &Handler{blocks: blocks, users: users}
+}
+
+// BlockRequest представляет запрос на блокировку пользователя
+type BlockRequest struct {
+    UserID string `json:"user_id" binding:"required" example:"12345"`
+}
+
+// BlockResponse представляет заблокированного пользователя
+type BlockResponse struct {
+    User      config.PublicUser `json:"user"`
+    CreatedAt time.Time         `json:"created_at"`
+}
+
+// BlockUser godoc
+// @Summary      Блокировка пользователя
+// @Description  Блокирует пользователя: он не сможет писать текущему пользователю, видеть его профиль и находить в поиске. Контакты и заявки между пользователями удаляются.
+// @Tags         blocks
+// @Accept       json
+// @Produce      json
+// @Param        request  body      BlockRequest  true  "ID пользователя"
+// @Success      201      {object}  config.Block
+// @Failure      400      {object}  config.ErrorResponse
+// @Failure      404      {object}  config.ErrorResponse
+// @Failure      500      {object}  config.ErrorResponse
+// @Router       /blocks [post]
+func (h *Handler) BlockUser(c *gin.Context) {
+    var req BlockRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: err.Error()})
+        return
+    }
+
+    userID := c.GetString("userID")
+    if req.UserID == userID {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Нельзя заблокировать самого себя"})
+        return
+    }
+
+    ctx := c.Request.Context()
+    if _, err := h.users.Get(ctx, req.UserID); err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Пользователь не найден"})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка блокировки пользователя"})
+        return
+    }
+
+    // Вместе с блокировкой удаляются взаимные контакты и отменяются заявки в контакты
+    block := config.Block{BlockerID: userID, BlockedID: req.UserID}
+    if err := h.blocks.Create(ctx, &block); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка блокировки пользователя"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, block)
+}
+
+// UnblockUser godoc
+// @Summary      Разблокировка пользователя
+// @Description  Снимает блокировку с пользователя
+// @Tags         blocks
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID пользователя"
+// @Success      200  {object}  config.SimpleResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /blocks/{id} [delete]
+func (h *Handler) UnblockUser(c *gin.Context) {
+    if err := h.blocks.Delete(c.Request.Context(), c.GetString("userID"), c.Param("id")); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка разблокировки пользователя"})
+        return
+    }
+
+    c.JSON(http.StatusOK, config.SimpleResponse{Message: "Пользователь разблокирован"})
+}
+
+// GetBlocks godoc
+// @Summary      Получение списка заблокированных пользователей
+// @Description  Возвращает пользователей, заблокированных текущим пользователем
+// @Tags         blocks
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   BlockResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /blocks [get]
+func (h *Handler) GetBlocks(c *gin.Context) {
+    ctx := c.Request.Context()
+    blocks, err := h.blocks.List(ctx, c.GetString("userID"))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения списка блокировок"})
+        return
+    }
+
+    blockedIDs := make([]string, 0, len(blocks))
+    for _, block := range blocks {
+        blockedIDs = append(blockedIDs, block.BlockedID)
+    }
+
+    users, err := h.users.GetMany(ctx, blockedIDs)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения списка блокировок"})
+        return
+    }
+    usersByID := make(map[string]config.User, len(users))
+    for _, user := range users {
+        usersByID[user.ID] = user
+    }
+
+    result := make([]BlockResponse, 0, len(blocks))
+    for _, block := range blocks {
+        user, ok := usersByID[block.BlockedID]
+        if !ok {
+            continue
+        }
+        result = append(result, BlockResponse{User: user.Public(), CreatedAt: block.CreatedAt})
+    }
+
+    c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,366 @@
+package contacts
+
+import (
+    "errors"
+    "net/http"
+    "strings"
+    "time"
+    "unicode/utf8"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/messaging"
+    "chatter-hub-server/repository"
+
+    "github.com/gin-gonic/gin"
+)
+
+const maxNicknameLength = 64 // Максимальная длина имени контакта в символах
+
+// Handler обрабатывает запросы к контактам и заявкам в контакты
+type Handler struct {
+    contacts  repository.ContactRepository
+    users     repository.UserRepository
+    messaging *messaging.Service
+}
+
+// NewHandler создает обработчики контактов
+func NewHandler(contacts repository.ContactRepository, users repository.UserRepository, messagingService *messaging.Service) *Handler {
+    return &Handler{contacts: contacts, users: users, messaging: messagingService}
+}
+
+// ContactRequestBody представляет запрос на добавление в контакты
+type ContactRequestBody struct {
+    UserID string `json:"user_id" binding:"required" example:"12345"`
+}
+
+// UpdateContactRequest представляет запрос на изменение имени контакта
+type UpdateContactRequest struct {
+    Nickname string `json:"nickname" example:"Коллега"`
+}
+
+// ContactResponse представляет контакт в списке контактов
+type ContactResponse struct {
+    User      config.PublicUser `json:"user"`
+    Nickname  string            `json:"nickname"`
+    CreatedAt time.Time         `json:"created_at"`
+}
+
+// SendContactRequest godoc
+// @Summary      Отправка заявки в контакты
+// @Description  Отправляет пользователю заявку в контакты. Если встречная заявка уже существует, она принимается.
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        request  body      ContactRequestBody  true  "ID пользователя"
+// @Success      201      {object}  config.ContactRequest
+// @Failure      400      {object}  config.ErrorResponse
+// @Failure      404      {object}  config.ErrorResponse
+// @Failure      409      {object}  config.ErrorResponse
+// @Failure      500      {object}  config.ErrorResponse
+// @Router       /contacts/requests [post]
+func (h *Handler) SendContactRequest(c *gin.Context) {
+    var body ContactRequestBody
+    if err := c.ShouldBindJSON(&body); err != nil {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: err.Error()})
+        return
+    }
+
+    userID := c.GetString("userID")
+    if body.UserID == userID {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Нельзя добавить в контакты самого себя"})
+        return
+    }
+
+    ctx := c.Request.Context()
+    target, err := h.users.Get(ctx, body.UserID)
+    if err != nil || !target.IsActive {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Пользователь не найден"})
+        return
+    }
+
+    // Заявки между пользователями, один из которых заблокировал другого, запрещены
+    blocked, err := h.messaging.IsBlocked(ctx, userID, body.UserID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отправки заявки"})
+        return
+    }
+    if blocked {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Пользователь не найден"})
+        return
+    }
+
+    isContact, err := h.messaging.IsContact(ctx, userID, body.UserID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отправки заявки"})
+        return
+    }
+    if isContact {
+        c.JSON(http.StatusConflict, config.ErrorResponse{Error: "Пользователь уже в контактах"})
+        return
+    }
+
+    // Встречная заявка означает взаимное согласие — сразу принимаем её
+    incoming, err := h.contacts.FindPendingRequest(ctx, body.UserID, userID)
+    if err == nil {
+        if err := h.contacts.AcceptRequest(ctx, incoming); err != nil {
+            c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка принятия заявки"})
+            return
+        }
+        c.JSON(http.StatusCreated, incoming)
+        return
+    }
+    if !errors.Is(err, repository.ErrNotFound) {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отправки заявки"})
+        return
+    }
+
+    _, err = h.contacts.FindPendingRequest(ctx, userID, body.UserID)
+    if err == nil {
+        c.JSON(http.StatusConflict, config.ErrorResponse{Error: "Заявка уже отправлена"})
+        return
+    }
+    if !errors.Is(err, repository.ErrNotFound) {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отправки заявки"})
+        return
+    }
+
+    request := config.ContactRequest{
+        RequesterID: userID,
+        AddresseeID: body.UserID,
+        Status:      config.ContactRequestPending,
+    }
+    if err := h.contacts.CreateRequest(ctx, &request); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отправки заявки"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, request)
+}
+
+// GetContactRequests godoc
+// @Summary      Получение заявок в контакты
+// @Description  Возвращает ожидающие заявки: входящие (по умолчанию) или исходящие
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        direction  query     string  false  "incoming или outgoing"
+// @Success      200        {array}   config.ContactRequest
+// @Failure      400        {object}  config.ErrorResponse
+// @Failure      500        {object}  config.ErrorResponse
+// @Router       /contacts/requests [get]
+func (h *Handler) GetContactRequests(c *gin.Context) {
+    direction := c.DefaultQuery("direction", repository.RequestsIncoming)
+    if direction != repository.RequestsIncoming && direction != repository.RequestsOutgoing {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "direction должен быть incoming или outgoing"})
+        return
+    }
+
+    requests, err := h.contacts.PendingRequests(c.Request.Context(), c.GetString("userID"), direction)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения заявок"})
+        return
+    }
+
+    c.JSON(http.StatusOK, requests)
+}
+
+// AcceptContactRequest godoc
+// @Summary      Принятие заявки в контакты
+// @Description  Принимает входящую заявку и добавляет пользователей в контакты друг друга
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "ID заявки"
+// @Success      200  {object}  config.ContactRequest
+// @Failure      404  {object}  config.ErrorResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /contacts/requests/{id}/accept [post]
+func (h *Handler) AcceptContactRequest(c *gin.Context) {
+    request, ok := h.findPendingRequest(c, repository.RequestsIncoming)
+    if !ok {
+        return
+    }
+
+    if err := h.contacts.AcceptRequest(c.Request.Context(), request); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка принятия заявки"})
+        return
+    }
+
+    c.JSON(http.StatusOK, request)
+}
+
+// DeclineContactRequest godoc
+// @Summary      Отклонение заявки в контакты
+// @Description  Отклоняет входящую заявку в контакты
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "ID заявки"
+// @Success      200  {object}  config.ContactRequest
+// @Failure      404  {object}  config.ErrorResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /contacts/requests/{id}/decline [post]
+func (h *Handler) DeclineContactRequest(c *gin.Context) {
+    request, ok := h.findPendingRequest(c, repository.RequestsIncoming)
+    if !ok {
+        return
+    }
+
+    if err := h.contacts.SetRequestStatus(c.Request.Context(), request, config.ContactRequestDeclined); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отклонения заявки"})
+        return
+    }
+
+    c.JSON(http.StatusOK, request)
+}
+
+// CancelContactRequest godoc
+// @Summary      Отмена заявки в контакты
+// @Description  Отменяет исходящую заявку в контакты
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "ID заявки"
+// @Success      200  {object}  config.ContactRequest
+// @Failure      404  {object}  config.ErrorResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /contacts/requests/{id} [delete]
+func (h *Handler) CancelContactRequest(c *gin.Context) {
+    request, ok := h.findPendingRequest(c, repository.RequestsOutgoing)
+    if !ok {
+        return
+    }
+
+    if err := h.contacts.SetRequestStatus(c.Request.Context(), request, config.ContactRequestCancelled); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отмены заявки"})
+        return
+    }
+
+    c.JSON(http.StatusOK, request)
+}
+
+// GetContacts godoc
+// @Summary      Получение списка контактов
+// @Description  Возвращает контакты текущего пользователя с их публичными профилями
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   ContactResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /contacts [get]
+func (h *Handler) GetContacts(c *gin.Context) {
+    ctx := c.Request.Context()
+    contacts, err := h.contacts.List(ctx, c.GetString("userID"))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения контактов"})
+        return
+    }
+
+    contactIDs := make([]string, 0, len(contacts))
+    for _, contact := range contacts {
+        contactIDs = append(contactIDs, contact.ContactID)
+    }
+
+    users, err := h.users.GetMany(ctx, contactIDs)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения контактов"})
+        return
+    }
+    usersByID := make(map[string]config.User, len(users))
+    for _, user := range users {
+        usersByID[user.ID] = user
+    }
+
+    result := make([]ContactResponse, 0, len(contacts))
+    for _, contact := range contacts {
+        user, ok := usersByID[contact.ContactID]
+        if !ok {
+            continue
+        }
+        result = append(result, ContactResponse{
+            User:      user.Public(),
+            Nickname:  contact.Nickname,
+            CreatedAt: contact.CreatedAt,
+        })
+    }
+
+    c.JSON(http.StatusOK, result)
+}
+
+// UpdateContact godoc
+// @Summary      Изменение имени контакта
+// @Description  Задает имя, под которым контакт отображается у текущего пользователя
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string                true  "ID пользователя-контакта"
+// @Param        contact  body      UpdateContactRequest  true  "Имя контакта"
+// @Success      200      {object}  config.Contact
+// @Failure      400      {object}  config.ErrorResponse
+// @Failure      404      {object}  config.ErrorResponse
+// @Failure      500      {object}  config.ErrorResponse
+// @Router       /contacts/{id} [put]
+func (h *Handler) UpdateContact(c *gin.Context) {
+    var req UpdateContactRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: err.Error()})
+        return
+    }
+
+    nickname := strings.TrimSpace(req.Nickname)
+    if utf8.RuneCountInString(nickname) > maxNicknameLength {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Имя контакта не должно превышать 64 символа"})
+        return
+    }
+
+    contact, err := h.contacts.Get(c.Request.Context(), c.GetString("userID"), c.Param("id"))
+    if errors.Is(err, repository.ErrNotFound) {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Контакт не найден"})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка обновления контакта"})
+        return
+    }
+
+    if err := h.contacts.SetNickname(c.Request.Context(), contact, nickname); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка обновления контакта"})
+        return
+    }
+
+    c.JSON(http.StatusOK, contact)
+}
+
+// DeleteContact godoc
+// @Summary      Удаление контакта
+// @Description  Удаляет пользователя из контактов (у обоих пользователей)
+// @Tags         contacts
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID пользователя-контакта"
+// @Success      200  {object}  config.SimpleResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /contacts/{id} [delete]
+func (h *Handler) DeleteContact(c *gin.Context) {
+    if err := h.contacts.Delete(c.Request.Context(), c.GetString("userID"), c.Param("id")); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка удаления контакта"})
+        return
+    }
+
+    c.JSON(http.StatusOK, config.SimpleResponse{Message: "Контакт удален"})
+}
+
+// findPendingRequest ищет ожидающую заявку по ID из пути, входящую или исходящую для текущего
+// пользователя в зависимости от direction. При ошибке сам отправляет ответ клиенту.
+func (h *Handler) findPendingRequest(c *gin.Context, direction string) (*config.ContactRequest, bool) {
+    request, err := h.contacts.GetPendingRequest(c.Request.Context(), c.Param("id"), c.GetString("userID"), direction)
+    if errors.Is(err, repository.ErrNotFound) {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Заявка не найдена"})
+        return nil, false
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения заявки"})
+        return nil, false
+    }
+    return request, true
+}
@@ -0,0 +1,193 @@
+package files
+
+import (
+    "context"
+    "errors"
+    "net/http"
+
+    "chatter-hub-server/attachments"
+    "chatter-hub-server/config"
+    "chatter-hub-server/messaging"
+    "chatter-hub-server/repository"
+    "chatter-hub-server/storage"
+
+    "github.com/gin-gonic/gin"
+)
+
+// formOverhead — запас на заголовки частей multipart сверх размера файла
+const formOverhead = 64 << 10
+
+// Handler обрабатывает запросы к вложениям
+type Handler struct {
+    files       repository.FileRepository
+    messages    repository.MessageRepository
+    messaging   *messaging.Service
+    store       storage.BlobStore
+    attachments *attachments.Service
+}
+
+// NewHandler создает обработчики вложений
+func NewHandler(files repository.FileRepository, messages repository.MessageRepository, messagingService *messaging.Service, store storage.BlobStore, attachmentService *attachments.Service) *Handler {
+    return &Handler{files: files, messages: messages, messaging: messagingService, store: store, attachments: attachmentService}
+}
+
+// UploadAttachment godoc
+//	@Summary		Загрузка вложения
+//	@Description	Загружает изображение, видео, аудио или документ. Тип определяется по содержимому файла, размер ограничен для каждого типа отдельно, общий объем файлов пользователя — квотой (413). Вложение затем привязывается к сообщению. Изображения обрабатываются в фоне: метаданные удаляются, создаются эскизы и blurhash; до завершения обработки ссылка на изображение не возвращается.
+//	@Tags			attachments
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			file	formData	file	true	"Файл"
+//	@Success		201		{object}	config.Attachment
+//	@Failure		400		{object}	config.ErrorResponse
+//	@Failure		413		{object}	config.ErrorResponse
+//	@Failure		415		{object}	config.ErrorResponse
+//	@Failure		500		{object}	config.ErrorResponse
+//	@Router			/attachments [post]
+func (h *Handler) UploadAttachment(c *gin.Context) {
+    // Не даем загрузить тело запроса больше наибольшего допустимого размера вложения
+    c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, attachments.MaxUploadSize()+formOverhead)
+
+    file, err := c.FormFile("file")
+    if err != nil {
+        var tooLarge *http.MaxBytesError
+        if errors.As(err, &tooLarge) {
+            c.JSON(http.StatusRequestEntityTooLarge, config.ErrorResponse{Error: attachments.ErrTooLarge.Error()})
+            return
+        }
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Файл обязателен"})
+        return
+    }
+
+    attachment, err := h.attachments.Upload(c.Request.Context(), c.GetString("userID"), file, attachments.UploadOptions{})
+    if err != nil {
+        status := attachments.HTTPStatus(err)
+        if status == http.StatusInternalServerError {
+            c.JSON(status, config.ErrorResponse{Error: "Ошибка загрузки файла"})
+            return
+        }
+        c.JSON(status, config.ErrorResponse{Error: err.Error()})
+        return
+    }
+
+    c.JSON(http.StatusCreated, attachment)
+}
+
+// GetAttachment godoc
+//	@Summary		Получение вложения
+//	@Description	Возвращает метаданные вложения со ссылками на файл и уменьшенные варианты изображения. Доступно владельцу и участникам переписки, к сообщению которой оно привязано, пока никто из них не заблокировал другого; необработанные изображения — только владельцу.
+//	@Tags			attachments
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"ID вложения"
+//	@Success		200	{object}	config.Attachment
+//	@Failure		404	{object}	config.ErrorResponse
+//	@Failure		500	{object}	config.ErrorResponse
+//	@Router			/attachments/{id} [get]
+func (h *Handler) GetAttachment(c *gin.Context) {
+    attachment, ok := h.findAccessibleAttachment(c)
+    if !ok {
+        return
+    }
+
+    list := []config.Attachment{*attachment}
+    if err := h.files.LoadVariants(c.Request.Context(), list); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения вложения"})
+        return
+    }
+    if err := h.attachments.Sign(c.Request.Context(), list); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения вложения"})
+        return
+    }
+
+    c.JSON(http.StatusOK, list[0])
+}
+
+// DownloadFile godoc
+//	@Summary		Скачивание файла
+//	@Description	Отдает содержимое вложения через сервер. Поддерживает заголовок Range для докачки и перемотки.
+//	@Tags			attachments
+//	@Produce		octet-stream
+//	@Param			id		path		string	true	"ID вложения"
+//	@Param			Range	header		string	false	"Диапазон байтов, например bytes=0-1023"
+//	@Success		200		{file}		binary
+//	@Success		206		{file}		binary
+//	@Failure		404		{object}	config.ErrorResponse
+//	@Failure		416		{object}	config.ErrorResponse
+//	@Failure		500		{object}	config.ErrorResponse
+//	@Router			/files/{id} [get]
+func (h *Handler) DownloadFile(c *gin.Context) {
+    attachment, ok := h.findAccessibleAttachment(c)
+    if !ok {
+        return
+    }
+
+    object, info, err := h.store.Get(c.Request.Context(), attachment.Bucket, attachment.ObjectKey)
+    if err != nil {
+        if errors.Is(err, storage.ErrNotFound) {
+            c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Файл не найден"})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения файла"})
+        return
+    }
+    defer object.Close()
+
+    c.Header("Content-Type", attachment.ContentType)
+    c.Header("Content-Disposition", attachments.ContentDisposition(attachment))
+    c.Header("X-Content-Type-Options", "nosniff")
+    c.Header("Cache-Control", "private, max-age=3600")
+    c.Header("ETag", `"`+attachment.Checksum+`"`)
+
+    // ServeContent обрабатывает Range, If-Range и If-None-Match
+    http.ServeContent(c.Writer, c.Request, "", info.LastModified, object)
+}
+
+// findAccessibleAttachment находит вложение по ID из пути и проверяет, что текущий
+// пользователь имеет к нему доступ. При ошибке сам отправляет ответ клиенту.
+func (h *Handler) findAccessibleAttachment(c *gin.Context) (*config.Attachment, bool) {
+    attachment, err := h.files.Get(c.Request.Context(), c.Param("id"))
+    if errors.Is(err, repository.ErrNotFound) {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Вложение не найдено"})
+        return nil, false
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения вложения"})
+        return nil, false
+    }
+
+    allowed, err := h.canAccess(c.Request.Context(), c.GetString("userID"), attachment)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения вложения"})
+        return nil, false
+    }
+    if !allowed {
+        // Не раскрываем существование чужих вложений
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Вложение не найдено"})
+        return nil, false
+    }
+
+    return attachment, true
+}
+
+// canAccess проверяет, может ли пользователь получить вложение: владелец или участник переписки,
+// к сообщению которой привязано вложение. Необработанные изображения доступны только владельцу,
+// а при блокировке между участниками вложения собеседника скрыты, как и сама переписка.
+func (h *Handler) canAccess(ctx context.Context, userID string, attachment *config.Attachment) (bool, error) {
+    if attachment.OwnerID == userID {
+        return true, nil
+    }
+    if attachment.MessageID == nil || !attachments.Ready(attachment) {
+        return false, nil
+    }
+    participant, err := h.messages.IsParticipant(ctx, attachment.MessageType, *attachment.MessageID, userID)
+    if err != nil || !participant {
+        return false, err
+    }
+    // Вложение привязывает к сообщению только его владелец, то есть второй участник переписки
+    blocked, err := h.messaging.IsBlocked(ctx, userID, attachment.OwnerID)
+    if err != nil {
+        return false, err
+    }
+    return !blocked, nil
+}
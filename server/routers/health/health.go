@@ -0,0 +1,47 @@
+package health
+
+import (
+    "net/http"
+
+    "chatter-hub-server/health"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Handler отвечает на проверки работоспособности сервера
+type Handler struct {
+    checker *health.Checker
+}
+
+// NewHandler создает обработчики проверок работоспособности
+func NewHandler(checker *health.Checker) *Handler {
+    return &Handler{checker: checker}
+}
+
+// Healthz godoc
+//	@Summary		Проверка работоспособности процесса
+//	@Description	Отвечает, пока процесс сервера работает. Зависимости не проверяются: если этот маршрут не отвечает, процесс нужно перезапустить.
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	health.Report
+//	@Router			/healthz [get]
+func (h *Handler) Healthz(c *gin.Context) {
+    c.JSON(http.StatusOK, health.Report{Status: health.StatusOK})
+}
+
+// Readyz godoc
+//	@Summary		Проверка готовности
+//	@Description	Проверяет базу данных, Redis и хранилище файлов и возвращает состояние каждой зависимости. Пока хотя бы одна из них недоступна, сервер не должен получать запросы.
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	health.Report
+//	@Failure		503	{object}	health.Report
+//	@Router			/readyz [get]
+func (h *Handler) Readyz(c *gin.Context) {
+    report := h.checker.Run(c.Request.Context())
+    status := http.StatusOK
+    if !report.Ready() {
+        status = http.StatusServiceUnavailable
+    }
+    c.JSON(status, report)
+}
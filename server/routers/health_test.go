@@ -0,0 +1,43 @@
+package routers_test
+
+import (
+    "net/http"
+    "testing"
+
+    "chatter-hub-server/health"
+)
+
+func TestHealthz(t *testing.T) {
+    w := request(t, http.MethodGet, "/healthz", "", nil)
+    expectStatus(t, w, http.StatusOK)
+
+    var report health.Report
+    decode(t, w, &report)
+    if report.Status != health.StatusOK {
+        t.Errorf("состояние %q, ожидалось %q", report.Status, health.StatusOK)
+    }
+}
+
+func TestReadyz(t *testing.T) {
+    w := request(t, http.MethodGet, "/readyz", "", nil)
+    expectStatus(t, w, http.StatusOK)
+
+    var report health.Report
+    decode(t, w, &report)
+    if !report.Ready() {
+        t.Errorf("сервер не готов: %+v", report.Components)
+    }
+    for _, name := range []string{"sqlite", "memory"} {
+        component, ok := report.Components[name]
+        if !ok {
+            t.Errorf("в отчете нет компонента %s", name)
+            continue
+        }
+        if component.Status != health.StatusOK {
+            t.Errorf("компонент %s: состояние %q, ошибка %q", name, component.Status, component.Error)
+        }
+    }
+    if _, ok := report.Components["redis"]; ok {
+        t.Error("Redis проверяется, хотя кэш хранится в памяти")
+    }
+}
@@ -0,0 +1,211 @@
+package routers_test
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "io"
+    "log"
+    "mime/multipart"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "sync/atomic"
+    "testing"
+
+    "chatter-hub-server/attachments"
+    "chatter-hub-server/auth"
+    "chatter-hub-server/cache"
+    "chatter-hub-server/config"
+    "chatter-hub-server/notify"
+    "chatter-hub-server/quota"
+    "chatter-hub-server/repository"
+    "chatter-hub-server/routers"
+    "chatter-hub-server/storage"
+    "chatter-hub-server/tracing"
+
+    "github.com/gin-gonic/gin"
+    "github.com/golang-jwt/jwt/v4"
+)
+
+const testSecret = "test-secret"
+
+// testMetricsToken — токен сборщика метрик
+const testMetricsToken = "metrics-token"
+
+// router — сервер со всеми маршрутами поверх SQLite, кэша в памяти и хранилища в памяти
+var router *gin.Engine
+
+var userCounter int64
+
+func TestMain(m *testing.M) {
+    gin.SetMode(gin.TestMode)
+    gin.DefaultWriter = io.Discard
+    log.SetOutput(io.Discard)
+
+    dir, err := os.MkdirTemp("", "chatter-hub-test")
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+
+    cfg := testConfig(filepath.Join(dir, "test.db"))
+    if err := config.InitDB(cfg); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    if err := config.DB.Use(tracing.GormPlugin()); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    cache.Init(cfg)
+    notify.Init(cfg, repository.NewMutes(config.DB))
+    if err := storage.Init(cfg); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    attachments.Init(cfg)
+    quota.Init(cfg)
+    router = routers.NewRouter(cfg, routers.NewHandlers(cfg))
+
+    code := m.Run()
+    os.RemoveAll(dir)
+    os.Exit(code)
+}
+
+func testConfig(dbPath string) *config.Config {
+    return &config.Config{
+        Database: config.DatabaseConfig{Driver: config.DatabaseSQLite, SQLitePath: dbPath},
+        Cache:    config.CacheConfig{Driver: "memory"},
+        Storage: config.StorageConfig{
+            Driver:     "memory",
+            PresignTTL: 900,
+            PublicURL:  "http://localhost:8080",
+            SigningKey: testSecret,
+        },
+        JWT:     config.JWTConfig{SecretKey: testSecret, ExpiresIn: 3600},
+        Health:  config.HealthConfig{CheckTimeout: 2000},
+        Metrics: config.MetricsConfig{Token: testMetricsToken},
+        Attachments: config.AttachmentConfig{
+            MaxImageSize:     10 << 20,
+            MaxVideoSize:     100 << 20,
+            MaxAudioSize:     20 << 20,
+            MaxDocumentSize:  50 << 20,
+            MaxVoiceDuration: 600,
+            UserQuota:        1 << 30,
+        },
+    }
+}
+
+// testUser — зарегистрированный в тесте пользователь
+type testUser struct {
+    ID       string
+    Username string
+    Password string
+    Token    string
+}
+
+// registerUser регистрирует пользователя с уникальным именем и возвращает его токен и ID
+func registerUser(t *testing.T) testUser {
+    t.Helper()
+    n := atomic.AddInt64(&userCounter, 1)
+    user := testUser{Username: fmt.Sprintf("user_%d", n), Password: fmt.Sprintf("password-%d", n)}
+
+    w := request(t, http.MethodPost, "/users", "", map[string]string{
+        "username":     user.Username,
+        "display_name": fmt.Sprintf("Test User %d", n),
+        "email":        user.Username + "@example.com",
+        "password":     user.Password,
+    })
+    expectStatus(t, w, http.StatusOK)
+
+    var response map[string]string
+    decode(t, w, &response)
+    user.Token = response["token"]
+    user.ID = tokenUserID(t, user.Token)
+    return user
+}
+
+// tokenUserID проверяет подпись токена и возвращает ID пользователя из него
+func tokenUserID(t *testing.T, token string) string {
+    t.Helper()
+    claims := &auth.Claims{}
+    parsed, err := jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+        return []byte(testSecret), nil
+    })
+    if err != nil || !parsed.Valid {
+        t.Fatalf("недействительный токен %q: %v", token, err)
+    }
+    if claims.UserID == "" {
+        t.Fatalf("в токене нет user_id")
+    }
+    return claims.UserID
+}
+
+// request выполняет запрос с телом JSON; token — пустой для запроса без авторизации
+func request(t *testing.T, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+    t.Helper()
+    var reader io.Reader
+    if body != nil {
+        encoded, err := json.Marshal(body)
+        if err != nil {
+            t.Fatal(err)
+        }
+        reader = bytes.NewReader(encoded)
+    }
+    req := httptest.NewRequest(method, path, reader)
+    if body != nil {
+        req.Header.Set("Content-Type", "application/json")
+    }
+    return serve(req, token)
+}
+
+// upload выполняет запрос multipart/form-data с полями fields и файлом в поле file
+func upload(t *testing.T, path, token string, fields map[string]string, fileName string, content []byte) *httptest.ResponseRecorder {
+    t.Helper()
+    var body bytes.Buffer
+    form := multipart.NewWriter(&body)
+    for name, value := range fields {
+        if err := form.WriteField(name, value); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if fileName != "" {
+        part, err := form.CreateFormFile("file", fileName)
+        if err != nil {
+            t.Fatal(err)
+        }
+        part.Write(content)
+    }
+    if err := form.Close(); err != nil {
+        t.Fatal(err)
+    }
+
+    req := httptest.NewRequest(http.MethodPost, path, &body)
+    req.Header.Set("Content-Type", form.FormDataContentType())
+    return serve(req, token)
+}
+
+func serve(req *http.Request, token string) *httptest.ResponseRecorder {
+    if token != "" {
+        req.Header.Set("Authorization", "Bearer "+token)
+    }
+    w := httptest.NewRecorder()
+    router.ServeHTTP(w, req)
+    return w
+}
+
+func expectStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+    t.Helper()
+    if w.Code != status {
+        t.Fatalf("статус %d, ожидался %d: %s", w.Code, status, w.Body.String())
+    }
+}
+
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+    t.Helper()
+    if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+        t.Fatalf("ошибка разбора ответа %q: %v", w.Body.String(), err)
+    }
+}
@@ -0,0 +1,207 @@
+package routers_test
+
+import (
+    "bytes"
+    "encoding/binary"
+    "math"
+    "net/http"
+    "net/url"
+    "testing"
+
+    "chatter-hub-server/config"
+)
+
+// conversationPath возвращает путь списка сообщений между двумя пользователями
+func conversationPath(kind, senderID, receiverID string) string {
+    query := url.Values{"sender_id": {senderID}, "receiver_id": {receiverID}}
+    return "/messages/" + kind + "/?" + query.Encode()
+}
+
+func TestTextMessaging(t *testing.T) {
+    alice := registerUser(t)
+    bob := registerUser(t)
+    eve := registerUser(t)
+
+    w := request(t, http.MethodPost, "/messages/text/", alice.Token, map[string]string{"receiver_id": bob.ID, "content": "Привет, Боб"})
+    expectStatus(t, w, http.StatusOK)
+    w = request(t, http.MethodPost, "/messages/text/", bob.Token, map[string]string{"receiver_id": alice.ID, "content": "Привет, Алиса"})
+    expectStatus(t, w, http.StatusOK)
+
+    for _, reader := range []testUser{alice, bob} {
+        w = request(t, http.MethodGet, conversationPath("text", alice.ID, bob.ID), reader.Token, nil)
+        expectStatus(t, w, http.StatusOK)
+        var messages []config.TextMessage
+        decode(t, w, &messages)
+        if len(messages) != 2 || messages[0].Content != "Привет, Боб" || messages[1].SenderID != bob.ID {
+            t.Fatalf("неожиданная переписка: %+v", messages)
+        }
+    }
+
+    t.Run("чужая переписка", func(t *testing.T) {
+        expectStatus(t, request(t, http.MethodGet, conversationPath("text", alice.ID, bob.ID), eve.Token, nil), http.StatusForbidden)
+    })
+
+    t.Run("без авторизации", func(t *testing.T) {
+        expectStatus(t, request(t, http.MethodPost, "/messages/text/", "", map[string]string{"receiver_id": bob.ID, "content": "Привет"}), http.StatusUnauthorized)
+        expectStatus(t, request(t, http.MethodGet, conversationPath("text", alice.ID, bob.ID), "", nil), http.StatusUnauthorized)
+    })
+
+    t.Run("пустое сообщение", func(t *testing.T) {
+        expectStatus(t, request(t, http.MethodPost, "/messages/text/", alice.Token, map[string]string{"receiver_id": bob.ID}), http.StatusBadRequest)
+    })
+
+    t.Run("повторяющиеся вложения", func(t *testing.T) {
+        w := upload(t, "/attachments", alice.Token, nil, "notes.txt", []byte("заметки к встрече"))
+        expectStatus(t, w, http.StatusCreated)
+        var attachment config.Attachment
+        decode(t, w, &attachment)
+
+        body := map[string]interface{}{"receiver_id": bob.ID, "attachment_ids": []string{attachment.ID, attachment.ID}}
+        expectStatus(t, request(t, http.MethodPost, "/messages/text/", alice.Token, body), http.StatusOK)
+
+        w = request(t, http.MethodGet, conversationPath("text", alice.ID, bob.ID), bob.Token, nil)
+        expectStatus(t, w, http.StatusOK)
+        var messages []config.TextMessage
+        decode(t, w, &messages)
+        last := messages[len(messages)-1]
+        if len(last.Attachments) != 1 || last.Attachments[0].ID != attachment.ID {
+            t.Fatalf("неожиданные вложения сообщения: %+v", last.Attachments)
+        }
+    })
+
+    t.Run("несуществующий получатель", func(t *testing.T) {
+        w := request(t, http.MethodPost, "/messages/text/", alice.Token, map[string]string{"receiver_id": "00000000-0000-0000-0000-000000000000", "content": "Привет"})
+        expectStatus(t, w, http.StatusNotFound)
+    })
+
+    t.Run("только контакты", func(t *testing.T) {
+        expectStatus(t, request(t, http.MethodPut, "/users/"+bob.ID, bob.Token, map[string]string{"message_policy": config.MessagePolicyContacts}), http.StatusOK)
+        // Eve не в контактах Боба, а с Алисой Боб уже переписывался
+        expectStatus(t, request(t, http.MethodPost, "/messages/text/", eve.Token, map[string]string{"receiver_id": bob.ID, "content": "Привет"}), http.StatusForbidden)
+        expectStatus(t, request(t, http.MethodPost, "/messages/text/", alice.Token, map[string]string{"receiver_id": bob.ID, "content": "Еще раз привет"}), http.StatusOK)
+    })
+
+    t.Run("блокировка", func(t *testing.T) {
+        expectStatus(t, request(t, http.MethodPost, "/blocks", alice.Token, map[string]string{"user_id": eve.ID}), http.StatusCreated)
+        expectStatus(t, request(t, http.MethodPost, "/messages/text/", eve.Token, map[string]string{"receiver_id": alice.ID, "content": "Привет"}), http.StatusForbidden)
+    })
+
+    t.Run("переписка скрыта для обоих при блокировке", func(t *testing.T) {
+        expectStatus(t, request(t, http.MethodPost, "/blocks", bob.Token, map[string]string{"user_id": alice.ID}), http.StatusCreated)
+        for _, reader := range []testUser{alice, bob} {
+            w := request(t, http.MethodGet, conversationPath("text", alice.ID, bob.ID), reader.Token, nil)
+            expectStatus(t, w, http.StatusOK)
+            var messages []config.TextMessage
+            decode(t, w, &messages)
+            if len(messages) != 0 {
+                t.Fatalf("переписка видна при блокировке: %+v", messages)
+            }
+        }
+
+        expectStatus(t, request(t, http.MethodDelete, "/blocks/"+alice.ID, bob.Token, nil), http.StatusOK)
+        w := request(t, http.MethodGet, conversationPath("text", alice.ID, bob.ID), alice.Token, nil)
+        expectStatus(t, w, http.StatusOK)
+        var messages []config.TextMessage
+        decode(t, w, &messages)
+        if len(messages) == 0 {
+            t.Fatalf("переписка не вернулась после снятия блокировки")
+        }
+    })
+}
+
+func TestVoiceMessaging(t *testing.T) {
+    alice := registerUser(t)
+    bob := registerUser(t)
+    eve := registerUser(t)
+
+    w := upload(t, "/messages/voice/", alice.Token, map[string]string{"receiver_id": bob.ID}, "voice.wav", testWAV(2, 16000))
+    expectStatus(t, w, http.StatusOK)
+
+    w = request(t, http.MethodGet, conversationPath("voice", alice.ID, bob.ID), bob.Token, nil)
+    expectStatus(t, w, http.StatusOK)
+    var messages []config.VoiceMessage
+    decode(t, w, &messages)
+    if len(messages) != 1 {
+        t.Fatalf("ожидалось одно сообщение: %+v", messages)
+    }
+    message := messages[0]
+    if message.SenderID != alice.ID || message.Codec != "pcm" || message.SampleRate != 16000 || message.DurationMs != 2000 {
+        t.Fatalf("неожиданные сведения о записи: %+v", message)
+    }
+    if message.FileURL == "" || message.AttachmentID == "" || len(message.Waveform) == 0 {
+        t.Fatalf("нет ссылки на файл или формы волны: %+v", message)
+    }
+
+    t.Run("файл доступен получателю", func(t *testing.T) {
+        w := request(t, http.MethodGet, "/files/"+message.AttachmentID, bob.Token, nil)
+        expectStatus(t, w, http.StatusOK)
+        if !bytes.Equal(w.Body.Bytes(), testWAV(2, 16000)) {
+            t.Fatalf("содержимое файла отличается от загруженного")
+        }
+        expectStatus(t, request(t, http.MethodGet, "/files/"+message.AttachmentID, eve.Token, nil), http.StatusNotFound)
+    })
+
+    t.Run("файл скрыт при блокировке", func(t *testing.T) {
+        for _, blocker := range []testUser{alice, bob} {
+            other := bob
+            if blocker.ID == bob.ID {
+                other = alice
+            }
+            expectStatus(t, request(t, http.MethodPost, "/blocks", blocker.Token, map[string]string{"user_id": other.ID}), http.StatusCreated)
+            expectStatus(t, request(t, http.MethodGet, "/files/"+message.AttachmentID, bob.Token, nil), http.StatusNotFound)
+            expectStatus(t, request(t, http.MethodGet, "/attachments/"+message.AttachmentID, bob.Token, nil), http.StatusNotFound)
+            // Владелец по-прежнему видит свой файл
+            expectStatus(t, request(t, http.MethodGet, "/files/"+message.AttachmentID, alice.Token, nil), http.StatusOK)
+            expectStatus(t, request(t, http.MethodDelete, "/blocks/"+other.ID, blocker.Token, nil), http.StatusOK)
+        }
+        expectStatus(t, request(t, http.MethodGet, "/files/"+message.AttachmentID, bob.Token, nil), http.StatusOK)
+    })
+
+    t.Run("чужая переписка", func(t *testing.T) {
+        expectStatus(t, request(t, http.MethodGet, conversationPath("voice", alice.ID, bob.ID), eve.Token, nil), http.StatusForbidden)
+    })
+
+    t.Run("без авторизации", func(t *testing.T) {
+        w := upload(t, "/messages/voice/", "", map[string]string{"receiver_id": bob.ID}, "voice.wav", testWAV(1, 16000))
+        expectStatus(t, w, http.StatusUnauthorized)
+    })
+
+    t.Run("без файла", func(t *testing.T) {
+        w := upload(t, "/messages/voice/", alice.Token, map[string]string{"receiver_id": bob.ID}, "", nil)
+        expectStatus(t, w, http.StatusBadRequest)
+    })
+
+    t.Run("не аудиофайл", func(t *testing.T) {
+        w := upload(t, "/messages/voice/", alice.Token, map[string]string{"receiver_id": bob.ID}, "voice.wav", []byte("definitely not audio"))
+        expectStatus(t, w, http.StatusUnsupportedMediaType)
+    })
+
+    t.Run("несуществующий получатель", func(t *testing.T) {
+        w := upload(t, "/messages/voice/", alice.Token, map[string]string{"receiver_id": "00000000-0000-0000-0000-000000000000"}, "voice.wav", testWAV(1, 16000))
+        expectStatus(t, w, http.StatusNotFound)
+    })
+}
+
+// testWAV возвращает WAV-файл с синусоидой 440 Гц: моно, 16 бит
+func testWAV(seconds, sampleRate int) []byte {
+    samples := seconds * sampleRate
+    var buf bytes.Buffer
+    write := func(v interface{}) { binary.Write(&buf, binary.LittleEndian, v) }
+
+    buf.WriteString("RIFF")
+    write(uint32(36 + samples*2))
+    buf.WriteString("WAVEfmt ")
+    write(uint32(16))
+    write(uint16(1)) // PCM
+    write(uint16(1)) // Моно
+    write(uint32(sampleRate))
+    write(uint32(sampleRate * 2))
+    write(uint16(2))
+    write(uint16(16))
+    buf.WriteString("data")
+    write(uint32(samples * 2))
+    for i := 0; i < samples; i++ {
+        write(int16(math.Sin(2*math.Pi*440*float64(i)/float64(sampleRate)) * 10000))
+    }
+    return buf.Bytes()
+}
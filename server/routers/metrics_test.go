@@ -0,0 +1,119 @@
+package routers_test
+
+import (
+    "bufio"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "strings"
+    "testing"
+    "time"
+)
+
+// scrapeMetrics возвращает метрики так же, как их получает сборщик
+func scrapeMetrics(t *testing.T) string {
+    t.Helper()
+    w := request(t, http.MethodGet, "/metrics", testMetricsToken, nil)
+    expectStatus(t, w, http.StatusOK)
+    return w.Body.String()
+}
+
+// metricValue возвращает значение метрики без меток
+func metricValue(t *testing.T, name string) float64 {
+    t.Helper()
+    scanner := bufio.NewScanner(strings.NewReader(scrapeMetrics(t)))
+    for scanner.Scan() {
+        value, found := strings.CutPrefix(scanner.Text(), name+" ")
+        if !found {
+            continue
+        }
+        v, err := strconv.ParseFloat(value, 64)
+        if err != nil {
+            t.Fatalf("значение %s: %v", name, err)
+        }
+        return v
+    }
+    t.Fatalf("в метриках нет %s", name)
+    return 0
+}
+
+// waitMetric ждет, пока метрика примет значение want
+func waitMetric(t *testing.T, name string, want float64) {
+    t.Helper()
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        got := metricValue(t, name)
+        if got == want {
+            return
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("%s = %v, ожидалось %v", name, got, want)
+        }
+        time.Sleep(20 * time.Millisecond)
+    }
+}
+
+func TestMetrics(t *testing.T) {
+    user := registerUser(t)
+    expectStatus(t, request(t, http.MethodPost, "/login", "", map[string]string{"username": user.Username, "password": user.Password}), http.StatusOK)
+    expectStatus(t, request(t, http.MethodPost, "/login", "", map[string]string{"username": user.Username, "password": "wrong"}), http.StatusUnauthorized)
+    expectStatus(t, request(t, http.MethodGet, "/users/"+user.ID, user.Token, nil), http.StatusOK)
+    expectStatus(t, request(t, http.MethodGet, "/no-such-route/"+user.ID, "", nil), http.StatusNotFound)
+
+    body := scrapeMetrics(t)
+    for _, series := range []string{
+        `chatter_http_requests_total{method="GET",route="/users/:id",status="200"}`,
+        `chatter_http_request_duration_seconds_bucket{method="GET",route="/users/:id",status="200"`,
+        `chatter_http_requests_total{method="GET",route="unmatched",status="404"}`,
+        `chatter_logins_total{result="success"}`,
+        `chatter_logins_total{result="failure"}`,
+        `chatter_notification_subscriptions_active`,
+        `go_goroutines`,
+    } {
+        if !strings.Contains(body, series) {
+            t.Errorf("в метриках нет %s", series)
+        }
+    }
+    if strings.Contains(body, user.ID) {
+        t.Error("путь запроса попал в метки метрик")
+    }
+}
+
+func TestMetricsRequireToken(t *testing.T) {
+    user := registerUser(t)
+    tests := []struct {
+        name  string
+        token string
+        want  int
+    }{
+        {name: "без токена", token: "", want: http.StatusUnauthorized},
+        {name: "неверный токен", token: "wrong", want: http.StatusUnauthorized},
+        {name: "токен пользователя", token: user.Token, want: http.StatusUnauthorized},
+        {name: "токен метрик", token: testMetricsToken, want: http.StatusOK},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            expectStatus(t, request(t, http.MethodGet, "/metrics", tt.token, nil), tt.want)
+        })
+    }
+}
+
+func TestNotificationSubscriptionsMetric(t *testing.T) {
+    const gauge = "chatter_notification_subscriptions_active"
+    server := httptest.NewServer(router)
+    defer server.Close()
+
+    // Подписки из предыдущих тестов закрываются асинхронно
+    waitMetric(t, gauge, 0)
+
+    user := registerUser(t)
+    first := dialNotifications(t, server, user.Token)
+    waitMetric(t, gauge, 1)
+    second := dialNotifications(t, server, user.Token)
+    waitMetric(t, gauge, 2)
+
+    first.Close()
+    waitMetric(t, gauge, 1)
+    second.Close()
+    waitMetric(t, gauge, 0)
+}
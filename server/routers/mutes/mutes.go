@@ -0,0 +1,117 @@
+package mutes
+
+import (
+    "errors"
+    "net/http"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/repository"
+
+    "github.com/gin-gonic/gin"
+)
+
+// Handler обрабатывает запросы к настройкам уведомлений переписок
+type Handler struct {
+    mutes repository.MuteRepository
+    users repository.UserRepository
+}
+
+// NewHandler создает обработчики настроек уведомлений
+func NewHandler(mutes repository.MuteRepository, users repository.UserRepository) *Handler {
+    return &Handler{mutes: mutes, users: users}
+}
+
+// MuteRequest представляет запрос на отключение уведомлений
+type MuteRequest struct {
+    // Время, до которого уведомления отключены (RFC 3339). Если не указано — бессрочно.
+    Until *time.Time `json:"until,omitempty" example:"2030-01-01T00:00:00Z"`
+}
+
+// MuteConversation godoc
+// @Summary      Отключение уведомлений переписки
+// @Description  Отключает уведомления о сообщениях от пользователя до указанного времени или бессрочно
+// @Tags         mutes
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string       true   "ID собеседника"
+// @Param        request  body      MuteRequest  false  "Время окончания"
+// @Success      200      {object}  config.ConversationMute
+// @Failure      400      {object}  config.ErrorResponse
+// @Failure      404      {object}  config.ErrorResponse
+// @Failure      500      {object}  config.ErrorResponse
+// @Router       /mutes/{id} [put]
+func (h *Handler) MuteConversation(c *gin.Context) {
+    var req MuteRequest
+    if c.Request.ContentLength != 0 {
+        if err := c.ShouldBindJSON(&req); err != nil {
+            c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: err.Error()})
+            return
+        }
+    }
+    if req.Until != nil && !req.Until.After(time.Now()) {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Время окончания должно быть в будущем"})
+        return
+    }
+
+    peerID := c.Param("id")
+    if _, err := h.users.Get(c.Request.Context(), peerID); err != nil {
+        if errors.Is(err, repository.ErrNotFound) {
+            c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Пользователь не найден"})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отключения уведомлений"})
+        return
+    }
+
+    mute := config.ConversationMute{
+        UserID:     c.GetString("userID"),
+        PeerID:     peerID,
+        MutedUntil: req.Until,
+        CreatedAt:  time.Now(),
+    }
+    if err := h.mutes.Save(c.Request.Context(), &mute); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отключения уведомлений"})
+        return
+    }
+
+    c.JSON(http.StatusOK, mute)
+}
+
+// UnmuteConversation godoc
+// @Summary      Включение уведомлений переписки
+// @Description  Снова включает уведомления о сообщениях от пользователя
+// @Tags         mutes
+// @Accept       json
+// @Produce      json
+// @Param        id   path      string  true  "ID собеседника"
+// @Success      200  {object}  config.SimpleResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /mutes/{id} [delete]
+func (h *Handler) UnmuteConversation(c *gin.Context) {
+    if err := h.mutes.Delete(c.Request.Context(), c.GetString("userID"), c.Param("id")); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка включения уведомлений"})
+        return
+    }
+
+    c.JSON(http.StatusOK, config.SimpleResponse{Message: "Уведомления включены"})
+}
+
+// GetMutes godoc
+// @Summary      Получение переписок с отключенными уведомлениями
+// @Description  Возвращает действующие отключения уведомлений текущего пользователя
+// @Tags         mutes
+// @Accept       json
+// @Produce      json
+// @Success      200  {array}   config.ConversationMute
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /mutes [get]
+func (h *Handler) GetMutes(c *gin.Context) {
+    mutes, err := h.mutes.Active(c.Request.Context(), c.GetString("userID"))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения настроек уведомлений"})
+        return
+    }
+
+    c.JSON(http.StatusOK, mutes)
+}
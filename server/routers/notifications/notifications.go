@@ -0,0 +1,109 @@
+package notifications
+
+import (
+    "context"
+    "log/slog"
+    "time"
+
+    "chatter-hub-server/notify"
+    "chatter-hub-server/presence"
+
+    "github.com/gin-gonic/gin"
+    "github.com/gorilla/websocket"
+)
+
+const (
+    pingInterval = 30 * time.Second // Период проверки соединения
+    pongWait     = 2 * pingInterval // Клиент, не ответивший на ping за это время, отключается
+    writeWait    = 10 * time.Second // Время на отправку одного сообщения клиенту
+    readLimit    = 4 << 10          // Клиент отправляет только управляющие сообщения
+)
+
+// Handler обрабатывает подписки на уведомления в реальном времени
+type Handler struct {
+    upgrader websocket.Upgrader
+    notifier *notify.Notifier
+    presence *presence.Tracker
+}
+
+// NewHandler создает обработчики подписок на уведомления notifier. Пока подписка открыта,
+// пользователь отмечается в сети в tracker.
+func NewHandler(notifier *notify.Notifier, tracker *presence.Tracker) *Handler {
+    return &Handler{notifier: notifier, presence: tracker}
+}
+
+// Subscribe godoc
+// @Summary      Подписка на уведомления
+// @Description  Открывает WebSocket, по которому сервер отправляет уведомления текущему пользователю: каждое уведомление — JSON-объект с полями type, from, payload и created_at (новые сообщения, результат обработки вложений и распознавания речи). Уведомления из переписок с отключенными уведомлениями не приходят. Если клиент не успевает получать уведомления, лишние отбрасываются. Клиент должен отвечать на ping, иначе соединение закрывается. Пока соединение открыто, пользователь считается в сети.
+// @Tags         notifications
+// @Success      101
+// @Failure      400
+// @Failure      401  {object}  config.ErrorResponse
+// @Router       /notifications/ws [get]
+func (h *Handler) Subscribe(c *gin.Context) {
+    userID := c.GetString("userID")
+
+    // Подписываемся до ответа клиенту, чтобы не потерять уведомления, отправленные сразу после подключения
+    ctx, cancel := context.WithCancel(c.Request.Context())
+    defer cancel()
+    events, unsubscribe := h.notifier.Subscribe(ctx, userID)
+    defer unsubscribe()
+
+    // При ошибке Upgrade сам отвечает клиенту
+    conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+    if err != nil {
+        return
+    }
+    defer conn.Close()
+
+    // Отметка присутствия не влияет на доставку уведомлений, поэтому ошибки кэша только записываются в журнал
+    if err := h.presence.Connect(ctx, userID); err != nil {
+        slog.Error("Ошибка отметки присутствия", "user_id", userID, "error", err)
+    }
+    defer func() {
+        // Соединение закрыто вместе с контекстом запроса, но отметку нужно снять
+        if err := h.presence.Disconnect(context.WithoutCancel(ctx), userID); err != nil {
+            slog.Error("Ошибка снятия отметки присутствия", "user_id", userID, "error", err)
+        }
+    }()
+
+    // Клиент ничего не отправляет; чтение нужно, чтобы получать pong и закрытие соединения
+    go func() {
+        defer cancel()
+        conn.SetReadLimit(readLimit)
+        conn.SetReadDeadline(time.Now().Add(pongWait))
+        conn.SetPongHandler(func(string) error {
+            return conn.SetReadDeadline(time.Now().Add(pongWait))
+        })
+        for {
+            if _, _, err := conn.ReadMessage(); err != nil {
+                return
+            }
+        }
+    }()
+
+    ticker := time.NewTicker(pingInterval)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            return
+        case payload, ok := <-events:
+            if !ok {
+                return
+            }
+            conn.SetWriteDeadline(time.Now().Add(writeWait))
+            if err := conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+                return
+            }
+        case <-ticker.C:
+            conn.SetWriteDeadline(time.Now().Add(writeWait))
+            if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+                return
+            }
+            if err := h.presence.Refresh(ctx, userID); err != nil {
+                slog.Error("Ошибка продления отметки присутствия", "user_id", userID, "error", err)
+            }
+        }
+    }
+}
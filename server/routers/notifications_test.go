@@ -0,0 +1,127 @@
+package routers_test
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+
+    "chatter-hub-server/notify"
+    "chatter-hub-server/routers/users"
+
+    "github.com/gorilla/websocket"
+)
+
+// dialNotifications открывает подписку пользователя на уведомления через WebSocket
+func dialNotifications(t *testing.T, server *httptest.Server, token string) *websocket.Conn {
+    t.Helper()
+    url := "ws" + strings.TrimPrefix(server.URL, "http") + "/notifications/ws"
+    header := http.Header{"Authorization": {"Bearer " + token}}
+    conn, resp, err := websocket.DefaultDialer.Dial(url, header)
+    if err != nil {
+        status := 0
+        if resp != nil {
+            status = resp.StatusCode
+        }
+        t.Fatalf("подключение к уведомлениям: %v (статус %d)", err, status)
+    }
+    t.Cleanup(func() { conn.Close() })
+    return conn
+}
+
+// readEvent ждет следующее уведомление
+func readEvent(t *testing.T, conn *websocket.Conn) notify.Event {
+    t.Helper()
+    conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    var event notify.Event
+    if err := conn.ReadJSON(&event); err != nil {
+        t.Fatalf("уведомление не получено: %v", err)
+    }
+    return event
+}
+
+func TestNotificationsSubscribe(t *testing.T) {
+    server := httptest.NewServer(router)
+    defer server.Close()
+
+    sender := registerUser(t)
+    receiver := registerUser(t)
+    conn := dialNotifications(t, server, receiver.Token)
+
+    w := request(t, http.MethodPost, "/messages/text/", sender.Token, map[string]string{
+        "receiver_id": receiver.ID,
+        "content":     "Привет!",
+    })
+    expectStatus(t, w, http.StatusOK)
+
+    event := readEvent(t, conn)
+    if event.Type != notify.EventTextMessage || event.From != sender.ID {
+        t.Fatalf("уведомление %s от %q, ожидалось %s от %q", event.Type, event.From, notify.EventTextMessage, sender.ID)
+    }
+    payload, _ := json.Marshal(event.Payload)
+    if !strings.Contains(string(payload), "Привет!") {
+        t.Fatalf("в уведомлении нет текста сообщения: %s", payload)
+    }
+}
+
+func TestNotificationsRequireToken(t *testing.T) {
+    server := httptest.NewServer(router)
+    defer server.Close()
+
+    url := "ws" + strings.TrimPrefix(server.URL, "http") + "/notifications/ws"
+    _, resp, err := websocket.DefaultDialer.Dial(url, nil)
+    if err == nil {
+        t.Fatalf("подписка без токена открыта")
+    }
+    if resp == nil || resp.StatusCode != http.StatusUnauthorized {
+        t.Fatalf("ожидался статус 401, получен %v", resp)
+    }
+}
+
+// presenceOf возвращает присутствие пользователя userID, видимое владельцу токена
+func presenceOf(t *testing.T, token, userID string) users.PresenceResponse {
+    t.Helper()
+    w := request(t, http.MethodGet, "/users/"+userID+"/presence", token, nil)
+    expectStatus(t, w, http.StatusOK)
+    var resp users.PresenceResponse
+    decode(t, w, &resp)
+    return resp
+}
+
+func TestPresence(t *testing.T) {
+    server := httptest.NewServer(router)
+    defer server.Close()
+
+    viewer := registerUser(t)
+    target := registerUser(t)
+
+    if status := presenceOf(t, viewer.Token, target.ID); status.Online || status.LastSeenAt != nil {
+        t.Fatalf("новый пользователь в сети: %+v", status)
+    }
+
+    conn := dialNotifications(t, server, target.Token)
+    if status := presenceOf(t, viewer.Token, target.ID); !status.Online {
+        t.Fatalf("пользователь с открытой подпиской не в сети: %+v", status)
+    }
+
+    // Отметка снимается, когда сервер замечает закрытие соединения
+    conn.Close()
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        status := presenceOf(t, viewer.Token, target.ID)
+        if !status.Online && status.LastSeenAt != nil {
+            break
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("пользователь в сети после закрытия подписки: %+v", status)
+        }
+        time.Sleep(20 * time.Millisecond)
+    }
+
+    // Заблокированный пользователь не видит присутствие заблокировавшего
+    expectStatus(t, request(t, http.MethodPost, "/blocks", target.Token, map[string]string{"user_id": viewer.ID}), http.StatusCreated)
+    expectStatus(t, request(t, http.MethodGet, "/users/"+target.ID+"/presence", viewer.Token, nil), http.StatusNotFound)
+    expectStatus(t, request(t, http.MethodGet, "/users/unknown/presence", viewer.Token, nil), http.StatusNotFound)
+}
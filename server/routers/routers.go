@@ -2,40 +2,157 @@ package routers
 
 import (
     "github.com/gin-gonic/gin"
+    "chatter-hub-server/routers/admin"
+    "chatter-hub-server/routers/blobs"
+    "chatter-hub-server/routers/blocks"
+    "chatter-hub-server/routers/contacts"
+    "chatter-hub-server/routers/files"
+    "chatter-hub-server/routers/health"
+    "chatter-hub-server/routers/mutes"
+    "chatter-hub-server/routers/notifications"
     "chatter-hub-server/routers/text"
+    "chatter-hub-server/routers/tus"
+    "chatter-hub-server/routers/uploads"
     "chatter-hub-server/routers/users"
     "chatter-hub-server/routers/voice"
 )
 
+// Handlers holds the handlers that receive their dependencies from main
+type Handlers struct {
+    Users    *users.Handler
+    Text     *text.Handler
+    Voice    *voice.Handler
+    Files    *files.Handler
+    Contacts *contacts.Handler
+    Blocks   *blocks.Handler
+    Mutes    *mutes.Handler
+    Uploads  *uploads.Handler
+    Tus      *tus.Handler
+    Admin    *admin.Handler
+    Notify   *notifications.Handler
+    Health   *health.Handler
+    Blobs    *blobs.Handler // nil when the storage signs its own URLs
+}
+
 // RegisterRoutes registers unprotected routes
-func RegisterRoutes(router *gin.Engine) {
+func RegisterRoutes(router *gin.Engine, h Handlers) {
     // Unprotected routes
-    router.POST("/users", users.CreateUser)  // Create user
-    router.POST("/login", users.LoginUser)   // User login
+    router.POST("/users", h.Users.CreateUser)  // Create user
+    router.POST("/login", h.Users.LoginUser)   // User login
+
+    // Health checks for orchestrators and load balancers
+    router.GET("/healthz", h.Health.Healthz) // Process is alive
+    router.GET("/readyz", h.Health.Readyz)   // Dependencies are available
 }
 
 // RegisterProtectedRoutes registers protected routes
-func RegisterProtectedRoutes(router *gin.RouterGroup) {
+func RegisterProtectedRoutes(router *gin.RouterGroup, h Handlers) {
     // Protected routes for users
     userGroup := router.Group("/users")
     {
-        userGroup.GET("/:id", users.GetUser)
-        userGroup.PUT("/:id", users.UpdateUser)
-        userGroup.POST("/:id/deactivate", users.DeactivateUser) // Новый маршрут
-        userGroup.POST("/:id/activate", users.ActivateUser)     // Новый маршрут
+        userGroup.GET("/search", h.Users.SearchUsers)
+        userGroup.GET("/:id", h.Users.GetUser)
+        userGroup.PUT("/:id", h.Users.UpdateUser)
+        userGroup.DELETE("/:id", h.Users.DeleteUser)
+        userGroup.POST("/:id/deactivate", h.Users.DeactivateUser) // Новый маршрут
+        userGroup.POST("/:id/activate", h.Users.ActivateUser)     // Новый маршрут
+        userGroup.PUT("/:id/avatar", h.Users.UploadAvatar)
+        userGroup.DELETE("/:id/avatar", h.Users.DeleteAvatar)
+        userGroup.GET("/:id/avatar/:size", h.Users.GetAvatar)
+        userGroup.GET("/:id/presence", h.Users.GetPresence)
+    }
+
+    // Protected routes for the current user
+    router.GET("/me/storage", h.Users.GetStorageUsage)
+
+    // Protected routes for contacts
+    contactGroup := router.Group("/contacts")
+    {
+        contactGroup.GET("", h.Contacts.GetContacts)
+        contactGroup.PUT("/:id", h.Contacts.UpdateContact)
+        contactGroup.DELETE("/:id", h.Contacts.DeleteContact)
+        contactGroup.POST("/requests", h.Contacts.SendContactRequest)
+        contactGroup.GET("/requests", h.Contacts.GetContactRequests)
+        contactGroup.POST("/requests/:id/accept", h.Contacts.AcceptContactRequest)
+        contactGroup.POST("/requests/:id/decline", h.Contacts.DeclineContactRequest)
+        contactGroup.DELETE("/requests/:id", h.Contacts.CancelContactRequest)
+    }
+
+    // Protected routes for blocked users
+    blockGroup := router.Group("/blocks")
+    {
+        blockGroup.GET("", h.Blocks.GetBlocks)
+        blockGroup.POST("", h.Blocks.BlockUser)
+        blockGroup.DELETE("/:id", h.Blocks.UnblockUser)
+    }
+
+    // Protected routes for muted conversations
+    muteGroup := router.Group("/mutes")
+    {
+        muteGroup.GET("", h.Mutes.GetMutes)
+        muteGroup.PUT("/:id", h.Mutes.MuteConversation)
+        muteGroup.DELETE("/:id", h.Mutes.UnmuteConversation)
+    }
+
+    // Protected routes for attachments
+    attachmentGroup := router.Group("/attachments")
+    {
+        attachmentGroup.POST("", h.Files.UploadAttachment)
+        attachmentGroup.GET("/:id", h.Files.GetAttachment)
     }
+    router.GET("/files/:id", h.Files.DownloadFile)
+
+    // Protected routes for direct-to-storage uploads
+    uploadGroup := router.Group("/uploads")
+    {
+        uploadGroup.POST("", h.Uploads.CreateUpload)
+        uploadGroup.POST("/:id/complete", h.Uploads.CompleteUpload)
+
+        // Resumable uploads using the tus protocol
+        tusGroup := uploadGroup.Group("/tus", tus.Middleware())
+        {
+            tusGroup.OPTIONS("", tus.Options)
+            tusGroup.POST("", h.Tus.CreateUpload)
+            tusGroup.HEAD("/:id", h.Tus.GetOffset)
+            tusGroup.PATCH("/:id", h.Tus.PatchUpload)
+            tusGroup.DELETE("/:id", h.Tus.TerminateUpload)
+        }
+    }
+
+    // Real-time notifications over WebSocket
+    router.GET("/notifications/ws", h.Notify.Subscribe)
 
     // Protected routes for text messages
     textGroup := router.Group("/messages/text")
     {
-        textGroup.POST("/", text.SendTextMessage)
-        textGroup.GET("/", text.GetTextMessages)
+        textGroup.POST("/", h.Text.SendTextMessage)
+        textGroup.GET("/", h.Text.GetTextMessages)
     }
 
     // Protected routes for voice messages
     voiceGroup := router.Group("/messages/voice")
     {
-        voiceGroup.POST("/", voice.SendVoiceMessage)
-        voiceGroup.GET("/", voice.GetVoiceMessages)
+        voiceGroup.POST("/", h.Voice.SendVoiceMessage)
+        voiceGroup.GET("/", h.Voice.GetVoiceMessages)
+    }
+}
+
+// RegisterAdminRoutes registers routes available only to administrators
+func RegisterAdminRoutes(router *gin.RouterGroup, h Handlers) {
+    // Background job queue
+    jobGroup := router.Group("/jobs")
+    {
+        jobGroup.GET("", h.Admin.GetJobs)
+        jobGroup.GET("/stats", h.Admin.GetJobStats)
+        jobGroup.GET("/:id", h.Admin.GetJob)
+        jobGroup.POST("/:id/retry", h.Admin.RetryJob)
+    }
+
+    // Storage garbage collection
+    gcGroup := router.Group("/gc")
+    {
+        gcGroup.POST("", h.Admin.RunGC)
+        gcGroup.GET("/reports", h.Admin.GetGCReports)
+        gcGroup.GET("/reports/:id", h.Admin.GetGCReport)
     }
 }
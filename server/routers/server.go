@@ -0,0 +1,132 @@
+package routers
+
+import (
+    "context"
+    "time"
+
+    "chatter-hub-server/accounts"
+    "chatter-hub-server/attachments"
+    "chatter-hub-server/auth"
+    "chatter-hub-server/cache"
+    "chatter-hub-server/config"
+    healthcheck "chatter-hub-server/health"
+    "chatter-hub-server/logging"
+    "chatter-hub-server/messaging"
+    "chatter-hub-server/metrics"
+    "chatter-hub-server/notify"
+    "chatter-hub-server/presence"
+    "chatter-hub-server/repository"
+    "chatter-hub-server/routers/admin"
+    "chatter-hub-server/routers/blobs"
+    "chatter-hub-server/routers/blocks"
+    "chatter-hub-server/routers/contacts"
+    "chatter-hub-server/routers/files"
+    "chatter-hub-server/routers/health"
+    "chatter-hub-server/routers/mutes"
+    "chatter-hub-server/routers/notifications"
+    "chatter-hub-server/routers/text"
+    "chatter-hub-server/routers/tus"
+    "chatter-hub-server/routers/uploads"
+    "chatter-hub-server/routers/users"
+    "chatter-hub-server/routers/voice"
+    "chatter-hub-server/storage"
+    "chatter-hub-server/tracing"
+    "chatter-hub-server/transcribe"
+
+    "github.com/gin-gonic/gin"
+    swaggerFiles "github.com/swaggo/files"
+    ginSwagger "github.com/swaggo/gin-swagger"
+)
+
+// NewHandlers creates repositories and services once and passes them to the handlers.
+// Database, cache, storage and notifications must be initialized beforehand.
+func NewHandlers(cfg *config.Config) Handlers {
+    userRepository := repository.NewUsers(config.DB)
+    messageRepository := repository.NewMessages(config.DB)
+    fileRepository := repository.NewFiles(config.DB)
+    contactRepository := repository.NewContacts(config.DB)
+    accountService := accounts.NewService(userRepository, cache.Default)
+    messagingService := messaging.NewService(userRepository, repository.NewPolicy(config.DB))
+    attachmentService := attachments.NewService(config.DB, storage.Store)
+    presenceTracker := presence.NewTracker(cache.Default)
+    handlers := Handlers{
+        Users: users.NewHandler(accountService, messagingService, fileRepository, repository.NewQuotas(config.DB), storage.Store,
+            auth.NewTokenIssuer(cfg), presenceTracker),
+        Text:     text.NewHandler(messageRepository, fileRepository, messagingService, attachmentService, notify.Default),
+        Voice:    voice.NewHandler(messageRepository, fileRepository, messagingService, storage.Store, attachmentService, notify.Default, transcribe.NewQueue()),
+        Files:    files.NewHandler(fileRepository, messageRepository, messagingService, storage.Store, attachmentService),
+        Contacts: contacts.NewHandler(contactRepository, userRepository, messagingService),
+        Blocks:   blocks.NewHandler(repository.NewBlocks(config.DB), userRepository),
+        Mutes:    mutes.NewHandler(repository.NewMutes(config.DB), userRepository),
+        Uploads:  uploads.NewHandler(repository.NewUploads(config.DB), storage.Store, attachmentService),
+        Tus:      tus.NewHandler(repository.NewTusUploads(config.DB), storage.Store, attachmentService),
+        Admin:    admin.NewHandler(repository.NewJobs(config.DB), repository.NewGCReports(config.DB)),
+        Notify:   notifications.NewHandler(notify.Default, presenceTracker),
+        Health:   health.NewHandler(newChecker(cfg)),
+    }
+    // Ссылки на файлы локального хранилища подписывает и обслуживает сам сервер
+    if storage.ServesSignedURLs(storage.Store) {
+        handlers.Blobs = blobs.NewHandler(storage.Store)
+    }
+    return handlers
+}
+
+// newChecker registers readiness checks for the database, Redis (unless the cache is in memory)
+// and file storage. Components are named after their drivers.
+func newChecker(cfg *config.Config) *healthcheck.Checker {
+    checker := healthcheck.NewChecker(time.Duration(cfg.Health.CheckTimeout) * time.Millisecond)
+    checker.Add(config.DB.Dialector.Name(), func(ctx context.Context) error {
+        sqlDB, err := config.DB.DB()
+        if err != nil {
+            return err
+        }
+        return sqlDB.PingContext(ctx)
+    })
+    if config.RedisClient != nil {
+        checker.Add("redis", func(ctx context.Context) error {
+            return config.RedisClient.Ping(ctx).Err()
+        })
+    }
+    storageName := cfg.Storage.Driver
+    if storageName == "" {
+        storageName = "minio"
+    }
+    checker.Add(storageName, storage.Ping)
+    return checker
+}
+
+// NewRouter builds the Gin engine with all routes of the server
+func NewRouter(cfg *config.Config, h Handlers) *gin.Engine {
+    router := gin.New()
+    router.Use(logging.RequestID(), logging.AccessLog(), tracing.Middleware(), metrics.Middleware(), logging.Recovery())
+
+    // Незапрашиваемые маршруты
+    router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+    // На адресе API метрики отдаются только по токену; при METRICS_ADDR — на отдельном адресе
+    if cfg.Metrics.Addr == "" && cfg.Metrics.Token != "" {
+        router.GET("/metrics", gin.WrapH(metrics.Handler(cfg.Metrics.Token)))
+    }
+    RegisterRoutes(router, h) // Создание пользователя и аутентификация (не защищено)
+
+    // Ссылки на файлы локального хранилища подписывает и обслуживает сам сервер
+    if h.Blobs != nil {
+        router.GET(storage.SignedPath+"/:bucket/*key", h.Blobs.GetObject)
+        router.PUT(storage.SignedPath+"/:bucket/*key", h.Blobs.PutObject)
+    }
+
+    // Защищенные маршруты
+    authorized := router.Group("/")
+    authorized.Use(auth.AuthMiddleware(cfg)) // Middleware аутентификации для защищенных маршрутов
+    {
+        RegisterProtectedRoutes(authorized, h)
+    }
+
+    // Маршруты администраторов
+    adminGroup := authorized.Group("/admin")
+    adminGroup.Use(auth.AdminMiddleware(cfg))
+    {
+        RegisterAdminRoutes(adminGroup, h)
+    }
+
+    return router
+}
@@ -4,46 +4,101 @@ import (
     "net/http"
     "time"
 
+    "chatter-hub-server/attachments"
     "chatter-hub-server/config"
+    "chatter-hub-server/messaging"
+    "chatter-hub-server/metrics"
+    "chatter-hub-server/notify"
+    "chatter-hub-server/repository"
 
     "github.com/gin-gonic/gin"
 )
 
+// Handler обрабатывает запросы к текстовым сообщениям
+type Handler struct {
+    messages  repository.MessageRepository
+    files     repository.FileRepository
+    messaging   *messaging.Service
+    attachments *attachments.Service
+    notifier    notify.Publisher
+}
+
+// NewHandler создает обработчики текстовых сообщений
+func NewHandler(messages repository.MessageRepository, files repository.FileRepository, messagingService *messaging.Service, attachmentService *attachments.Service, notifier notify.Publisher) *Handler {
+    return &Handler{messages: messages, files: files, messaging: messagingService, attachments: attachmentService, notifier: notifier}
+}
+
 // SendTextMessage godoc
 //	@Summary		Отправка текстового сообщения
 //	@Description	Отправляет текстовое сообщение от одного пользователя к другому
 //	@Tags			text
 //	@Accept			json
 //	@Produce		json
-//	@Param			message	body		config.TextMessage	true	"Текстовое сообщение"
+//	@Param			message	body		SendTextMessageRequest	true	"Текстовое сообщение"
 //	@Success		200		{object}	config.SimpleResponse
 //	@Failure		400		{object}	config.ErrorResponse
+//	@Failure		403		{object}	config.ErrorResponse
+//	@Failure		404		{object}	config.ErrorResponse
 //	@Failure		500		{object}	config.ErrorResponse
 //	@Router			/messages/text [post]
-func SendTextMessage(c *gin.Context) {
-    var message config.TextMessage
-    if err := c.ShouldBindJSON(&message); err != nil {
+func (h *Handler) SendTextMessage(c *gin.Context) {
+    var req SendTextMessageRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
         c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: err.Error()})
         return
     }
+    if req.Content == "" && len(req.AttachmentIDs) == 0 {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Сообщение не может быть пустым"})
+        return
+    }
 
     // Получаем ID пользователя из контекста (из токена)
     senderID := c.GetString("userID")
-    message.SenderID = senderID
-    message.CreatedAt = time.Now()
+    message := config.TextMessage{
+        SenderID:   senderID,
+        ReceiverID: req.ReceiverID,
+        Content:    req.Content,
+        CreatedAt:  time.Now(),
+    }
 
-    // Сохраняем сообщение в базе данных
-    if err := config.DB.Create(&message).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отправки сообщения"})
+    // Проверяем, что получатель существует и принимает сообщения от отправителя
+    if err := h.messaging.CheckCanSend(c.Request.Context(), senderID, message.ReceiverID); err != nil {
+        status := messaging.HTTPStatus(err)
+        if status == http.StatusInternalServerError {
+            c.JSON(status, config.ErrorResponse{Error: "Ошибка отправки сообщения"})
+            return
+        }
+        c.JSON(status, config.ErrorResponse{Error: err.Error()})
         return
     }
 
+    // Сохраняем сообщение и привязываем к нему вложения
+    if err := h.messages.CreateText(c.Request.Context(), &message, req.AttachmentIDs); err != nil {
+        status := attachments.HTTPStatus(err)
+        if status == http.StatusInternalServerError {
+            c.JSON(status, config.ErrorResponse{Error: "Ошибка отправки сообщения"})
+            return
+        }
+        c.JSON(status, config.ErrorResponse{Error: err.Error()})
+        return
+    }
+
+    metrics.MessagesSent.WithLabelValues("text").Inc()
+    h.notifier.Publish(c.Request.Context(), message.ReceiverID, notify.Event{Type: notify.EventTextMessage, From: senderID, Payload: message})
+
     c.JSON(http.StatusOK, config.SimpleResponse{Message: "Текстовое сообщение отправлено"})
 }
 
+// SendTextMessageRequest представляет запрос на отправку текстового сообщения
+type SendTextMessageRequest struct {
+    ReceiverID    string   `json:"receiver_id" example:"12345"`
+    Content       string   `json:"content" example:"Привет!"`
+    AttachmentIDs []string `json:"attachment_ids,omitempty"` // ID ранее загруженных вложений
+}
+
 // GetTextMessages godoc
 //	@Summary		Получение текстовых сообщений
-//	@Description	Возвращает список текстовых сообщений между двумя пользователями
+//	@Description	Возвращает список текстовых сообщений между двумя пользователями. Если один из участников заблокировал другого, переписка скрыта для обоих: возвращается пустой список.
 //	@Tags			text
 //	@Accept			json
 //	@Produce		json
@@ -53,12 +108,10 @@ func SendTextMessage(c *gin.Context) {
 //	@Failure		400			{object}	config.ErrorResponse
 //	@Failure		500			{object}	config.ErrorResponse
 //	@Router			/messages/text [get]
-func GetTextMessages(c *gin.Context) {
+func (h *Handler) GetTextMessages(c *gin.Context) {
     senderID := c.Query("sender_id")
     receiverID := c.Query("receiver_id")
 
-    var messages []config.TextMessage
-
     // Проверяем права доступа
     tokenUserID := c.GetString("userID")
     if tokenUserID != senderID && tokenUserID != receiverID {
@@ -66,12 +119,45 @@ func GetTextMessages(c *gin.Context) {
         return
     }
 
+    // Переписка скрыта для обоих участников, пока один из них заблокировал другого
+    peerID := receiverID
+    if tokenUserID == receiverID {
+        peerID = senderID
+    }
+    blocked, err := h.messaging.IsBlocked(c.Request.Context(), tokenUserID, peerID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения сообщений"})
+        return
+    }
+    if blocked {
+        c.JSON(http.StatusOK, []config.TextMessage{})
+        return
+    }
+
     // Получаем сообщения из базы данных
-    if err := config.DB.Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
-        senderID, receiverID, receiverID, senderID).Order("created_at asc").Find(&messages).Error; err != nil {
+    messages, err := h.messages.TextConversation(c.Request.Context(), senderID, receiverID)
+    if err != nil {
         c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения сообщений"})
         return
     }
 
+    // Добавляем к сообщениям их вложения
+    messageIDs := make([]uint, 0, len(messages))
+    for _, message := range messages {
+        messageIDs = append(messageIDs, message.ID)
+    }
+    messageAttachments, err := h.files.ForMessages(c.Request.Context(), config.MessageTypeText, messageIDs)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения сообщений"})
+        return
+    }
+    for i := range messages {
+        messages[i].Attachments = messageAttachments[messages[i].ID]
+        if err := h.attachments.Sign(c.Request.Context(), messages[i].Attachments); err != nil {
+            c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения сообщений"})
+            return
+        }
+    }
+
     c.JSON(http.StatusOK, messages)
 }
@@ -0,0 +1,71 @@
+package routers_test
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/propagation"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    "go.opentelemetry.io/otel/sdk/trace/tracetest"
+    "go.opentelemetry.io/otel/trace"
+)
+
+func TestTracing(t *testing.T) {
+    exporter := tracetest.NewInMemoryExporter()
+    provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+    previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+    otel.SetTracerProvider(provider)
+    otel.SetTextMapPropagator(propagation.TraceContext{})
+    // Остальные тесты пакета выполняются без записи спанов, как до этого теста
+    t.Cleanup(func() {
+        provider.Shutdown(context.Background())
+        otel.SetTracerProvider(previousProvider)
+        otel.SetTextMapPropagator(previousPropagator)
+    })
+
+    alice := registerUser(t)
+    bob := registerUser(t)
+    exporter.Reset()
+
+    // Запрос приходит от другого сервиса с заголовком traceparent
+    const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+    const parentID = "00f067aa0ba902b7"
+    body, _ := json.Marshal(map[string]string{"receiver_id": bob.ID, "content": "Привет"})
+    req := httptest.NewRequest(http.MethodPost, "/messages/text/", bytes.NewReader(body))
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("traceparent", "00-"+traceID+"-"+parentID+"-01")
+    expectStatus(t, serve(req, alice.Token), http.StatusOK)
+
+    spans := exporter.GetSpans()
+    var server *tracetest.SpanStub
+    queries := 0
+    for i, span := range spans {
+        if span.SpanContext.TraceID().String() != traceID {
+            t.Errorf("спан %s вне трассы запроса: %s", span.Name, span.SpanContext.TraceID())
+        }
+        switch {
+        case span.SpanKind == trace.SpanKindServer:
+            server = &spans[i]
+        case strings.HasPrefix(span.Name, "gorm."):
+            queries++
+        }
+    }
+    if server == nil {
+        t.Fatalf("нет спана HTTP-запроса среди %d спанов", len(spans))
+    }
+    if server.Name != "POST /messages/text/" {
+        t.Errorf("имя спана %q", server.Name)
+    }
+    if server.Parent.SpanID().String() != parentID {
+        t.Errorf("родитель спана запроса %s, ожидался %s", server.Parent.SpanID(), parentID)
+    }
+    if queries == 0 {
+        t.Error("нет спанов запросов к базе данных")
+    }
+}
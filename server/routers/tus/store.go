@@ -0,0 +1,266 @@
+package tus
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "io"
+    "log/slog"
+    "net/http"
+    "time"
+
+    "chatter-hub-server/attachments"
+    "chatter-hub-server/cache"
+    "chatter-hub-server/config"
+    "chatter-hub-server/jobs"
+    "chatter-hub-server/storage"
+
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// partSize — размер части составной загрузки; S3 требует не меньше 5 МиБ для всех частей, кроме последней
+const partSize = 5 << 20
+
+// CleanupJobType — тип фоновой задачи очистки просроченных загрузок
+const CleanupJobType = "tus.cleanup"
+
+// lockTTL ограничивает время блокировки загрузки, если запрос завершился аварийно
+const lockTTL = 10 * time.Minute
+
+// persistTimeout ограничивает сохранение уже полученных данных после отключения клиента
+const persistTimeout = time.Minute
+
+// multipart возвращает составную загрузку хранилища обработчика
+func (h *Handler) multipart() (storage.Multipart, error) {
+    mp, ok := h.store.(storage.Multipart)
+    if !ok {
+        return nil, storage.ErrMultipartUnsupported
+    }
+    return mp, nil
+}
+
+// tailKey возвращает ключ объекта с данными, которых пока не хватает на целую часть
+func tailKey(upload *config.TusUpload) string {
+    return upload.ObjectKey + ".tail"
+}
+
+// persistContext возвращает контекст для записи уже полученных данных. Отключение клиента
+// отменяет контекст запроса, но принятые байты все равно сохраняются.
+func persistContext(ctx context.Context) (context.Context, context.CancelFunc) {
+    return context.WithTimeout(context.WithoutCancel(ctx), persistTimeout)
+}
+
+// writeChunk дописывает данные из body к загрузке. Полные части отправляются в составную загрузку,
+// остаток сохраняется в хвостовом объекте. Поля Offset, PartCount и TailSize обновляются после
+// каждой успешной записи, поэтому при ошибке они отражают уже сохраненные данные. Обрыв соединения
+// клиента ошибкой не считается: полученные байты сохраняются, клиент продолжит с нового смещения.
+// Превышение размера body возвращается как *http.MaxBytesError после сохранения принятых байтов.
+// Тело читается, пока жив запрос ctx, а запись в хранилище не зависит от его отмены.
+func (h *Handler) writeChunk(ctx context.Context, upload *config.TusUpload, body io.Reader) error {
+    mp, err := h.multipart()
+    if err != nil {
+        return err
+    }
+
+    buf := make([]byte, partSize)
+    filled := 0
+    hadTail := upload.TailSize > 0
+    if hadTail {
+        tail, _, err := h.store.Get(ctx, upload.Bucket, tailKey(upload))
+        if err != nil {
+            return err
+        }
+        _, err = io.ReadFull(tail, buf[:upload.TailSize])
+        tail.Close()
+        if err != nil {
+            return err
+        }
+        filled = int(upload.TailSize)
+    }
+
+    partsSize := upload.Offset - upload.TailSize
+    received := 0
+    var tooLarge *http.MaxBytesError
+    for {
+        n, readErr := io.ReadFull(body, buf[filled:])
+        filled += n
+        received += n
+
+        last := partsSize+int64(filled) == upload.Length
+        if filled == len(buf) || (last && filled > 0) {
+            persistCtx, cancel := persistContext(ctx)
+            err := mp.PutPart(persistCtx, upload.Bucket, upload.ObjectKey, upload.MultipartID, upload.PartCount+1,
+                bytes.NewReader(buf[:filled]), int64(filled))
+            cancel()
+            if err != nil {
+                return err
+            }
+            upload.PartCount++
+            partsSize += int64(filled)
+            upload.TailSize = 0
+            upload.Offset = partsSize
+            filled = 0
+        }
+        if readErr != nil {
+            errors.As(readErr, &tooLarge)
+            break
+        }
+    }
+
+    persistCtx, cancel := persistContext(ctx)
+    defer cancel()
+    if filled > 0 && received > 0 {
+        err := h.store.Put(persistCtx, upload.Bucket, tailKey(upload), bytes.NewReader(buf[:filled]), int64(filled), "application/octet-stream")
+        if err != nil {
+            return err
+        }
+        upload.TailSize = int64(filled)
+        upload.Offset = partsSize + upload.TailSize
+    } else if hadTail && upload.TailSize == 0 {
+        // Хвост вошел в отправленную часть
+        h.store.Delete(persistCtx, upload.Bucket, tailKey(upload))
+    }
+    if tooLarge != nil {
+        return tooLarge
+    }
+    return nil
+}
+
+// saveProgress сохраняет состояние загрузки после записи данных, в том числе
+// когда клиент уже отключился
+func (h *Handler) saveProgress(ctx context.Context, upload *config.TusUpload) error {
+    persistCtx, cancel := persistContext(ctx)
+    defer cancel()
+    return h.uploads.SaveProgress(persistCtx, upload)
+}
+
+// finish собирает составную загрузку в объект, проверяет его и создает вложение.
+// Если файл не прошел проверку, загрузка помечается неудачной.
+func (h *Handler) finish(ctx context.Context, upload *config.TusUpload) error {
+    if upload.MultipartID != "" {
+        mp, err := h.multipart()
+        if err != nil {
+            return err
+        }
+        // Собранную составную загрузку нельзя собрать повторно, поэтому сборка и отметка о ней
+        // не прерываются отключением клиента. Повторная попытка после сбоя ниже начнется сразу
+        // с проверки объекта.
+        persistCtx, cancel := persistContext(ctx)
+        defer cancel()
+        if err := mp.CompleteMultipart(persistCtx, upload.Bucket, upload.ObjectKey, upload.MultipartID); err != nil {
+            return err
+        }
+        if err := h.uploads.ClearMultipart(persistCtx, upload); err != nil {
+            return err
+        }
+    }
+
+    attachment, err := h.attachments.CreateFromObject(ctx, attachments.ObjectSpec{
+        OwnerID:   upload.OwnerID,
+        Kind:      upload.Kind,
+        Bucket:    upload.Bucket,
+        ObjectKey: upload.ObjectKey,
+        FileName:  upload.FileName,
+        Size:      upload.Length,
+        // Загрузка завершается в одной транзакции с созданием вложения, поэтому
+        // повторная попытка после сбоя не создаст второе вложение для того же объекта
+        Claim: func(tx *gorm.DB, attachment *config.Attachment) error {
+            return h.uploads.CompleteTx(tx, upload, attachment.ID)
+        },
+    })
+    if err != nil {
+        if attachments.HTTPStatus(err) != http.StatusInternalServerError {
+            h.uploads.Fail(ctx, upload)
+        }
+        return err
+    }
+
+    upload.Status = config.UploadCompleted
+    upload.AttachmentID = attachment.ID
+    return nil
+}
+
+// discard удаляет данные незавершенной загрузки из хранилища и запись о ней
+func (h *Handler) discard(ctx context.Context, upload *config.TusUpload) error {
+    if upload.Status != config.UploadCompleted {
+        if upload.MultipartID != "" {
+            mp, err := h.multipart()
+            if err != nil {
+                return err
+            }
+            if err := mp.AbortMultipart(ctx, upload.Bucket, upload.ObjectKey, upload.MultipartID); err != nil {
+                return err
+            }
+        }
+        if upload.TailSize > 0 {
+            if err := h.store.Delete(ctx, upload.Bucket, tailKey(upload)); err != nil {
+                return err
+            }
+        }
+        // Объект мог быть собран, но не стать вложением
+        if err := h.store.Delete(ctx, upload.Bucket, upload.ObjectKey); err != nil {
+            return err
+        }
+    }
+    return h.uploads.Delete(ctx, upload)
+}
+
+// CleanupExpired удаляет загрузки с истекшим сроком. Данные незавершенных загрузок удаляются
+// из хранилища, у завершенных удаляется только запись: файл уже принадлежит вложению.
+func (h *Handler) CleanupExpired(ctx context.Context) error {
+    uploads, err := h.uploads.Expired(ctx, time.Now())
+    if err != nil {
+        return err
+    }
+    for i := range uploads {
+        token, ok := lock(ctx, uploads[i].ID)
+        if !ok {
+            continue
+        }
+        err := h.discard(ctx, &uploads[i])
+        unlock(ctx, uploads[i].ID, token)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// RegisterJobs регистрирует и планирует ежечасную очистку просроченных загрузок
+func (h *Handler) RegisterJobs() {
+    jobs.Register(CleanupJobType, func(ctx context.Context, job *config.Job) error {
+        return h.CleanupExpired(ctx)
+    }, jobs.Options{MaxAttempts: 1})
+    jobs.Schedule(CleanupJobType, time.Hour, nil)
+}
+
+func lockKey(id string) string {
+    return "tus:lock:" + id
+}
+
+// lock захватывает блокировку загрузки в кэше и возвращает токен владельца блокировки;
+// false — загрузку уже обрабатывает другой запрос
+func lock(ctx context.Context, id string) (string, bool) {
+    token := uuid.New().String()
+    ok, err := cache.Default.SetNX(ctx, lockKey(id), []byte(token), lockTTL)
+    if err != nil {
+        slog.Error("Ошибка блокировки загрузки", "upload_id", id, "error", err)
+        return "", false
+    }
+    return token, ok
+}
+
+// unlock освобождает блокировку и тогда, когда клиент уже отключился: иначе загрузку
+// нельзя было бы продолжить до истечения lockTTL. Блокировка удаляется, только если ее
+// держит токен token: после истечения lockTTL ее мог захватить другой запрос.
+func unlock(ctx context.Context, id, token string) {
+    released, err := cache.Default.DeleteIfEquals(context.WithoutCancel(ctx), lockKey(id), []byte(token))
+    if err != nil {
+        slog.Error("Ошибка снятия блокировки загрузки", "upload_id", id, "error", err)
+        return
+    }
+    if !released {
+        slog.Warn("Блокировка загрузки истекла до завершения запроса", "upload_id", id)
+    }
+}
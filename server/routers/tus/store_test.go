@@ -0,0 +1,38 @@
+package tus
+
+import (
+    "context"
+    "testing"
+
+    "chatter-hub-server/cache"
+)
+
+func TestUnlockKeepsForeignLock(t *testing.T) {
+    cache.Default = cache.NewMemory()
+    ctx := context.Background()
+
+    stale, ok := lock(ctx, "upload")
+    if !ok {
+        t.Fatal("блокировка не захвачена")
+    }
+    if _, ok := lock(ctx, "upload"); ok {
+        t.Fatal("блокировка захвачена повторно")
+    }
+
+    // Блокировка истекла, пока первый запрос еще работал, и ее захватил второй
+    cache.Default.Delete(ctx, lockKey("upload"))
+    current, ok := lock(ctx, "upload")
+    if !ok {
+        t.Fatal("блокировка не захвачена после истечения")
+    }
+
+    unlock(ctx, "upload", stale)
+    if _, ok := lock(ctx, "upload"); ok {
+        t.Fatal("первый запрос снял чужую блокировку")
+    }
+
+    unlock(ctx, "upload", current)
+    if _, ok := lock(ctx, "upload"); !ok {
+        t.Fatal("блокировка не снята владельцем")
+    }
+}
@@ -0,0 +1,363 @@
+package tus
+
+import (
+    "context"
+    "encoding/base64"
+    "errors"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "chatter-hub-server/attachments"
+    "chatter-hub-server/config"
+    "chatter-hub-server/repository"
+    "chatter-hub-server/storage"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+)
+
+// Поддерживаемая версия протокола tus и расширения
+const (
+    Version    = "1.0.0"
+    Extensions = "creation,termination,expiration"
+)
+
+// uploadTTL — время, в течение которого незавершенную загрузку можно продолжить
+const uploadTTL = 24 * time.Hour
+
+// AttachmentHeader содержит ID вложения, созданного после получения последнего байта загрузки
+const AttachmentHeader = "X-Attachment-Id"
+
+// Handler обрабатывает запросы протокола tus
+type Handler struct {
+    uploads     repository.TusUploadRepository
+    store       storage.BlobStore
+    attachments *attachments.Service
+}
+
+// NewHandler создает обработчики возобновляемых загрузок. Хранилище store должно
+// поддерживать составные загрузки, иначе создание загрузки вернет 501.
+func NewHandler(uploads repository.TusUploadRepository, store storage.BlobStore, attachmentService *attachments.Service) *Handler {
+    return &Handler{uploads: uploads, store: store, attachments: attachmentService}
+}
+
+// Middleware проверяет версию протокола в запросе и добавляет заголовок Tus-Resumable в ответ
+func Middleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        c.Header("Tus-Resumable", Version)
+        if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != Version {
+            c.Header("Tus-Version", Version)
+            c.JSON(http.StatusPreconditionFailed, config.ErrorResponse{Error: "Неподдерживаемая версия протокола tus"})
+            c.Abort()
+            return
+        }
+        c.Next()
+    }
+}
+
+// Options godoc
+//	@Summary		Возможности сервера tus
+//	@Description	Возвращает версию протокола tus, поддерживаемые расширения и максимальный размер загрузки
+//	@Tags			tus
+//	@Success		204
+//	@Router			/uploads/tus [options]
+func Options(c *gin.Context) {
+    c.Header("Tus-Version", Version)
+    c.Header("Tus-Extension", Extensions)
+    c.Header("Tus-Max-Size", strconv.FormatInt(attachments.MaxUploadSize(), 10))
+    c.Status(http.StatusNoContent)
+}
+
+// CreateUpload godoc
+//	@Summary		Создание возобновляемой загрузки
+//	@Description	Создает загрузку по протоколу tus. В Upload-Metadata передаются filename, filetype и необязательный kind (image, video, audio, document) в base64. Адрес загрузки возвращается в заголовке Location.
+//	@Tags			tus
+//	@Param			Tus-Resumable	header	string	true	"Версия протокола"	default(1.0.0)
+//	@Param			Upload-Length	header	int		true	"Размер файла в байтах"
+//	@Param			Upload-Metadata	header	string	false	"Метаданные файла"
+//	@Success		201
+//	@Failure		400	{object}	config.ErrorResponse
+//	@Failure		412	{object}	config.ErrorResponse
+//	@Failure		413	{object}	config.ErrorResponse
+//	@Failure		415	{object}	config.ErrorResponse
+//	@Failure		500	{object}	config.ErrorResponse
+//	@Failure		501	{object}	config.ErrorResponse
+//	@Router			/uploads/tus [post]
+func (h *Handler) CreateUpload(c *gin.Context) {
+    if c.GetHeader("Upload-Defer-Length") != "" {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Отложенное указание размера не поддерживается"})
+        return
+    }
+    length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+    if err != nil || length < 0 {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Некорректный заголовок Upload-Length"})
+        return
+    }
+    metadata, err := parseMetadata(c.GetHeader("Upload-Metadata"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Некорректный заголовок Upload-Metadata"})
+        return
+    }
+
+    contentType := metadata["filetype"]
+    if contentType == "" {
+        contentType = "application/octet-stream"
+    }
+    kind := metadata["kind"]
+    if kind == "" {
+        kind = attachments.KindOf(contentType)
+    }
+    switch kind {
+    case config.AttachmentImage, config.AttachmentVideo, config.AttachmentAudio, config.AttachmentDocument:
+    default:
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Некорректный тип вложения"})
+        return
+    }
+    ownerID := c.GetString("userID")
+    if err := h.attachments.CheckDeclared(c.Request.Context(), ownerID, kind, contentType, length); err != nil {
+        status := attachments.HTTPStatus(err)
+        if status == http.StatusInternalServerError {
+            c.JSON(status, config.ErrorResponse{Error: "Ошибка создания загрузки"})
+            return
+        }
+        c.JSON(status, config.ErrorResponse{Error: err.Error()})
+        return
+    }
+
+    upload := config.TusUpload{
+        ID:          uuid.New().String(),
+        OwnerID:     ownerID,
+        Kind:        kind,
+        Bucket:      attachments.BucketFor(kind),
+        FileName:    metadata["filename"],
+        ContentType: contentType,
+        Length:      length,
+        Status:      config.UploadPending,
+        ExpiresAt:   time.Now().Add(uploadTTL),
+        CreatedAt:   time.Now(),
+    }
+    upload.ObjectKey = ownerID + "/" + upload.ID
+
+    mp, err := h.multipart()
+    if err != nil {
+        c.JSON(http.StatusNotImplemented, config.ErrorResponse{Error: err.Error()})
+        return
+    }
+    upload.MultipartID, err = mp.CreateMultipart(c.Request.Context(), upload.Bucket, upload.ObjectKey, contentType)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка создания загрузки"})
+        return
+    }
+    if err := h.uploads.Create(c.Request.Context(), &upload); err != nil {
+        mp.AbortMultipart(context.WithoutCancel(c.Request.Context()), upload.Bucket, upload.ObjectKey, upload.MultipartID)
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка создания загрузки"})
+        return
+    }
+
+    c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+upload.ID)
+    c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+    c.Status(http.StatusCreated)
+}
+
+// GetOffset godoc
+//	@Summary		Состояние возобновляемой загрузки
+//	@Description	Возвращает количество уже полученных байтов в заголовке Upload-Offset. Для завершенной загрузки в заголовке X-Attachment-Id возвращается ID вложения.
+//	@Tags			tus
+//	@Param			id				path	string	true	"ID загрузки"
+//	@Param			Tus-Resumable	header	string	true	"Версия протокола"	default(1.0.0)
+//	@Success		200
+//	@Failure		404	{object}	config.ErrorResponse
+//	@Failure		410	{object}	config.ErrorResponse
+//	@Router			/uploads/tus/{id} [head]
+func (h *Handler) GetOffset(c *gin.Context) {
+    upload, ok := h.findUpload(c)
+    if !ok {
+        return
+    }
+
+    c.Header("Cache-Control", "no-store")
+    c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+    c.Header("Upload-Length", strconv.FormatInt(upload.Length, 10))
+    setStateHeaders(c, upload)
+    c.Status(http.StatusOK)
+}
+
+// PatchUpload godoc
+//	@Summary		Передача части файла
+//	@Description	Дописывает данные к загрузке начиная с Upload-Offset. После получения последнего байта файл проверяется и становится вложением, ID которого возвращается в заголовке X-Attachment-Id.
+//	@Tags			tus
+//	@Accept			application/offset+octet-stream
+//	@Param			id				path	string	true	"ID загрузки"
+//	@Param			Tus-Resumable	header	string	true	"Версия протокола"	default(1.0.0)
+//	@Param			Upload-Offset	header	int		true	"Смещение передаваемых данных"
+//	@Success		204
+//	@Failure		400	{object}	config.ErrorResponse
+//	@Failure		404	{object}	config.ErrorResponse
+//	@Failure		409	{object}	config.ErrorResponse
+//	@Failure		410	{object}	config.ErrorResponse
+//	@Failure		413	{object}	config.ErrorResponse
+//	@Failure		415	{object}	config.ErrorResponse
+//	@Failure		423	{object}	config.ErrorResponse
+//	@Failure		500	{object}	config.ErrorResponse
+//	@Router			/uploads/tus/{id} [patch]
+func (h *Handler) PatchUpload(c *gin.Context) {
+    if c.ContentType() != "application/offset+octet-stream" {
+        c.JSON(http.StatusUnsupportedMediaType, config.ErrorResponse{Error: "Ожидается Content-Type application/offset+octet-stream"})
+        return
+    }
+    offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+    if err != nil || offset < 0 {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Некорректный заголовок Upload-Offset"})
+        return
+    }
+
+    // Параллельные запросы к одной загрузке записали бы одну и ту же часть
+    token, ok := lock(c.Request.Context(), c.Param("id"))
+    if !ok {
+        c.JSON(http.StatusLocked, config.ErrorResponse{Error: "Загрузка уже обрабатывается другим запросом"})
+        return
+    }
+    defer unlock(c.Request.Context(), c.Param("id"), token)
+
+    upload, ok := h.findUpload(c)
+    if !ok {
+        return
+    }
+    if upload.Status != config.UploadPending || offset != upload.Offset {
+        c.JSON(http.StatusConflict, config.ErrorResponse{Error: "Смещение не совпадает с состоянием загрузки"})
+        return
+    }
+    remaining := upload.Length - upload.Offset
+    if c.Request.ContentLength > remaining {
+        c.JSON(http.StatusRequestEntityTooLarge, config.ErrorResponse{Error: "Данные превышают заявленный размер файла"})
+        return
+    }
+
+    err = h.writeChunk(c.Request.Context(), upload, http.MaxBytesReader(c.Writer, c.Request.Body, remaining))
+    // Прогресс сохраняем и при ошибке: части, записанные до нее, уже в хранилище
+    if saveErr := h.saveProgress(c.Request.Context(), upload); err == nil {
+        err = saveErr
+    }
+    if err != nil {
+        // Тело без Content-Length оказалось длиннее оставшейся части файла
+        var tooLarge *http.MaxBytesError
+        if errors.As(err, &tooLarge) {
+            c.JSON(http.StatusRequestEntityTooLarge, config.ErrorResponse{Error: "Данные превышают заявленный размер файла"})
+            return
+        }
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка сохранения данных"})
+        return
+    }
+
+    if upload.Offset == upload.Length {
+        if err := h.finish(c.Request.Context(), upload); err != nil {
+            status := attachments.HTTPStatus(err)
+            if status == http.StatusInternalServerError {
+                c.JSON(status, config.ErrorResponse{Error: "Ошибка проверки файла"})
+                return
+            }
+            c.JSON(status, config.ErrorResponse{Error: err.Error()})
+            return
+        }
+    }
+
+    c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+    setStateHeaders(c, upload)
+    c.Status(http.StatusNoContent)
+}
+
+// TerminateUpload godoc
+//	@Summary		Отмена возобновляемой загрузки
+//	@Description	Отменяет незавершенную загрузку и удаляет полученные данные
+//	@Tags			tus
+//	@Param			id				path	string	true	"ID загрузки"
+//	@Param			Tus-Resumable	header	string	true	"Версия протокола"	default(1.0.0)
+//	@Success		204
+//	@Failure		404	{object}	config.ErrorResponse
+//	@Failure		409	{object}	config.ErrorResponse
+//	@Failure		423	{object}	config.ErrorResponse
+//	@Failure		500	{object}	config.ErrorResponse
+//	@Router			/uploads/tus/{id} [delete]
+func (h *Handler) TerminateUpload(c *gin.Context) {
+    token, ok := lock(c.Request.Context(), c.Param("id"))
+    if !ok {
+        c.JSON(http.StatusLocked, config.ErrorResponse{Error: "Загрузка уже обрабатывается другим запросом"})
+        return
+    }
+    defer unlock(c.Request.Context(), c.Param("id"), token)
+
+    upload, err := h.uploads.GetOwned(c.Request.Context(), c.Param("id"), c.GetString("userID"))
+    if errors.Is(err, repository.ErrNotFound) {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Загрузка не найдена"})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отмены загрузки"})
+        return
+    }
+    if upload.Status == config.UploadCompleted {
+        c.JSON(http.StatusConflict, config.ErrorResponse{Error: "Загрузка завершена, файл уже стал вложением"})
+        return
+    }
+
+    if err := h.discard(c.Request.Context(), upload); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отмены загрузки"})
+        return
+    }
+    c.Status(http.StatusNoContent)
+}
+
+// findUpload находит загрузку текущего пользователя по ID из пути.
+// При ошибке сам отправляет ответ клиенту.
+func (h *Handler) findUpload(c *gin.Context) (*config.TusUpload, bool) {
+    upload, err := h.uploads.GetOwned(c.Request.Context(), c.Param("id"), c.GetString("userID"))
+    if errors.Is(err, repository.ErrNotFound) {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Загрузка не найдена"})
+        return nil, false
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения загрузки"})
+        return nil, false
+    }
+    if upload.Status == config.UploadPending && time.Now().After(upload.ExpiresAt) {
+        c.JSON(http.StatusGone, config.ErrorResponse{Error: "Срок загрузки истек"})
+        return nil, false
+    }
+    return upload, true
+}
+
+// setStateHeaders добавляет срок действия незавершенной загрузки или ID созданного вложения
+func setStateHeaders(c *gin.Context, upload *config.TusUpload) {
+    if upload.Status == config.UploadPending {
+        c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+    }
+    if upload.AttachmentID != "" {
+        c.Header(AttachmentHeader, upload.AttachmentID)
+    }
+}
+
+// parseMetadata разбирает заголовок Upload-Metadata: пары "ключ значение_в_base64" через запятую
+func parseMetadata(header string) (map[string]string, error) {
+    metadata := make(map[string]string)
+    if strings.TrimSpace(header) == "" {
+        return metadata, nil
+    }
+    for _, pair := range strings.Split(header, ",") {
+        fields := strings.Fields(pair)
+        switch len(fields) {
+        case 1:
+            metadata[fields[0]] = ""
+        case 2:
+            value, err := base64.StdEncoding.DecodeString(fields[1])
+            if err != nil {
+                return nil, err
+            }
+            metadata[fields[0]] = string(value)
+        default:
+            return nil, errors.New("некорректная пара метаданных")
+        }
+    }
+    return metadata, nil
+}
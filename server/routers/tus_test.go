@@ -0,0 +1,138 @@
+package routers_test
+
+import (
+    "bytes"
+    "context"
+    "encoding/base64"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "strconv"
+    "testing"
+
+    "chatter-hub-server/routers/tus"
+)
+
+// createTusUpload создает загрузку текстового файла размером length и возвращает ее адрес
+func createTusUpload(t *testing.T, user testUser, length int) string {
+    t.Helper()
+    req := httptest.NewRequest(http.MethodPost, "/uploads/tus", nil)
+    req.Header.Set("Tus-Resumable", tus.Version)
+    req.Header.Set("Upload-Length", strconv.Itoa(length))
+    req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("notes.txt"))+
+        ",filetype "+base64.StdEncoding.EncodeToString([]byte("text/plain")))
+    w := serve(req, user.Token)
+    expectStatus(t, w, http.StatusCreated)
+    return w.Header().Get("Location")
+}
+
+// patchTus отправляет данные body начиная со смещения offset
+func patchTus(ctx context.Context, location, token string, offset int, body io.Reader) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(http.MethodPatch, location, body).WithContext(ctx)
+    req.Header.Set("Tus-Resumable", tus.Version)
+    req.Header.Set("Content-Type", "application/offset+octet-stream")
+    req.Header.Set("Upload-Offset", strconv.Itoa(offset))
+    return serve(req, token)
+}
+
+// tusOffset возвращает количество байтов, которые сервер считает полученными
+func tusOffset(t *testing.T, location, token string) int {
+    t.Helper()
+    req := httptest.NewRequest(http.MethodHead, location, nil)
+    req.Header.Set("Tus-Resumable", tus.Version)
+    w := serve(req, token)
+    expectStatus(t, w, http.StatusOK)
+    offset, err := strconv.Atoi(w.Header().Get("Upload-Offset"))
+    if err != nil {
+        t.Fatalf("некорректный Upload-Offset %q", w.Header().Get("Upload-Offset"))
+    }
+    return offset
+}
+
+// disconnectingReader отдает данные, затем отменяет запрос и возвращает ошибку,
+// как при обрыве соединения клиентом
+type disconnectingReader struct {
+    data   io.Reader
+    cancel context.CancelFunc
+}
+
+func (r *disconnectingReader) Read(p []byte) (int, error) {
+    n, err := r.data.Read(p)
+    if err == io.EOF {
+        r.cancel()
+        return n, io.ErrUnexpectedEOF
+    }
+    return n, err
+}
+
+func TestTusUpload(t *testing.T) {
+    user := registerUser(t)
+    content := bytes.Repeat([]byte("tus "), 1000)
+    location := createTusUpload(t, user, len(content))
+
+    half := len(content) / 2
+    w := patchTus(context.Background(), location, user.Token, 0, bytes.NewReader(content[:half]))
+    expectStatus(t, w, http.StatusNoContent)
+    if offset := tusOffset(t, location, user.Token); offset != half {
+        t.Fatalf("смещение %d, ожидалось %d", offset, half)
+    }
+
+    // Повтор с устаревшим смещением не дописывает данные второй раз
+    w = patchTus(context.Background(), location, user.Token, 0, bytes.NewReader(content[:half]))
+    expectStatus(t, w, http.StatusConflict)
+
+    w = patchTus(context.Background(), location, user.Token, half, bytes.NewReader(content[half:]))
+    expectStatus(t, w, http.StatusNoContent)
+    if w.Header().Get(tus.AttachmentHeader) == "" {
+        t.Fatalf("после последнего байта не вернулся ID вложения")
+    }
+}
+
+func TestTusPatchDisconnect(t *testing.T) {
+    user := registerUser(t)
+    // Больше одной части составной загрузки, чтобы при обрыве были и отправленная часть, и хвост
+    content := bytes.Repeat([]byte("disconnect "), 600_000)
+    location := createTusUpload(t, user, len(content))
+
+    sent := len(content) - 100_000
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    patchTus(ctx, location, user.Token, 0, &disconnectingReader{data: bytes.NewReader(content[:sent]), cancel: cancel})
+
+    // Байты, полученные до обрыва, сохранены, хотя контекст запроса отменен
+    offset := tusOffset(t, location, user.Token)
+    if offset != sent {
+        t.Fatalf("смещение после обрыва %d, ожидалось %d", offset, sent)
+    }
+
+    w := patchTus(context.Background(), location, user.Token, offset, bytes.NewReader(content[offset:]))
+    expectStatus(t, w, http.StatusNoContent)
+    if w.Header().Get(tus.AttachmentHeader) == "" {
+        t.Fatalf("после продолжения загрузки не вернулся ID вложения")
+    }
+}
+
+func TestTusPatchTooLarge(t *testing.T) {
+    user := registerUser(t)
+    content := bytes.Repeat([]byte("large "), 100)
+    location := createTusUpload(t, user, len(content))
+
+    // Тело без Content-Length: превышение размера обнаруживается только при чтении
+    body := io.MultiReader(bytes.NewReader(content), bytes.NewReader([]byte("лишние данные")))
+    w := patchTus(context.Background(), location, user.Token, 0, body)
+    expectStatus(t, w, http.StatusRequestEntityTooLarge)
+    if w.Header().Get(tus.AttachmentHeader) != "" {
+        t.Fatalf("загрузка с лишними данными стала вложением")
+    }
+
+    // Принятая часть сохранена, загрузку можно завершить пустым запросом
+    offset := tusOffset(t, location, user.Token)
+    if offset != len(content) {
+        t.Fatalf("смещение %d, ожидалось %d", offset, len(content))
+    }
+    w = patchTus(context.Background(), location, user.Token, offset, bytes.NewReader(nil))
+    expectStatus(t, w, http.StatusNoContent)
+    if w.Header().Get(tus.AttachmentHeader) == "" {
+        t.Fatalf("после завершения загрузки не вернулся ID вложения")
+    }
+}
@@ -0,0 +1,41 @@
+package uploads
+
+import (
+    "context"
+    "errors"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/jobs"
+    "chatter-hub-server/storage"
+)
+
+// CleanupJobType — тип фоновой задачи очистки неподтвержденных загрузок
+const CleanupJobType = "uploads.cleanup"
+
+// RegisterJobs регистрирует и планирует ежечасную очистку неподтвержденных загрузок
+func (h *Handler) RegisterJobs() {
+    jobs.Register(CleanupJobType, func(ctx context.Context, job *config.Job) error {
+        return h.CleanupExpired(ctx)
+    }, jobs.Options{MaxAttempts: 1})
+    jobs.Schedule(CleanupJobType, time.Hour, nil)
+}
+
+// CleanupExpired удаляет из хранилища файлы загрузок, которые не были подтверждены
+// до истечения срока, и помечает такие загрузки как неудачные
+func (h *Handler) CleanupExpired(ctx context.Context) error {
+    uploads, err := h.uploads.Expired(ctx, time.Now())
+    if err != nil {
+        return err
+    }
+    for _, upload := range uploads {
+        err := h.store.Delete(ctx, upload.Bucket, upload.ObjectKey)
+        if err != nil && !errors.Is(err, storage.ErrNotFound) {
+            return err
+        }
+        if err := h.uploads.Fail(ctx, upload.ID); err != nil {
+            return err
+        }
+    }
+    return nil
+}
@@ -0,0 +1,201 @@
+package uploads
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "regexp"
+    "strconv"
+    "time"
+
+    "chatter-hub-server/attachments"
+    "chatter-hub-server/config"
+    "chatter-hub-server/repository"
+    "chatter-hub-server/storage"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+    "gorm.io/gorm"
+)
+
+// Handler обрабатывает запросы к прямым загрузкам файлов
+type Handler struct {
+    uploads     repository.UploadRepository
+    store       storage.BlobStore
+    attachments *attachments.Service
+}
+
+// NewHandler создает обработчики прямых загрузок
+func NewHandler(uploads repository.UploadRepository, store storage.BlobStore, attachmentService *attachments.Service) *Handler {
+    return &Handler{uploads: uploads, store: store, attachments: attachmentService}
+}
+
+// uploadTTL — время, в течение которого клиент должен загрузить файл и подтвердить загрузку
+const uploadTTL = 15 * time.Minute
+
+var checksumPattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// CreateUploadRequest представляет запрос на прямую загрузку файла
+type CreateUploadRequest struct {
+    FileName    string `json:"file_name" binding:"required" example:"voice.ogg"`
+    Kind        string `json:"kind" binding:"required,oneof=image video audio document" example:"audio"`
+    ContentType string `json:"content_type" binding:"required" example:"audio/ogg"`
+    Size        int64  `json:"size" binding:"required" example:"48213"`
+    // SHA-256 содержимого в шестнадцатеричном виде; если указан, сверяется при подтверждении
+    Checksum string `json:"checksum,omitempty"`
+}
+
+// CreateUploadResponse содержит подписанную ссылку для загрузки файла
+type CreateUploadResponse struct {
+    UploadID  string `json:"upload_id"`
+    UploadURL string `json:"upload_url"`
+    Method    string `json:"method" example:"PUT"`
+    // Заголовки, с которыми нужно отправить файл: они входят в подпись ссылки
+    Headers   map[string]string `json:"headers"`
+    MaxSize   int64             `json:"max_size"`
+    ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// CreateUpload godoc
+//	@Summary		Начало прямой загрузки файла
+//	@Description	Проверяет заявленные тип и размер файла и возвращает подписанную ссылку, по которой клиент загружает файл в хранилище запросом PUT с заголовками из ответа: хранилище примет только файл заявленного размера и типа. После загрузки ее нужно подтвердить через /uploads/{id}/complete.
+//	@Tags			uploads
+//	@Accept			json
+//	@Produce		json
+//	@Param			request	body		CreateUploadRequest	true	"Параметры файла"
+//	@Success		201		{object}	CreateUploadResponse
+//	@Failure		400		{object}	config.ErrorResponse
+//	@Failure		413		{object}	config.ErrorResponse
+//	@Failure		415		{object}	config.ErrorResponse
+//	@Failure		500		{object}	config.ErrorResponse
+//	@Router			/uploads [post]
+func (h *Handler) CreateUpload(c *gin.Context) {
+    var req CreateUploadRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: err.Error()})
+        return
+    }
+    if req.Checksum != "" && !checksumPattern.MatchString(req.Checksum) {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Контрольная сумма должна быть SHA-256 в шестнадцатеричном виде"})
+        return
+    }
+    ownerID := c.GetString("userID")
+    if err := h.attachments.CheckDeclared(c.Request.Context(), ownerID, req.Kind, req.ContentType, req.Size); err != nil {
+        status := attachments.HTTPStatus(err)
+        if status == http.StatusInternalServerError {
+            c.JSON(status, config.ErrorResponse{Error: "Ошибка создания загрузки"})
+            return
+        }
+        c.JSON(status, config.ErrorResponse{Error: err.Error()})
+        return
+    }
+
+    upload := config.Upload{
+        ID:          uuid.New().String(),
+        OwnerID:     ownerID,
+        Kind:        req.Kind,
+        Bucket:      attachments.BucketFor(req.Kind),
+        FileName:    req.FileName,
+        ContentType: req.ContentType,
+        Size:        req.Size,
+        Checksum:    req.Checksum,
+        Status:      config.UploadPending,
+        ExpiresAt:   time.Now().Add(uploadTTL),
+        CreatedAt:   time.Now(),
+    }
+    upload.ObjectKey = ownerID + "/" + upload.ID
+
+    uploadURL, err := h.store.PresignPut(c.Request.Context(), upload.Bucket, upload.ObjectKey, uploadTTL, upload.Size, upload.ContentType)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка создания загрузки"})
+        return
+    }
+    if err := h.uploads.Create(c.Request.Context(), &upload); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка создания загрузки"})
+        return
+    }
+
+    c.JSON(http.StatusCreated, CreateUploadResponse{
+        UploadID:  upload.ID,
+        UploadURL: uploadURL,
+        Method:    http.MethodPut,
+        Headers: map[string]string{
+            "Content-Type":   upload.ContentType,
+            "Content-Length": strconv.FormatInt(upload.Size, 10),
+        },
+        MaxSize:   attachments.MaxSize(upload.Kind),
+        ExpiresAt: upload.ExpiresAt,
+    })
+}
+
+// CompleteUpload godoc
+//	@Summary		Подтверждение прямой загрузки файла
+//	@Description	Проверяет загруженный файл (размер, контрольную сумму и тип по содержимому) и создает вложение, на которое могут ссылаться сообщения. Если проверка не пройдена, файл удаляется.
+//	@Tags			uploads
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		string	true	"ID загрузки"
+//	@Success		201	{object}	config.Attachment
+//	@Failure		400	{object}	config.ErrorResponse
+//	@Failure		404	{object}	config.ErrorResponse
+//	@Failure		409	{object}	config.ErrorResponse
+//	@Failure		410	{object}	config.ErrorResponse
+//	@Failure		413	{object}	config.ErrorResponse
+//	@Failure		415	{object}	config.ErrorResponse
+//	@Failure		500	{object}	config.ErrorResponse
+//	@Router			/uploads/{id}/complete [post]
+func (h *Handler) CompleteUpload(c *gin.Context) {
+    upload, err := h.uploads.GetOwned(c.Request.Context(), c.Param("id"), c.GetString("userID"))
+    if errors.Is(err, repository.ErrNotFound) {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Загрузка не найдена"})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка проверки файла"})
+        return
+    }
+    if upload.Status != config.UploadPending {
+        c.JSON(http.StatusConflict, config.ErrorResponse{Error: "Загрузка уже завершена"})
+        return
+    }
+    if time.Now().After(upload.ExpiresAt) {
+        c.JSON(http.StatusGone, config.ErrorResponse{Error: "Срок загрузки истек"})
+        return
+    }
+
+    attachment, err := h.attachments.CreateFromObject(c.Request.Context(), attachments.ObjectSpec{
+        OwnerID:   upload.OwnerID,
+        Kind:      upload.Kind,
+        Bucket:    upload.Bucket,
+        ObjectKey: upload.ObjectKey,
+        FileName:  upload.FileName,
+        Size:      upload.Size,
+        Checksum:  upload.Checksum,
+        // Загрузка отмечается завершенной в транзакции создания вложения. Условие на статус
+        // защищает от повторного подтверждения параллельным запросом: проигравший запрос откатывает
+        // свое вложение вместе с учетом в квоте и задачами обработки.
+        Claim: func(tx *gorm.DB, attachment *config.Attachment) error {
+            return h.uploads.CompleteTx(tx, upload.ID, attachment.ID)
+        },
+    })
+    if errors.Is(err, repository.ErrUploadCompleted) {
+        c.JSON(http.StatusConflict, config.ErrorResponse{Error: "Загрузка уже завершена"})
+        return
+    }
+    if err != nil {
+        status := attachments.HTTPStatus(err)
+        if status == http.StatusInternalServerError {
+            c.JSON(status, config.ErrorResponse{Error: "Ошибка проверки файла"})
+            return
+        }
+        // Файл не загружен — клиент может повторить загрузку по той же ссылке.
+        // Остальные ошибки окончательные: объект уже удален.
+        if !errors.Is(err, attachments.ErrObjectMissing) {
+            h.uploads.Fail(context.WithoutCancel(c.Request.Context()), upload.ID)
+        }
+        c.JSON(status, config.ErrorResponse{Error: err.Error()})
+        return
+    }
+
+    c.JSON(http.StatusCreated, attachment)
+}
@@ -0,0 +1,109 @@
+package routers_test
+
+import (
+    "bytes"
+    "net/http"
+    "net/http/httptest"
+    "net/url"
+    "strconv"
+    "sync"
+    "testing"
+)
+
+// createDirectUpload начинает прямую загрузку текстового документа и возвращает ее ID и ссылку
+func createDirectUpload(t *testing.T, user testUser, content []byte) (string, *url.URL, map[string]string) {
+    t.Helper()
+    w := request(t, http.MethodPost, "/uploads", user.Token, map[string]interface{}{
+        "file_name":    "notes.txt",
+        "kind":         "document",
+        "content_type": "text/plain",
+        "size":         len(content),
+    })
+    expectStatus(t, w, http.StatusCreated)
+    var response struct {
+        UploadID  string            `json:"upload_id"`
+        UploadURL string            `json:"upload_url"`
+        Headers   map[string]string `json:"headers"`
+    }
+    decode(t, w, &response)
+    uploadURL, err := url.Parse(response.UploadURL)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return response.UploadID, uploadURL, response.Headers
+}
+
+// putObject отправляет файл по подписанной ссылке
+func putObject(uploadURL *url.URL, contentType string, content []byte) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(http.MethodPut, uploadURL.RequestURI(), bytes.NewReader(content))
+    req.Header.Set("Content-Type", contentType)
+    return serve(req, "")
+}
+
+func usedBytes(t *testing.T, user testUser) int64 {
+    t.Helper()
+    w := request(t, http.MethodGet, "/me/storage", user.Token, nil)
+    expectStatus(t, w, http.StatusOK)
+    var usage struct {
+        UsedBytes int64 `json:"used_bytes"`
+    }
+    decode(t, w, &usage)
+    return usage.UsedBytes
+}
+
+func TestDirectUploadSignedHeaders(t *testing.T) {
+    user := registerUser(t)
+    content := []byte("Список покупок: хлеб, молоко, сыр.")
+    _, uploadURL, headers := createDirectUpload(t, user, content)
+
+    if headers["Content-Type"] != "text/plain" || headers["Content-Length"] != strconv.Itoa(len(content)) {
+        t.Fatalf("заголовки загрузки %v", headers)
+    }
+    t.Run("другой размер", func(t *testing.T) {
+        expectStatus(t, putObject(uploadURL, "text/plain", append(content, content...)), http.StatusForbidden)
+    })
+    t.Run("другой тип", func(t *testing.T) {
+        expectStatus(t, putObject(uploadURL, "image/png", content), http.StatusForbidden)
+    })
+    t.Run("заявленные размер и тип", func(t *testing.T) {
+        expectStatus(t, putObject(uploadURL, "text/plain", content), http.StatusOK)
+    })
+}
+
+func TestDirectUploadCompleteOnce(t *testing.T) {
+    user := registerUser(t)
+    content := []byte("Протокол встречи: обсудили планы на квартал.")
+    uploadID, uploadURL, _ := createDirectUpload(t, user, content)
+    expectStatus(t, putObject(uploadURL, "text/plain", content), http.StatusOK)
+
+    // Параллельные подтверждения создают одно вложение и учитывают файл в квоте один раз
+    const attempts = 20
+    codes := make([]int, attempts)
+    var wg sync.WaitGroup
+    for i := range codes {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            req := httptest.NewRequest(http.MethodPost, "/uploads/"+uploadID+"/complete", nil)
+            codes[i] = serve(req, user.Token).Code
+        }(i)
+    }
+    wg.Wait()
+
+    created := 0
+    for _, code := range codes {
+        switch code {
+        case http.StatusCreated:
+            created++
+        case http.StatusConflict:
+        default:
+            t.Errorf("статус подтверждения %d", code)
+        }
+    }
+    if created != 1 {
+        t.Fatalf("создано %d вложений, ожидалось 1: %v", created, codes)
+    }
+    if used := usedBytes(t, user); used != int64(len(content)) {
+        t.Fatalf("в квоте учтено %d байт, ожидалось %d", used, len(content))
+    }
+}
@@ -0,0 +1,217 @@
+package users
+
+import (
+    "bytes"
+    "context"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/imaging"
+    "chatter-hub-server/metrics"
+
+    "github.com/gin-gonic/gin"
+    "github.com/google/uuid"
+)
+
+const (
+    avatarMaxFileSize = 5 << 20 // Максимальный размер исходного файла аватара (5 МБ)
+    avatarJPEGQuality = 85      // Качество JPEG для миниатюр аватара
+)
+
+// UploadAvatar godoc
+// @Summary      Загрузка аватара
+// @Description  Загружает изображение (JPEG, PNG, GIF, WebP) и сохраняет его квадратные миниатюры 64, 128 и 256 пикселей
+// @Tags         users
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        id    path      string  true  "ID пользователя"
+// @Param        file  formData  file    true  "Изображение"
+// @Success      200   {object}  config.PrivateUser
+// @Failure      400   {object}  config.ErrorResponse
+// @Failure      403   {object}  config.ErrorResponse
+// @Failure      413   {object}  config.ErrorResponse
+// @Failure      500   {object}  config.ErrorResponse
+// @Router       /users/{id}/avatar [put]
+func (h *Handler) UploadAvatar(c *gin.Context) {
+    userID := c.Param("id")
+
+    // Проверяем, совпадает ли ID пользователя с тем, который в токене
+    if c.GetString("userID") != userID {
+        c.JSON(http.StatusForbidden, config.ErrorResponse{Error: "Нет прав для изменения этого пользователя"})
+        return
+    }
+
+    file, err := c.FormFile("file")
+    if err != nil {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Файл обязателен"})
+        return
+    }
+    if file.Size > avatarMaxFileSize {
+        c.JSON(http.StatusRequestEntityTooLarge, config.ErrorResponse{Error: "Размер аватара не должен превышать 5 МБ"})
+        return
+    }
+
+    fileContent, err := file.Open()
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка открытия файла"})
+        return
+    }
+    defer fileContent.Close()
+
+    // Формат определяем по содержимому файла, а не по заголовкам клиента
+    img, _, err := imaging.Decode(fileContent)
+    if errors.Is(err, imaging.ErrTooLarge) {
+        c.JSON(http.StatusRequestEntityTooLarge, config.ErrorResponse{Error: "Слишком большое разрешение изображения"})
+        return
+    }
+    if err != nil {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Файл не является изображением JPEG, PNG, GIF или WebP"})
+        return
+    }
+
+    user, err := h.accounts.Get(c.Request.Context(), userID)
+    if err != nil {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Пользователь не найден"})
+        return
+    }
+
+    // Каждая загрузка получает новый идентификатор, чтобы клиенты не видели устаревший аватар из кэша
+    avatarID := uuid.New().String()
+    for _, size := range config.AvatarSizes {
+        var buf bytes.Buffer
+        if err := imaging.EncodeJPEG(&buf, imaging.SquareThumbnail(img, size), avatarJPEGQuality); err != nil {
+            c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка обработки изображения"})
+            return
+        }
+
+        err := h.store.Put(c.Request.Context(), config.AvatarBucket, avatarObjectKey(userID, avatarID, size), &buf, int64(buf.Len()), "image/jpeg")
+        if err != nil {
+            h.removeAvatarObjects(c.Request.Context(), userID, avatarID)
+            c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка загрузки аватара"})
+            return
+        }
+    }
+
+    if err := h.accounts.Update(c.Request.Context(), userID, map[string]interface{}{"avatar_id": avatarID}); err != nil {
+        h.removeAvatarObjects(c.Request.Context(), userID, avatarID)
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка обновления пользователя"})
+        return
+    }
+
+    // Старые миниатюры больше не нужны
+    if user.AvatarID != "" {
+        h.removeAvatarObjects(c.Request.Context(), userID, user.AvatarID)
+    }
+
+    metrics.UploadBytes.WithLabelValues("avatar").Add(float64(file.Size))
+    user.AvatarID = avatarID
+    c.JSON(http.StatusOK, user.Private())
+}
+
+// DeleteAvatar godoc
+// @Summary      Удаление аватара
+// @Description  Удаляет аватар пользователя
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id    path      string  true  "ID пользователя"
+// @Success      200   {object}  config.SimpleResponse
+// @Failure      403   {object}  config.ErrorResponse
+// @Failure      500   {object}  config.ErrorResponse
+// @Router       /users/{id}/avatar [delete]
+func (h *Handler) DeleteAvatar(c *gin.Context) {
+    userID := c.Param("id")
+
+    // Проверяем, совпадает ли ID пользователя с тем, который в токене
+    if c.GetString("userID") != userID {
+        c.JSON(http.StatusForbidden, config.ErrorResponse{Error: "Нет прав для изменения этого пользователя"})
+        return
+    }
+
+    user, err := h.accounts.Get(c.Request.Context(), userID)
+    if err != nil {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Пользователь не найден"})
+        return
+    }
+
+    if user.AvatarID != "" {
+        if err := h.accounts.Update(c.Request.Context(), userID, map[string]interface{}{"avatar_id": ""}); err != nil {
+            c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка обновления пользователя"})
+            return
+        }
+        h.removeAvatarObjects(c.Request.Context(), userID, user.AvatarID)
+    }
+
+    c.JSON(http.StatusOK, config.SimpleResponse{Message: "Аватар удален"})
+}
+
+// GetAvatar godoc
+// @Summary      Получение аватара
+// @Description  Возвращает миниатюру аватара пользователя в формате JPEG
+// @Tags         users
+// @Produce      jpeg
+// @Param        id    path      string  true  "ID пользователя"
+// @Param        size  path      int     true  "Размер миниатюры (64, 128 или 256)"
+// @Success      200   {file}    binary
+// @Failure      400   {object}  config.ErrorResponse
+// @Failure      404   {object}  config.ErrorResponse
+// @Router       /users/{id}/avatar/{size} [get]
+func (h *Handler) GetAvatar(c *gin.Context) {
+    userID := c.Param("id")
+
+    size, err := strconv.Atoi(c.Param("size"))
+    if err != nil || !isAvatarSize(size) {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Недопустимый размер аватара"})
+        return
+    }
+
+    // Заблокированный пользователь не видит аватар заблокировавшего его
+    if blocked, err := h.messaging.HasBlocked(c.Request.Context(), userID, c.GetString("userID")); err != nil || blocked {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Аватар не найден"})
+        return
+    }
+
+    user, err := h.accounts.Get(c.Request.Context(), userID)
+    if err != nil || user.AvatarID == "" {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Аватар не найден"})
+        return
+    }
+
+    object, info, err := h.store.Get(c.Request.Context(), config.AvatarBucket, avatarObjectKey(userID, user.AvatarID, size))
+    if err != nil {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Аватар не найден"})
+        return
+    }
+    defer object.Close()
+
+    c.DataFromReader(http.StatusOK, info.Size, "image/jpeg", object, map[string]string{
+        "Cache-Control": "private, max-age=86400",
+    })
+}
+
+// avatarObjectKey возвращает ключ объекта в хранилище для миниатюры аватара
+func avatarObjectKey(userID, avatarID string, size int) string {
+    return fmt.Sprintf("%s/%s/%d.jpg", userID, avatarID, size)
+}
+
+// removeAvatarObjects удаляет все миниатюры набора аватара. Ошибки игнорируются:
+// оставшиеся объекты не влияют на работу сервера. Удаление не прерывается, если клиент отключился.
+func (h *Handler) removeAvatarObjects(ctx context.Context, userID, avatarID string) {
+    ctx = context.WithoutCancel(ctx)
+    for _, size := range config.AvatarSizes {
+        h.store.Delete(ctx, config.AvatarBucket, avatarObjectKey(userID, avatarID, size))
+    }
+}
+
+// isAvatarSize проверяет, что сервер генерирует миниатюры указанного размера
+func isAvatarSize(size int) bool {
+    for _, allowed := range config.AvatarSizes {
+        if allowed == size {
+            return true
+        }
+    }
+    return false
+}
@@ -0,0 +1,53 @@
+package users
+
+import (
+    "net/http"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/presence"
+
+    "github.com/gin-gonic/gin"
+)
+
+// PresenceResponse — присутствие пользователя в сети
+type PresenceResponse struct {
+    UserID string `json:"user_id" example:"12345"`
+    presence.Status
+}
+
+// GetPresence godoc
+// @Summary      Присутствие пользователя в сети
+// @Description  Возвращает, в сети ли пользователь, и время его последнего выхода из сети. Пользователь в сети, пока у него открыта подписка на уведомления (/notifications/ws). Изменения присутствия не рассылаются в уведомлениях — клиент запрашивает их сам. Заблокировавший пользователь не виден.
+// @Tags         users
+// @Produce      json
+// @Param        id   path      string  true  "ID пользователя"
+// @Success      200  {object}  PresenceResponse
+// @Failure      404  {object}  config.ErrorResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /users/{id}/presence [get]
+func (h *Handler) GetPresence(c *gin.Context) {
+    userID := c.Param("id")
+
+    if _, err := h.accounts.Profile(c.Request.Context(), userID); err != nil {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Пользователь не найден"})
+        return
+    }
+
+    // Заблокированный пользователь не видит, в сети ли заблокировавший его
+    blocked, err := h.messaging.HasBlocked(c.Request.Context(), userID, c.GetString("userID"))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения присутствия"})
+        return
+    }
+    if blocked {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Пользователь не найден"})
+        return
+    }
+
+    status, err := h.presence.Status(c.Request.Context(), userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения присутствия"})
+        return
+    }
+    c.JSON(http.StatusOK, PresenceResponse{UserID: userID, Status: status})
+}
@@ -0,0 +1,66 @@
+package users
+
+import (
+    "net/http"
+
+    "chatter-hub-server/attachments"
+    "chatter-hub-server/config"
+
+    "github.com/gin-gonic/gin"
+)
+
+// StorageUsageResponse содержит объем файлов пользователя и действующие ограничения
+type StorageUsageResponse struct {
+    UsedBytes      int64            `json:"used_bytes" example:"10485760"`
+    QuotaBytes     int64            `json:"quota_bytes" example:"1073741824"`
+    AvailableBytes int64            `json:"available_bytes" example:"1063256064"`
+    ByKind         map[string]int64 `json:"by_kind"` // Объем вложений по типам, без эскизов
+    Limits         StorageLimits    `json:"limits"`
+}
+
+// StorageLimits — ограничения на один файл
+type StorageLimits struct {
+    MaxImageSize     int64 `json:"max_image_size"`
+    MaxVideoSize     int64 `json:"max_video_size"`
+    MaxAudioSize     int64 `json:"max_audio_size"`
+    MaxDocumentSize  int64 `json:"max_document_size"`
+    MaxVoiceDuration int64 `json:"max_voice_duration"` // В секундах
+}
+
+// GetStorageUsage godoc
+// @Summary      Использование хранилища
+// @Description  Возвращает объем файлов текущего пользователя, его квоту и ограничения на размер файлов и длительность голосовых сообщений
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  StorageUsageResponse
+// @Failure      500  {object}  config.ErrorResponse
+// @Router       /me/storage [get]
+func (h *Handler) GetStorageUsage(c *gin.Context) {
+    userID := c.GetString("userID")
+
+    usage, err := h.quotas.Usage(c.Request.Context(), userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения объема хранилища"})
+        return
+    }
+
+    byKind, err := h.files.UsageByKind(c.Request.Context(), userID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения объема хранилища"})
+        return
+    }
+
+    c.JSON(http.StatusOK, StorageUsageResponse{
+        UsedBytes:      usage.Used,
+        QuotaBytes:     usage.Quota,
+        AvailableBytes: usage.Available(),
+        ByKind:         byKind,
+        Limits: StorageLimits{
+            MaxImageSize:     attachments.MaxSize(config.AttachmentImage),
+            MaxVideoSize:     attachments.MaxSize(config.AttachmentVideo),
+            MaxAudioSize:     attachments.MaxSize(config.AttachmentAudio),
+            MaxDocumentSize:  attachments.MaxSize(config.AttachmentDocument),
+            MaxVoiceDuration: int64(attachments.MaxVoiceDuration().Seconds()),
+        },
+    })
+}
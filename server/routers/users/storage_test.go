@@ -0,0 +1,106 @@
+package users
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+
+    "chatter-hub-server/quota"
+    "chatter-hub-server/repository"
+
+    "github.com/gin-gonic/gin"
+)
+
+// fakeQuotas возвращает заданный объем или ошибку
+type fakeQuotas struct {
+    usage *quota.Usage
+    err   error
+}
+
+func (f fakeQuotas) Usage(ctx context.Context, userID string) (*quota.Usage, error) {
+    return f.usage, f.err
+}
+
+// fakeFiles реализует только UsageByKind; остальные методы в этом обработчике не вызываются
+type fakeFiles struct {
+    repository.FileRepository
+    byKind map[string]int64
+    err    error
+}
+
+func (f fakeFiles) UsageByKind(ctx context.Context, ownerID string) (map[string]int64, error) {
+    return f.byKind, f.err
+}
+
+func TestGetStorageUsage(t *testing.T) {
+    gin.SetMode(gin.TestMode)
+    failure := errors.New("база данных недоступна")
+
+    tests := []struct {
+        name       string
+        quotas     fakeQuotas
+        files      fakeFiles
+        wantStatus int
+        want       StorageUsageResponse
+    }{
+        {
+            name:       "объем и квота",
+            quotas:     fakeQuotas{usage: &quota.Usage{Used: 300, Quota: 1000}},
+            files:      fakeFiles{byKind: map[string]int64{"image": 200, "audio": 100}},
+            wantStatus: http.StatusOK,
+            want:       StorageUsageResponse{UsedBytes: 300, QuotaBytes: 1000, AvailableBytes: 700},
+        },
+        {
+            name:       "квота превышена",
+            quotas:     fakeQuotas{usage: &quota.Usage{Used: 1500, Quota: 1000}},
+            files:      fakeFiles{byKind: map[string]int64{}},
+            wantStatus: http.StatusOK,
+            want:       StorageUsageResponse{UsedBytes: 1500, QuotaBytes: 1000, AvailableBytes: 0},
+        },
+        {
+            name:       "ошибка квоты",
+            quotas:     fakeQuotas{err: failure},
+            wantStatus: http.StatusInternalServerError,
+        },
+        {
+            name:       "ошибка объема по типам",
+            quotas:     fakeQuotas{usage: &quota.Usage{Used: 300, Quota: 1000}},
+            files:      fakeFiles{err: failure},
+            wantStatus: http.StatusInternalServerError,
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            h := &Handler{files: tt.files, quotas: tt.quotas}
+            w := httptest.NewRecorder()
+            c, _ := gin.CreateTestContext(w)
+            c.Request = httptest.NewRequest(http.MethodGet, "/me/storage", nil)
+            c.Set("userID", "user")
+
+            h.GetStorageUsage(c)
+
+            if w.Code != tt.wantStatus {
+                t.Fatalf("статус %d, ожидался %d: %s", w.Code, tt.wantStatus, w.Body.String())
+            }
+            if tt.wantStatus != http.StatusOK {
+                return
+            }
+            var got StorageUsageResponse
+            if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+                t.Fatal(err)
+            }
+            if got.UsedBytes != tt.want.UsedBytes || got.QuotaBytes != tt.want.QuotaBytes || got.AvailableBytes != tt.want.AvailableBytes {
+                t.Fatalf("ответ %+v, ожидалось %+v", got, tt.want)
+            }
+            if len(got.ByKind) != len(tt.files.byKind) {
+                t.Fatalf("объем по типам %v, ожидалось %v", got.ByKind, tt.files.byKind)
+            }
+            if got.Limits.MaxImageSize <= 0 || got.Limits.MaxVoiceDuration <= 0 {
+                t.Fatalf("не заполнены ограничения: %+v", got.Limits)
+            }
+        })
+    }
+}
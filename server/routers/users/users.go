@@ -1,60 +1,108 @@
 package users
 
 import (
+    "errors"
     "net/http"
+    "strconv"
+    "strings"
+    "unicode/utf8"
 
+    "chatter-hub-server/accounts"
     "chatter-hub-server/auth"
     "chatter-hub-server/config"
+    "chatter-hub-server/messaging"
+    "chatter-hub-server/metrics"
+    "chatter-hub-server/presence"
+    "chatter-hub-server/repository"
+    "chatter-hub-server/storage"
 
     "github.com/gin-gonic/gin"
-    "golang.org/x/crypto/bcrypt"
-    "github.com/google/uuid"
 )
 
+const (
+    maxDisplayNameLength = 64  // Максимальная длина отображаемого имени в символах
+    maxBioLength         = 500 // Максимальная длина описания профиля в символах
+)
+
+const (
+    searchMinQueryLength = 2  // Минимальная длина поискового запроса
+    searchDefaultLimit   = 20 // Количество результатов поиска по умолчанию
+    searchMaxLimit       = 50 // Максимальное количество результатов поиска
+)
+
+// Handler обрабатывает запросы к профилям пользователей
+type Handler struct {
+    accounts  *accounts.Service
+    messaging *messaging.Service
+    files     repository.FileRepository
+    quotas    repository.QuotaRepository
+    store     storage.BlobStore
+    tokens    *auth.TokenIssuer
+    presence  *presence.Tracker
+}
+
+// NewHandler создает обработчики профилей пользователей
+func NewHandler(accountService *accounts.Service, messagingService *messaging.Service, files repository.FileRepository, quotas repository.QuotaRepository,
+    store storage.BlobStore, tokens *auth.TokenIssuer, tracker *presence.Tracker) *Handler {
+    return &Handler{accounts: accountService, messaging: messagingService, files: files, quotas: quotas, store: store, tokens: tokens, presence: tracker}
+}
+
 // CreateUser godoc
 // @Summary      Создание пользователя
 // @Description  Создает нового пользователя и возвращает JWT токен
 // @Tags         users
 // @Accept       json
 // @Produce      json
-// @Param        user  body      config.User       true  "Информация о пользователе"
+// @Param        user  body      CreateUserRequest true  "Информация о пользователе"
 // @Success      200   {object}  map[string]string "token" "JWT токен"
 // @Failure      400   {object}  config.ErrorResponse
 // @Failure      500   {object}  config.ErrorResponse
 // @Router       /users [post]
-func CreateUser(c *gin.Context) {
-    var user config.User
-    if err := c.ShouldBindJSON(&user); err != nil {
+func (h *Handler) CreateUser(c *gin.Context) {
+    var req CreateUserRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
         c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: err.Error()})
         return
     }
 
-    // Хешируем пароль
-    hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка хеширования пароля"})
+    // Учетная запись всегда создается активной; остальные поля проверяются так же, как при обновлении
+    user := config.User{
+        Username:      req.Username,
+        DisplayName:   strings.TrimSpace(req.DisplayName),
+        Bio:           strings.TrimSpace(req.Bio),
+        Email:         req.Email,
+        Password:      req.Password,
+        IsActive:      true,
+        Discoverable:  true,
+        MessagePolicy: config.MessagePolicyEveryone,
+    }
+    if utf8.RuneCountInString(user.DisplayName) > maxDisplayNameLength {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Отображаемое имя не должно превышать 64 символа"})
         return
     }
-    user.Password = string(hashedPassword)
-
-    // Генерируем уникальный ID для пользователя
-    user.ID = uuid.New().String()
-
-    // Сохраняем пользователя в базе данных
-    if err := config.DB.Create(&user).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка создания пользователя"})
+    if utf8.RuneCountInString(user.Bio) > maxBioLength {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Описание профиля не должно превышать 500 символов"})
         return
     }
+    if req.Discoverable != nil {
+        user.Discoverable = *req.Discoverable
+    }
+    if req.MessagePolicy != "" {
+        if !isMessagePolicy(req.MessagePolicy) {
+            c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "message_policy должен быть everyone или contacts"})
+            return
+        }
+        user.MessagePolicy = req.MessagePolicy
+    }
 
-    // Загружаем конфигурацию
-    cfg, err := config.LoadConfig()
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка загрузки конфигурации"})
+    // Сохраняем пользователя в базе данных; пароль хешируется
+    if err := h.accounts.Register(c.Request.Context(), &user); err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка создания пользователя"})
         return
     }
 
     // Генерируем JWT токен
-    token, err := auth.GenerateToken(user.ID, cfg) // user.ID - это строка
+    token, err := h.tokens.Issue(user.ID)
     if err != nil {
         c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка создания JWT токена"})
         return
@@ -65,36 +113,93 @@ func CreateUser(c *gin.Context) {
 
 // GetUser godoc
 // @Summary      Получение информации о пользователе
-// @Description  Возвращает информацию о пользователе по ID
+// @Description  Возвращает публичный профиль пользователя по ID. Владельцу профиля дополнительно возвращаются email и настройки.
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        id    path      string     true  "ID пользователя"
-// @Success      200   {object}  config.User
+// @Success      200   {object}  config.PublicUser
 // @Failure      404   {object}  config.ErrorResponse
 // @Failure      500   {object}  config.ErrorResponse
 // @Router       /users/{id} [get]
-func GetUser(c *gin.Context) {
+func (h *Handler) GetUser(c *gin.Context) {
     userID := c.Param("id")
-    var user config.User
 
-    // Проверяем кэш Redis
-    val, err := config.RedisClient.Get(config.Ctx, "user:"+userID).Result()
-    if err == nil {
-        // Пользователь найден в кэше
-        c.JSON(http.StatusOK, val)
+    profile, err := h.accounts.Profile(c.Request.Context(), userID)
+    if err != nil {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Пользователь не найден"})
+        return
+    }
+
+    // Email и настройки видит только сам пользователь
+    if c.GetString("userID") == userID {
+        c.JSON(http.StatusOK, profile)
         return
     }
 
-    if err := config.DB.First(&user, "id = ?", userID).Error; err != nil {
+    // Заблокированный пользователь не видит профиль заблокировавшего его
+    blocked, err := h.messaging.HasBlocked(c.Request.Context(), userID, c.GetString("userID"))
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения пользователя"})
+        return
+    }
+    if blocked {
         c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Пользователь не найден"})
         return
     }
 
-    // Кэшируем пользователя в Redis
-    config.RedisClient.Set(config.Ctx, "user:"+userID, user, 0)
+    c.JSON(http.StatusOK, profile.PublicUser)
+}
+
+// SearchUsers godoc
+// @Summary      Поиск пользователей
+// @Description  Ищет активных пользователей по префиксу и триграммному сходству имени пользователя и отображаемого имени
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        q      query     string  true   "Поисковый запрос (минимум 2 символа)"
+// @Param        limit  query     int     false  "Количество результатов (по умолчанию 20, максимум 50)"
+// @Success      200    {array}   config.PublicUser
+// @Failure      400    {object}  config.ErrorResponse
+// @Failure      500    {object}  config.ErrorResponse
+// @Router       /users/search [get]
+func (h *Handler) SearchUsers(c *gin.Context) {
+    query := strings.ToLower(strings.TrimSpace(c.Query("q")))
+    if utf8.RuneCountInString(query) < searchMinQueryLength {
+        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Поисковый запрос должен содержать минимум 2 символа"})
+        return
+    }
+
+    limit := searchDefaultLimit
+    if rawLimit := c.Query("limit"); rawLimit != "" {
+        parsedLimit, err := strconv.Atoi(rawLimit)
+        if err != nil || parsedLimit < 1 {
+            c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Некорректное значение limit"})
+            return
+        }
+        if parsedLimit > searchMaxLimit {
+            parsedLimit = searchMaxLimit
+        }
+        limit = parsedLimit
+    }
+
+    users, err := h.accounts.Search(c.Request.Context(), repository.UserSearch{
+        Query:    query,
+        ViewerID: c.GetString("userID"),
+        Limit:    limit,
+    })
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка поиска пользователей"})
+        return
+    }
+
+    // Возвращаем только публичные поля профиля
+    result := make([]config.PublicUser, 0, len(users))
+    for _, user := range users {
+        result = append(result, user.Public())
+    }
 
-    c.JSON(http.StatusOK, user)
+    c.JSON(http.StatusOK, result)
 }
 
 // UpdateUser godoc
@@ -103,16 +208,16 @@ func GetUser(c *gin.Context) {
 // @Tags         users
 // @Accept       json
 // @Produce      json
-// @Param        id    path      string         true  "ID пользователя"
-// @Param        user  body      config.User    true  "Обновленная информация о пользователе"
-// @Success      200   {object}  config.User
+// @Param        id    path      string             true  "ID пользователя"
+// @Param        user  body      UpdateUserRequest  true  "Обновленная информация о пользователе"
+// @Success      200   {object}  config.PrivateUser
 // @Failure      400   {object}  config.ErrorResponse
 // @Failure      500   {object}  config.ErrorResponse
 // @Router       /users/{id} [put]
-func UpdateUser(c *gin.Context) {
+func (h *Handler) UpdateUser(c *gin.Context) {
     userID := c.Param("id")
-    var user config.User
-    if err := c.ShouldBindJSON(&user); err != nil {
+    var req UpdateUserRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
         c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: err.Error()})
         return
     }
@@ -124,26 +229,95 @@ func UpdateUser(c *gin.Context) {
         return
     }
 
+    // Собираем только переданные поля, чтобы можно было сбросить значение в false или пустую строку
+    updates := map[string]interface{}{}
+    if req.Username != nil {
+        updates["username"] = *req.Username
+    }
+    if req.DisplayName != nil {
+        displayName := strings.TrimSpace(*req.DisplayName)
+        if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+            c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Отображаемое имя не должно превышать 64 символа"})
+            return
+        }
+        updates["display_name"] = displayName
+    }
+    if req.Bio != nil {
+        bio := strings.TrimSpace(*req.Bio)
+        if utf8.RuneCountInString(bio) > maxBioLength {
+            c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Описание профиля не должно превышать 500 символов"})
+            return
+        }
+        updates["bio"] = bio
+    }
+    if req.Email != nil {
+        updates["email"] = *req.Email
+    }
+    if req.Discoverable != nil {
+        updates["discoverable"] = *req.Discoverable
+    }
+    if req.MessagePolicy != nil {
+        if !isMessagePolicy(*req.MessagePolicy) {
+            c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "message_policy должен быть everyone или contacts"})
+            return
+        }
+        updates["message_policy"] = *req.MessagePolicy
+    }
+
     // Хешируем пароль, если он изменяется
-    if user.Password != "" {
-        hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+    if req.Password != nil && *req.Password != "" {
+        hashedPassword, err := accounts.HashPassword(*req.Password)
         if err != nil {
             c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка хеширования пароля"})
             return
         }
-        user.Password = string(hashedPassword)
+        updates["password"] = hashedPassword
     }
 
-    // Обновляем пользователя в базе данных
-    if err := config.DB.Model(&config.User{}).Where("id = ?", userID).Updates(user).Error; err != nil {
+    // Обновляем пользователя в базе данных и сбрасываем кэш профиля
+    if err := h.accounts.Update(c.Request.Context(), userID, updates); err != nil {
         c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка обновления пользователя"})
         return
     }
 
-    // Удаляем пользователя из кэша Redis
-    config.RedisClient.Del(config.Ctx, "user:"+userID)
+    profile, err := h.accounts.Profile(c.Request.Context(), userID)
+    if err != nil {
+        c.JSON(http.StatusNotFound, config.ErrorResponse{Error: "Пользователь не найден"})
+        return
+    }
+
+    c.JSON(http.StatusOK, profile)
+}
+
+// CreateUserRequest представляет запрос на создание пользователя
+type CreateUserRequest struct {
+    Username    string `json:"username" binding:"required" example:"john_doe"`
+    DisplayName string `json:"display_name" example:"John Doe"`
+    Bio         string `json:"bio" example:"Люблю голосовые сообщения"`
+    Email       string `json:"email" example:"john@example.com"`
+    Password    string `json:"password" binding:"required" example:"secret"`
+    // Показывать ли пользователя в поиске, по умолчанию true
+    Discoverable *bool `json:"discoverable,omitempty" example:"true"`
+    // Кто может начать переписку: everyone (по умолчанию) или contacts
+    MessagePolicy string `json:"message_policy,omitempty" example:"everyone"`
+}
 
-    c.JSON(http.StatusOK, user)
+// isMessagePolicy проверяет значение настройки message_policy
+func isMessagePolicy(policy string) bool {
+    return policy == config.MessagePolicyEveryone || policy == config.MessagePolicyContacts
+}
+
+// UpdateUserRequest представляет запрос на обновление пользователя.
+// Непереданные поля остаются без изменений.
+type UpdateUserRequest struct {
+    Username     *string `json:"username,omitempty" example:"john_doe"`
+    DisplayName  *string `json:"display_name,omitempty" example:"John Doe"`
+    Bio          *string `json:"bio,omitempty" example:"Люблю голосовые сообщения"`
+    Email        *string `json:"email,omitempty" example:"john@example.com"`
+    Password     *string `json:"password,omitempty" example:"secret"`
+    Discoverable *bool   `json:"discoverable,omitempty" example:"true"`
+    // Кто может начать переписку: everyone или contacts
+    MessagePolicy *string `json:"message_policy,omitempty" example:"contacts"`
 }
 
 // DeleteUser godoc
@@ -156,7 +330,7 @@ func UpdateUser(c *gin.Context) {
 // @Success      200   {object}  config.SimpleResponse
 // @Failure      500   {object}  config.ErrorResponse
 // @Router       /users/{id} [delete]
-func DeleteUser(c *gin.Context) {
+func (h *Handler) DeleteUser(c *gin.Context) {
     userID := c.Param("id")
 
     // Проверяем, совпадает ли ID пользователя с тем, который в токене
@@ -166,15 +340,12 @@ func DeleteUser(c *gin.Context) {
         return
     }
 
-    // Удаляем пользователя из базы данных
-    if err := config.DB.Delete(&config.User{}, "id = ?", userID).Error; err != nil {
+    // Удаляем пользователя из базы данных и из кэша
+    if err := h.accounts.Delete(c.Request.Context(), userID); err != nil {
         c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка удаления пользователя"})
         return
     }
 
-    // Удаляем пользователя из кэша Redis
-    config.RedisClient.Del(config.Ctx, "user:"+userID)
-
     c.JSON(http.StatusOK, config.SimpleResponse{Message: "Пользователь удален"})
 }
 
@@ -190,55 +361,40 @@ func DeleteUser(c *gin.Context) {
 // @Failure      401          {object}  config.ErrorResponse
 // @Failure      500          {object}  config.ErrorResponse
 // @Router       /login [post]
-func LoginUser(c *gin.Context) {
+func (h *Handler) LoginUser(c *gin.Context) {
     var req LoginRequest
     if err := c.ShouldBindJSON(&req); err != nil {
         c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: err.Error()})
         return
     }
 
-    var user config.User
-    // Поиск пользователя по email или username
-    if req.Email != "" {
-        if err := config.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
-            c.JSON(http.StatusUnauthorized, config.ErrorResponse{Error: "Неверные учетные данные"})
-            return
-        }
-    } else if req.Username != "" {
-        if err := config.DB.Where("username = ?", req.Username).First(&user).Error; err != nil {
-            c.JSON(http.StatusUnauthorized, config.ErrorResponse{Error: "Неверные учетные данные"})
-            return
-        }
-    } else {
+    // Поиск пользователя по email или username и проверка пароля
+    user, err := h.accounts.Authenticate(c.Request.Context(), req.Email, req.Username, req.Password)
+    switch {
+    case errors.Is(err, accounts.ErrLoginRequired):
         c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Необходимо указать email или username"})
         return
-    }
-
-    // Проверка пароля
-    if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+    case errors.Is(err, accounts.ErrInvalidCredentials):
+        metrics.Logins.WithLabelValues("failure").Inc()
         c.JSON(http.StatusUnauthorized, config.ErrorResponse{Error: "Неверные учетные данные"})
         return
-    }
-
-    // Проверка, активен ли аккаунт
-    if !user.IsActive {
+    case errors.Is(err, accounts.ErrInactive):
+        metrics.Logins.WithLabelValues("failure").Inc()
         c.JSON(http.StatusForbidden, config.ErrorResponse{Error: "Аккаунт деактивирован"})
         return
-    }
-
-    // Генерируем JWT токен
-    cfg, err := config.LoadConfig()
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка загрузки конфигурации"})
+    case err != nil:
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка аутентификации"})
         return
     }
 
-    token, err := auth.GenerateToken(user.ID, cfg)
+    // Генерируем JWT токен
+    token, err := h.tokens.Issue(user.ID)
     if err != nil {
         c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка создания JWT токена"})
         return
     }
 
+    metrics.Logins.WithLabelValues("success").Inc()
     c.JSON(http.StatusOK, TokenResponse{Token: token})
 }
 
@@ -264,7 +420,7 @@ type TokenResponse struct {
 // @Success      200   {object}  config.SimpleResponse
 // @Failure      500   {object}  config.ErrorResponse
 // @Router       /users/{id}/deactivate [post]
-func DeactivateUser(c *gin.Context) {
+func (h *Handler) DeactivateUser(c *gin.Context) {
     userID := c.Param("id")
 
     // Проверяем, совпадает ли ID пользователя с тем, который в токене
@@ -275,7 +431,7 @@ func DeactivateUser(c *gin.Context) {
     }
 
     // Обновляем поле IsActive в базе данных
-    if err := config.DB.Model(&config.User{}).Where("id = ?", userID).Update("is_active", false).Error; err != nil {
+    if err := h.accounts.SetActive(c.Request.Context(), userID, false); err != nil {
         c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка деактивации пользователя"})
         return
     }
@@ -293,7 +449,7 @@ func DeactivateUser(c *gin.Context) {
 // @Success      200   {object}  config.SimpleResponse
 // @Failure      500   {object}  config.ErrorResponse
 // @Router       /users/{id}/activate [post]
-func ActivateUser(c *gin.Context) {
+func (h *Handler) ActivateUser(c *gin.Context) {
     userID := c.Param("id")
 
     // Проверяем, совпадает ли ID пользователя с тем, который в токене
@@ -304,7 +460,7 @@ func ActivateUser(c *gin.Context) {
     }
 
     // Обновляем поле IsActive в базе данных
-    if err := config.DB.Model(&config.User{}).Where("id = ?", userID).Update("is_active", true).Error; err != nil {
+    if err := h.accounts.SetActive(c.Request.Context(), userID, true); err != nil {
         c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка активации пользователя"})
         return
     }
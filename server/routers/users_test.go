@@ -0,0 +1,269 @@
+package routers_test
+
+import (
+    "net/http"
+    "testing"
+    "time"
+
+    "chatter-hub-server/auth"
+    "chatter-hub-server/config"
+
+    "github.com/golang-jwt/jwt/v4"
+)
+
+func TestRegisterAndLogin(t *testing.T) {
+    user := registerUser(t)
+
+    t.Run("по имени пользователя", func(t *testing.T) {
+        w := request(t, http.MethodPost, "/login", "", map[string]string{"username": user.Username, "password": user.Password})
+        expectStatus(t, w, http.StatusOK)
+        var response map[string]string
+        decode(t, w, &response)
+        if id := tokenUserID(t, response["token"]); id != user.ID {
+            t.Fatalf("токен выдан пользователю %s, ожидался %s", id, user.ID)
+        }
+    })
+
+    t.Run("по email", func(t *testing.T) {
+        w := request(t, http.MethodPost, "/login", "", map[string]string{"email": user.Username + "@example.com", "password": user.Password})
+        expectStatus(t, w, http.StatusOK)
+    })
+
+    t.Run("неверный пароль", func(t *testing.T) {
+        w := request(t, http.MethodPost, "/login", "", map[string]string{"username": user.Username, "password": "wrong"})
+        expectStatus(t, w, http.StatusUnauthorized)
+    })
+
+    t.Run("неизвестный пользователь", func(t *testing.T) {
+        w := request(t, http.MethodPost, "/login", "", map[string]string{"username": "nobody", "password": "secret"})
+        expectStatus(t, w, http.StatusUnauthorized)
+    })
+
+    t.Run("без логина", func(t *testing.T) {
+        w := request(t, http.MethodPost, "/login", "", map[string]string{"password": user.Password})
+        expectStatus(t, w, http.StatusBadRequest)
+    })
+
+    t.Run("без пароля", func(t *testing.T) {
+        w := request(t, http.MethodPost, "/login", "", map[string]string{"username": user.Username})
+        expectStatus(t, w, http.StatusBadRequest)
+    })
+
+    t.Run("пароль не возвращается", func(t *testing.T) {
+        w := request(t, http.MethodGet, "/users/"+user.ID, user.Token, nil)
+        expectStatus(t, w, http.StatusOK)
+        var profile map[string]interface{}
+        decode(t, w, &profile)
+        if _, ok := profile["password"]; ok {
+            t.Fatalf("профиль содержит пароль: %v", profile)
+        }
+    })
+}
+
+func TestTokenValidation(t *testing.T) {
+    user := registerUser(t)
+    path := "/users/" + user.ID
+
+    sign := func(claims *auth.Claims, secret string) string {
+        token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+        if err != nil {
+            t.Fatal(err)
+        }
+        return token
+    }
+    valid := jwt.StandardClaims{ExpiresAt: time.Now().Add(time.Hour).Unix()}
+
+    cases := []struct {
+        name   string
+        header string
+        status int
+    }{
+        {"без заголовка", "", http.StatusUnauthorized},
+        {"без Bearer", user.Token, http.StatusUnauthorized},
+        {"не JWT", "Bearer not-a-token", http.StatusUnauthorized},
+        {"чужая подпись", "Bearer " + sign(&auth.Claims{UserID: user.ID, StandardClaims: valid}, "other-secret"), http.StatusUnauthorized},
+        {"истекший", "Bearer " + sign(&auth.Claims{UserID: user.ID, StandardClaims: jwt.StandardClaims{ExpiresAt: time.Now().Add(-time.Minute).Unix()}}, testSecret), http.StatusUnauthorized},
+        {"действительный", "Bearer " + user.Token, http.StatusOK},
+    }
+    for _, tc := range cases {
+        t.Run(tc.name, func(t *testing.T) {
+            req, _ := http.NewRequest(http.MethodGet, path, nil)
+            if tc.header != "" {
+                req.Header.Set("Authorization", tc.header)
+            }
+            expectStatus(t, serve(req, ""), tc.status)
+        })
+    }
+}
+
+func TestUserCRUD(t *testing.T) {
+    owner := registerUser(t)
+    other := registerUser(t)
+
+    t.Run("свой профиль с email", func(t *testing.T) {
+        w := request(t, http.MethodGet, "/users/"+owner.ID, owner.Token, nil)
+        expectStatus(t, w, http.StatusOK)
+        var profile config.PrivateUser
+        decode(t, w, &profile)
+        if profile.Username != owner.Username || profile.Email != owner.Username+"@example.com" {
+            t.Fatalf("неожиданный профиль: %+v", profile)
+        }
+    })
+
+    t.Run("чужой профиль без email", func(t *testing.T) {
+        w := request(t, http.MethodGet, "/users/"+owner.ID, other.Token, nil)
+        expectStatus(t, w, http.StatusOK)
+        var profile map[string]interface{}
+        decode(t, w, &profile)
+        if _, ok := profile["email"]; ok {
+            t.Fatalf("чужой профиль содержит email: %v", profile)
+        }
+    })
+
+    t.Run("несуществующий пользователь", func(t *testing.T) {
+        w := request(t, http.MethodGet, "/users/00000000-0000-0000-0000-000000000000", owner.Token, nil)
+        expectStatus(t, w, http.StatusNotFound)
+    })
+
+    t.Run("обновление", func(t *testing.T) {
+        w := request(t, http.MethodPut, "/users/"+owner.ID, owner.Token, map[string]interface{}{
+            "display_name":   "  Новое имя  ",
+            "bio":            "О себе",
+            "message_policy": config.MessagePolicyContacts,
+        })
+        expectStatus(t, w, http.StatusOK)
+        var profile config.PrivateUser
+        decode(t, w, &profile)
+        if profile.DisplayName != "Новое имя" || profile.Bio != "О себе" || profile.MessagePolicy != config.MessagePolicyContacts {
+            t.Fatalf("профиль не обновлен: %+v", profile)
+        }
+
+        // Кэш профиля сброшен: другой пользователь видит новое имя
+        w = request(t, http.MethodGet, "/users/"+owner.ID, other.Token, nil)
+        expectStatus(t, w, http.StatusOK)
+        var public config.PublicUser
+        decode(t, w, &public)
+        if public.DisplayName != "Новое имя" {
+            t.Fatalf("профиль из кэша устарел: %+v", public)
+        }
+    })
+
+    t.Run("смена пароля", func(t *testing.T) {
+        w := request(t, http.MethodPut, "/users/"+owner.ID, owner.Token, map[string]string{"password": "new-password"})
+        expectStatus(t, w, http.StatusOK)
+        expectStatus(t, request(t, http.MethodPost, "/login", "", map[string]string{"username": owner.Username, "password": owner.Password}), http.StatusUnauthorized)
+        expectStatus(t, request(t, http.MethodPost, "/login", "", map[string]string{"username": owner.Username, "password": "new-password"}), http.StatusOK)
+    })
+
+    t.Run("некорректные значения", func(t *testing.T) {
+        w := request(t, http.MethodPut, "/users/"+owner.ID, owner.Token, map[string]string{"message_policy": "nobody"})
+        expectStatus(t, w, http.StatusBadRequest)
+    })
+
+    t.Run("чужой профиль не изменяется", func(t *testing.T) {
+        w := request(t, http.MethodPut, "/users/"+owner.ID, other.Token, map[string]string{"display_name": "Взлом"})
+        expectStatus(t, w, http.StatusForbidden)
+    })
+
+    t.Run("поиск", func(t *testing.T) {
+        w := request(t, http.MethodGet, "/users/search?q="+other.Username, owner.Token, nil)
+        expectStatus(t, w, http.StatusOK)
+        var found []config.PublicUser
+        decode(t, w, &found)
+        if len(found) != 1 || found[0].ID != other.ID {
+            t.Fatalf("поиск вернул %+v, ожидался %s", found, other.ID)
+        }
+        expectStatus(t, request(t, http.MethodGet, "/users/search?q=a", owner.Token, nil), http.StatusBadRequest)
+    })
+
+    t.Run("чужой профиль не удаляется", func(t *testing.T) {
+        expectStatus(t, request(t, http.MethodDelete, "/users/"+owner.ID, other.Token, nil), http.StatusForbidden)
+    })
+
+    t.Run("удаление", func(t *testing.T) {
+        expectStatus(t, request(t, http.MethodDelete, "/users/"+owner.ID, owner.Token, nil), http.StatusOK)
+        expectStatus(t, request(t, http.MethodGet, "/users/"+owner.ID, other.Token, nil), http.StatusNotFound)
+        expectStatus(t, request(t, http.MethodPost, "/login", "", map[string]string{"username": owner.Username, "password": "new-password"}), http.StatusUnauthorized)
+    })
+}
+
+func TestActivation(t *testing.T) {
+    user := registerUser(t)
+    other := registerUser(t)
+    login := map[string]string{"username": user.Username, "password": user.Password}
+
+    expectStatus(t, request(t, http.MethodPost, "/users/"+user.ID+"/deactivate", other.Token, nil), http.StatusForbidden)
+
+    expectStatus(t, request(t, http.MethodPost, "/users/"+user.ID+"/deactivate", user.Token, nil), http.StatusOK)
+    expectStatus(t, request(t, http.MethodPost, "/login", "", login), http.StatusForbidden)
+
+    w := request(t, http.MethodGet, "/users/"+user.ID, user.Token, nil)
+    expectStatus(t, w, http.StatusOK)
+    var profile config.PrivateUser
+    decode(t, w, &profile)
+    if profile.IsActive {
+        t.Fatalf("профиль активен после деактивации")
+    }
+
+    // Деактивированному пользователю нельзя написать
+    w = request(t, http.MethodPost, "/messages/text/", other.Token, map[string]string{"receiver_id": user.ID, "content": "Привет"})
+    expectStatus(t, w, http.StatusNotFound)
+
+    expectStatus(t, request(t, http.MethodPost, "/users/"+user.ID+"/activate", other.Token, nil), http.StatusForbidden)
+    expectStatus(t, request(t, http.MethodPost, "/users/"+user.ID+"/activate", user.Token, nil), http.StatusOK)
+    expectStatus(t, request(t, http.MethodPost, "/login", "", login), http.StatusOK)
+}
+
+func TestAdminRoutesRequireAdmin(t *testing.T) {
+    user := registerUser(t)
+    expectStatus(t, request(t, http.MethodGet, "/admin/jobs", "", nil), http.StatusUnauthorized)
+    expectStatus(t, request(t, http.MethodGet, "/admin/jobs", user.Token, nil), http.StatusForbidden)
+}
+
+func TestCreateUserFields(t *testing.T) {
+    create := func(body map[string]interface{}) testUser {
+        t.Helper()
+        w := request(t, http.MethodPost, "/users", "", body)
+        expectStatus(t, w, http.StatusOK)
+        var response map[string]string
+        decode(t, w, &response)
+        return testUser{Token: response["token"], ID: tokenUserID(t, response["token"])}
+    }
+    profile := func(user testUser) map[string]interface{} {
+        t.Helper()
+        w := request(t, http.MethodGet, "/users/"+user.ID, user.Token, nil)
+        expectStatus(t, w, http.StatusOK)
+        var profile map[string]interface{}
+        decode(t, w, &profile)
+        return profile
+    }
+
+    t.Run("служебные поля игнорируются", func(t *testing.T) {
+        user := create(map[string]interface{}{
+            "id": "chosen-id", "username": "create_fields_1", "password": "secret", "is_active": false,
+        })
+        if user.ID == "chosen-id" {
+            t.Fatal("ID пользователя задан клиентом")
+        }
+        if got := profile(user)["is_active"]; got != true {
+            t.Fatalf("is_active = %v, ожидалось true", got)
+        }
+    })
+
+    t.Run("настройки профиля", func(t *testing.T) {
+        user := create(map[string]interface{}{
+            "username": "create_fields_2", "password": "secret", "discoverable": false, "message_policy": config.MessagePolicyContacts,
+        })
+        got := profile(user)
+        if got["discoverable"] != false || got["message_policy"] != config.MessagePolicyContacts {
+            t.Fatalf("настройки не сохранены: %v", got)
+        }
+    })
+
+    t.Run("недопустимая message_policy", func(t *testing.T) {
+        w := request(t, http.MethodPost, "/users", "", map[string]interface{}{
+            "username": "create_fields_3", "password": "secret", "message_policy": "nobody",
+        })
+        expectStatus(t, w, http.StatusBadRequest)
+    })
+}
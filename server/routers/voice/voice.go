@@ -1,85 +1,207 @@
 package voice
 
 import (
-    "fmt"
+    "context"
+    "errors"
+    "mime/multipart"
     "net/http"
     "time"
 
+    "chatter-hub-server/attachments"
+    "chatter-hub-server/audio"
     "chatter-hub-server/config"
+    "chatter-hub-server/logging"
+    "chatter-hub-server/messaging"
+    "chatter-hub-server/metrics"
+    "chatter-hub-server/notify"
+    "chatter-hub-server/repository"
+    "chatter-hub-server/storage"
+    "chatter-hub-server/transcribe"
 
     "github.com/gin-gonic/gin"
-    "github.com/google/uuid"
-    "github.com/minio/minio-go/v7"
 )
 
+const (
+    // formOverhead — запас на поля формы и заголовки частей multipart сверх размера файла
+    formOverhead = 64 << 10
+    // multipartMemory — часть формы, которая хранится в памяти; остальное записывается во временный файл
+    multipartMemory = 32 << 20
+)
+
+// Handler обрабатывает запросы к голосовым сообщениям
+type Handler struct {
+    messages  repository.MessageRepository
+    files     repository.FileRepository
+    messaging *messaging.Service
+    store       storage.BlobStore
+    attachments *attachments.Service
+    notifier    notify.Publisher
+    transcripts transcribe.Queue
+}
+
+// NewHandler создает обработчики голосовых сообщений
+func NewHandler(messages repository.MessageRepository, files repository.FileRepository, messagingService *messaging.Service, store storage.BlobStore, attachmentService *attachments.Service, notifier notify.Publisher, transcripts transcribe.Queue) *Handler {
+    return &Handler{messages: messages, files: files, messaging: messagingService, store: store, attachments: attachmentService,
+        notifier: notifier, transcripts: transcripts}
+}
+
 // SendVoiceMessage godoc
 //	@Summary		Отправка голосового сообщения
-//	@Description	Отправляет голосовое сообщение от одного пользователя к другому
+//	@Description	Отправляет голосовое сообщение от одного пользователя к другому. Поддерживаются WAV, MP3, Ogg (Opus, Vorbis) и WebM; длительность, кодек и форма волны определяются по файлу. Аудиофайл передается в поле file или заранее загружается напрямую в хранилище через /uploads и передается как attachment_id. Размер файла, длительность записи и общий объем файлов пользователя ограничены (413). Если включена антивирусная проверка, ссылка на файл выдается только после нее; при обнаружении угрозы файл удаляется, сообщение блокируется, а участники получают уведомление attachment.infected с причиной.
 //	@Tags			voice
 //	@Accept			multipart/form-data
 //	@Produce		json
 //	@Param			sender_id	formData	string	true	"ID отправителя"
 //	@Param			receiver_id	formData	string	true	"ID получателя"
-//	@Param			file		formData	file	true	"Аудиофайл"
+//	@Param			file		formData	file	false	"Аудиофайл"
+//	@Param			attachment_id	formData	string	false	"ID аудиофайла, загруженного через /uploads (вместо file)"
 //	@Success		200			{object}	config.SimpleResponse
 //	@Failure		400			{object}	config.ErrorResponse
+//	@Failure		403			{object}	config.ErrorResponse
+//	@Failure		404			{object}	config.ErrorResponse
+//	@Failure		413			{object}	config.ErrorResponse
+//	@Failure		415			{object}	config.ErrorResponse
 //	@Failure		500			{object}	config.ErrorResponse
 //	@Router			/messages/voice [post]
-func SendVoiceMessage(c *gin.Context) {
+func (h *Handler) SendVoiceMessage(c *gin.Context) {
+    // Не даем загрузить тело запроса больше допустимого размера аудиофайла
+    c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, attachments.MaxSize(config.AttachmentAudio)+formOverhead)
+    var tooLarge *http.MaxBytesError
+    if err := c.Request.ParseMultipartForm(multipartMemory); errors.As(err, &tooLarge) {
+        c.JSON(http.StatusRequestEntityTooLarge, config.ErrorResponse{Error: attachments.ErrTooLarge.Error()})
+        return
+    }
+
     receiverID := c.PostForm("receiver_id")
 
     // Получаем ID пользователя из контекста (из токена)
     senderID := c.GetString("userID")
 
-    file, err := c.FormFile("file")
-    if err != nil {
-        c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Файл обязателен"})
+    // Проверяем, что получатель существует и принимает сообщения от отправителя
+    if err := h.messaging.CheckCanSend(c.Request.Context(), senderID, receiverID); err != nil {
+        status := messaging.HTTPStatus(err)
+        if status == http.StatusInternalServerError {
+            c.JSON(status, config.ErrorResponse{Error: "Ошибка отправки сообщения"})
+            return
+        }
+        c.JSON(status, config.ErrorResponse{Error: err.Error()})
         return
     }
 
-    // Открываем файл
-    fileContent, err := file.Open()
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка открытия файла"})
-        return
-    }
-    defer fileContent.Close()
+    var attachment *config.Attachment
+    var info *audio.Info
+    if attachmentID := c.PostForm("attachment_id"); attachmentID != "" {
+        // Аудиофайл уже загружен напрямую в хранилище и подтвержден через /uploads
+        var err error
+        attachment, err = h.files.GetUnlinked(c.Request.Context(), attachmentID, senderID)
+        if err != nil {
+            c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: attachments.ErrUnavailable.Error()})
+            return
+        }
+        if attachment.Kind != config.AttachmentAudio || attachment.Bucket != config.VoiceBucket {
+            c.JSON(http.StatusUnsupportedMediaType, config.ErrorResponse{Error: attachments.ErrUnsupportedType.Error()})
+            return
+        }
+        if attachment.ScanStatus == config.ScanInfected {
+            c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: attachments.ErrInfected.Error()})
+            return
+        }
+        if info, err = h.parseStoredAudio(c.Request.Context(), attachment); err != nil {
+            respondAudioError(c, err)
+            return
+        }
+        if info.Duration > attachments.MaxVoiceDuration() {
+            c.JSON(http.StatusRequestEntityTooLarge, config.ErrorResponse{Error: attachments.ErrTooLong.Error()})
+            return
+        }
+        list := []config.Attachment{*attachment}
+        if err = h.files.LoadVariants(c.Request.Context(), list); err == nil {
+            err = h.attachments.Sign(c.Request.Context(), list)
+        }
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отправки сообщения"})
+            return
+        }
+        *attachment = list[0]
+    } else {
+        file, err := c.FormFile("file")
+        if err != nil {
+            c.JSON(http.StatusBadRequest, config.ErrorResponse{Error: "Файл или attachment_id обязателен"})
+            return
+        }
 
-    // Генерируем уникальное имя файла
-    fileName := uuid.New().String() + "-" + file.Filename
+        // Разбираем запись до загрузки, чтобы не сохранять файлы, которые не являются звуком
+        if info, err = parseUploadedAudio(file); err != nil {
+            respondAudioError(c, err)
+            return
+        }
+        if info.Duration > attachments.MaxVoiceDuration() {
+            c.JSON(http.StatusRequestEntityTooLarge, config.ErrorResponse{Error: attachments.ErrTooLong.Error()})
+            return
+        }
 
-    // Загрузка файла в MinIO
-    bucketName := "voice-messages"
-    _, err = config.MinioClient.PutObject(config.Ctx, bucketName, fileName, fileContent, file.Size, minio.PutObjectOptions{
-        ContentType: file.Header.Get("Content-Type"),
-    })
-    if err != nil {
-        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка загрузки файла"})
-        return
+        // Загружаем файл через подсистему вложений: тип проверяется по содержимому, размер — по лимиту для аудио
+        attachment, err = h.attachments.Upload(c.Request.Context(), senderID, file, attachments.UploadOptions{
+            Kind:   config.AttachmentAudio,
+            Bucket: config.VoiceBucket,
+        })
+        if err != nil {
+            status := attachments.HTTPStatus(err)
+            if status == http.StatusInternalServerError {
+                c.JSON(status, config.ErrorResponse{Error: "Ошибка загрузки файла"})
+                return
+            }
+            c.JSON(status, config.ErrorResponse{Error: err.Error()})
+            return
+        }
     }
 
-    // Генерируем URL для доступа к файлу
-    fileURL := fmt.Sprintf("http://%s/%s/%s", config.MinioClient.EndpointURL().Host, bucketName, fileName)
-
     message := config.VoiceMessage{
-        SenderID:   senderID,
-        ReceiverID: receiverID,
-        FileURL:    fileURL,
-        CreatedAt:  time.Now(),
+        SenderID:     senderID,
+        ReceiverID:   receiverID,
+        ObjectKey:    attachment.ObjectKey,
+        FileURL:      attachment.URL,
+        AttachmentID: attachment.ID,
+        DurationMs:   info.Duration.Milliseconds(),
+        Codec:        info.Codec,
+        SampleRate:   info.SampleRate,
+        Waveform:     info.Waveform,
+        CreatedAt:    time.Now(),
+    }
+    if h.transcripts.Enabled() {
+        message.TranscriptionStatus = config.TranscriptionPending
     }
 
-    // Сохраняем сообщение в базе данных
-    if err := config.DB.Create(&message).Error; err != nil {
-        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка отправки сообщения"})
+    // Сохраняем сообщение в базе данных и привязываем к нему аудиофайл
+    if err := h.messages.CreateVoice(c.Request.Context(), &message); err != nil {
+        status := attachments.HTTPStatus(err)
+        if status == http.StatusInternalServerError {
+            c.JSON(status, config.ErrorResponse{Error: "Ошибка отправки сообщения"})
+            return
+        }
+        c.JSON(status, config.ErrorResponse{Error: err.Error()})
         return
     }
 
+    metrics.MessagesSent.WithLabelValues("voice").Inc()
+    h.notifier.Publish(c.Request.Context(), message.ReceiverID, notify.Event{Type: notify.EventVoiceMessage, From: senderID, Payload: message})
+
+    // Распознавание выполняется в фоне; о результате участники узнают из уведомления.
+    // Сообщение уже сохранено, поэтому отключение клиента не должно оставить его без задачи.
+    if message.TranscriptionStatus == config.TranscriptionPending {
+        ctx := context.WithoutCancel(c.Request.Context())
+        if err := h.transcripts.Enqueue(ctx, message.ID); err != nil {
+            logging.FromContext(ctx).Error("Ошибка постановки сообщения в очередь распознавания", "message_id", message.ID, "error", err)
+            h.messages.SetTranscriptionStatus(ctx, message.ID, config.TranscriptionFailed)
+        }
+    }
+
     c.JSON(http.StatusOK, config.SimpleResponse{Message: "Голосовое сообщение отправлено"})
 }
 
 // GetVoiceMessages godoc
 //	@Summary		Получение голосовых сообщений
-//	@Description	Возвращает список голосовых сообщений между двумя пользователями
+//	@Description	Возвращает список голосовых сообщений между двумя пользователями. Если один из участников заблокировал другого, переписка скрыта для обоих: возвращается пустой список. У заблокированных сообщений и сообщений, файл которых еще проверяется, нет ссылки на файл.
 //	@Tags			voice
 //	@Accept			json
 //	@Produce		json
@@ -89,12 +211,10 @@ func SendVoiceMessage(c *gin.Context) {
 //	@Failure		400			{object}	config.ErrorResponse
 //	@Failure		500			{object}	config.ErrorResponse
 //	@Router			/messages/voice [get]
-func GetVoiceMessages(c *gin.Context) {
+func (h *Handler) GetVoiceMessages(c *gin.Context) {
     senderID := c.Query("sender_id")
     receiverID := c.Query("receiver_id")
 
-    var messages []config.VoiceMessage
-
     // Проверяем права доступа
     tokenUserID := c.GetString("userID")
     if tokenUserID != senderID && tokenUserID != receiverID {
@@ -102,12 +222,83 @@ func GetVoiceMessages(c *gin.Context) {
         return
     }
 
+    // Переписка скрыта для обоих участников, пока один из них заблокировал другого
+    peerID := receiverID
+    if tokenUserID == receiverID {
+        peerID = senderID
+    }
+    blocked, err := h.messaging.IsBlocked(c.Request.Context(), tokenUserID, peerID)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения сообщений"})
+        return
+    }
+    if blocked {
+        c.JSON(http.StatusOK, []config.VoiceMessage{})
+        return
+    }
+
     // Получаем сообщения из базы данных
-    if err := config.DB.Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)",
-        senderID, receiverID, receiverID, senderID).Order("created_at asc").Find(&messages).Error; err != nil {
+    messages, err := h.messages.VoiceConversation(c.Request.Context(), senderID, receiverID)
+    if err != nil {
         c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения сообщений"})
         return
     }
 
+    // Файлы, которые еще проверяются антивирусом, и файлы заблокированных сообщений не выдаются
+    attachmentIDs := make([]string, 0, len(messages))
+    for _, message := range messages {
+        if message.AttachmentID != "" {
+            attachmentIDs = append(attachmentIDs, message.AttachmentID)
+        }
+    }
+    messageAttachments, err := h.files.List(c.Request.Context(), attachmentIDs)
+    if err != nil {
+        c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения сообщений"})
+        return
+    }
+    quarantined := attachments.Quarantined(messageAttachments)
+
+    // Ссылки на файлы подписываются на короткое время и только для участников переписки
+    for i := range messages {
+        if messages[i].ObjectKey == "" || messages[i].Blocked || quarantined[messages[i].AttachmentID] {
+            continue
+        }
+        fileURL, err := h.store.PresignGet(c.Request.Context(), config.VoiceBucket, messages[i].ObjectKey, storage.PresignTTL(), nil)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка получения сообщений"})
+            return
+        }
+        messages[i].FileURL = fileURL
+    }
+
     c.JSON(http.StatusOK, messages)
 }
+
+// parseUploadedAudio разбирает аудиофайл из запроса
+func parseUploadedAudio(header *multipart.FileHeader) (*audio.Info, error) {
+    file, err := header.Open()
+    if err != nil {
+        return nil, err
+    }
+    defer file.Close()
+    return audio.Parse(file)
+}
+
+// parseStoredAudio разбирает аудиофайл, уже сохраненный в хранилище
+func (h *Handler) parseStoredAudio(ctx context.Context, attachment *config.Attachment) (*audio.Info, error) {
+    object, _, err := h.store.Get(ctx, attachment.Bucket, attachment.ObjectKey)
+    if err != nil {
+        return nil, err
+    }
+    defer object.Close()
+    return audio.Parse(object)
+}
+
+// respondAudioError отправляет ответ на ошибку разбора аудиофайла
+func respondAudioError(c *gin.Context, err error) {
+    if errors.Is(err, audio.ErrInvalid) || errors.Is(err, audio.ErrUnsupportedFormat) {
+        c.JSON(http.StatusUnsupportedMediaType, config.ErrorResponse{Error: err.Error()})
+        return
+    }
+    c.JSON(http.StatusInternalServerError, config.ErrorResponse{Error: "Ошибка обработки файла"})
+}
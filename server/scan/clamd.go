@@ -0,0 +1,86 @@
+package scan
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "strings"
+)
+
+// clamdChunkSize — размер блока данных в команде INSTREAM
+const clamdChunkSize = 64 << 10
+
+// Clamd проверяет файлы демоном ClamAV по протоколу clamd (команда INSTREAM)
+type Clamd struct {
+    network string
+    address string
+    dialer  net.Dialer
+}
+
+// NewClamd создает клиент clamd. address — host:port или unix:/path/to/clamd.sock.
+func NewClamd(address string) *Clamd {
+    if path, ok := strings.CutPrefix(address, "unix:"); ok {
+        return &Clamd{network: "unix", address: strings.TrimPrefix(path, "//")}
+    }
+    return &Clamd{network: "tcp", address: strings.TrimPrefix(address, "tcp://")}
+}
+
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+    conn, err := c.dialer.DialContext(ctx, c.network, c.address)
+    if err != nil {
+        return nil, fmt.Errorf("подключение к clamd: %w", err)
+    }
+    defer conn.Close()
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+    }
+
+    if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+        return nil, err
+    }
+
+    // Данные передаются блоками: 4 байта длины в сетевом порядке и сам блок; нулевая длина — конец файла
+    buf := make([]byte, 4+clamdChunkSize)
+    for {
+        n, readErr := io.ReadFull(r, buf[4:])
+        if n > 0 {
+            binary.BigEndian.PutUint32(buf, uint32(n))
+            if _, err := conn.Write(buf[:4+n]); err != nil {
+                // clamd закрывает соединение при превышении StreamMaxLength; причина будет в ответе
+                break
+            }
+        }
+        if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+            break
+        }
+        if readErr != nil {
+            return nil, readErr
+        }
+    }
+    conn.Write([]byte{0, 0, 0, 0})
+
+    reply, err := bufio.NewReader(conn).ReadBytes(0)
+    if err != nil && len(reply) == 0 {
+        return nil, fmt.Errorf("ответ clamd: %w", err)
+    }
+    return parseClamdReply(string(bytes.TrimRight(reply, "\x00")))
+}
+
+// parseClamdReply разбирает ответ вида "stream: OK", "stream: <сигнатура> FOUND" или "... ERROR"
+func parseClamdReply(reply string) (*Result, error) {
+    reply = strings.TrimSpace(reply)
+    status := strings.TrimPrefix(reply, "stream: ")
+    switch {
+    case status == "OK":
+        return &Result{}, nil
+    case strings.HasSuffix(status, " FOUND"):
+        return &Result{Infected: true, Signature: strings.TrimSuffix(status, " FOUND")}, nil
+    default:
+        return nil, errors.New("ошибка clamd: " + reply)
+    }
+}
@@ -0,0 +1,49 @@
+package scan
+
+import (
+    "bytes"
+    "context"
+    "io"
+    "sync"
+)
+
+// eicar — стандартная тестовая строка антивирусов EICAR
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// Fake находит в файлах только тестовую строку EICAR. Используется в тестах
+// и при локальном запуске.
+type Fake struct {
+    Signature string // Если задана, любой файл считается зараженным с этой сигнатурой
+    Err       error
+
+    mu    sync.Mutex
+    calls int
+}
+
+func (f *Fake) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+    f.mu.Lock()
+    f.calls++
+    f.mu.Unlock()
+
+    data, err := io.ReadAll(r)
+    if err != nil {
+        return nil, err
+    }
+    if f.Err != nil {
+        return nil, f.Err
+    }
+    if f.Signature != "" {
+        return &Result{Infected: true, Signature: f.Signature}, nil
+    }
+    if bytes.Contains(data, []byte(eicar)) {
+        return &Result{Infected: true, Signature: "Eicar-Test-Signature"}, nil
+    }
+    return &Result{}, nil
+}
+
+// Calls возвращает количество вызовов Scan
+func (f *Fake) Calls() int {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return f.calls
+}
@@ -0,0 +1,62 @@
+// Package scan проверяет загруженные файлы на вредоносное содержимое. Реализация
+// выбирается в конфигурации: clamd (ClamAV) или Fake для тестов и локального запуска.
+package scan
+
+import (
+    "context"
+    "io"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/logging"
+)
+
+// Result — результат проверки файла
+type Result struct {
+    Infected  bool
+    Signature string // Название обнаруженной угрозы
+}
+
+// Scanner проверяет содержимое файла
+type Scanner interface {
+    Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
+
+// scanner — реализация, выбранная в конфигурации; nil — проверка отключена
+var scanner Scanner
+
+// Timeout ограничивает время проверки одного файла
+var Timeout = 2 * time.Minute
+
+// Init выбирает реализацию проверки файлов
+func Init(cfg *config.Config) {
+    if cfg.Scan.Timeout > 0 {
+        Timeout = time.Duration(cfg.Scan.Timeout) * time.Second
+    }
+    switch cfg.Scan.Driver {
+    case "":
+    case "clamd":
+        scanner = NewClamd(cfg.Scan.Address)
+    case "fake":
+        scanner = &Fake{}
+    default:
+        logging.Fatal("Неизвестный драйвер проверки файлов", "driver", cfg.Scan.Driver)
+    }
+}
+
+// SetScanner подменяет реализацию проверки; nil отключает проверку
+func SetScanner(s Scanner) {
+    scanner = s
+}
+
+// Enabled сообщает, включена ли проверка файлов
+func Enabled() bool {
+    return scanner != nil
+}
+
+// Scan проверяет файл выбранной реализацией
+func Scan(ctx context.Context, r io.Reader) (*Result, error) {
+    ctx, cancel := context.WithTimeout(ctx, Timeout)
+    defer cancel()
+    return scanner.Scan(ctx, r)
+}
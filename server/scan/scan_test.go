@@ -0,0 +1,210 @@
+package scan
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/binary"
+    "errors"
+    "io"
+    "net"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestParseClamdReply(t *testing.T) {
+    tests := []struct {
+        name    string
+        reply   string
+        want    *Result
+        wantErr bool
+    }{
+        {name: "чистый файл", reply: "stream: OK", want: &Result{}},
+        {name: "перевод строки в ответе", reply: "stream: OK\n", want: &Result{}},
+        {name: "угроза", reply: "stream: Eicar-Test-Signature FOUND", want: &Result{Infected: true, Signature: "Eicar-Test-Signature"}},
+        {name: "превышен размер", reply: "INSTREAM size limit exceeded. ERROR", wantErr: true},
+        {name: "ошибка проверки", reply: "stream: Can't allocate memory ERROR", wantErr: true},
+        {name: "пустой ответ", reply: "", wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            got, err := parseClamdReply(tt.reply)
+            if tt.wantErr {
+                if err == nil {
+                    t.Fatalf("ожидалась ошибка, получен результат %+v", got)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if *got != *tt.want {
+                t.Fatalf("результат %+v, ожидался %+v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestNewClamdAddress(t *testing.T) {
+    tests := []struct {
+        address     string
+        wantNetwork string
+        wantAddress string
+    }{
+        {address: "localhost:3310", wantNetwork: "tcp", wantAddress: "localhost:3310"},
+        {address: "tcp://clamav:3310", wantNetwork: "tcp", wantAddress: "clamav:3310"},
+        {address: "unix:/run/clamd.sock", wantNetwork: "unix", wantAddress: "/run/clamd.sock"},
+        {address: "unix:///run/clamd.sock", wantNetwork: "unix", wantAddress: "/run/clamd.sock"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.address, func(t *testing.T) {
+            c := NewClamd(tt.address)
+            if c.network != tt.wantNetwork || c.address != tt.wantAddress {
+                t.Fatalf("%s %s, ожидалось %s %s", c.network, c.address, tt.wantNetwork, tt.wantAddress)
+            }
+        })
+    }
+}
+
+// clamdSession — то, что фиктивный clamd получил от клиента
+type clamdSession struct {
+    command string
+    chunks  []int
+    data    []byte
+    err     error
+}
+
+// serveClamd принимает одно соединение, читает команду INSTREAM с блоками до нулевой длины
+// и отвечает reply
+func serveClamd(t *testing.T, reply string) (string, <-chan clamdSession) {
+    t.Helper()
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { listener.Close() })
+
+    sessions := make(chan clamdSession, 1)
+    go func() {
+        conn, err := listener.Accept()
+        if err != nil {
+            sessions <- clamdSession{err: err}
+            return
+        }
+        defer conn.Close()
+        conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+        var session clamdSession
+        r := bufio.NewReader(conn)
+        session.command, session.err = r.ReadString(0)
+        for session.err == nil {
+            var size uint32
+            if session.err = binary.Read(r, binary.BigEndian, &size); session.err != nil || size == 0 {
+                break
+            }
+            chunk := make([]byte, size)
+            if _, session.err = io.ReadFull(r, chunk); session.err != nil {
+                break
+            }
+            session.chunks = append(session.chunks, int(size))
+            session.data = append(session.data, chunk...)
+        }
+        if session.err == nil {
+            _, session.err = conn.Write([]byte(reply + "\x00"))
+        }
+        sessions <- session
+    }()
+    return listener.Addr().String(), sessions
+}
+
+func TestClamdInstream(t *testing.T) {
+    // Больше одного блока, чтобы проверить разбиение и последний неполный блок
+    content := bytes.Repeat([]byte("0123456789abcdef"), clamdChunkSize/16*2+100)
+    address, sessions := serveClamd(t, "stream: Eicar-Test-Signature FOUND")
+
+    result, err := NewClamd(address).Scan(context.Background(), bytes.NewReader(content))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !result.Infected || result.Signature != "Eicar-Test-Signature" {
+        t.Fatalf("неожиданный результат %+v", result)
+    }
+
+    session := <-sessions
+    if session.err != nil {
+        t.Fatalf("ошибка чтения команды: %v", session.err)
+    }
+    if session.command != "zINSTREAM\x00" {
+        t.Fatalf("команда %q", session.command)
+    }
+    wantChunks := []int{clamdChunkSize, clamdChunkSize, 1600}
+    if len(session.chunks) != len(wantChunks) {
+        t.Fatalf("блоки %v, ожидались %v", session.chunks, wantChunks)
+    }
+    for i := range wantChunks {
+        if session.chunks[i] != wantChunks[i] {
+            t.Fatalf("блоки %v, ожидались %v", session.chunks, wantChunks)
+        }
+    }
+    if !bytes.Equal(session.data, content) {
+        t.Fatal("clamd получил не то содержимое, которое передано на проверку")
+    }
+}
+
+func TestClamdEmptyFile(t *testing.T) {
+    address, sessions := serveClamd(t, "stream: OK")
+
+    result, err := NewClamd(address).Scan(context.Background(), strings.NewReader(""))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if result.Infected {
+        t.Fatalf("пустой файл признан зараженным: %+v", result)
+    }
+    // Пустой файл передается одним завершающим блоком нулевой длины
+    if session := <-sessions; session.err != nil || len(session.chunks) != 0 {
+        t.Fatalf("блоки %v, ошибка %v", session.chunks, session.err)
+    }
+}
+
+func TestFake(t *testing.T) {
+    failure := errors.New("clamd недоступен")
+
+    tests := []struct {
+        name    string
+        fake    *Fake
+        content string
+        want    *Result
+        wantErr bool
+    }{
+        {name: "чистый файл", fake: &Fake{}, content: "обычный текст", want: &Result{}},
+        {name: "тестовая строка EICAR", fake: &Fake{}, content: "prefix " + eicar, want: &Result{Infected: true, Signature: "Eicar-Test-Signature"}},
+        {name: "заданная сигнатура", fake: &Fake{Signature: "Test.Virus"}, content: "обычный текст", want: &Result{Infected: true, Signature: "Test.Virus"}},
+        {name: "ошибка проверки", fake: &Fake{Err: failure}, content: "обычный текст", wantErr: true},
+    }
+
+    defer SetScanner(nil)
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            SetScanner(tt.fake)
+            if !Enabled() {
+                t.Fatal("проверка не включена после SetScanner")
+            }
+
+            got, err := Scan(context.Background(), strings.NewReader(tt.content))
+            if tt.wantErr {
+                if !errors.Is(err, failure) {
+                    t.Fatalf("ошибка %v, ожидалась %v", err, failure)
+                }
+            } else if err != nil || *got != *tt.want {
+                t.Fatalf("результат %+v, ошибка %v, ожидался %+v", got, err, tt.want)
+            }
+            if tt.fake.Calls() != 1 {
+                t.Fatalf("вызовов %d, ожидался один", tt.fake.Calls())
+            }
+        })
+    }
+}
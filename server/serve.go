@@ -0,0 +1,51 @@
+package main
+
+import (
+    "context"
+    "errors"
+    "log/slog"
+    "net"
+    "net/http"
+    "time"
+)
+
+// readHeaderTimeout — время на получение заголовков запроса. Тело не ограничено по времени:
+// загрузка больших файлов на медленном соединении может длиться долго.
+const readHeaderTimeout = 10 * time.Second
+
+// newServer создает HTTP-сервер API
+func newServer(handler http.Handler) *http.Server {
+    return &http.Server{
+        Handler: handler,
+        // Клиент, не отправивший заголовки запроса, не занимает соединение бесконечно
+        ReadHeaderTimeout: readHeaderTimeout,
+    }
+}
+
+// serve принимает запросы на listener, пока не отменен ctx. После отмены сервер перестает
+// принимать соединения, закрывает простаивающие и ждет завершения начатых запросов, но не дольше
+// shutdownTimeout. Ошибка возвращается, только если сервер не смог принимать соединения;
+// запросы, не успевшие завершиться, записываются в журнал.
+func serve(ctx context.Context, server *http.Server, listener net.Listener, shutdownTimeout time.Duration) error {
+    serverErr := make(chan error, 1)
+    go func() {
+        serverErr <- server.Serve(listener)
+    }()
+
+    select {
+    case err := <-serverErr:
+        return err
+    case <-ctx.Done():
+    }
+
+    slog.Info("Остановка сервера", "timeout_s", shutdownTimeout.Seconds())
+    shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+    defer cancel()
+    if err := server.Shutdown(shutdownCtx); err != nil {
+        slog.Error("Не все запросы завершились до остановки сервера", "error", err)
+    }
+    if err := <-serverErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+        return err
+    }
+    return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+    "context"
+    "io"
+    "log/slog"
+    "net"
+    "net/http"
+    "testing"
+    "time"
+)
+
+// startServe запускает serve с обработчиком handler и возвращает адрес сервера,
+// функцию остановки и канал с результатом serve
+func startServe(t *testing.T, handler http.Handler, shutdownTimeout time.Duration) (string, context.CancelFunc, <-chan error) {
+    t.Helper()
+    previous := slog.Default()
+    slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+    t.Cleanup(func() { slog.SetDefault(previous) })
+
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    ctx, cancel := context.WithCancel(context.Background())
+    t.Cleanup(cancel)
+    done := make(chan error, 1)
+    go func() {
+        done <- serve(ctx, newServer(handler), listener, shutdownTimeout)
+    }()
+    return "http://" + listener.Addr().String(), cancel, done
+}
+
+// slowHandler сообщает в started о начале запроса и отвечает только после закрытия release
+func slowHandler(started chan<- struct{}, release <-chan struct{}) http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        started <- struct{}{}
+        <-release
+        io.WriteString(w, "done")
+    })
+}
+
+func TestServeShutdown(t *testing.T) {
+    tests := []struct {
+        name            string
+        shutdownTimeout time.Duration
+        releaseAfter    time.Duration
+        // waitsForRequest — serve завершается только после ответа на начатый запрос
+        waitsForRequest bool
+    }{
+        {name: "ждет начатый запрос", shutdownTimeout: 5 * time.Second, releaseAfter: 100 * time.Millisecond, waitsForRequest: true},
+        {name: "не ждет дольше таймаута", shutdownTimeout: 100 * time.Millisecond, releaseAfter: 2 * time.Second, waitsForRequest: false},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            started := make(chan struct{}, 1)
+            release := make(chan struct{})
+            address, stop, done := startServe(t, slowHandler(started, release), tt.shutdownTimeout)
+
+            responses := make(chan string, 1)
+            go func() {
+                resp, err := http.Get(address)
+                if err != nil {
+                    responses <- err.Error()
+                    return
+                }
+                defer resp.Body.Close()
+                body, _ := io.ReadAll(resp.Body)
+                responses <- string(body)
+            }()
+            <-started
+
+            // Останавливаем сервер, пока запрос выполняется
+            stop()
+            time.Sleep(50 * time.Millisecond)
+            if _, err := http.Get(address); err == nil {
+                t.Fatalf("сервер принимает новые соединения после начала остановки")
+            }
+
+            released := make(chan struct{})
+            go func() {
+                time.Sleep(tt.releaseAfter)
+                close(release)
+                close(released)
+            }()
+            select {
+            case err := <-done:
+                if err != nil {
+                    t.Fatalf("serve вернул ошибку: %v", err)
+                }
+            case <-time.After(5 * time.Second):
+                t.Fatalf("serve не завершился")
+            }
+
+            select {
+            case <-released:
+                if !tt.waitsForRequest {
+                    t.Fatalf("serve ждал запрос дольше таймаута остановки")
+                }
+            default:
+                if tt.waitsForRequest {
+                    t.Fatalf("serve завершился до ответа на начатый запрос")
+                }
+            }
+            if body := <-responses; body != "done" {
+                t.Fatalf("начатый запрос не завершился: %q", body)
+            }
+        })
+    }
+}
+
+func TestServeListenerError(t *testing.T) {
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    listener.Close()
+
+    err = serve(context.Background(), newServer(http.NotFoundHandler()), listener, time.Second)
+    if err == nil {
+        t.Fatalf("serve не вернул ошибку закрытого listener")
+    }
+}
@@ -0,0 +1,311 @@
+package storage
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "io"
+    "mime"
+    "net/http"
+    "net/url"
+    "os"
+    "path/filepath"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+
+    "chatter-hub-server/config"
+)
+
+// multipartDir — каталог незавершенных составных загрузок внутри корня хранилища
+const multipartDir = ".multipart"
+
+// LocalStore хранит файлы на локальном диске: бакет — каталог, ключ — путь внутри него.
+// Ссылки на файлы подписывает Signer, обслуживает их сервер.
+type LocalStore struct {
+    root string
+    sign *Signer
+}
+
+// NewLocal создает хранилище в каталоге root
+func NewLocal(root string, signer *Signer) (*LocalStore, error) {
+    root, err := filepath.Abs(root)
+    if err != nil {
+        return nil, err
+    }
+    if err := os.MkdirAll(root, 0o750); err != nil {
+        return nil, err
+    }
+    return &LocalStore{root: root, sign: signer}, nil
+}
+
+func (s *LocalStore) signer() *Signer {
+    return s.sign
+}
+
+// path возвращает путь к объекту, не позволяя ключу выйти за пределы бакета
+func (s *LocalStore) path(bucket, key string) (string, error) {
+    if bucket == "" || strings.ContainsAny(bucket, `/\`) || bucket == multipartDir || strings.HasPrefix(bucket, ".") {
+        return "", ErrInvalidKey
+    }
+    cleaned := filepath.Clean("/" + filepath.FromSlash(key))
+    if key == "" || cleaned == string(filepath.Separator) || cleaned != string(filepath.Separator)+filepath.FromSlash(key) {
+        return "", ErrInvalidKey
+    }
+    return filepath.Join(s.root, bucket, cleaned), nil
+}
+
+func (s *LocalStore) EnsureBucket(ctx context.Context, bucket string) error {
+    if _, err := s.path(bucket, "x"); err != nil {
+        return err
+    }
+    return os.MkdirAll(filepath.Join(s.root, bucket), 0o750)
+}
+
+// Ping проверяет, что каталоги бакетов сервера существуют и доступны
+func (s *LocalStore) Ping(ctx context.Context) error {
+    for _, bucket := range config.Buckets {
+        info, err := os.Stat(filepath.Join(s.root, bucket))
+        if err != nil {
+            return err
+        }
+        if !info.IsDir() {
+            return fmt.Errorf("%s не является каталогом", info.Name())
+        }
+    }
+    return nil
+}
+
+// Put записывает объект во временный файл и переименовывает его, чтобы читатели
+// никогда не видели частично записанный объект
+func (s *LocalStore) Put(ctx context.Context, bucket, key string, r io.Reader, size int64, contentType string) error {
+    path, err := s.path(bucket, key)
+    if err != nil {
+        return err
+    }
+    if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+        return err
+    }
+    return writeFile(path, r, size)
+}
+
+func (s *LocalStore) Get(ctx context.Context, bucket, key string) (io.ReadSeekCloser, ObjectInfo, error) {
+    path, err := s.path(bucket, key)
+    if err != nil {
+        return nil, ObjectInfo{}, err
+    }
+    file, err := os.Open(path)
+    if err != nil {
+        return nil, ObjectInfo{}, convertFileError(err)
+    }
+    info, err := fileInfo(file)
+    if err != nil {
+        file.Close()
+        return nil, ObjectInfo{}, err
+    }
+    return file, info, nil
+}
+
+func (s *LocalStore) Stat(ctx context.Context, bucket, key string) (ObjectInfo, error) {
+    path, err := s.path(bucket, key)
+    if err != nil {
+        return ObjectInfo{}, err
+    }
+    stat, err := os.Stat(path)
+    if err != nil {
+        return ObjectInfo{}, convertFileError(err)
+    }
+    if stat.IsDir() {
+        return ObjectInfo{}, ErrNotFound
+    }
+    return ObjectInfo{Size: stat.Size(), ContentType: mime.TypeByExtension(filepath.Ext(path)), LastModified: stat.ModTime()}, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, bucket, key string) error {
+    path, err := s.path(bucket, key)
+    if err != nil {
+        return err
+    }
+    if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+        return err
+    }
+    return nil
+}
+
+func (s *LocalStore) List(ctx context.Context, bucket string, fn func(ListedObject) error) error {
+    if _, err := s.path(bucket, "x"); err != nil {
+        return err
+    }
+    dir := filepath.Join(s.root, bucket)
+    // WalkDir обходит каталоги в лексическом порядке имен
+    return filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+        if err != nil {
+            if errors.Is(err, os.ErrNotExist) {
+                return nil
+            }
+            return err
+        }
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+        // Временные файлы незавершенной записи объектами не являются
+        if entry.IsDir() || strings.HasPrefix(entry.Name(), ".upload-") {
+            return nil
+        }
+        stat, err := entry.Info()
+        if err != nil {
+            if errors.Is(err, os.ErrNotExist) {
+                return nil
+            }
+            return err
+        }
+        rel, err := filepath.Rel(dir, path)
+        if err != nil {
+            return err
+        }
+        return fn(ListedObject{
+            Key: filepath.ToSlash(rel),
+            ObjectInfo: ObjectInfo{
+                Size:         stat.Size(),
+                ContentType:  mime.TypeByExtension(filepath.Ext(path)),
+                LastModified: stat.ModTime(),
+            },
+        })
+    })
+}
+
+func (s *LocalStore) PresignGet(ctx context.Context, bucket, key string, ttl time.Duration, params url.Values) (string, error) {
+    if _, err := s.path(bucket, key); err != nil {
+        return "", err
+    }
+    return s.sign.URL(http.MethodGet, bucket, key, ttl, params), nil
+}
+
+func (s *LocalStore) PresignPut(ctx context.Context, bucket, key string, ttl time.Duration, size int64, contentType string) (string, error) {
+    if _, err := s.path(bucket, key); err != nil {
+        return "", err
+    }
+    return s.sign.URL(http.MethodPut, bucket, key, ttl, putParams(size, contentType)), nil
+}
+
+// uploadDir возвращает каталог частей составной загрузки
+func (s *LocalStore) uploadDir(uploadID string) (string, error) {
+    if _, err := hex.DecodeString(uploadID); err != nil || uploadID == "" {
+        return "", ErrInvalidKey
+    }
+    return filepath.Join(s.root, multipartDir, uploadID), nil
+}
+
+func (s *LocalStore) CreateMultipart(ctx context.Context, bucket, key, contentType string) (string, error) {
+    if _, err := s.path(bucket, key); err != nil {
+        return "", err
+    }
+    id := make([]byte, 16)
+    if _, err := rand.Read(id); err != nil {
+        return "", err
+    }
+    uploadID := hex.EncodeToString(id)
+    dir, _ := s.uploadDir(uploadID)
+    return uploadID, os.MkdirAll(dir, 0o750)
+}
+
+func (s *LocalStore) PutPart(ctx context.Context, bucket, key, uploadID string, number int, r io.Reader, size int64) error {
+    dir, err := s.uploadDir(uploadID)
+    if err != nil {
+        return err
+    }
+    if _, err := os.Stat(dir); err != nil {
+        return convertFileError(err)
+    }
+    return writeFile(filepath.Join(dir, strconv.Itoa(number)), r, size)
+}
+
+// CompleteMultipart склеивает части в порядке номеров и удаляет каталог загрузки
+func (s *LocalStore) CompleteMultipart(ctx context.Context, bucket, key, uploadID string) error {
+    dir, err := s.uploadDir(uploadID)
+    if err != nil {
+        return err
+    }
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        return convertFileError(err)
+    }
+    numbers := make([]int, 0, len(entries))
+    for _, entry := range entries {
+        if number, err := strconv.Atoi(entry.Name()); err == nil {
+            numbers = append(numbers, number)
+        }
+    }
+    sort.Ints(numbers)
+
+    readers := make([]io.Reader, 0, len(numbers))
+    var size int64
+    for _, number := range numbers {
+        part, err := os.Open(filepath.Join(dir, strconv.Itoa(number)))
+        if err != nil {
+            return err
+        }
+        defer part.Close()
+        stat, err := part.Stat()
+        if err != nil {
+            return err
+        }
+        size += stat.Size()
+        readers = append(readers, part)
+    }
+
+    if err := s.Put(ctx, bucket, key, io.MultiReader(readers...), size, ""); err != nil {
+        return err
+    }
+    return os.RemoveAll(dir)
+}
+
+func (s *LocalStore) AbortMultipart(ctx context.Context, bucket, key, uploadID string) error {
+    dir, err := s.uploadDir(uploadID)
+    if err != nil {
+        return err
+    }
+    return os.RemoveAll(dir)
+}
+
+// writeFile атомарно записывает ровно size байт из r в файл path
+func writeFile(path string, r io.Reader, size int64) error {
+    tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+    if err != nil {
+        return err
+    }
+    defer os.Remove(tmp.Name())
+
+    written, err := io.Copy(tmp, io.LimitReader(r, size))
+    if closeErr := tmp.Close(); err == nil {
+        err = closeErr
+    }
+    if err != nil {
+        return err
+    }
+    if written != size {
+        return fmt.Errorf("записано %d байт из %d", written, size)
+    }
+    return os.Rename(tmp.Name(), path)
+}
+
+func fileInfo(file *os.File) (ObjectInfo, error) {
+    stat, err := file.Stat()
+    if err != nil {
+        return ObjectInfo{}, err
+    }
+    if stat.IsDir() {
+        return ObjectInfo{}, ErrNotFound
+    }
+    return ObjectInfo{Size: stat.Size(), ContentType: mime.TypeByExtension(filepath.Ext(file.Name())), LastModified: stat.ModTime()}, nil
+}
+
+func convertFileError(err error) error {
+    if errors.Is(err, os.ErrNotExist) {
+        return ErrNotFound
+    }
+    return err
+}
@@ -0,0 +1,218 @@
+package storage
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "sort"
+    "sync"
+    "time"
+
+    "github.com/google/uuid"
+)
+
+// MemoryStore хранит файлы в памяти процесса. Предназначено для тестов и локального запуска.
+type MemoryStore struct {
+    mu      sync.RWMutex
+    buckets map[string]map[string]memoryObject
+    uploads map[string]map[int][]byte
+    sign    *Signer
+}
+
+type memoryObject struct {
+    data         []byte
+    contentType  string
+    lastModified time.Time
+}
+
+// NewMemory создает пустое хранилище в памяти
+func NewMemory(signer *Signer) *MemoryStore {
+    return &MemoryStore{
+        buckets: make(map[string]map[string]memoryObject),
+        uploads: make(map[string]map[int][]byte),
+        sign:    signer,
+    }
+}
+
+func (s *MemoryStore) signer() *Signer {
+    return s.sign
+}
+
+func (s *MemoryStore) EnsureBucket(ctx context.Context, bucket string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if _, ok := s.buckets[bucket]; !ok {
+        s.buckets[bucket] = make(map[string]memoryObject)
+    }
+    return nil
+}
+
+func (s *MemoryStore) Put(ctx context.Context, bucket, key string, r io.Reader, size int64, contentType string) error {
+    // Как и сетевые хранилища, не записываем данные по отмененному запросу
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    data, err := readExactly(r, size)
+    if err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    objects, ok := s.buckets[bucket]
+    if !ok {
+        return ErrNotFound
+    }
+    objects[key] = memoryObject{data: data, contentType: contentType, lastModified: time.Now()}
+    return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, bucket, key string) (io.ReadSeekCloser, ObjectInfo, error) {
+    object, err := s.object(bucket, key)
+    if err != nil {
+        return nil, ObjectInfo{}, err
+    }
+    // Данные объекта не изменяются после записи, поэтому читатель может использовать их без копирования
+    return nopCloser{bytes.NewReader(object.data)}, object.info(), nil
+}
+
+func (s *MemoryStore) Stat(ctx context.Context, bucket, key string) (ObjectInfo, error) {
+    object, err := s.object(bucket, key)
+    if err != nil {
+        return ObjectInfo{}, err
+    }
+    return object.info(), nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, bucket, key string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.buckets[bucket], key)
+    return nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, bucket string, fn func(ListedObject) error) error {
+    // Обходим снимок бакета, чтобы fn могла изменять хранилище
+    s.mu.RLock()
+    objects, ok := s.buckets[bucket]
+    if !ok {
+        s.mu.RUnlock()
+        return ErrNotFound
+    }
+    listed := make([]ListedObject, 0, len(objects))
+    for key, object := range objects {
+        listed = append(listed, ListedObject{Key: key, ObjectInfo: object.info()})
+    }
+    s.mu.RUnlock()
+
+    sort.Slice(listed, func(i, j int) bool { return listed[i].Key < listed[j].Key })
+    for _, object := range listed {
+        if err := fn(object); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (s *MemoryStore) PresignGet(ctx context.Context, bucket, key string, ttl time.Duration, params url.Values) (string, error) {
+    return s.sign.URL(http.MethodGet, bucket, key, ttl, params), nil
+}
+
+func (s *MemoryStore) PresignPut(ctx context.Context, bucket, key string, ttl time.Duration, size int64, contentType string) (string, error) {
+    return s.sign.URL(http.MethodPut, bucket, key, ttl, putParams(size, contentType)), nil
+}
+
+func (s *MemoryStore) CreateMultipart(ctx context.Context, bucket, key, contentType string) (string, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    uploadID := uuid.New().String()
+    s.uploads[uploadID] = make(map[int][]byte)
+    return uploadID, nil
+}
+
+func (s *MemoryStore) PutPart(ctx context.Context, bucket, key, uploadID string, number int, r io.Reader, size int64) error {
+    if err := ctx.Err(); err != nil {
+        return err
+    }
+    data, err := readExactly(r, size)
+    if err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    parts, ok := s.uploads[uploadID]
+    if !ok {
+        return ErrNotFound
+    }
+    parts[number] = data
+    return nil
+}
+
+func (s *MemoryStore) CompleteMultipart(ctx context.Context, bucket, key, uploadID string) error {
+    s.mu.Lock()
+    parts, ok := s.uploads[uploadID]
+    delete(s.uploads, uploadID)
+    s.mu.Unlock()
+    if !ok {
+        return ErrNotFound
+    }
+
+    numbers := make([]int, 0, len(parts))
+    var size int64
+    for number, part := range parts {
+        numbers = append(numbers, number)
+        size += int64(len(part))
+    }
+    sort.Ints(numbers)
+
+    readers := make([]io.Reader, 0, len(numbers))
+    for _, number := range numbers {
+        readers = append(readers, bytes.NewReader(parts[number]))
+    }
+    return s.Put(ctx, bucket, key, io.MultiReader(readers...), size, "")
+}
+
+func (s *MemoryStore) AbortMultipart(ctx context.Context, bucket, key, uploadID string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    delete(s.uploads, uploadID)
+    return nil
+}
+
+func (s *MemoryStore) object(bucket, key string) (memoryObject, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    object, ok := s.buckets[bucket][key]
+    if !ok {
+        return memoryObject{}, ErrNotFound
+    }
+    return object, nil
+}
+
+func (o memoryObject) info() ObjectInfo {
+    return ObjectInfo{Size: int64(len(o.data)), ContentType: o.contentType, LastModified: o.lastModified}
+}
+
+// readExactly читает ровно size байт из r
+func readExactly(r io.Reader, size int64) ([]byte, error) {
+    data, err := io.ReadAll(io.LimitReader(r, size))
+    if err != nil {
+        return nil, err
+    }
+    if int64(len(data)) != size {
+        return nil, fmt.Errorf("прочитано %d байт из %d", len(data), size)
+    }
+    return data, nil
+}
+
+type nopCloser struct {
+    *bytes.Reader
+}
+
+func (nopCloser) Close() error {
+    return nil
+}
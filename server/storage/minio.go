@@ -0,0 +1,269 @@
+package storage
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "net/http"
+    "net/url"
+    "strconv"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/metrics"
+    "chatter-hub-server/tracing"
+
+    "github.com/minio/minio-go/v7"
+    "github.com/minio/minio-go/v7/pkg/credentials"
+    "go.opentelemetry.io/otel/attribute"
+    "go.opentelemetry.io/otel/trace"
+)
+
+// Регион указываем явно, чтобы подпись ссылок не требовала запроса местоположения бакета
+const minioRegion = "us-east-1"
+
+// MinioStore хранит файлы в MinIO или другом S3-совместимом хранилище
+type MinioStore struct {
+    client *minio.Client
+    // presign подписывает ссылки для клиентов. Подпись S3 включает хост,
+    // поэтому для внешнего адреса MinIO нужен отдельный клиент.
+    presign *minio.Client
+    // timeout ограничивает обращения, длительность которых не зависит от размера объекта
+    timeout time.Duration
+}
+
+// NewMinio создает клиент MinIO по конфигурации
+func NewMinio(cfg config.MinioConfig) (*MinioStore, error) {
+    client, err := minio.New(cfg.Endpoint, &minio.Options{
+        Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+        Secure: cfg.UseSSL,
+        Region: minioRegion,
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    store := &MinioStore{client: client, presign: client, timeout: time.Duration(cfg.Timeout) * time.Second}
+    if cfg.PublicEndpoint != "" {
+        store.presign, err = minio.New(cfg.PublicEndpoint, &minio.Options{
+            Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+            Secure: cfg.UseSSL,
+            Region: minioRegion,
+        })
+        if err != nil {
+            return nil, err
+        }
+    }
+    return store, nil
+}
+
+func (s *MinioStore) EnsureBucket(ctx context.Context, bucket string) (err error) {
+    ctx, call := startCall(ctx, "ensure_bucket", bucket, "", s.timeout)
+    defer call.end(&err)
+    exists, err := s.client.BucketExists(ctx, bucket)
+    if err != nil && minio.ToErrorResponse(err).Code != "NoSuchBucket" {
+        return err
+    }
+    if exists {
+        return nil
+    }
+    return s.client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: minioRegion})
+}
+
+func (s *MinioStore) Ping(ctx context.Context) (err error) {
+    ctx, call := startCall(ctx, "ping", "", "", s.timeout)
+    defer call.end(&err)
+    for _, bucket := range config.Buckets {
+        exists, err := s.client.BucketExists(ctx, bucket)
+        if err != nil {
+            return err
+        }
+        if !exists {
+            return fmt.Errorf("бакет %s не найден", bucket)
+        }
+    }
+    return nil
+}
+
+func (s *MinioStore) Put(ctx context.Context, bucket, key string, r io.Reader, size int64, contentType string) (err error) {
+    ctx, call := startCall(ctx, "put", bucket, key, 0)
+    defer call.end(&err)
+    _, err = s.client.PutObject(ctx, bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+    return err
+}
+
+func (s *MinioStore) Get(ctx context.Context, bucket, key string) (_ io.ReadSeekCloser, _ ObjectInfo, err error) {
+    ctx, call := startCall(ctx, "get", bucket, key, 0)
+    defer call.end(&err)
+    object, err := s.client.GetObject(ctx, bucket, key, minio.GetObjectOptions{})
+    if err != nil {
+        return nil, ObjectInfo{}, convertError(err)
+    }
+    // GetObject не обращается к серверу до первого чтения; Stat проверяет, что объект существует
+    info, err := object.Stat()
+    if err != nil {
+        object.Close()
+        return nil, ObjectInfo{}, convertError(err)
+    }
+    return object, objectInfo(info), nil
+}
+
+func (s *MinioStore) Stat(ctx context.Context, bucket, key string) (_ ObjectInfo, err error) {
+    ctx, call := startCall(ctx, "stat", bucket, key, s.timeout)
+    defer call.end(&err)
+    info, err := s.client.StatObject(ctx, bucket, key, minio.StatObjectOptions{})
+    if err != nil {
+        return ObjectInfo{}, convertError(err)
+    }
+    return objectInfo(info), nil
+}
+
+func (s *MinioStore) Delete(ctx context.Context, bucket, key string) (err error) {
+    ctx, call := startCall(ctx, "delete", bucket, key, s.timeout)
+    defer call.end(&err)
+    return s.client.RemoveObject(ctx, bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *MinioStore) List(ctx context.Context, bucket string, fn func(ListedObject) error) error {
+    // Прерываем листинг в MinIO, если fn вернула ошибку
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+    for object := range s.client.ListObjects(ctx, bucket, minio.ListObjectsOptions{Recursive: true}) {
+        if object.Err != nil {
+            return convertError(object.Err)
+        }
+        err := fn(ListedObject{
+            Key: object.Key,
+            ObjectInfo: ObjectInfo{
+                Size:         object.Size,
+                ContentType:  object.ContentType,
+                LastModified: object.LastModified,
+            },
+        })
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (s *MinioStore) PresignGet(ctx context.Context, bucket, key string, ttl time.Duration, params url.Values) (string, error) {
+    u, err := s.presign.PresignedGetObject(ctx, bucket, key, ttl, params)
+    if err != nil {
+        return "", err
+    }
+    return u.String(), nil
+}
+
+func (s *MinioStore) PresignPut(ctx context.Context, bucket, key string, ttl time.Duration, size int64, contentType string) (string, error) {
+    // Подписанные заголовки MinIO сверяет с запросом клиента
+    headers := http.Header{}
+    headers.Set("Content-Length", strconv.FormatInt(size, 10))
+    headers.Set("Content-Type", contentType)
+    u, err := s.presign.PresignHeader(ctx, http.MethodPut, bucket, key, ttl, nil, headers)
+    if err != nil {
+        return "", err
+    }
+    return u.String(), nil
+}
+
+func (s *MinioStore) core() minio.Core {
+    return minio.Core{Client: s.client}
+}
+
+func (s *MinioStore) CreateMultipart(ctx context.Context, bucket, key, contentType string) (_ string, err error) {
+    ctx, call := startCall(ctx, "create_multipart", bucket, key, s.timeout)
+    defer call.end(&err)
+    return s.core().NewMultipartUpload(ctx, bucket, key, minio.PutObjectOptions{ContentType: contentType})
+}
+
+func (s *MinioStore) PutPart(ctx context.Context, bucket, key, uploadID string, number int, r io.Reader, size int64) (err error) {
+    ctx, call := startCall(ctx, "put_part", bucket, key, 0)
+    defer call.end(&err)
+    _, err = s.core().PutObjectPart(ctx, bucket, key, uploadID, number, r, size, minio.PutObjectPartOptions{})
+    return err
+}
+
+// CompleteMultipart собирает объект из всех загруженных частей в порядке их номеров
+func (s *MinioStore) CompleteMultipart(ctx context.Context, bucket, key, uploadID string) (err error) {
+    ctx, call := startCall(ctx, "complete_multipart", bucket, key, 0)
+    defer call.end(&err)
+    var parts []minio.CompletePart
+    marker := 0
+    for {
+        result, err := s.core().ListObjectParts(ctx, bucket, key, uploadID, marker, 1000)
+        if err != nil {
+            return err
+        }
+        for _, part := range result.ObjectParts {
+            parts = append(parts, minio.CompletePart{PartNumber: part.PartNumber, ETag: part.ETag})
+        }
+        if !result.IsTruncated {
+            break
+        }
+        marker = result.NextPartNumberMarker
+    }
+
+    _, err = s.core().CompleteMultipartUpload(ctx, bucket, key, uploadID, parts, minio.PutObjectOptions{})
+    return err
+}
+
+func (s *MinioStore) AbortMultipart(ctx context.Context, bucket, key, uploadID string) (err error) {
+    ctx, call := startCall(ctx, "abort_multipart", bucket, key, s.timeout)
+    defer call.end(&err)
+    err = s.core().AbortMultipartUpload(ctx, bucket, key, uploadID)
+    if err != nil && minio.ToErrorResponse(err).Code == "NoSuchUpload" {
+        return nil
+    }
+    return err
+}
+
+// call — обращение к MinIO: для него записываются спан трассировки и время выполнения
+type call struct {
+    operation string
+    start     time.Time
+    span      trace.Span
+    cancel    context.CancelFunc
+}
+
+// startCall начинает обращение. Если timeout больше нуля, обращение прерывается по его истечении,
+// даже если контекст запроса не ограничен по времени.
+func startCall(ctx context.Context, operation, bucket, key string, timeout time.Duration) (context.Context, *call) {
+    cancel := context.CancelFunc(func() {})
+    if timeout > 0 {
+        ctx, cancel = context.WithTimeout(ctx, timeout)
+    }
+    attrs := []attribute.KeyValue{attribute.String("storage.bucket", bucket)}
+    if key != "" {
+        attrs = append(attrs, attribute.String("storage.key", key))
+    }
+    ctx, span := tracing.StartChild(ctx, "minio."+operation,
+        trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+    return ctx, &call{operation: operation, start: time.Now(), span: span, cancel: cancel}
+}
+
+// end завершает обращение; отсутствие объекта ошибкой не считается
+func (c *call) end(err *error) {
+    c.cancel()
+    result := *err
+    if errors.Is(result, ErrNotFound) {
+        result = nil
+    }
+    metrics.ObserveStorage("minio", c.operation, c.start, result)
+    tracing.End(c.span, result)
+}
+
+func objectInfo(info minio.ObjectInfo) ObjectInfo {
+    return ObjectInfo{Size: info.Size, ContentType: info.ContentType, LastModified: info.LastModified}
+}
+
+// convertError заменяет ошибку отсутствия объекта на ErrNotFound
+func convertError(err error) error {
+    switch minio.ToErrorResponse(err).Code {
+    case "NoSuchKey", "NoSuchBucket":
+        return ErrNotFound
+    default:
+        return err
+    }
+}
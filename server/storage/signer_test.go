@@ -0,0 +1,126 @@
+package storage
+
+import (
+    "context"
+    "errors"
+    "net/http"
+    "net/url"
+    "strings"
+    "testing"
+    "time"
+)
+
+// signedQuery подписывает ссылку и возвращает путь к объекту и параметры запроса
+func signedQuery(t *testing.T, signer *Signer, method, key string, ttl time.Duration, params url.Values) (string, url.Values) {
+    t.Helper()
+    link, err := url.Parse(signer.URL(method, testBucket, key, ttl, params))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return link.Path, link.Query()
+}
+
+func TestSignerURL(t *testing.T) {
+    signer := NewSigner("test-key", "http://localhost:8080/")
+    path, query := signedQuery(t, signer, http.MethodGet, "dir/file name.txt", time.Minute, nil)
+
+    if path != SignedPath+"/"+testBucket+"/dir/file name.txt" {
+        t.Fatalf("путь ссылки %q", path)
+    }
+    if err := signer.Verify(http.MethodGet, testBucket, "dir/file name.txt", query); err != nil {
+        t.Fatalf("действительная ссылка отклонена: %v", err)
+    }
+}
+
+func TestSignerVerify(t *testing.T) {
+    signer := NewSigner("test-key", "http://localhost:8080")
+    params := url.Values{"response-content-disposition": {"attachment"}}
+
+    tests := []struct {
+        name    string
+        signer  *Signer
+        ttl     time.Duration
+        method  string
+        key     string
+        tamper  func(url.Values)
+        wantErr error
+    }{
+        {name: "действительная ссылка", ttl: time.Minute},
+        {name: "срок истек", ttl: -time.Second, wantErr: ErrExpired},
+        {name: "другой метод", ttl: time.Minute, method: http.MethodPut, wantErr: ErrInvalidSignature},
+        {name: "другой объект", ttl: time.Minute, key: "other.txt", wantErr: ErrInvalidSignature},
+        {name: "другой ключ подписи", signer: NewSigner("other-key", ""), ttl: time.Minute, wantErr: ErrInvalidSignature},
+        {
+            name: "продлен срок", ttl: -time.Second, wantErr: ErrInvalidSignature,
+            tamper: func(query url.Values) { query.Set("expires", "99999999999") },
+        },
+        {
+            name: "изменен параметр ответа", ttl: time.Minute, wantErr: ErrInvalidSignature,
+            tamper: func(query url.Values) { query.Set("response-content-disposition", "inline") },
+        },
+        {
+            name: "добавлен параметр", ttl: time.Minute, wantErr: ErrInvalidSignature,
+            tamper: func(query url.Values) { query.Set("response-content-type", "text/html") },
+        },
+        {
+            name: "нет подписи", ttl: time.Minute, wantErr: ErrInvalidSignature,
+            tamper: func(query url.Values) { query.Del("signature") },
+        },
+        {
+            name: "некорректный срок", ttl: time.Minute, wantErr: ErrInvalidSignature,
+            tamper: func(query url.Values) { query.Set("expires", "завтра") },
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            _, query := signedQuery(t, signer, http.MethodGet, "file.txt", tt.ttl, params)
+            if tt.tamper != nil {
+                tt.tamper(query)
+            }
+            verifier, method, key := signer, http.MethodGet, "file.txt"
+            if tt.signer != nil {
+                verifier = tt.signer
+            }
+            if tt.method != "" {
+                method = tt.method
+            }
+            if tt.key != "" {
+                key = tt.key
+            }
+
+            err := verifier.Verify(method, testBucket, key, query)
+            if !errors.Is(err, tt.wantErr) {
+                t.Fatalf("Verify вернул %v, ожидалось %v", err, tt.wantErr)
+            }
+        })
+    }
+}
+
+func TestVerifySigned(t *testing.T) {
+    store := NewMemory(NewSigner("test-key", "http://localhost:8080"))
+    if !ServesSignedURLs(store) {
+        t.Fatal("хранилище в памяти должно обслуживаться сервером")
+    }
+
+    link, err := store.PresignPut(context.Background(), testBucket, "upload.txt", time.Minute, 5, "text/plain")
+    if err != nil {
+        t.Fatal(err)
+    }
+    query, err := url.ParseQuery(link[strings.Index(link, "?")+1:])
+    if err != nil {
+        t.Fatal(err)
+    }
+    if query.Get(ParamContentLength) != "5" || query.Get(ParamContentType) != "text/plain" {
+        t.Fatalf("в ссылке на загрузку нет размера и типа: %v", query)
+    }
+    if err := VerifySigned(store, http.MethodPut, testBucket, "upload.txt", query); err != nil {
+        t.Fatalf("действительная ссылка отклонена: %v", err)
+    }
+
+    // Размер загрузки входит в подпись
+    query.Set(ParamContentLength, "50")
+    if err := VerifySigned(store, http.MethodPut, testBucket, "upload.txt", query); !errors.Is(err, ErrInvalidSignature) {
+        t.Fatalf("ссылка с измененным размером: %v", err)
+    }
+}
@@ -0,0 +1,130 @@
+package storage
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "net/url"
+    "time"
+
+    "chatter-hub-server/config"
+)
+
+var (
+    ErrNotFound             = errors.New("объект не найден")
+    ErrInvalidKey           = errors.New("недопустимый ключ объекта")
+    ErrMultipartUnsupported = errors.New("хранилище не поддерживает составную загрузку")
+)
+
+// ObjectInfo описывает объект в хранилище
+type ObjectInfo struct {
+    Size         int64
+    ContentType  string
+    LastModified time.Time
+}
+
+// ListedObject — объект, найденный при обходе бакета
+type ListedObject struct {
+    Key string
+    ObjectInfo
+}
+
+// BlobStore — хранилище файлов. Объекты адресуются бакетом и ключом.
+type BlobStore interface {
+    // EnsureBucket создает бакет, если его еще нет
+    EnsureBucket(ctx context.Context, bucket string) error
+    // Put сохраняет объект размером size байт, перезаписывая существующий
+    Put(ctx context.Context, bucket, key string, r io.Reader, size int64, contentType string) error
+    // Get открывает объект для чтения; возвращает ErrNotFound, если объекта нет
+    Get(ctx context.Context, bucket, key string) (io.ReadSeekCloser, ObjectInfo, error)
+    // Stat возвращает сведения об объекте; возвращает ErrNotFound, если объекта нет
+    Stat(ctx context.Context, bucket, key string) (ObjectInfo, error)
+    // Delete удаляет объект; отсутствие объекта ошибкой не считается
+    Delete(ctx context.Context, bucket, key string) error
+    // List вызывает fn для каждого объекта бакета в порядке ключей. fn может удалять объекты;
+    // ошибка fn прерывает обход и возвращается из List.
+    List(ctx context.Context, bucket string, fn func(ListedObject) error) error
+    // PresignGet возвращает временную ссылку на скачивание. Параметры response-content-type
+    // и response-content-disposition задают заголовки ответа.
+    PresignGet(ctx context.Context, bucket, key string, ttl time.Duration, params url.Values) (string, error)
+    // PresignPut возвращает временную ссылку для загрузки объекта запросом PUT. Размер и тип
+    // содержимого входят в подпись: хранилище отклонит запрос с другими Content-Length и Content-Type.
+    PresignPut(ctx context.Context, bucket, key string, ttl time.Duration, size int64, contentType string) (string, error)
+}
+
+// Multipart — необязательная поддержка составной загрузки, в которой объект собирается из частей
+type Multipart interface {
+    CreateMultipart(ctx context.Context, bucket, key, contentType string) (string, error)
+    PutPart(ctx context.Context, bucket, key, uploadID string, number int, r io.Reader, size int64) error
+    CompleteMultipart(ctx context.Context, bucket, key, uploadID string) error
+    AbortMultipart(ctx context.Context, bucket, key, uploadID string) error
+}
+
+// Store — хранилище, выбранное в конфигурации; задается через Init
+var Store BlobStore
+
+// presignTTL — время жизни подписанных ссылок по умолчанию
+var presignTTL = 15 * time.Minute
+
+// Pinger — необязательная проверка доступности хранилища
+type Pinger interface {
+    // Ping проверяет, что хранилище отвечает и бакеты сервера существуют
+    Ping(ctx context.Context) error
+}
+
+// Init создает хранилище выбранного в конфигурации драйвера и бакеты сервера
+func Init(cfg *config.Config) error {
+    store, err := New(cfg)
+    if err != nil {
+        return err
+    }
+    for _, bucket := range config.Buckets {
+        if err := store.EnsureBucket(context.Background(), bucket); err != nil {
+            return fmt.Errorf("ошибка создания бакета %s: %w", bucket, err)
+        }
+    }
+    if cfg.Storage.PresignTTL > 0 {
+        presignTTL = time.Duration(cfg.Storage.PresignTTL) * time.Second
+    }
+    Store = store
+    return nil
+}
+
+// Ping проверяет доступность хранилища; хранилище в памяти доступно всегда
+func Ping(ctx context.Context) error {
+    if pinger, ok := Store.(Pinger); ok {
+        return pinger.Ping(ctx)
+    }
+    return nil
+}
+
+// New создает хранилище драйвера cfg.Storage.Driver
+func New(cfg *config.Config) (BlobStore, error) {
+    switch cfg.Storage.Driver {
+    case "", "minio":
+        return NewMinio(cfg.Minio)
+    case "local":
+        return NewLocal(cfg.Storage.LocalPath, NewSigner(cfg.Storage.SigningKey, cfg.Storage.PublicURL))
+    case "memory":
+        return NewMemory(NewSigner(cfg.Storage.SigningKey, cfg.Storage.PublicURL)), nil
+    default:
+        return nil, fmt.Errorf("неизвестный драйвер хранилища %q", cfg.Storage.Driver)
+    }
+}
+
+// PresignTTL возвращает время жизни подписанных ссылок по умолчанию
+func PresignTTL() time.Duration {
+    return presignTTL
+}
+
+// PresignedGetURL возвращает подписанную ссылку на скачивание со сроком действия по умолчанию
+func PresignedGetURL(ctx context.Context, bucket, key string, params url.Values) (string, error) {
+    return Store.PresignGet(ctx, bucket, key, presignTTL, params)
+}
+
+// PresignedPutURL возвращает подписанную ссылку для загрузки объекта размером size
+// с типом contentType запросом PUT
+func PresignedPutURL(ctx context.Context, bucket, key string, ttl time.Duration, size int64, contentType string) (string, error) {
+    return Store.PresignPut(ctx, bucket, key, ttl, size, contentType)
+}
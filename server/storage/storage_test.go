@@ -0,0 +1,222 @@
+package storage
+
+import (
+    "context"
+    "errors"
+    "io"
+    "strings"
+    "testing"
+)
+
+const testBucket = "test"
+
+// drivers возвращает хранилища, которые проверяются одинаковыми тестами
+func drivers(t *testing.T) map[string]BlobStore {
+    t.Helper()
+    signer := NewSigner("test-key", "http://localhost:8080")
+    local, err := NewLocal(t.TempDir(), signer)
+    if err != nil {
+        t.Fatal(err)
+    }
+    stores := map[string]BlobStore{"local": local, "memory": NewMemory(signer)}
+    for name, store := range stores {
+        if err := store.EnsureBucket(context.Background(), testBucket); err != nil {
+            t.Fatalf("%s: %v", name, err)
+        }
+    }
+    return stores
+}
+
+// put сохраняет объект с содержимым content
+func put(t *testing.T, store BlobStore, key, content string) {
+    t.Helper()
+    if err := store.Put(context.Background(), testBucket, key, strings.NewReader(content), int64(len(content)), "text/plain"); err != nil {
+        t.Fatal(err)
+    }
+}
+
+// keys возвращает ключи объектов бакета в порядке обхода List
+func keys(t *testing.T, store BlobStore) []string {
+    t.Helper()
+    var listed []string
+    err := store.List(context.Background(), testBucket, func(object ListedObject) error {
+        listed = append(listed, object.Key)
+        return nil
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    return listed
+}
+
+func TestPutGetDelete(t *testing.T) {
+    ctx := context.Background()
+    for name, store := range drivers(t) {
+        t.Run(name, func(t *testing.T) {
+            put(t, store, "dir/note.txt", "первая версия")
+            put(t, store, "dir/note.txt", "вторая версия")
+
+            object, info, err := store.Get(ctx, testBucket, "dir/note.txt")
+            if err != nil {
+                t.Fatal(err)
+            }
+            data, err := io.ReadAll(object)
+            object.Close()
+            if err != nil {
+                t.Fatal(err)
+            }
+            if string(data) != "вторая версия" || info.Size != int64(len("вторая версия")) {
+                t.Fatalf("содержимое %q, размер %d", data, info.Size)
+            }
+
+            // Объект поддерживает перемотку для ответов на запросы с Range
+            object, _, err = store.Get(ctx, testBucket, "dir/note.txt")
+            if err != nil {
+                t.Fatal(err)
+            }
+            if _, err := object.Seek(int64(len("вторая ")), io.SeekStart); err != nil {
+                t.Fatal(err)
+            }
+            data, _ = io.ReadAll(object)
+            object.Close()
+            if string(data) != "версия" {
+                t.Fatalf("после перемотки прочитано %q", data)
+            }
+
+            if err := store.Delete(ctx, testBucket, "dir/note.txt"); err != nil {
+                t.Fatal(err)
+            }
+            if _, err := store.Stat(ctx, testBucket, "dir/note.txt"); !errors.Is(err, ErrNotFound) {
+                t.Fatalf("после удаления Stat вернул %v", err)
+            }
+            // Повторное удаление ошибкой не считается
+            if err := store.Delete(ctx, testBucket, "dir/note.txt"); err != nil {
+                t.Fatalf("повторное удаление: %v", err)
+            }
+        })
+    }
+}
+
+func TestNotFound(t *testing.T) {
+    ctx := context.Background()
+    for name, store := range drivers(t) {
+        t.Run(name, func(t *testing.T) {
+            if _, _, err := store.Get(ctx, testBucket, "missing"); !errors.Is(err, ErrNotFound) {
+                t.Fatalf("Get вернул %v", err)
+            }
+            if _, err := store.Stat(ctx, testBucket, "missing"); !errors.Is(err, ErrNotFound) {
+                t.Fatalf("Stat вернул %v", err)
+            }
+        })
+    }
+}
+
+func TestPutShortBody(t *testing.T) {
+    ctx := context.Background()
+    for name, store := range drivers(t) {
+        t.Run(name, func(t *testing.T) {
+            if err := store.Put(ctx, testBucket, "short", strings.NewReader("abc"), 10, "text/plain"); err == nil {
+                t.Fatal("сохранен объект короче заявленного размера")
+            }
+            if _, err := store.Stat(ctx, testBucket, "short"); !errors.Is(err, ErrNotFound) {
+                t.Fatalf("после неудачной записи Stat вернул %v", err)
+            }
+        })
+    }
+}
+
+func TestList(t *testing.T) {
+    ctx := context.Background()
+    for name, store := range drivers(t) {
+        t.Run(name, func(t *testing.T) {
+            for _, key := range []string{"b.txt", "a/2.txt", "a/1.txt"} {
+                put(t, store, key, key)
+            }
+            want := []string{"a/1.txt", "a/2.txt", "b.txt"}
+            if got := keys(t, store); strings.Join(got, ",") != strings.Join(want, ",") {
+                t.Fatalf("ключи %v, ожидались %v", got, want)
+            }
+
+            // fn может удалять объекты во время обхода
+            err := store.List(ctx, testBucket, func(object ListedObject) error {
+                return store.Delete(ctx, testBucket, object.Key)
+            })
+            if err != nil {
+                t.Fatal(err)
+            }
+            if got := keys(t, store); len(got) != 0 {
+                t.Fatalf("после удаления остались ключи %v", got)
+            }
+
+            // Ошибка fn прерывает обход
+            put(t, store, "a.txt", "a")
+            put(t, store, "b.txt", "b")
+            stop := errors.New("стоп")
+            visited := 0
+            err = store.List(ctx, testBucket, func(object ListedObject) error {
+                visited++
+                return stop
+            })
+            if !errors.Is(err, stop) || visited != 1 {
+                t.Fatalf("ошибка %v после %d объектов", err, visited)
+            }
+        })
+    }
+}
+
+func TestLocalInvalidKey(t *testing.T) {
+    store, err := NewLocal(t.TempDir(), NewSigner("test-key", ""))
+    if err != nil {
+        t.Fatal(err)
+    }
+    tests := []struct {
+        name   string
+        bucket string
+        key    string
+    }{
+        {name: "выход из бакета", bucket: testBucket, key: "../other/secret"},
+        {name: "абсолютный путь", bucket: testBucket, key: "/etc/passwd"},
+        {name: "пустой ключ", bucket: testBucket, key: ""},
+        {name: "бакет с разделителем", bucket: "a/b", key: "key"},
+        {name: "скрытый бакет", bucket: ".hidden", key: "key"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            err := store.Put(context.Background(), tt.bucket, tt.key, strings.NewReader("x"), 1, "text/plain")
+            if !errors.Is(err, ErrInvalidKey) {
+                t.Fatalf("Put вернул %v, ожидалась ErrInvalidKey", err)
+            }
+        })
+    }
+}
+
+func TestMultipart(t *testing.T) {
+    ctx := context.Background()
+    for name, store := range drivers(t) {
+        t.Run(name, func(t *testing.T) {
+            mp := store.(Multipart)
+            uploadID, err := mp.CreateMultipart(ctx, testBucket, "assembled", "text/plain")
+            if err != nil {
+                t.Fatal(err)
+            }
+            for i, part := range []string{"первая ", "вторая"} {
+                if err := mp.PutPart(ctx, testBucket, "assembled", uploadID, i+1, strings.NewReader(part), int64(len(part))); err != nil {
+                    t.Fatal(err)
+                }
+            }
+            if err := mp.CompleteMultipart(ctx, testBucket, "assembled", uploadID); err != nil {
+                t.Fatal(err)
+            }
+
+            object, _, err := store.Get(ctx, testBucket, "assembled")
+            if err != nil {
+                t.Fatal(err)
+            }
+            data, _ := io.ReadAll(object)
+            object.Close()
+            if string(data) != "первая вторая" {
+                t.Fatalf("собранный объект %q", data)
+            }
+        })
+    }
+}
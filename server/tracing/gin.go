@@ -0,0 +1,54 @@
+package tracing
+
+import (
+    "net/http"
+
+    "chatter-hub-server/logging"
+
+    "github.com/gin-gonic/gin"
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/propagation"
+    semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+    "go.opentelemetry.io/otel/trace"
+)
+
+// Middleware создает спан для каждого HTTP-запроса. Контекст трассировки берется из заголовка
+// traceparent, если запрос пришел от другого сервиса. Идентификатор трассы добавляется в журнал запроса.
+// Подключается после logging.RequestID.
+func Middleware() gin.HandlerFunc {
+    return func(c *gin.Context) {
+        ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+        route := c.FullPath()
+        name := c.Request.Method
+        if route != "" {
+            name += " " + route
+        }
+        ctx, span := Tracer().Start(ctx, name,
+            trace.WithSpanKind(trace.SpanKindServer),
+            trace.WithAttributes(
+                semconv.HTTPRequestMethodKey.String(c.Request.Method),
+                semconv.HTTPRoute(route),
+                semconv.URLPath(c.Request.URL.Path),
+                semconv.ClientAddress(c.ClientIP()),
+                semconv.UserAgentOriginal(c.Request.UserAgent()),
+            ))
+        defer span.End()
+
+        c.Request = c.Request.WithContext(ctx)
+        if span.SpanContext().IsSampled() {
+            logging.With(c, "trace_id", span.SpanContext().TraceID().String())
+        }
+        c.Next()
+
+        status := c.Writer.Status()
+        span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+        if userID := c.GetString("userID"); userID != "" {
+            span.SetAttributes(semconv.EnduserID(userID))
+        }
+        if status >= http.StatusInternalServerError {
+            span.SetStatus(codes.Error, http.StatusText(status))
+        }
+    }
+}
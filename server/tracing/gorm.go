@@ -0,0 +1,78 @@
+package tracing
+
+import (
+    "errors"
+
+    "go.opentelemetry.io/otel/attribute"
+    semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+    "go.opentelemetry.io/otel/trace"
+    "gorm.io/gorm"
+)
+
+const gormSpanKey = "tracing:span"
+
+var rowsAffected = attribute.Key("db.rows_affected")
+
+// gormPlugin создает спан для каждого запроса GORM в контексте, переданном через WithContext
+type gormPlugin struct{}
+
+// GormPlugin возвращает плагин трассировки для подключения через DB.Use
+func GormPlugin() gorm.Plugin {
+    return gormPlugin{}
+}
+
+func (gormPlugin) Name() string {
+    return "tracing"
+}
+
+func (gormPlugin) Initialize(db *gorm.DB) error {
+    callback := db.Callback()
+    return errors.Join(
+        callback.Create().Before("gorm:create").Register("tracing:before_create", startGormSpan("create")),
+        callback.Create().After("gorm:create").Register("tracing:after_create", endGormSpan),
+        callback.Query().Before("gorm:query").Register("tracing:before_query", startGormSpan("query")),
+        callback.Query().After("gorm:query").Register("tracing:after_query", endGormSpan),
+        callback.Update().Before("gorm:update").Register("tracing:before_update", startGormSpan("update")),
+        callback.Update().After("gorm:update").Register("tracing:after_update", endGormSpan),
+        callback.Delete().Before("gorm:delete").Register("tracing:before_delete", startGormSpan("delete")),
+        callback.Delete().After("gorm:delete").Register("tracing:after_delete", endGormSpan),
+        callback.Row().Before("gorm:row").Register("tracing:before_row", startGormSpan("row")),
+        callback.Row().After("gorm:row").Register("tracing:after_row", endGormSpan),
+        callback.Raw().Before("gorm:raw").Register("tracing:before_raw", startGormSpan("raw")),
+        callback.Raw().After("gorm:raw").Register("tracing:after_raw", endGormSpan),
+    )
+}
+
+func startGormSpan(operation string) func(*gorm.DB) {
+    return func(db *gorm.DB) {
+        system := semconv.DBSystemPostgreSQL
+        if db.Dialector.Name() == "sqlite" {
+            system = semconv.DBSystemSqlite
+        }
+        ctx, span := StartChild(db.Statement.Context, "gorm."+operation,
+            trace.WithSpanKind(trace.SpanKindClient),
+            trace.WithAttributes(system, semconv.DBOperationName(operation)))
+        db.Statement.Context = ctx
+        db.InstanceSet(gormSpanKey, span)
+    }
+}
+
+// endGormSpan завершает спан запроса. В спан попадает текст запроса без значений параметров.
+func endGormSpan(db *gorm.DB) {
+    value, ok := db.InstanceGet(gormSpanKey)
+    if !ok {
+        return
+    }
+    span := value.(trace.Span)
+    if db.Statement.Table != "" {
+        span.SetAttributes(semconv.DBCollectionName(db.Statement.Table))
+    }
+    span.SetAttributes(semconv.DBQueryText(db.Statement.SQL.String()))
+    span.SetAttributes(rowsAffected.Int64(db.Statement.RowsAffected))
+
+    err := db.Error
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        err = nil
+    }
+    End(span, err)
+}
@@ -0,0 +1,55 @@
+package tracing
+
+import (
+    "context"
+    "errors"
+
+    "github.com/go-redis/redis/v8"
+    "go.opentelemetry.io/otel/attribute"
+    semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+    "go.opentelemetry.io/otel/trace"
+)
+
+var pipelineLength = attribute.Key("db.redis.pipeline_length")
+
+// RedisHook создает спаны команд и конвейеров Redis. Аргументы команд в спан не попадают.
+// Подключается к клиенту через AddHook.
+type RedisHook struct{}
+
+func (RedisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+    ctx, _ = StartChild(ctx, "redis."+cmd.Name(),
+        trace.WithSpanKind(trace.SpanKindClient),
+        trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperationName(cmd.Name())))
+    return ctx, nil
+}
+
+func (RedisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+    End(trace.SpanFromContext(ctx), redisError(cmd))
+    return nil
+}
+
+func (RedisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+    ctx, _ = StartChild(ctx, "redis.pipeline",
+        trace.WithSpanKind(trace.SpanKindClient),
+        trace.WithAttributes(semconv.DBSystemRedis, pipelineLength.Int(len(cmds))))
+    return ctx, nil
+}
+
+func (RedisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+    var err error
+    for _, cmd := range cmds {
+        if err = redisError(cmd); err != nil {
+            break
+        }
+    }
+    End(trace.SpanFromContext(ctx), err)
+    return nil
+}
+
+// redisError возвращает ошибку команды; отсутствие ключа (redis.Nil) ошибкой не считается
+func redisError(cmd redis.Cmder) error {
+    if err := cmd.Err(); err != nil && !errors.Is(err, redis.Nil) {
+        return err
+    }
+    return nil
+}
@@ -0,0 +1,97 @@
+// Package tracing настраивает трассировку OpenTelemetry: спаны HTTP-запросов, запросов GORM,
+// команд Redis и обращений к MinIO, распространение контекста в формате W3C Trace Context
+// и экспорт спанов по OTLP/HTTP или в стандартный вывод.
+package tracing
+
+import (
+    "context"
+    "fmt"
+
+    "chatter-hub-server/config"
+
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/codes"
+    "go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+    "go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+    "go.opentelemetry.io/otel/propagation"
+    "go.opentelemetry.io/otel/sdk/resource"
+    sdktrace "go.opentelemetry.io/otel/sdk/trace"
+    semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+    "go.opentelemetry.io/otel/trace"
+)
+
+// Экспортеры спанов
+const (
+    ExporterOTLP   = "otlp"
+    ExporterStdout = "stdout"
+)
+
+const instrumentationName = "chatter-hub-server"
+
+// Tracer возвращает трассировщик текущего глобального провайдера. До Init и при отключенной
+// трассировке спаны не записываются. Провайдер не запоминается, чтобы его замена
+// (например, в тестах) сразу действовала на все спаны.
+func Tracer() trace.Tracer {
+    return otel.Tracer(instrumentationName)
+}
+
+// Init настраивает экспорт спанов и распространение контекста трассировки.
+// Возвращает функцию, которая отправляет накопленные спаны и останавливает экспорт.
+func Init(cfg *config.Config) (func(context.Context) error, error) {
+    // Заголовки traceparent и baggage принимаются и передаются дальше, даже если спаны не экспортируются
+    otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+    var exporter sdktrace.SpanExporter
+    var err error
+    switch cfg.Tracing.Exporter {
+    case "":
+        return func(context.Context) error { return nil }, nil
+    case ExporterOTLP:
+        var options []otlptracehttp.Option
+        if cfg.Tracing.Endpoint != "" {
+            options = append(options, otlptracehttp.WithEndpointURL(cfg.Tracing.Endpoint))
+        }
+        exporter, err = otlptracehttp.New(context.Background(), options...)
+    case ExporterStdout:
+        exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+    default:
+        return nil, fmt.Errorf("неизвестный экспортер трассировки %q", cfg.Tracing.Exporter)
+    }
+    if err != nil {
+        return nil, err
+    }
+
+    res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+        semconv.ServiceName(cfg.Tracing.ServiceName)))
+    if err != nil {
+        return nil, err
+    }
+
+    provider := sdktrace.NewTracerProvider(
+        sdktrace.WithBatcher(exporter),
+        sdktrace.WithResource(res),
+        // Решение о записи принимает первый сервис в цепочке; свои запросы сервер выбирает с долей SampleRatio
+        sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+    )
+    otel.SetTracerProvider(provider)
+    return provider.Shutdown, nil
+}
+
+// StartChild создает спан, только если ctx уже относится к трассе: запросы к базе данных,
+// Redis и хранилищу трассируются в составе HTTP-запроса или фоновой задачи, а не отдельными трассами.
+// Без родительского спана возвращает ctx и пустой спан.
+func StartChild(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+    if !trace.SpanContextFromContext(ctx).IsValid() {
+        return ctx, trace.SpanFromContext(ctx)
+    }
+    return Tracer().Start(ctx, name, opts...)
+}
+
+// End отмечает ошибку в спане и завершает его
+func End(span trace.Span, err error) {
+    if err != nil {
+        span.RecordError(err)
+        span.SetStatus(codes.Error, err.Error())
+    }
+    span.End()
+}
@@ -0,0 +1,39 @@
+package transcribe
+
+import (
+    "context"
+    "io"
+    "sync"
+)
+
+// Fake возвращает заданный результат без обращения к серверу. Используется в тестах
+// и при локальном запуске.
+type Fake struct {
+    Text     string
+    Language string
+    Err      error
+
+    mu    sync.Mutex
+    calls int
+}
+
+func (f *Fake) Transcribe(ctx context.Context, audio io.Reader, fileName string) (*Result, error) {
+    f.mu.Lock()
+    f.calls++
+    f.mu.Unlock()
+
+    if _, err := io.Copy(io.Discard, audio); err != nil {
+        return nil, err
+    }
+    if f.Err != nil {
+        return nil, f.Err
+    }
+    return &Result{Text: f.Text, Language: f.Language}, nil
+}
+
+// Calls возвращает количество вызовов Transcribe
+func (f *Fake) Calls() int {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return f.calls
+}
@@ -0,0 +1,175 @@
+// Package transcribe распознает речь в голосовых сообщениях. Сообщения ставятся в очередь
+// фоновых задач, обработчик передает файл реализации Transcriber и сохраняет текст.
+package transcribe
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "io"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/jobs"
+    "chatter-hub-server/logging"
+    "chatter-hub-server/notify"
+    "chatter-hub-server/storage"
+)
+
+// EventTranscription — уведомление о завершении распознавания голосового сообщения
+const EventTranscription = "message.voice.transcription"
+
+// JobType — тип фоновой задачи распознавания
+const JobType = "transcription"
+
+// Result — результат распознавания
+type Result struct {
+    Text     string
+    Language string // Код языка ISO 639-1
+}
+
+// Transcriber распознает речь в аудиофайле
+type Transcriber interface {
+    Transcribe(ctx context.Context, audio io.Reader, fileName string) (*Result, error)
+}
+
+// TranscriptionEvent — содержимое уведомления EventTranscription
+type TranscriptionEvent struct {
+    MessageID uint   `json:"message_id"`
+    Status    string `json:"status"`
+    Text      string `json:"text,omitempty"`
+    Language  string `json:"language,omitempty"`
+}
+
+// jobPayload — данные задачи распознавания
+type jobPayload struct {
+    MessageID uint `json:"message_id"`
+}
+
+// transcriber — реализация, выбранная в конфигурации; nil — распознавание отключено
+var transcriber Transcriber
+
+// Init выбирает реализацию распознавания и регистрирует обработчик фоновых задач
+func Init(cfg *config.Config) {
+    timeout := time.Duration(cfg.Transcription.Timeout) * time.Second
+    switch cfg.Transcription.Driver {
+    case "":
+        return
+    case "whisper":
+        transcriber = NewWhisper(cfg.Transcription.URL, cfg.Transcription.APIKey, cfg.Transcription.Model, timeout)
+    case "fake":
+        transcriber = &Fake{Text: "Тестовая расшифровка", Language: "ru"}
+    default:
+        logging.Fatal("Неизвестный драйвер распознавания речи", "driver", cfg.Transcription.Driver)
+    }
+
+    jobs.Register(JobType, handle, jobs.Options{MaxAttempts: 3, Timeout: timeout + time.Minute})
+}
+
+// Queue ставит голосовые сообщения в очередь на распознавание
+type Queue interface {
+    // Enabled сообщает, включено ли распознавание речи
+    Enabled() bool
+    Enqueue(ctx context.Context, messageID uint) error
+}
+
+type jobQueue struct{}
+
+// NewQueue возвращает очередь распознавания на основе очереди фоновых задач.
+// Распознавание включено, если реализация выбрана через Init.
+func NewQueue() Queue {
+    return jobQueue{}
+}
+
+func (jobQueue) Enabled() bool {
+    return transcriber != nil
+}
+
+func (jobQueue) Enqueue(ctx context.Context, messageID uint) error {
+    _, err := jobs.Enqueue(ctx, JobType, jobPayload{MessageID: messageID}, jobs.EnqueueOptions{})
+    return err
+}
+
+// handle обрабатывает задачу распознавания. Сообщение помечается как нераспознанное
+// только после последней неудачной попытки, до этого оно снова ждет в очереди.
+func handle(ctx context.Context, job *config.Job) error {
+    var payload jobPayload
+    if err := jobs.DecodePayload(job, &payload); err != nil {
+        return err
+    }
+    return Process(ctx, transcriber, payload.MessageID, jobs.IsFinalAttempt(job))
+}
+
+// Process распознает голосовое сообщение, сохраняет текст и уведомляет участников переписки.
+// При ошибке распознавания с final сообщение помечается как нераспознанное, без final —
+// возвращается в ожидание повторной попытки.
+func Process(ctx context.Context, t Transcriber, messageID uint, final bool) error {
+    db := config.DB.WithContext(ctx)
+    var message config.VoiceMessage
+    if err := db.First(&message, messageID).Error; err != nil {
+        return err
+    }
+    if message.TranscriptionStatus == config.TranscriptionCompleted {
+        return nil
+    }
+    if err := db.Model(&message).Update("transcription_status", config.TranscriptionProcessing).Error; err != nil {
+        return err
+    }
+
+    result, err := transcribeMessage(ctx, t, &message)
+    if err != nil {
+        // Статус сохраняем и тогда, когда распознавание прервала остановка сервера
+        statusDB := config.DB.WithContext(context.WithoutCancel(ctx))
+        if !final {
+            statusDB.Model(&message).Update("transcription_status", config.TranscriptionPending)
+            return err
+        }
+        statusDB.Model(&message).Update("transcription_status", config.TranscriptionFailed)
+        publish(ctx, &message, TranscriptionEvent{MessageID: message.ID, Status: config.TranscriptionFailed})
+        return err
+    }
+
+    err = db.Model(&message).Updates(map[string]interface{}{
+        "transcription_status": config.TranscriptionCompleted,
+        "transcript":           result.Text,
+        "transcript_language":  result.Language,
+    }).Error
+    if err != nil {
+        return err
+    }
+
+    publish(ctx, &message, TranscriptionEvent{
+        MessageID: message.ID,
+        Status:    config.TranscriptionCompleted,
+        Text:      result.Text,
+        Language:  result.Language,
+    })
+    return nil
+}
+
+func transcribeMessage(ctx context.Context, t Transcriber, message *config.VoiceMessage) (*Result, error) {
+    if message.ObjectKey == "" {
+        return nil, errors.New("у сообщения нет аудиофайла")
+    }
+    object, _, err := storage.Store.Get(ctx, config.VoiceBucket, message.ObjectKey)
+    if err != nil {
+        return nil, fmt.Errorf("получение аудиофайла: %w", err)
+    }
+    defer object.Close()
+
+    fileName := "voice"
+    if message.AttachmentID != "" {
+        var attachment config.Attachment
+        if config.DB.WithContext(ctx).Select("file_name").First(&attachment, "id = ?", message.AttachmentID).Error == nil && attachment.FileName != "" {
+            fileName = attachment.FileName
+        }
+    }
+    return t.Transcribe(ctx, object, fileName)
+}
+
+// publish уведомляет отправителя и получателя сообщения о результате распознавания
+func publish(ctx context.Context, message *config.VoiceMessage, payload TranscriptionEvent) {
+    event := notify.Event{Type: EventTranscription, From: message.SenderID, Payload: payload}
+    notify.Publish(ctx, message.SenderID, event)
+    notify.Publish(ctx, message.ReceiverID, event)
+}
@@ -0,0 +1,225 @@
+package transcribe
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "log/slog"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+
+    "chatter-hub-server/config"
+    "chatter-hub-server/notify"
+    "chatter-hub-server/storage"
+)
+
+func TestMain(m *testing.M) {
+    slog.SetDefault(slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+    dir, err := os.MkdirTemp("", "chatter-hub-transcribe")
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    cfg := &config.Config{
+        Database: config.DatabaseConfig{Driver: config.DatabaseSQLite, SQLitePath: filepath.Join(dir, "test.db")},
+        Storage:  config.StorageConfig{Driver: "memory"},
+    }
+    if err := config.InitDB(cfg); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    if err := storage.Init(cfg); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+
+    code := m.Run()
+    os.RemoveAll(dir)
+    os.Exit(code)
+}
+
+// createMessage сохраняет голосовое сообщение с аудиофайлом в хранилище
+func createMessage(t *testing.T, status string) *config.VoiceMessage {
+    t.Helper()
+    message := &config.VoiceMessage{SenderID: "alice", ReceiverID: "bob", ObjectKey: fmt.Sprintf("voice-%d.ogg", time.Now().UnixNano()), TranscriptionStatus: status}
+    if err := storage.Store.Put(context.Background(), config.VoiceBucket, message.ObjectKey, strings.NewReader("audio"), 5, "audio/ogg"); err != nil {
+        t.Fatal(err)
+    }
+    if err := config.DB.Create(message).Error; err != nil {
+        t.Fatal(err)
+    }
+    return message
+}
+
+// receivedEvents возвращает уведомления о распознавании, уже опубликованные в канал
+func receivedEvents(t *testing.T, events <-chan []byte) []TranscriptionEvent {
+    t.Helper()
+    var received []TranscriptionEvent
+    for {
+        select {
+        case data := <-events:
+            var event struct {
+                Type    string             `json:"type"`
+                Payload TranscriptionEvent `json:"payload"`
+            }
+            if err := json.Unmarshal(data, &event); err != nil {
+                t.Fatal(err)
+            }
+            if event.Type != EventTranscription {
+                t.Fatalf("неожиданный тип уведомления %q", event.Type)
+            }
+            received = append(received, event.Payload)
+        default:
+            return received
+        }
+    }
+}
+
+func TestProcess(t *testing.T) {
+    failure := errors.New("сервер распознавания недоступен")
+
+    tests := []struct {
+        name       string
+        status     string
+        fake       *Fake
+        final      bool
+        wantErr    bool
+        wantStatus string
+        wantCalls  int
+        wantEvent  bool
+    }{
+        {
+            name: "успешное распознавание", status: config.TranscriptionPending,
+            fake:       &Fake{Text: "Привет", Language: "ru"},
+            wantStatus: config.TranscriptionCompleted, wantCalls: 1, wantEvent: true,
+        },
+        {
+            name: "ошибка до последней попытки", status: config.TranscriptionPending,
+            fake:    &Fake{Err: failure},
+            wantErr: true, wantStatus: config.TranscriptionPending, wantCalls: 1,
+        },
+        {
+            name: "ошибка последней попытки", status: config.TranscriptionPending,
+            fake: &Fake{Err: failure}, final: true,
+            wantErr: true, wantStatus: config.TranscriptionFailed, wantCalls: 1, wantEvent: true,
+        },
+        {
+            name: "уже распознано", status: config.TranscriptionCompleted,
+            fake:       &Fake{Text: "Повтор"},
+            wantStatus: config.TranscriptionCompleted,
+        },
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            message := createMessage(t, tt.status)
+            senderEvents, cancelSender := notify.Default.Subscribe(context.Background(), message.SenderID)
+            defer cancelSender()
+            receiverEvents, cancelReceiver := notify.Default.Subscribe(context.Background(), message.ReceiverID)
+            defer cancelReceiver()
+
+            err := Process(context.Background(), tt.fake, message.ID, tt.final)
+            if (err != nil) != tt.wantErr {
+                t.Fatalf("ошибка %v, ожидалась ошибка: %v", err, tt.wantErr)
+            }
+            if tt.fake.Calls() != tt.wantCalls {
+                t.Fatalf("вызовов распознавания %d, ожидалось %d", tt.fake.Calls(), tt.wantCalls)
+            }
+
+            var saved config.VoiceMessage
+            if err := config.DB.First(&saved, message.ID).Error; err != nil {
+                t.Fatal(err)
+            }
+            if saved.TranscriptionStatus != tt.wantStatus {
+                t.Fatalf("статус %q, ожидался %q", saved.TranscriptionStatus, tt.wantStatus)
+            }
+            if tt.wantStatus == config.TranscriptionCompleted && tt.wantCalls > 0 &&
+                (saved.Transcript != tt.fake.Text || saved.TranscriptLanguage != tt.fake.Language) {
+                t.Fatalf("сохранен текст %q на языке %q", saved.Transcript, saved.TranscriptLanguage)
+            }
+
+            for _, events := range []<-chan []byte{senderEvents, receiverEvents} {
+                received := receivedEvents(t, events)
+                if !tt.wantEvent {
+                    if len(received) != 0 {
+                        t.Fatalf("неожиданные уведомления: %+v", received)
+                    }
+                    continue
+                }
+                want := TranscriptionEvent{MessageID: message.ID, Status: tt.wantStatus, Text: tt.fake.Text, Language: tt.fake.Language}
+                if tt.fake.Err != nil {
+                    want = TranscriptionEvent{MessageID: message.ID, Status: tt.wantStatus}
+                }
+                if len(received) != 1 || received[0] != want {
+                    t.Fatalf("уведомления %+v, ожидалось %+v", received, want)
+                }
+            }
+        })
+    }
+}
+
+func TestWhisper(t *testing.T) {
+    tests := []struct {
+        name         string
+        status       int
+        body         string
+        wantErr      bool
+        wantText     string
+        wantLanguage string
+    }{
+        {name: "код языка", status: http.StatusOK, body: `{"text":" Привет, мир ","language":"ru"}`, wantText: "Привет, мир", wantLanguage: "ru"},
+        {name: "название языка", status: http.StatusOK, body: `{"text":"Hello","language":"English"}`, wantText: "Hello", wantLanguage: "en"},
+        {name: "ошибка сервера", status: http.StatusBadGateway, body: "модель не загружена", wantErr: true},
+        {name: "некорректный ответ", status: http.StatusOK, body: "не json", wantErr: true},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+                if r.URL.Path != "/v1/audio/transcriptions" || r.Header.Get("Authorization") != "Bearer key" {
+                    http.Error(w, "неожиданный запрос", http.StatusBadRequest)
+                    return
+                }
+                if r.FormValue("model") != "whisper-1" || r.FormValue("response_format") != "verbose_json" {
+                    http.Error(w, "неожиданные параметры", http.StatusBadRequest)
+                    return
+                }
+                file, header, err := r.FormFile("file")
+                if err != nil || header.Filename != "voice.ogg" {
+                    http.Error(w, "нет файла", http.StatusBadRequest)
+                    return
+                }
+                if content, _ := io.ReadAll(file); string(content) != "audio" {
+                    http.Error(w, "неожиданное содержимое файла", http.StatusBadRequest)
+                    return
+                }
+                w.WriteHeader(tt.status)
+                io.WriteString(w, tt.body)
+            }))
+            defer server.Close()
+
+            whisper := NewWhisper(server.URL+"/", "key", "whisper-1", time.Second)
+            result, err := whisper.Transcribe(context.Background(), strings.NewReader("audio"), "voice.ogg")
+            if tt.wantErr {
+                if err == nil {
+                    t.Fatalf("ожидалась ошибка, получен результат %+v", result)
+                }
+                return
+            }
+            if err != nil {
+                t.Fatal(err)
+            }
+            if result.Text != tt.wantText || result.Language != tt.wantLanguage {
+                t.Fatalf("результат %+v, ожидался текст %q на языке %q", result, tt.wantText, tt.wantLanguage)
+            }
+        })
+    }
+}
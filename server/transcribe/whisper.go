@@ -0,0 +1,115 @@
+package transcribe
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "io"
+    "mime/multipart"
+    "net/http"
+    "strings"
+    "time"
+
+    "go.opentelemetry.io/otel"
+    "go.opentelemetry.io/otel/propagation"
+)
+
+// Whisper обращается к серверу с API, совместимым с OpenAI Whisper
+// (POST /v1/audio/transcriptions): faster-whisper-server, LocalAI, whisper.cpp server и т. п.
+type Whisper struct {
+    url    string
+    apiKey string
+    model  string
+    client *http.Client
+}
+
+// NewWhisper создает клиент сервера распознавания с адресом baseURL
+func NewWhisper(baseURL, apiKey, model string, timeout time.Duration) *Whisper {
+    return &Whisper{
+        url:    strings.TrimSuffix(baseURL, "/") + "/v1/audio/transcriptions",
+        apiKey: apiKey,
+        model:  model,
+        client: &http.Client{Timeout: timeout},
+    }
+}
+
+// whisperResponse — ответ в формате verbose_json
+type whisperResponse struct {
+    Text     string `json:"text"`
+    Language string `json:"language"`
+}
+
+// Transcribe отправляет файл на сервер распознавания. Тело запроса формируется потоково,
+// чтобы не держать файл в памяти.
+func (w *Whisper) Transcribe(ctx context.Context, audio io.Reader, fileName string) (*Result, error) {
+    body, writer := io.Pipe()
+    form := multipart.NewWriter(writer)
+    go func() {
+        writer.CloseWithError(writeForm(form, audio, fileName, w.model))
+    }()
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, body)
+    if err != nil {
+        body.Close()
+        return nil, err
+    }
+    req.Header.Set("Content-Type", form.FormDataContentType())
+    if w.apiKey != "" {
+        req.Header.Set("Authorization", "Bearer "+w.apiKey)
+    }
+    // Передаем контекст трассировки, чтобы распознавание попало в трассу задачи
+    otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+    resp, err := w.client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+        return nil, fmt.Errorf("сервер распознавания вернул %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+    }
+
+    var result whisperResponse
+    if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+        return nil, fmt.Errorf("разбор ответа сервера распознавания: %w", err)
+    }
+    return &Result{Text: strings.TrimSpace(result.Text), Language: normalizeLanguage(result.Language)}, nil
+}
+
+func writeForm(form *multipart.Writer, audio io.Reader, fileName, model string) error {
+    if err := form.WriteField("model", model); err != nil {
+        return err
+    }
+    if err := form.WriteField("response_format", "verbose_json"); err != nil {
+        return err
+    }
+    part, err := form.CreateFormFile("file", fileName)
+    if err != nil {
+        return err
+    }
+    if _, err := io.Copy(part, audio); err != nil {
+        return err
+    }
+    return form.Close()
+}
+
+// whisperLanguages сопоставляет названия языков, которые возвращают некоторые серверы, с кодами ISO 639-1
+var whisperLanguages = map[string]string{
+    "russian":   "ru",
+    "english":   "en",
+    "ukrainian": "uk",
+    "german":    "de",
+    "french":    "fr",
+    "spanish":   "es",
+}
+
+// normalizeLanguage приводит язык к коду ISO 639-1, если сервер вернул его название
+func normalizeLanguage(language string) string {
+    language = strings.ToLower(strings.TrimSpace(language))
+    if code, ok := whisperLanguages[language]; ok {
+        return code
+    }
+    return language
+}